@@ -24,6 +24,8 @@ func newAwsCommand() *cobra.Command {
 	addCommand(cmd, newAwsUninstallCommand())
 	addCommand(cmd, newAwsNodesList())
 	addCommand(cmd, newAwsResources())
+	addCommand(cmd, newAwsRotateKeysCommand())
+	addCommand(cmd, newAwsGCCommand())
 	return cmd
 }
 
@@ -86,6 +88,7 @@ func newAwsInstallCommand() *cobra.Command {
 	}
 	setUsageTemplate(cmd, texts.AwsInstall.Arguments)
 	bindAwsInstallFlags(cmd, a)
+	cmd.Flags().IntVar(&a.LogRetentionDays, "log-retention-days", domain.DefaultLogRetentionDays, "Retention of the node log groups in days")
 	return cmd
 }
 
@@ -107,7 +110,11 @@ func newAwsUpgradeCommand() *cobra.Command {
 				showAwsDryRunInfo(a)
 				return nil
 			}
-			if err := stp.Upgrade(domain.Version(), domain.Deployment().GetPath); err != nil {
+			d := domain.Deployment()
+			if a.Version != "" {
+				d = domain.ReleaseDeployment(a.Version)
+			}
+			if err := stp.Upgrade(d.String(), d.GetPath); err != nil {
 				return log.Wrap(err)
 			}
 			showNextSteps(texts.AwsUpgrade.NextSteps)
@@ -116,6 +123,37 @@ func newAwsUpgradeCommand() *cobra.Command {
 	}
 	setUsageTemplate(cmd, texts.AwsUpgrade.Arguments)
 	bindAwsInstallFlags(cmd, a)
+	cmd.Flags().StringVar(&a.Version, "to", "", "Released version to upgrade or downgrade the node to instead of the CLI version")
+	cmd.Flags().BoolVar(&a.Force, "force", false, "Downgrade even if project stages were deployed with a newer version")
+	return cmd
+}
+
+func newAwsRotateKeysCommand() *cobra.Command {
+	a := &controller.SetupArgs{}
+	cmd := &cobra.Command{
+		Use:     "rotate-keys [node-name] [options]",
+		Short:   texts.AwsRotateKeys.Short,
+		Long:    texts.AwsRotateKeys.Long,
+		Example: texts.AwsRotateKeys.Examples,
+		Args:    cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			a.ParseArgs(args)
+			stp, err := controller.NewSetup(a)
+			if err != nil {
+				return log.Wrap(err)
+			}
+			if a.DryRun {
+				showAwsDryRunInfo(a)
+				return nil
+			}
+			if err := stp.RotateKeys(); err != nil {
+				return log.Wrap(err)
+			}
+			return nil
+		},
+	}
+	setUsageTemplate(cmd, texts.AwsRotateKeys.Arguments)
+	bindAwsInstallFlags(cmd, a)
 	return cmd
 }
 
@@ -161,14 +199,46 @@ func newAwsUninstallCommand() *cobra.Command {
 	return cmd
 }
 
+func newAwsGCCommand() *cobra.Command {
+	a := &controller.SetupArgs{}
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:     "gc [node-name] [options]",
+		Short:   texts.AwsGC.Short,
+		Long:    texts.AwsGC.Long,
+		Example: texts.AwsGC.Examples,
+		Args:    cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			a.ParseArgs(args)
+			stp, err := controller.NewSetup(a)
+			if err != nil {
+				return log.Wrap(err)
+			}
+			if err := stp.GC(dryRun); err != nil {
+				return log.Wrap(err)
+			}
+			return nil
+		},
+	}
+	setUsageTemplate(cmd, texts.AwsGC.Arguments)
+	bindAwsCredentialsFlags(cmd, a)
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only list orphaned resources without deleting them")
+	cmd.Flags().BoolVarP(&a.Yes, "yes", "y", false, "Assume 'yes' as answer to all prompts")
+	return cmd
+}
+
 func bindAwsInstallFlags(cmd *cobra.Command, a *controller.SetupArgs) {
+	bindAwsCredentialsFlags(cmd, a)
+	cmd.Flags().BoolVar(&a.DryRun, "dry-run", false, "Don't start install/uninstall just show what credentials will be used")
+	cmd.Flags().StringVar(&a.GithubUser, "github-user", "", "The GitHub user that owns the node")
+}
+
+func bindAwsCredentialsFlags(cmd *cobra.Command, a *controller.SetupArgs) {
 	cmd.Flags().StringVar(&a.AccessKeyID, "aws-access-key-id", "", "Access key ID for the AWS account, must be used with the aws-secret-access-key and aws-region options")
 	cmd.Flags().StringVar(&a.SecretAccessKey, "aws-secret-access-key", "", "Secret access key for the AWS account, must be used with the aws-access-key-id and aws-region options")
 	cmd.Flags().StringVar(&a.Region, "aws-region", "", "Region for the AWS account, must be used with and aws-access-key-id and aws-secret-access-key options")
 	cmd.Flags().BoolVar(&a.UseEnv, "aws-env", false, "Use AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_DEFAULT_REGION environment variables for AWS authentication")
 	cmd.Flags().StringVar(&a.Profile, "aws-profile", "", "Use the given profile for AWS authentication")
-	cmd.Flags().BoolVar(&a.DryRun, "dry-run", false, "Don't start install/uninstall just show what credentials will be used")
-	cmd.Flags().StringVar(&a.GithubUser, "github-user", "", "The GitHub user that owns the node")
 }
 
 func showAwsDryRunInfo(a *controller.SetupArgs) {
@@ -253,6 +323,53 @@ func newLogsCommand() *cobra.Command {
 	return cmd
 }
 
+func newMetricsCommand() *cobra.Command {
+	var a controller.MetricsArgs
+	cmd := &cobra.Command{
+		Use:     "metrics [function]",
+		Short:   texts.Metrics.Short,
+		Long:    texts.Metrics.Long,
+		Example: texts.Metrics.Examples,
+		Args:    cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				a.Function = args[0]
+			}
+			if err := controller.Metrics(a); err != nil {
+				return log.Wrap(err)
+			}
+			return nil
+		},
+	}
+	setUsageTemplate(cmd, texts.Metrics.Arguments)
+	cmd.Flags().StringVarP(&a.Stage, "stage", "s", "", "Project stage to target instead of default")
+	cmd.Flags().DurationVar(&a.Since, "since", time.Hour, "Time range of the metrics ending now")
+	cmd.Flags().BoolVar(&a.JSON, "json", false, "Show metrics in JSON format")
+	return cmd
+}
+
+func newCostCommand() *cobra.Command {
+	var a controller.CostArgs
+	cmd := &cobra.Command{
+		Use:     "cost",
+		Short:   texts.Cost.Short,
+		Long:    texts.Cost.Long,
+		Example: texts.Cost.Examples,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := controller.Cost(a); err != nil {
+				return log.Wrap(err)
+			}
+			return nil
+		},
+	}
+	setUsageTemplate(cmd, texts.Cost.Arguments)
+	cmd.Flags().StringVarP(&a.Stage, "stage", "s", "", "Project stage to target instead of default")
+	cmd.Flags().IntVar(&a.Days, "days", 30, "Number of days ending now for which the cost is estimated")
+	cmd.Flags().BoolVar(&a.Actual, "actual", false, "Also show actual cost from AWS Cost Explorer")
+	return cmd
+}
+
 func newNewCommand() *cobra.Command {
 	var a controller.NewArgs
 	cmd := &cobra.Command{
@@ -273,6 +390,67 @@ func newNewCommand() *cobra.Command {
 	setUsageTemplate(cmd, texts.New.Arguments)
 	cmd.Flags().StringVar(&a.From, "from", "", "Name of the template or URL of the repository that will be used as one")
 	cmd.Flags().StringVar(&a.ModuleName, "module-name", "", "Replace module name and import paths")
+	cmd.Flags().StringToStringVar(&a.Params, "set", nil, "Template parameter in the form key=value, can be repeated")
+	cmd.Flags().BoolVarP(&a.Yes, "yes", "y", false, "Run post create hooks of the remote template without confirmation")
+	return cmd
+}
+
+func newTemplateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "template",
+		Short: texts.Template.Short,
+		Long:  texts.Template.Long,
+	}
+	addCommand(cmd, newTemplateAddCommand())
+	addCommand(cmd, newTemplateListCommand())
+	addCommand(cmd, newTemplateRemoveCommand())
+	return cmd
+}
+
+func newTemplateAddCommand() *cobra.Command {
+	var a controller.TemplateAddArgs
+	cmd := &cobra.Command{
+		Use:     "add <name> <source>",
+		Short:   texts.TemplateAdd.Short,
+		Example: texts.TemplateAdd.Examples,
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			a.Name = args[0]
+			a.Source = args[1]
+			return controller.TemplateAdd(a)
+		},
+	}
+	setUsageTemplate(cmd, texts.TemplateAdd.Arguments)
+	cmd.Flags().StringVarP(&a.Description, "description", "d", "", "Template description shown in the templates list")
+	return cmd
+}
+
+func newTemplateListCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   texts.TemplateList.Short,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return controller.TemplateList()
+		},
+	}
+	return cmd
+}
+
+func newTemplateRemoveCommand() *cobra.Command {
+	var a controller.TemplateRemoveArgs
+	cmd := &cobra.Command{
+		Use:     "remove <name>",
+		Aliases: []string{"rm"},
+		Short:   texts.TemplateRemove.Short,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			a.Name = args[0]
+			return controller.TemplateRemove(a)
+		},
+	}
+	setUsageTemplate(cmd, texts.TemplateRemove.Arguments)
 	return cmd
 }
 
@@ -329,6 +507,10 @@ func newStageCommand() *cobra.Command {
 	addCommand(cmd, newStageDestroyCommand())
 	addCommand(cmd, newStageList())
 	addCommand(cmd, newStageUse())
+	addCommand(cmd, newStageExtend())
+	addCommand(cmd, newStageStatus())
+	addCommand(cmd, newStageRotateKeys())
+	addCommand(cmd, newStageToken())
 	return cmd
 }
 
@@ -357,6 +539,8 @@ func newStageNewCommand() *cobra.Command {
 	}
 	setUsageTemplate(cmd, texts.StageNew.Arguments)
 	cmd.Flags().StringVarP(&a.Node, "node", "n", "", "Node in which the stage will be created")
+	cmd.Flags().DurationVar(&a.TTL, "ttl", 0, "Destroy the stage after ttl, like 48h")
+	cmd.Flags().StringVar(&a.Notify, "notify", "", "Webhook URL notified when the expired stage is destroyed")
 	return cmd
 }
 
@@ -432,6 +616,101 @@ func newStageUse() *cobra.Command {
 	return cmd
 }
 
+func newStageExtend() *cobra.Command {
+	var a controller.StageArgs
+	cmd := &cobra.Command{
+		Use:   "extend <stage>",
+		Short: texts.StageExtend.Short,
+		Long:  texts.StageExtend.Long,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			a.Stage = args[0]
+			s, err := controller.NewStage(a)
+			if err != nil {
+				return log.Wrap(err)
+			}
+			if err := s.Extend(); err != nil {
+				return log.Wrap(err)
+			}
+			return nil
+		},
+	}
+	setUsageTemplate(cmd, texts.StageExtend.Arguments)
+	cmd.Flags().DurationVar(&a.TTL, "ttl", 24*time.Hour, "Time by which the stage expiry is pushed out")
+	return cmd
+}
+
+func newStageStatus() *cobra.Command {
+	var a controller.StageStatusArgs
+	cmd := &cobra.Command{
+		Use:   "status [stage]",
+		Short: texts.StageStatus.Short,
+		Long:  texts.StageStatus.Long,
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				a.Stage = args[0]
+			}
+			if err := controller.StageStatus(a); err != nil {
+				return log.Wrap(err)
+			}
+			return nil
+		},
+	}
+	setUsageTemplate(cmd, texts.StageStatus.Arguments)
+	cmd.Flags().StringVarP(&a.Stage, "stage", "s", "", "Name of the stage to check")
+	cmd.Flags().BoolVar(&a.Fix, "fix", false, "Redeploy drifted resources from the stage state")
+	return cmd
+}
+
+func newStageRotateKeys() *cobra.Command {
+	var a controller.StageArgs
+	cmd := &cobra.Command{
+		Use:   "rotate-keys [stage]",
+		Short: texts.StageRotateKeys.Short,
+		Long:  texts.StageRotateKeys.Long,
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				a.Stage = args[0]
+			}
+			s, err := controller.NewStage(a)
+			if err != nil {
+				return log.Wrap(err)
+			}
+			if err := s.RotateKeys(); err != nil {
+				return log.Wrap(err)
+			}
+			return nil
+		},
+	}
+	setUsageTemplate(cmd, texts.StageRotateKeys.Arguments)
+	return cmd
+}
+
+func newStageToken() *cobra.Command {
+	var a controller.StageTokenArgs
+	cmd := &cobra.Command{
+		Use:     "token [stage]",
+		Short:   texts.StageToken.Short,
+		Long:    texts.StageToken.Long,
+		Example: texts.StageToken.Examples,
+		Args:    cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				a.Stage = args[0]
+			}
+			return controller.StageToken(a)
+		},
+	}
+	setUsageTemplate(cmd, texts.StageToken.Arguments)
+	cmd.Flags().DurationVar(&a.ExpiresIn, "expires-in", 24*time.Hour, "Duration for which the token is valid")
+	cmd.Flags().StringVar(&a.Subject, "subject", "", "Subject (username) of the token")
+	cmd.Flags().StringToStringVar(&a.Claims, "claim", nil, "Custom claim in the form key=value, can be repeated")
+	cmd.Flags().StringSliceVar(&a.Functions, "function", nil, "Function allowed to be called with the token, can be repeated, all if not set")
+	return cmd
+}
+
 func newGenerateCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "generate",
@@ -439,18 +718,23 @@ func newGenerateCommand() *cobra.Command {
 		Long:  texts.Generate.Long,
 	}
 	addCommand(cmd, newGenerateApiCommand())
+	addCommand(cmd, newGenerateOpenAPICommand())
+	addCommand(cmd, newGenerateClientCommand())
 	return cmd
 }
 
 func newGenerateApiCommand() *cobra.Command {
 	var a controller.GenerateApiArgs
 	cmd := &cobra.Command{
-		Use:   "api <name>",
-		Short: texts.GenerateApi.Short,
-		Long:  texts.GenerateApi.Long,
-		Args:  cobra.ExactArgs(1),
+		Use:     "api [name] [options]",
+		Short:   texts.GenerateApi.Short,
+		Long:    texts.GenerateApi.Long,
+		Example: texts.GenerateApi.Examples,
+		Args:    cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			a.Name = args[0]
+			if len(args) > 0 {
+				a.Name = args[0]
+			}
 			if err := controller.GenerateApi(a); err != nil {
 				return log.Wrap(err)
 			}
@@ -459,16 +743,61 @@ func newGenerateApiCommand() *cobra.Command {
 	}
 	setUsageTemplate(cmd, texts.GenerateApi.Arguments)
 	cmd.Flags().StringSliceVarP(&a.Methods, "methods", "m", nil, "Additional function methods, if left empty only the Default method will be created")
+	cmd.Flags().StringVar(&a.From, "from", "", "OpenAPI document in YAML or JSON format from which APIs are created")
+	return cmd
+}
+
+func newGenerateOpenAPICommand() *cobra.Command {
+	var a controller.GenerateOpenAPIArgs
+	cmd := &cobra.Command{
+		Use:     "openapi",
+		Short:   texts.GenerateOpenAPI.Short,
+		Long:    texts.GenerateOpenAPI.Long,
+		Example: texts.GenerateOpenAPI.Examples,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := controller.GenerateOpenAPI(a); err != nil {
+				return log.Wrap(err)
+			}
+			return nil
+		},
+	}
+	setUsageTemplate(cmd, texts.GenerateOpenAPI.Arguments)
+	cmd.Flags().StringVarP(&a.Stage, "stage", "s", "", "Stage whose endpoint is used as the server url, default stage if not specified")
+	cmd.Flags().StringVarP(&a.Output, "output", "o", "", "File to write the specification to, default openapi.json in the project root")
+	return cmd
+}
+
+func newGenerateClientCommand() *cobra.Command {
+	var a controller.GenerateClientArgs
+	cmd := &cobra.Command{
+		Use:     "client",
+		Short:   texts.GenerateClient.Short,
+		Long:    texts.GenerateClient.Long,
+		Example: texts.GenerateClient.Examples,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := controller.GenerateClient(a); err != nil {
+				return log.Wrap(err)
+			}
+			return nil
+		},
+	}
+	setUsageTemplate(cmd, texts.GenerateClient.Arguments)
+	cmd.Flags().StringVarP(&a.Lang, "lang", "l", controller.ClientLangGo, "Client language, go or ts")
+	cmd.Flags().StringVarP(&a.Stage, "stage", "s", "", "Stage whose configuration is used to find private functions, default stage if not specified")
+	cmd.Flags().StringVarP(&a.Output, "output", "o", "", "Directory to write the client to, default client in the project root")
 	return cmd
 }
 
 func newDeployCommand() *cobra.Command {
 	var a controller.DeployArgs
 	cmd := &cobra.Command{
-		Use:   "deploy",
-		Short: texts.Deploy.Short,
-		Long:  texts.Deploy.Long,
-		Args:  cobra.NoArgs,
+		Use:     "deploy",
+		Short:   texts.Deploy.Short,
+		Long:    texts.Deploy.Long,
+		Example: texts.Deploy.Examples,
+		Args:    cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			err := controller.NewDeploy(a)
 			if err != nil {
@@ -480,6 +809,7 @@ func newDeployCommand() *cobra.Command {
 	}
 	setUsageTemplate(cmd, texts.Deploy.Arguments)
 	cmd.Flags().StringVarP(&a.Stage, "stage", "s", "", "Project stage to target instead of default")
+	cmd.Flags().BoolVar(&a.All, "all", false, "Deploy all projects in the repository to the stage")
 	return cmd
 }
 
@@ -498,6 +828,19 @@ func newReportCommand() *cobra.Command {
 	return cmd
 }
 
+func newDoctorCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: texts.Doctor.Short,
+		Long:  texts.Doctor.Long,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return controller.Doctor()
+		},
+	}
+	return cmd
+}
+
 func newNodeCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:    "node",
@@ -507,6 +850,8 @@ func newNodeCommand() *cobra.Command {
 	addCommand(cmd, newNodeUserRemoveCommand())
 	addCommand(cmd, newNodeLoginCommand())
 	addCommand(cmd, newNodeLogoutCommand())
+	addCommand(cmd, newNodeSessionsCommand())
+	addCommand(cmd, newNodeAuditCommand())
 	return cmd
 }
 
@@ -563,3 +908,33 @@ func newNodeLogoutCommand() *cobra.Command {
 	}
 	return cmd
 }
+
+func newNodeSessionsCommand() *cobra.Command {
+	var a controller.NodeSessionsArgs
+	cmd := &cobra.Command{
+		Use: "sessions",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return controller.NodeSessions(a)
+		},
+	}
+	cmd.Flags().StringVarP(&a.Node, "node", "n", "", "Node for which sessions will be listed")
+	cmd.Flags().StringVarP(&a.Username, "user", "u", "", "List only sessions of this user")
+	cmd.Flags().StringVar(&a.Revoke, "revoke", "", "Revoke session with this id")
+	return cmd
+}
+
+func newNodeAuditCommand() *cobra.Command {
+	var a controller.NodeAuditArgs
+	cmd := &cobra.Command{
+		Use: "audit",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return controller.NodeAudit(a)
+		},
+	}
+	cmd.Flags().StringVarP(&a.Node, "node", "n", "", "Node for which audit log will be shown")
+	cmd.Flags().DurationVar(&a.Since, "since", 24*time.Hour, "Show records newer than this, default is 24 hours ago")
+	cmd.Flags().StringVarP(&a.Stage, "stage", "s", "", "Show only records for this stage")
+	cmd.Flags().StringVarP(&a.Username, "user", "u", "", "Show only records of this user")
+	cmd.Flags().BoolVar(&a.JSON, "json", false, "Print records as JSON")
+	return cmd
+}
@@ -63,7 +63,10 @@ func root() *cobra.Command {
 		newEnvCommand,
 		newInvokeCommand,
 		newLogsCommand,
+		newMetricsCommand,
+		newCostCommand,
 		newNewCommand,
+		newTemplateCommand,
 		newTestCommand,
 		newWatchCommand,
 		newDeployCommand,
@@ -71,6 +74,7 @@ func root() *cobra.Command {
 		newAwsCommand,
 		newStageCommand,
 		newReportCommand,
+		newDoctorCommand,
 		newNodeCommand,
 
 		// for testing:
@@ -222,7 +226,7 @@ Please check the following rules when naming projects, stages and functions:
 
 	var naud *domain.NodeAlreadyUpToDateError
 	if errors.As(err, &naud) {
-		ui.Info("Node %s is already up to date with the current version %s of CLI.", naud.Name, naud.Version)
+		ui.Info("Node %s is already at version %s.", naud.Name, naud.Version)
 		return
 	}
 
@@ -8,9 +8,12 @@ import (
 	"net/url"
 
 	"github.com/mantil-io/mantil.go/logs"
+	"github.com/mantil-io/mantil/cli/controller/invoke"
 	"github.com/mantil-io/mantil/cli/log"
 	"github.com/mantil-io/mantil/cli/secret"
+	"github.com/mantil-io/mantil/cli/ui"
 	"github.com/mantil-io/mantil/domain"
+	"github.com/mantil-io/mantil/node/dto"
 	"github.com/nats-io/nats.go"
 	"github.com/pkg/browser"
 )
@@ -23,29 +26,61 @@ func AuthToken(n *domain.Node) (string, error) {
 	t, err := n.AuthToken()
 	var terr *domain.TokenExpiredError
 	if errors.As(err, &terr) && n.GithubAuthEnabled() {
-		var err error
-		t, err = githubAuth(n.Endpoints.Rest)
+		t, err := refreshToken(n)
+		if err == nil {
+			n.UpdateToken(t)
+			return t, nil
+		}
+		if !errors.Is(err, errRefreshTokenNotFound) {
+			log.Error(err)
+		}
+		tokens, err := githubAuth(n.Endpoints.Rest)
 		if err != nil {
 			return "", log.Wrap(err)
 		}
-		n.UpdateToken(t)
+		n.UpdateSession(tokens.JWT, tokens.RefreshToken)
+		return tokens.JWT, nil
 	} else if err != nil {
 		return "", log.Wrap(err)
 	}
 	return t, nil
 }
 
-func githubAuth(nodeEndpoint string) (string, error) {
+// errRefreshTokenNotFound is expected for nodes logged in before refresh
+// tokens were issued, user is asked to log in again
+var errRefreshTokenNotFound = errors.New("refresh token not found")
+
+// refreshToken silently renews expired access token using refresh token
+// issued on login
+func refreshToken(n *domain.Node) (string, error) {
+	rt := n.RefreshToken()
+	if rt == "" {
+		return "", errRefreshTokenNotFound
+	}
+	i := invoke.Node(n.Endpoints.Rest, "", ui.NodeLogsSink)
+	var rsp dto.RefreshTokenResponse
+	if err := i.Do(RefreshTokenHTTPMethod, &dto.RefreshTokenRequest{RefreshToken: rt}, &rsp); err != nil {
+		return "", log.Wrap(err)
+	}
+	return rsp.Token, nil
+}
+
+type authTokens struct {
+	JWT          string `json:"jwt"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func githubAuth(nodeEndpoint string) (*authTokens, error) {
 	s, err := createState(nodeEndpoint)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if err := githubLogin(s); err != nil {
-		return "", err
+		return nil, err
 	}
 	t, err := waitToken(s.Inbox)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	return t, nil
 }
@@ -82,10 +117,8 @@ func githubLogin(state *state) error {
 	return browser.OpenURL(u.String())
 }
 
-func waitToken(inbox string) (string, error) {
-	rsp := struct {
-		JWT string `json:"jwt"`
-	}{}
+func waitToken(inbox string) (*authTokens, error) {
+	var rsp authTokens
 	lc := logs.ListenerConfig{
 		ListenerJWT: secret.LogsListenerCreds,
 		Subject:     inbox,
@@ -93,10 +126,10 @@ func waitToken(inbox string) (string, error) {
 	}
 	l, err := logs.NewLambdaListener(lc)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if err := l.Done(context.Background()); err != nil {
-		return "", err
+		return nil, err
 	}
-	return rsp.JWT, nil
+	return &rsp, nil
 }
@@ -0,0 +1,257 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+	"github.com/mantil-io/mantil/cli/log"
+	"github.com/mantil-io/mantil/cli/ui"
+	"github.com/mantil-io/mantil/domain"
+	"github.com/mantil-io/mantil/kit/aws"
+)
+
+// resource types which are not deleted by gc but are reported so they can
+// be deleted manually
+var gcReportedResourceTypes = []string{"apigateway", "events:rule", "sns:topic"}
+
+// order in which orphans are deleted, log groups are deleted last so they
+// are not recreated by the functions
+var gcDeleteOrder = map[string]int{
+	"cloudwatch:alarm": 1,
+	"lambda:function":  2,
+	"dynamodb:table":   3,
+	"s3":               4,
+	"ssm:parameter":    5,
+	"logs:log-group":   6,
+}
+
+// orphan is resource of the stage which is not known to the workspace nor
+// the node, left behind by failed deploy or destroy
+type orphan struct {
+	Project string
+	Stage   string
+	ARN     string
+}
+
+func (o orphan) Type() string {
+	return aws.ResourceType(o.ARN)
+}
+
+func (o orphan) Name() string {
+	return aws.ResourceName(o.ARN)
+}
+
+func (o orphan) deletable() bool {
+	_, ok := gcDeleteOrder[o.Type()]
+	return ok
+}
+
+type stageKey struct {
+	project string
+	stage   string
+}
+
+// GC finds resources of the node stages which no longer exist and deletes
+// them after confirmation.
+func (c *Setup) GC(dryRun bool) error {
+	n := c.store.Workspace().Node(c.nodeName)
+	if n == nil {
+		return log.Wrap(&domain.NodeNotFoundError{Name: c.nodeName})
+	}
+	known, err := c.knownStages(n)
+	if err != nil {
+		return log.Wrap(err)
+	}
+	orphans, err := c.findOrphans(n, known)
+	if err != nil {
+		return log.Wrap(err)
+	}
+	if len(orphans) == 0 {
+		ui.Info("There are no orphaned resources on node %s.", n.Name)
+		return nil
+	}
+	showOrphans(orphans)
+	if dryRun {
+		return nil
+	}
+	var deletable []orphan
+	for _, o := range orphans {
+		if o.deletable() {
+			deletable = append(deletable, o)
+		}
+	}
+	if len(deletable) == 0 {
+		ui.Info("Orphaned resources can't be deleted by Mantil, please delete them manually.")
+		return nil
+	}
+	if !c.confirmGC(len(deletable)) {
+		return nil
+	}
+	failed := 0
+	for _, o := range deletable {
+		ui.Info("Deleting %s %s", o.Type(), o.Name())
+		if err := c.aws.DeleteResource(o.ARN); err != nil {
+			ui.Errorf("%v", err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return log.Wrapf("%d of %d orphaned resources could not be deleted", failed, len(deletable))
+	}
+	ui.Info("")
+	ui.Title("Deleted %d orphaned resources.\n", len(deletable))
+	return nil
+}
+
+// knownStages are stages of the node in the workspace and stages which have
+// terraform state in the node bucket, those can be created from other workspaces
+func (c *Setup) knownStages(n *domain.Node) (map[stageKey]bool, error) {
+	known := make(map[stageKey]bool)
+	for _, s := range n.Stages {
+		known[stageKey{project: s.ProjectName, stage: s.Name}] = true
+	}
+	projects, err := c.aws.S3().ListPrefixes(n.Bucket, domain.StateBucketPrefix)
+	if err != nil {
+		return nil, log.Wrap(err, "could not list stages in node bucket %s", n.Bucket)
+	}
+	for _, p := range projects {
+		stages, err := c.aws.S3().ListPrefixes(n.Bucket, p)
+		if err != nil {
+			return nil, log.Wrap(err, "could not list stages in node bucket %s", n.Bucket)
+		}
+		for _, s := range stages {
+			parts := strings.Split(strings.Trim(s, "/"), "/")
+			if len(parts) != 3 {
+				continue
+			}
+			known[stageKey{project: parts[1], stage: parts[2]}] = true
+		}
+	}
+	return known, nil
+}
+
+func (c *Setup) findOrphans(n *domain.Node, known map[stageKey]bool) ([]orphan, error) {
+	types := append(append([]string{}, aws.DeletableResourceTypes...), gcReportedResourceTypes...)
+	resources, err := c.aws.GetTaggedResources(types, []aws.TagFilter{{Key: domain.TagKey, Values: []string{n.ID}}})
+	if err != nil {
+		return nil, log.Wrap(err, "could not get resources of node %s", n.Name)
+	}
+	orphans := taggedOrphans(resources, known)
+	// log groups created by the lambda runtime are not tagged
+	groups, err := c.aws.ListLogGroups(aws.LambdaLogGroup(""))
+	if err != nil {
+		return nil, log.Wrap(err, "could not list log groups")
+	}
+	lambdaExists := func(name string) bool {
+		exists, err := c.aws.LambdaExists(name)
+		// assume function exists when it can't be checked
+		return exists || err != nil
+	}
+	for _, g := range logGroupOrphans(groups, orphans, n.ResourceSuffix(), known, lambdaExists) {
+		orphans = append(orphans, orphan{
+			ARN: fmt.Sprintf("arn:aws:logs:%s:%s:log-group:%s", c.aws.Region(), c.aws.AccountID(), g),
+		})
+	}
+	sortOrphans(orphans)
+	return orphans, nil
+}
+
+// taggedOrphans returns resources tagged with project and stage which are not known
+func taggedOrphans(resources []aws.TaggedResource, known map[stageKey]bool) []orphan {
+	var orphans []orphan
+	for _, r := range resources {
+		project, stage := r.Tags[domain.TagProjectName], r.Tags[domain.TagStageName]
+		if project == "" || stage == "" {
+			// node resource
+			continue
+		}
+		if known[stageKey{project: project, stage: stage}] {
+			continue
+		}
+		orphans = append(orphans, orphan{Project: project, Stage: stage, ARN: r.ARN})
+	}
+	return orphans
+}
+
+// logGroupOrphans returns names of the lambda log groups of the node which
+// are not already found by tags, don't belong to the known stage and whose
+// function doesn't exist or is orphan itself
+func logGroupOrphans(groups []string, tagged []orphan, suffix string, known map[stageKey]bool, lambdaExists func(string) bool) []string {
+	found := make(map[string]bool)
+	orphanLambdas := make(map[string]bool)
+	for _, o := range tagged {
+		switch o.Type() {
+		case "logs:log-group":
+			found[o.Name()] = true
+		case "lambda:function":
+			orphanLambdas[o.Name()] = true
+		}
+	}
+	var orphans []string
+	for _, g := range groups {
+		name := strings.TrimPrefix(g, aws.LambdaLogGroup(""))
+		if found[g] || !strings.HasSuffix(name, "-"+suffix) {
+			continue
+		}
+		if !orphanLambdas[name] && (knownStageResource(name, known) || lambdaExists(name)) {
+			continue
+		}
+		orphans = append(orphans, g)
+	}
+	return orphans
+}
+
+func knownStageResource(name string, known map[stageKey]bool) bool {
+	for k := range known {
+		prefix := fmt.Sprintf("%s-%s-", k.project, k.stage)
+		if strings.HasPrefix(name, prefix) || strings.HasPrefix(name, "mantil-"+prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortOrphans(orphans []orphan) {
+	sort.SliceStable(orphans, func(i, j int) bool {
+		oi, oj := gcDeleteOrder[orphans[i].Type()], gcDeleteOrder[orphans[j].Type()]
+		if oi != oj {
+			// not deletable at the end
+			if oi == 0 || oj == 0 {
+				return oj == 0
+			}
+			return oi < oj
+		}
+		return orphans[i].ARN < orphans[j].ARN
+	})
+}
+
+func showOrphans(orphans []orphan) {
+	var data [][]string
+	for _, o := range orphans {
+		action := "delete"
+		if !o.deletable() {
+			action = "delete manually"
+		}
+		data = append(data, []string{o.Project, o.Stage, o.Type(), o.Name(), action})
+	}
+	ShowTable([]string{"project", "stage", "type", "name", "action"}, data)
+}
+
+func (c *Setup) confirmGC(count int) bool {
+	if c.yes {
+		return true
+	}
+	ui.Title("? Do you really want to delete %d orphaned resources?\n", count)
+	ui.Info("This action cannot be reversed.")
+	confirmationPrompt := promptui.Prompt{
+		Label: "To confirm, type 'yes'",
+	}
+	res, err := confirmationPrompt.Run()
+	if err != nil {
+		return false
+	}
+	res = strings.ToLower(res)
+	return res == "yes" || res == "y"
+}
@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/mantil-io/mantil/domain"
+	"github.com/mantil-io/mantil/kit/aws"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaggedOrphans(t *testing.T) {
+	known := map[stageKey]bool{
+		{project: "project", stage: "dev"}: true,
+	}
+	tags := func(project, stage string) map[string]string {
+		return map[string]string{
+			domain.TagKey:         "abcdefg",
+			domain.TagProjectName: project,
+			domain.TagStageName:   stage,
+		}
+	}
+	resources := []aws.TaggedResource{
+		{ARN: "arn:aws:lambda:eu-central-1:123456789012:function:project-dev-ping-abcdefg", Tags: tags("project", "dev")},
+		{ARN: "arn:aws:lambda:eu-central-1:123456789012:function:project-prod-ping-abcdefg", Tags: tags("project", "prod")},
+		{ARN: "arn:aws:s3:::mantil-abcdefg", Tags: map[string]string{domain.TagKey: "abcdefg"}},
+	}
+	orphans := taggedOrphans(resources, known)
+	require.Len(t, orphans, 1)
+	require.Equal(t, "project", orphans[0].Project)
+	require.Equal(t, "prod", orphans[0].Stage)
+	require.Equal(t, "lambda:function", orphans[0].Type())
+	require.Equal(t, "project-prod-ping-abcdefg", orphans[0].Name())
+	require.True(t, orphans[0].deletable())
+}
+
+func TestLogGroupOrphans(t *testing.T) {
+	known := map[stageKey]bool{
+		{project: "project", stage: "dev"}: true,
+	}
+	tagged := []orphan{
+		{ARN: "arn:aws:lambda:eu-central-1:123456789012:function:project-prod-ping-abcdefg"},
+		{ARN: "arn:aws:logs:eu-central-1:123456789012:log-group:/aws/lambda/project-prod-tagged-abcdefg:*"},
+	}
+	groups := []string{
+		"/aws/lambda/project-dev-ping-abcdefg",
+		"/aws/lambda/project-prod-ping-abcdefg",
+		"/aws/lambda/project-prod-tagged-abcdefg",
+		"/aws/lambda/project-test-ping-abcdefg",
+		"/aws/lambda/project-test-existing-abcdefg",
+		"/aws/lambda/other-node-ping-hijklmn",
+	}
+	lambdaExists := func(name string) bool {
+		return name == "project-test-existing-abcdefg" || name == "project-prod-ping-abcdefg"
+	}
+	orphans := logGroupOrphans(groups, tagged, "abcdefg", known, lambdaExists)
+	require.Equal(t, []string{
+		"/aws/lambda/project-prod-ping-abcdefg",
+		"/aws/lambda/project-test-ping-abcdefg",
+	}, orphans)
+}
+
+func TestKnownStageResource(t *testing.T) {
+	known := map[stageKey]bool{
+		{project: "project", stage: "dev"}: true,
+	}
+	require.True(t, knownStageResource("project-dev-ping-abcdefg", known))
+	require.True(t, knownStageResource("mantil-project-dev-ping-abcdefg", known))
+	require.False(t, knownStageResource("project-prod-ping-abcdefg", known))
+	require.False(t, knownStageResource("project-development-ping-abcdefg", known))
+}
+
+func TestSortOrphans(t *testing.T) {
+	orphans := []orphan{
+		{ARN: "arn:aws:logs:eu-central-1:123456789012:log-group:/aws/lambda/b"},
+		{ARN: "arn:aws:apigateway:eu-central-1::/apis/abcdefg"},
+		{ARN: "arn:aws:lambda:eu-central-1:123456789012:function:b"},
+		{ARN: "arn:aws:dynamodb:eu-central-1:123456789012:table/a"},
+		{ARN: "arn:aws:lambda:eu-central-1:123456789012:function:a"},
+	}
+	sortOrphans(orphans)
+	var types []string
+	for _, o := range orphans {
+		types = append(types, o.Type()+" "+o.Name())
+	}
+	require.Equal(t, []string{
+		"lambda:function a",
+		"lambda:function b",
+		"dynamodb:table a",
+		"logs:log-group /aws/lambda/b",
+		"apigateway apis/abcdefg",
+	}, types)
+	require.False(t, orphans[4].deletable())
+}
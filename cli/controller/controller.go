@@ -64,8 +64,17 @@ func awsClient(node *domain.Node, stage *domain.Stage) (*aws.AWS, error) {
 	if stage != nil {
 		if stage.HasPublic() {
 			req.Buckets = append(req.Buckets, stage.Public.Bucket)
+			req.PublicBucket = stage.Public.Bucket
+		}
+		if stage.Distribution != nil {
+			req.DistributionID = stage.Distribution.ID
 		}
 		req.LogGroupsPrefix = aws.LambdaLogGroup(stage.LogGroupsPrefix())
+		req.FunctionsPrefix = stage.LogGroupsPrefix()
+		req.FunctionsBucket = node.Bucket
+		req.FunctionsBucketPrefix = stage.FunctionsBucketPrefix()
+		req.ProjectName = stage.Project().Name
+		req.StageName = stage.Name
 	}
 	buf, err := json.Marshal(req)
 	if err != nil {
@@ -100,17 +109,68 @@ func nodeInvoker(node *domain.Node) (*invoke.HTTPClient, error) {
 	return invoke.Node(node.Endpoints.Rest, token, ui.NodeLogsSink), nil
 }
 
+// traceIDLogPrefix marks log line with the X-Ray trace id which traced
+// functions send to the invoke command through the logs stream, enabled by
+// the logs config header of the request
+const (
+	traceIDLogPrefix = "mantil trace id: "
+	logsConfigHeader = "mantil-nats-config"
+)
+
 func stageInvokeCallback(stage *domain.Stage, path, req string, excludeLogs bool, cb func(*http.Response) error) (func() error, error) {
 	token, err := stage.AuthToken()
 	if err != nil {
 		return nil, log.Wrap(err)
 	}
-	is := invoke.Stage(stage.RestEndpoint(), excludeLogs, cb, token, ui.InvokeLogsSink)
+	fn := stage.FindFunction(strings.Split(path, "/")[0])
+	if excludeLogs || fn == nil || fn.Tracing != domain.TracingActive {
+		is := invoke.Stage(stage.RestEndpoint(), excludeLogs, cb, token, ui.InvokeLogsSink)
+		return func() error {
+			return is.Do(path, []byte(req), nil)
+		}, nil
+	}
+	// traced function echoes trace id of the invocation in the logs
+	var traceID string
+	is := invoke.Stage(stage.RestEndpoint(), excludeLogs, cb, token, traceLogsSink(&traceID))
 	return func() error {
-		return is.Do(path, []byte(req), nil)
+		err := is.Do(path, []byte(req), nil)
+		if traceID != "" {
+			ui.Info("")
+			ui.Info("Trace ID: %s", traceID)
+			ui.Info("%s", aws.TraceConsoleURL(stage.Node().Region, traceID))
+		}
+		return err
 	}, nil
 }
 
+// traceLogsSink shows function logs and picks trace id from them
+func traceLogsSink(traceID *string) invoke.LogSinkCallback {
+	return func(logsCh chan []byte) {
+		lines := make(chan []byte)
+		go func() {
+			defer close(lines)
+			for buf := range logsCh {
+				if id, ok := traceIDFromLog(string(buf)); ok {
+					*traceID = id
+					continue
+				}
+				lines <- buf
+			}
+		}()
+		ui.InvokeLogsSink(lines)
+	}
+}
+
+// traceIDFromLog finds trace id in the log line, line can be prefixed with
+// the source file of the log call
+func traceIDFromLog(line string) (string, bool) {
+	i := strings.Index(line, traceIDLogPrefix)
+	if i < 0 {
+		return "", false
+	}
+	return strings.TrimSpace(line[i+len(traceIDLogPrefix):]), true
+}
+
 func newStore() (*domain.FileStore, error) {
 	fs, err := domain.NewSingleDeveloperWorkspaceStore()
 	if err != nil {
@@ -195,3 +255,14 @@ func ShowTable(header []string, data [][]string) {
 	}
 	table.Render()
 }
+
+// printJSON writes v to stdout without any ui decoration so that the output
+// of --json options can be piped to other tools
+func printJSON(v interface{}) error {
+	buf, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return log.Wrap(err)
+	}
+	fmt.Fprintln(os.Stdout, string(buf))
+	return nil
+}
@@ -0,0 +1,22 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceLogsSink(t *testing.T) {
+	var traceID string
+	sink := traceLogsSink(&traceID)
+	ch := make(chan []byte, 3)
+	ch <- []byte("handler.go:12: request received")
+	ch <- []byte("/var/task/main.go:180: mantil trace id: 1-61a5c1b2-0d4e5f6a7b8c9d0e1f2a3b4c")
+	ch <- []byte("handler.go:20: request done")
+	close(ch)
+	sink(ch)
+	require.Equal(t, "1-61a5c1b2-0d4e5f6a7b8c9d0e1f2a3b4c", traceID)
+
+	_, ok := traceIDFromLog("handler.go:12: request received")
+	require.False(t, ok)
+}
@@ -0,0 +1,303 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mantil-io/mantil/cli/log"
+	"github.com/mantil-io/mantil/cli/ui"
+	"github.com/mantil-io/mantil/domain"
+	"github.com/mantil-io/mantil/kit/aws"
+)
+
+const (
+	costDefaultRegion = "us-east-1"
+	// memory of the lambda functions created without memory size
+	costDefaultMemorySize = 128
+	bytesInGB             = 1 << 30
+	million               = 1e6
+	daysInMonth           = 30
+)
+
+// costPricing are on-demand prices in USD, lambda functions are arm64
+type costPricing struct {
+	LambdaRequests  float64 // per million requests
+	LambdaGBSecond  float64 // per GB-second of the duration
+	HttpRequests    float64 // per million http api requests
+	WsMessages      float64 // per million websocket messages
+	DynamoDBReads   float64 // per million read request units
+	DynamoDBWrites  float64 // per million write request units
+	S3StorageGB     float64 // per GB-month of the standard storage
+	LogsIngestionGB float64 // per GB of ingested logs
+}
+
+// costPricingTable holds prices for each of the supported regions. Prices
+// are changed by AWS from time to time so the estimate is approximate.
+var costPricingTable = map[string]costPricing{
+	"ap-south-1":     {0.20, 0.0000133334, 1.00, 1.00, 0.285, 1.4231, 0.025, 0.67},
+	"ap-southeast-1": {0.20, 0.0000133334, 1.20, 1.20, 0.285, 1.4231, 0.025, 0.67},
+	"ap-southeast-2": {0.20, 0.0000133334, 1.29, 1.29, 0.285, 1.4231, 0.025, 0.70},
+	"ap-northeast-1": {0.20, 0.0000133334, 1.29, 1.29, 0.2854, 1.4269, 0.025, 0.76},
+	"eu-central-1":   {0.20, 0.0000133334, 1.20, 1.20, 0.305, 1.525, 0.0245, 0.63},
+	"eu-west-1":      {0.20, 0.0000133334, 1.11, 1.14, 0.283, 1.4135, 0.023, 0.57},
+	"eu-west-2":      {0.20, 0.0000133334, 1.16, 1.19, 0.297, 1.4846, 0.024, 0.58},
+	"us-east-1":      {0.20, 0.0000133334, 1.00, 1.00, 0.25, 1.25, 0.023, 0.50},
+	"us-east-2":      {0.20, 0.0000133334, 1.00, 1.00, 0.25, 1.25, 0.023, 0.50},
+	"us-west-2":      {0.20, 0.0000133334, 1.00, 1.00, 0.25, 1.25, 0.023, 0.50},
+}
+
+type CostArgs struct {
+	Stage  string
+	Days   int
+	Actual bool
+}
+
+func Cost(a CostArgs) error {
+	if a.Days < 1 {
+		return log.Wrapf("days must be positive, got %d", a.Days)
+	}
+	_, stage, err := newStoreWithStage(a.Stage)
+	if err != nil {
+		return log.Wrap(err)
+	}
+	awsClient, err := awsClient(stage.Node(), stage)
+	if err != nil {
+		return log.Wrap(err)
+	}
+	region := stage.Node().Region
+	pricing, ok := costPricingTable[region]
+	if !ok {
+		ui.Notice("prices for region %s are not available, using %s prices", region, costDefaultRegion)
+		pricing = costPricingTable[costDefaultRegion]
+	}
+	q := newCostQuery(stage, a.Days, time.Now())
+	series, err := awsClient.GetMetrics(q.queries(), q.start, q.end)
+	if err != nil {
+		return log.Wrap(err, "could not get metrics for stage %s", stage.Name)
+	}
+	e := q.estimate(series, pricing)
+	showCostEstimate(stage.Name, a.Days, e)
+	if !a.Actual {
+		return nil
+	}
+	ui.Info("")
+	actual, unit, err := awsClient.GetCostAndUsage(stage.ResourceTags(), q.start, q.end)
+	if err != nil {
+		ui.Notice("actual cost is not available: %v", err)
+		return nil
+	}
+	if actual == 0 {
+		ui.Notice("Cost Explorer reports no cost for the stage, activate %s and %s as cost allocation tags in the billing console to see actual cost",
+			domain.TagProjectName, domain.TagStageName)
+		return nil
+	}
+	ui.Info("Actual cost from Cost Explorer: %.2f %s", actual, unit)
+	return nil
+}
+
+type costQuery struct {
+	lambdas  []costLambda
+	tables   []string
+	bucket   string
+	httpApi  string
+	wsApi    string
+	start    time.Time
+	end      time.Time
+	days     int
+	interval time.Duration
+}
+
+type costLambda struct {
+	name       string
+	awsName    string
+	memorySize int
+}
+
+func newCostQuery(stage *domain.Stage, days int, now time.Time) *costQuery {
+	interval := time.Duration(days) * 24 * time.Hour
+	end := now.Truncate(time.Hour)
+	q := &costQuery{
+		httpApi:  stage.RestApiID(),
+		wsApi:    stage.WsApiID(),
+		start:    end.Add(-interval),
+		end:      end,
+		days:     days,
+		interval: interval,
+	}
+	memorySize := make(map[string]int)
+	for _, f := range stage.Functions {
+		memorySize[f.LambdaName()] = f.MemorySize
+	}
+	for _, r := range stage.Resources() {
+		switch r.Type {
+		case domain.AwsResourceLambda:
+			ms := memorySize[r.AWSName]
+			if ms == 0 {
+				ms = costDefaultMemorySize
+			}
+			q.lambdas = append(q.lambdas, costLambda{name: r.Name, awsName: r.AWSName, memorySize: ms})
+		case domain.AwsResourceDynamoDB:
+			q.tables = append(q.tables, r.AWSName)
+		case domain.AwsResourceS3Bucket:
+			q.bucket = r.AWSName
+		}
+	}
+	return q
+}
+
+func (q *costQuery) queries() []aws.MetricQuery {
+	var queries []aws.MetricQuery
+	add := func(id, namespace, name, stat string, dims map[string]string, period time.Duration) {
+		queries = append(queries, aws.MetricQuery{
+			ID:         id,
+			Namespace:  namespace,
+			Name:       name,
+			Dimensions: dims,
+			Stat:       stat,
+			Period:     period,
+		})
+	}
+	for i, l := range q.lambdas {
+		dims := map[string]string{"FunctionName": l.awsName}
+		add(lambdaCostQueryID(i, "Invocations"), aws.LambdaMetricsNamespace, "Invocations", "Sum", dims, q.interval)
+		add(lambdaCostQueryID(i, "Duration"), aws.LambdaMetricsNamespace, "Duration", "Sum", dims, q.interval)
+		add(lambdaCostQueryID(i, "IncomingBytes"), aws.LogsMetricsNamespace, "IncomingBytes", "Sum",
+			map[string]string{"LogGroupName": lambdaLogGroup(l.awsName)}, q.interval)
+	}
+	for i, t := range q.tables {
+		dims := map[string]string{"TableName": t}
+		add(tableCostQueryID(i, "ConsumedReadCapacityUnits"), aws.DynamoDBMetricsNamespace, "ConsumedReadCapacityUnits", "Sum", dims, q.interval)
+		add(tableCostQueryID(i, "ConsumedWriteCapacityUnits"), aws.DynamoDBMetricsNamespace, "ConsumedWriteCapacityUnits", "Sum", dims, q.interval)
+	}
+	if q.bucket != "" {
+		// bucket size is reported once a day
+		add("s3_bucketsizebytes_average", aws.S3MetricsNamespace, "BucketSizeBytes", "Average",
+			map[string]string{"BucketName": q.bucket, "StorageType": "StandardStorage"}, 24*time.Hour)
+	}
+	if q.httpApi != "" {
+		add("http_count_sum", aws.ApiGatewayMetricsNamespace, "Count", "Sum", map[string]string{"ApiId": q.httpApi}, q.interval)
+	}
+	if q.wsApi != "" {
+		add("ws_messagecount_sum", aws.ApiGatewayMetricsNamespace, "MessageCount", "Sum", map[string]string{"ApiId": q.wsApi}, q.interval)
+	}
+	return queries
+}
+
+func lambdaCostQueryID(idx int, name string) string {
+	return fmt.Sprintf("l%d_%s", idx, metricQueryID(name, "Sum", false))
+}
+
+func tableCostQueryID(idx int, name string) string {
+	return fmt.Sprintf("t%d_%s", idx, metricQueryID(name, "Sum", false))
+}
+
+func lambdaLogGroup(functionName string) string {
+	return "/aws/lambda/" + functionName
+}
+
+type costEstimate struct {
+	Services  []serviceCost
+	Functions []functionCost
+	Total     float64
+}
+
+type serviceCost struct {
+	Service string
+	Usage   float64
+	Unit    string
+	Cost    float64
+}
+
+type functionCost struct {
+	Name        string
+	Invocations float64
+	GBSeconds   float64
+	Cost        float64
+}
+
+func (q *costQuery) estimate(series map[string]aws.MetricSeries, p costPricing) *costEstimate {
+	e := &costEstimate{}
+	var invocations, gbSeconds, logsBytes float64
+	for i, l := range q.lambdas {
+		fi := summaryValue(series[lambdaCostQueryID(i, "Invocations")], "Sum")
+		durationMs := summaryValue(series[lambdaCostQueryID(i, "Duration")], "Sum")
+		fgbs := durationMs / 1000 * float64(l.memorySize) / 1024
+		flb := summaryValue(series[lambdaCostQueryID(i, "IncomingBytes")], "Sum")
+		invocations += fi
+		gbSeconds += fgbs
+		logsBytes += flb
+		e.Functions = append(e.Functions, functionCost{
+			Name:        l.name,
+			Invocations: fi,
+			GBSeconds:   fgbs,
+			Cost:        fi/million*p.LambdaRequests + fgbs*p.LambdaGBSecond + flb/bytesInGB*p.LogsIngestionGB,
+		})
+	}
+	sort.SliceStable(e.Functions, func(i, j int) bool { return e.Functions[i].Cost > e.Functions[j].Cost })
+
+	var reads, writes float64
+	for i := range q.tables {
+		reads += summaryValue(series[tableCostQueryID(i, "ConsumedReadCapacityUnits")], "Sum")
+		writes += summaryValue(series[tableCostQueryID(i, "ConsumedWriteCapacityUnits")], "Sum")
+	}
+	httpRequests := summaryValue(series["http_count_sum"], "Sum")
+	wsMessages := summaryValue(series["ws_messagecount_sum"], "Sum")
+	storageGB := average(series["s3_bucketsizebytes_average"].Values) / bytesInGB
+
+	add := func(service string, usage float64, unit string, cost float64) {
+		e.Services = append(e.Services, serviceCost{Service: service, Usage: usage, Unit: unit, Cost: cost})
+		e.Total += cost
+	}
+	add("Lambda requests", invocations, "requests", invocations/million*p.LambdaRequests)
+	add("Lambda duration", gbSeconds, "GB-seconds", gbSeconds*p.LambdaGBSecond)
+	add("API Gateway HTTP", httpRequests, "requests", httpRequests/million*p.HttpRequests)
+	add("API Gateway WebSocket", wsMessages, "messages", wsMessages/million*p.WsMessages)
+	add("DynamoDB reads", reads, "read units", reads/million*p.DynamoDBReads)
+	add("DynamoDB writes", writes, "write units", writes/million*p.DynamoDBWrites)
+	add("S3 storage", storageGB, "GB", storageGB*p.S3StorageGB*float64(q.days)/daysInMonth)
+	add("CloudWatch Logs", logsBytes/bytesInGB, "GB ingested", logsBytes/bytesInGB*p.LogsIngestionGB)
+	return e
+}
+
+func average(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func showCostEstimate(stage string, days int, e *costEstimate) {
+	ui.Info("Stage %s estimated cost for the last %d days, free tier is not included", stage, days)
+	ui.Info("")
+	var data [][]string
+	for _, s := range e.Services {
+		data = append(data, []string{s.Service, fmt.Sprintf("%s %s", formatUsage(s.Usage), s.Unit), formatCost(s.Cost)})
+	}
+	data = append(data, []string{"total", "", formatCost(e.Total)})
+	ShowTable([]string{"service", "usage", "cost"}, data)
+	ui.Info("")
+	data = nil
+	for _, f := range e.Functions {
+		data = append(data, []string{f.Name, formatCount(f.Invocations), formatUsage(f.GBSeconds), formatCost(f.Cost)})
+	}
+	ShowTable([]string{"function", "invocations", "GB-seconds", "cost"}, data)
+}
+
+func formatUsage(v float64) string {
+	if v != 0 && v < 10 {
+		return fmt.Sprintf("%.2f", v)
+	}
+	return fmt.Sprintf("%.0f", v)
+}
+
+func formatCost(v float64) string {
+	if v != 0 && v < 0.01 {
+		return "<$0.01"
+	}
+	return fmt.Sprintf("$%.2f", v)
+}
@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mantil-io/mantil/kit/aws"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCostPricingTable(t *testing.T) {
+	for _, r := range supportedAWSRegions {
+		_, ok := costPricingTable[r]
+		require.True(t, ok, r)
+	}
+}
+
+func TestCostEstimate(t *testing.T) {
+	end := time.Date(2021, 11, 1, 12, 0, 0, 0, time.UTC)
+	q := &costQuery{
+		lambdas: []costLambda{
+			{name: "ping", awsName: "project-dev-ping-abcdefg", memorySize: 128},
+			{name: "heavy", awsName: "project-dev-heavy-abcdefg", memorySize: 1024},
+		},
+		tables:   []string{"project-dev-kv-abcdefg"},
+		bucket:   "project-dev-public-abcdefg",
+		httpApi:  "a1b2c3d4e5",
+		start:    end.Add(-30 * 24 * time.Hour),
+		end:      end,
+		days:     30,
+		interval: 30 * 24 * time.Hour,
+	}
+	queries := q.queries()
+	require.Len(t, queries, 3*2+2+1+1)
+	ids := make(map[string]bool)
+	for _, mq := range queries {
+		ids[mq.ID] = true
+	}
+	require.Len(t, ids, len(queries))
+	require.True(t, ids["l1_duration_sum"])
+	require.True(t, ids["t0_consumedwritecapacityunits_sum"])
+
+	series := map[string]aws.MetricSeries{
+		"l0_invocations_sum":                {Values: []float64{1e6}},
+		"l0_duration_sum":                   {Values: []float64{1e6 * 1000}},
+		"l1_invocations_sum":                {Values: []float64{1e5}},
+		"l1_duration_sum":                   {Values: []float64{1e5 * 1000}},
+		"l1_incomingbytes_sum":              {Values: []float64{2 << 30}},
+		"t0_consumedreadcapacityunits_sum":  {Values: []float64{2e6}},
+		"t0_consumedwritecapacityunits_sum": {Values: []float64{1e6}},
+		"s3_bucketsizebytes_average":        {Values: []float64{1 << 30, 3 << 30}},
+		"http_count_sum":                    {Values: []float64{5e5, 5e5}},
+	}
+	p := costPricingTable["us-east-1"]
+	e := q.estimate(series, p)
+
+	cost := func(service string) float64 {
+		for _, s := range e.Services {
+			if s.Service == service {
+				return s.Cost
+			}
+		}
+		t.Fatalf("service %s not found", service)
+		return 0
+	}
+	require.InDelta(t, 1.1*p.LambdaRequests, cost("Lambda requests"), 1e-9)
+	// 1M * 1s * 0.125GB + 100k * 1s * 1GB
+	require.InDelta(t, 225000*p.LambdaGBSecond, cost("Lambda duration"), 1e-9)
+	require.InDelta(t, p.HttpRequests, cost("API Gateway HTTP"), 1e-9)
+	require.InDelta(t, 2*p.DynamoDBReads, cost("DynamoDB reads"), 1e-9)
+	require.InDelta(t, p.DynamoDBWrites, cost("DynamoDB writes"), 1e-9)
+	require.InDelta(t, 2*p.S3StorageGB, cost("S3 storage"), 1e-9)
+	require.InDelta(t, 2*p.LogsIngestionGB, cost("CloudWatch Logs"), 1e-9)
+	require.Zero(t, cost("API Gateway WebSocket"))
+
+	var total float64
+	for _, s := range e.Services {
+		total += s.Cost
+	}
+	require.InDelta(t, total, e.Total, 1e-9)
+
+	require.Len(t, e.Functions, 2)
+	require.Equal(t, "heavy", e.Functions[0].Name)
+	require.InDelta(t, 100000.0, e.Functions[0].GBSeconds, 1e-9)
+	require.Equal(t, "ping", e.Functions[1].Name)
+	require.InDelta(t, 125000.0, e.Functions[1].GBSeconds, 1e-9)
+}
+
+func TestFormatCost(t *testing.T) {
+	require.Equal(t, "$0.00", formatCost(0))
+	require.Equal(t, "<$0.01", formatCost(0.001))
+	require.Equal(t, "$12.35", formatCost(12.345))
+}
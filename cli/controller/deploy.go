@@ -3,17 +3,19 @@ package controller
 import (
 	"fmt"
 	"net/http"
+	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/mantil-io/mantil/cli/log"
 	"github.com/mantil-io/mantil/cli/ui"
 	"github.com/mantil-io/mantil/domain"
+	"github.com/mantil-io/mantil/kit/aws"
 	"github.com/mantil-io/mantil/node/dto"
 )
 
 const (
-	ApiDir           = "api"
 	PublicDir        = "public"
 	BuildDir         = "build"
 	BinaryName       = "bootstrap"
@@ -28,15 +30,22 @@ var (
 
 type DeployArgs struct {
 	Stage string
+	// deploy all projects in the repository
+	All bool
 }
 
 type Deploy struct {
-	repoPut func(bucket, key string, content []byte) error
-	diff    *domain.StageDiff
+	repoPut        func(bucket, key string, content []byte) error
+	repoPutObject  func(bucket, key string, content []byte, o aws.PutObjectOptions) error
+	repoDelete     func(bucket, key string) error
+	repoInvalidate func(distributionID string, paths []string) error
+	diff           *domain.StageDiff
 
 	store *domain.FileStore
 	stage *domain.Stage
 	title string
+	// api methods configuration from the comment directives by api name
+	sourceMethods map[string]map[string]domain.MethodConfiguration
 
 	buildDuration     time.Duration
 	lastBuildDuration time.Duration
@@ -46,6 +55,9 @@ type Deploy struct {
 }
 
 func NewDeploy(a DeployArgs) error {
+	if a.All {
+		return deployAll(a.Stage)
+	}
 	fs, _, err := newProjectStore()
 	if err != nil {
 		return log.Wrap(err)
@@ -61,6 +73,41 @@ func NewDeploy(a DeployArgs) error {
 	return d.Deploy()
 }
 
+// deployAll deploys each project found in the repository to the stage
+func deployAll(stage string) error {
+	if stage == "" {
+		return log.Wrapf("stage is required when deploying all projects, set it with --stage")
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return log.Wrap(err)
+	}
+	root, err := findRepositoryRoot(wd)
+	if err != nil {
+		return log.Wrap(err)
+	}
+	projects, err := findProjects(root)
+	if err != nil {
+		return log.Wrap(err)
+	}
+	if len(projects) == 0 {
+		return log.Wrap(&domain.ProjectNotFoundError{})
+	}
+	// project store and build are relative to the current dir
+	defer os.Chdir(wd)
+	for _, p := range projects {
+		ui.Info("")
+		ui.Title("Deploying project in %s\n", relativePath(root, p))
+		if err := os.Chdir(p); err != nil {
+			return log.Wrap(err)
+		}
+		if err := NewDeploy(DeployArgs{Stage: stage}); err != nil {
+			return log.Wrap(err, "deploy of the project in %s failed", relativePath(root, p))
+		}
+	}
+	return nil
+}
+
 func createStage(name string) error {
 	ui.Info("\nNo stages found for this project, creating a new stage...")
 	s, err := NewStage(StageArgs{
@@ -91,6 +138,9 @@ func (d *Deploy) setAWSclient() error {
 		return log.Wrap(err)
 	}
 	d.repoPut = awsClient.S3().Put
+	d.repoPutObject = awsClient.S3().PutObject
+	d.repoDelete = awsClient.S3().DeleteObject
+	d.repoInvalidate = awsClient.CreateInvalidation
 	return nil
 }
 
@@ -222,60 +272,125 @@ func (d *Deploy) callBackend() error {
 func (d *Deploy) backendRequest() dto.DeployRequest {
 	req := dto.DeployRequest{
 		ProjectName:        d.stage.Project().Name,
+		StageName:          d.stage.Name,
 		NodeBucket:         d.stage.Node().Bucket,
 		FunctionsForUpdate: nil,
 		StageTemplate:      nil,
 	}
-	var fns []dto.Function
 	var fnsu []dto.Function
 	for _, f := range d.stage.Functions {
-		df := d.workspaceFunction2dto(*f)
-		fns = append(fns, df)
 		for _, fn := range d.diff.UpdatedFunctions() {
 			if fn == f.Name {
-				fnsu = append(fnsu, df)
+				fnsu = append(fnsu, d.workspaceFunction2dto(*f))
 			}
 		}
 	}
 	req.FunctionsForUpdate = fnsu
 	if d.diff.InfrastructureChanged() {
-		req.StageTemplate = &dto.StageTemplate{
-			Project:             d.stage.Project().Name,
-			Bucket:              d.stage.Node().Bucket,
-			BucketPrefix:        d.stage.StateBucketPrefix(),
-			Functions:           fns,
-			Region:              d.stage.Node().Region,
-			Stage:               d.stage.Name,
-			NodeFunctionsBucket: d.stage.Node().Functions.Bucket,
-			NodeFunctionsPath:   d.stage.Node().Functions.Path,
-			ResourceSuffix:      d.stage.Node().ResourceSuffix(),
-			ResourceTags:        d.stage.ResourceTags(),
-			WsEnv:               d.stage.WsEnv(),
-			AuthEnv:             d.stage.AuthEnv(),
-			HasPublic:           d.stage.HasPublic(),
-			NamingTemplate:      d.stage.ResourceNamingTemplate(),
-			PublicBucketName:    d.stage.PublicBucketName(),
-			CustomDomain:        d.workspaceCustomDomain2dto(d.stage.CustomDomain),
-		}
+		req.StageTemplate = d.stageTemplate()
 	}
 	return req
 }
 
+func (d *Deploy) stageTemplate() *dto.StageTemplate {
+	var fns []dto.Function
+	for _, f := range d.stage.Functions {
+		fns = append(fns, d.workspaceFunction2dto(*f))
+	}
+	return &dto.StageTemplate{
+		Project:             d.stage.Project().Name,
+		Bucket:              d.stage.Node().Bucket,
+		BucketPrefix:        d.stage.StateBucketPrefix(),
+		Functions:           fns,
+		Region:              d.stage.Node().Region,
+		Stage:               d.stage.Name,
+		NodeFunctionsBucket: d.stage.Node().Functions.Bucket,
+		NodeFunctionsPath:   d.stage.Node().Functions.Path,
+		ResourceSuffix:      d.stage.Node().ResourceSuffix(),
+		ResourceTags:        d.stage.ResourceTags(),
+		WsEnv:               d.stage.WsEnv(),
+		AuthEnv:             d.stage.AuthEnv(),
+		HasPublic:           d.stage.HasPublic(),
+		NamingTemplate:      d.stage.ResourceNamingTemplate(),
+		PublicBucketName:    d.stage.PublicBucketName(),
+		CustomDomain:        d.workspaceCustomDomain2dto(d.stage.CustomDomain),
+		CDN:                 d.workspaceCDN2dto(),
+		AlarmTopics:         d.workspaceAlarmTopics2dto(),
+		Tracing:             d.stage.HasTracing(),
+		LogRetentionDays:    d.stage.LogRetentionDays(),
+	}
+}
+
 func (d *Deploy) workspaceFunction2dto(w domain.Function) dto.Function {
 	return dto.Function{
-		Name:       w.Name,
-		LambdaName: w.LambdaName(),
-		S3Key:      w.S3Key,
-		Runtime:    "provided.al2",
-		Handler:    "bootstrap",
-		MemorySize: w.MemorySize,
-		Timeout:    w.Timeout,
-		Env:        w.Env,
-		Cron:       w.Cron,
-		EnableAuth: w.Private,
+		Name:        w.Name,
+		LambdaName:  w.LambdaName(),
+		S3Key:       w.S3Key,
+		Runtime:     "provided.al2",
+		Handler:     "bootstrap",
+		MemorySize:  w.MemorySize,
+		Timeout:     w.Timeout,
+		Env:         w.Env,
+		Cron:        w.Cron,
+		EnableAuth:  w.IsPrivate(),
+		Routes:      d.workspaceRoutes2dto(w.Routes()),
+		RootRouted:  w.RootRouted(),
+		Alarms:      d.workspaceAlarms2dto(w.Alarms()),
+		TracingMode: w.TracingMode(),
 	}
 }
 
+func (d *Deploy) workspaceAlarms2dto(alarms []domain.FunctionAlarm) []dto.Alarm {
+	var as []dto.Alarm
+	for _, a := range alarms {
+		da := dto.Alarm{
+			Name:               a.AWSName,
+			MetricName:         a.MetricName(),
+			ErrorRate:          a.Metric == domain.AlarmMetricErrorRate,
+			Threshold:          *a.Threshold,
+			Period:             a.Period,
+			EvaluationPeriods:  a.EvaluationPeriods,
+			Topics:             a.Topics(),
+			SubscriptionsTopic: a.SubscriptionsKey(),
+		}
+		// percentiles are extended statistics in the CloudWatch api
+		if strings.HasPrefix(a.Statistic, "p") {
+			da.ExtendedStatistic = a.Statistic
+		} else {
+			da.Statistic = a.Statistic
+		}
+		as = append(as, da)
+	}
+	return as
+}
+
+func (d *Deploy) workspaceAlarmTopics2dto() []dto.AlarmTopic {
+	var topics []dto.AlarmTopic
+	for _, t := range d.stage.AlarmTopics() {
+		dt := dto.AlarmTopic{Key: t.Key}
+		for _, s := range t.Subscriptions {
+			dt.Subscriptions = append(dt.Subscriptions, dto.AlarmSubscription{
+				Protocol: s.Protocol,
+				Endpoint: s.Endpoint,
+			})
+		}
+		topics = append(topics, dt)
+	}
+	return topics
+}
+
+func (d *Deploy) workspaceRoutes2dto(routes []domain.FunctionRoute) []dto.Route {
+	var rs []dto.Route
+	for _, r := range routes {
+		rs = append(rs, dto.Route{
+			Method:     r.HTTPMethod,
+			Path:       r.Path,
+			EnableAuth: r.Private,
+		})
+	}
+	return rs
+}
+
 func (d *Deploy) workspaceCustomDomain2dto(cd domain.CustomDomain) dto.CustomDomain {
 	return dto.CustomDomain{
 		DomainName:       cd.DomainName,
@@ -286,8 +401,25 @@ func (d *Deploy) workspaceCustomDomain2dto(cd domain.CustomDomain) dto.CustomDom
 	}
 }
 
+func (d *Deploy) workspaceCDN2dto() dto.CDN {
+	cdn := d.stage.CDN
+	priceClass := cdn.PriceClass
+	if priceClass == "" {
+		priceClass = "PriceClass_100"
+	}
+	return dto.CDN{
+		Enabled:          cdn.Enabled,
+		Domain:           d.stage.CDNDomain(),
+		CertDomain:       d.stage.CustomDomain.CertDomain,
+		HostedZoneDomain: d.stage.CustomDomain.HostedZoneDomain,
+		SPA:              cdn.SPA,
+		PriceClass:       priceClass,
+	}
+}
+
 func (d *Deploy) updateStage(rsp dto.DeployResponse) {
 	d.stage.SetEndpoints(rsp.Rest, rsp.Ws)
+	d.stage.SetDistribution(rsp.CDNDistributionID, rsp.CDNURL)
 	if rsp.PublicBucket != "" {
 		d.stage.SetPublicBucket(rsp.PublicBucket)
 	}
@@ -19,14 +19,29 @@ import (
 
 func (d *Deploy) createMains() error {
 	os.RemoveAll(filepath.Join(d.store.ProjectRoot(), FunctionsPath))
-	apis, err := d.localDirs(ApiDir)
+	apis, err := localDirs(d.store.ApiDir())
 	if err != nil {
 		return log.Wrap(err)
 	}
+	importPath, err := findPackageImportPath(d.store.ApiDir())
+	if err != nil {
+		return log.Wrap(err)
+	}
+	d.sourceMethods = make(map[string]map[string]domain.MethodConfiguration)
 	for _, api := range apis {
-		dir := d.apiDir(api)
+		ap, err := parseApi(api, d.apiDir(api))
+		if err != nil {
+			return log.Wrap(err)
+		}
+		methods, err := ap.sourceMethods()
+		if err != nil {
+			return log.Wrap(err, "invalid api %s", api)
+		}
+		d.sourceMethods[api] = methods
 		mainDest := filepath.Join(d.apiMainDir(api), MainFile)
-		if err := generateMain(api, dir, mainDest); err != nil {
+		routes := d.stage.FunctionRoutes(api, methods)
+		trace := d.stage.FunctionTracing(api, methods)
+		if err := generateMain(ap, importPath, routes, trace, mainDest); err != nil {
 			return log.Wrap(err)
 		}
 	}
@@ -34,7 +49,7 @@ func (d *Deploy) createMains() error {
 }
 
 func (d *Deploy) apiDir(api string) string {
-	return filepath.Join(d.store.ProjectRoot(), ApiDir, api)
+	return filepath.Join(d.store.ApiDir(), api)
 }
 
 func (d *Deploy) apiMainDir(api string) string {
@@ -42,7 +57,7 @@ func (d *Deploy) apiMainDir(api string) string {
 }
 
 func (d *Deploy) localFunctions() ([]domain.Resource, error) {
-	localFuncNames, err := d.localDirs(FunctionsPath)
+	localFuncNames, err := localDirs(filepath.Join(d.store.ProjectRoot(), FunctionsPath))
 	if err != nil {
 		return nil, log.Wrap(err)
 	}
@@ -58,8 +73,9 @@ func (d *Deploy) localFunctions() ([]domain.Resource, error) {
 			return nil, log.Wrap(err, "failed to hash %s", binaryPath)
 		}
 		localFuncs = append(localFuncs, domain.Resource{
-			Name: n,
-			Hash: hash,
+			Name:    n,
+			Hash:    hash,
+			Methods: d.sourceMethods[n],
 		})
 		log.Event(domain.Event{GoBuild: &domain.GoBuild{
 			Name:     n,
@@ -70,8 +86,8 @@ func (d *Deploy) localFunctions() ([]domain.Resource, error) {
 	return localFuncs, nil
 }
 
-func (d *Deploy) localDirs(path string) ([]string, error) {
-	files, err := ioutil.ReadDir(filepath.Join(d.store.ProjectRoot(), path))
+func localDirs(path string) ([]string, error) {
+	files, err := ioutil.ReadDir(path)
 	if os.IsNotExist(err) {
 		return nil, nil
 	}
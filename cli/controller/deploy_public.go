@@ -5,9 +5,11 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
-	"io"
 	"io/ioutil"
+	"mime"
+	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -15,84 +17,237 @@ import (
 	"github.com/mantil-io/mantil/cli/log"
 	"github.com/mantil-io/mantil/cli/ui"
 	"github.com/mantil-io/mantil/domain"
-	"golang.org/x/mod/sumdb/dirhash"
+	"github.com/mantil-io/mantil/kit/aws"
+	"github.com/mantil-io/mantil/kit/gz"
 )
 
+// publicFile is a file in the project public folder with the http headers
+// used when serving it from the public site bucket
+type publicFile struct {
+	path            string
+	hash            string
+	contentType     string
+	cacheControl    string
+	contentEncoding string
+}
+
 func (d *Deploy) updatePublicContent() error {
 	if !d.diff.HasPublicUpdates() {
 		return nil
 	}
-	basePath := filepath.Join(d.store.ProjectRoot(), PublicDir)
-	err := filepath.Walk(basePath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
+	files, err := d.publicFiles()
+	if err != nil {
+		return log.Wrap(err)
+	}
+	var resources []domain.Resource
+	byPath := make(map[string]publicFile)
+	for _, f := range files {
+		resources = append(resources, domain.Resource{Name: f.path, Hash: f.hash})
+		byPath[f.path] = f
+	}
+	added, updated, removed := d.stage.Public.ApplyFiles(resources)
+	for _, p := range added {
+		ui.Info("\tadded   %s", p)
+		if err := d.uploadPublicFile(byPath[p]); err != nil {
 			return log.Wrap(err)
 		}
-		if info.IsDir() {
-			return nil
-		}
-		relPath, err := filepath.Rel(basePath, path)
-		if err != nil {
+	}
+	for _, p := range updated {
+		ui.Info("\tchanged %s", p)
+		if err := d.uploadPublicFile(byPath[p]); err != nil {
 			return log.Wrap(err)
 		}
-		ui.Info("\t%s", relPath)
-		buf, err := ioutil.ReadFile(path)
-		if err != nil {
+	}
+	for _, p := range removed {
+		ui.Info("\tremoved %s", p)
+		if err := d.repoDelete(d.stage.Public.Bucket, p); err != nil {
 			return log.Wrap(err)
 		}
-		if err := d.repoPut(d.stage.Public.Bucket, relPath, buf); err != nil {
+	}
+	if d.stage.Distribution != nil {
+		if err := d.invalidateCDN(append(updated, removed...)); err != nil {
 			return log.Wrap(err)
 		}
-		return nil
-	})
-	if err != nil {
-		return log.Wrap(err)
 	}
 	pe, err := d.stage.PublicEnv()
 	if err != nil {
 		return log.Wrap(err)
 	}
-	if err := d.repoPut(d.stage.Public.Bucket, domain.PublicEnvKey, pe); err != nil {
+	if err := d.repoPutObject(d.stage.Public.Bucket, domain.PublicEnvKey, pe, aws.PutObjectOptions{
+		ContentType:  contentType(domain.PublicEnvKey, pe),
+		CacheControl: "no-cache",
+	}); err != nil {
 		return log.Wrap(err)
 	}
 	return nil
 }
 
-func (d *Deploy) publicHash() (string, error) {
-	// inspired by Hash1 in dirhash but changed encoding to hex and removed prefix
-	hashFunc := func(files []string, open func(string) (io.ReadCloser, error)) (string, error) {
-		h := sha256.New()
-		files = append([]string(nil), files...)
-		sort.Strings(files)
-		for _, file := range files {
-			if strings.Contains(file, "\n") {
-				return "", errors.New("filenames with newlines are not supported")
-			}
-			r, err := open(file)
-			if err != nil {
-				return "", err
-			}
-			hf := sha256.New()
-			_, err = io.Copy(hf, r)
-			r.Close()
-			if err != nil {
-				return "", err
-			}
-			fmt.Fprintf(h, "%x  %s\n", hf.Sum(nil), file)
+func (d *Deploy) invalidateCDN(files []string) error {
+	paths := invalidationPaths(files)
+	if len(paths) == 0 {
+		return nil
+	}
+	ui.Info("\tinvalidating %d paths in CDN", len(paths))
+	return d.repoInvalidate(d.stage.Distribution.ID, paths)
+}
+
+// invalidationPaths returns distribution paths of the public files, index
+// files are also cached under the directory path
+func invalidationPaths(files []string) []string {
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, "/"+f)
+		if path.Base(f) != "index.html" {
+			continue
+		}
+		if dir := path.Dir(f); dir == "." {
+			paths = append(paths, "/")
+		} else {
+			paths = append(paths, "/"+dir+"/")
 		}
-		return hex.EncodeToString(h.Sum(nil)), nil
 	}
-	dir := filepath.Join(d.store.ProjectRoot(), PublicDir)
-	hash, err := dirhash.HashDir(dir, "", hashFunc)
+	return paths
+}
+
+func (d *Deploy) uploadPublicFile(f publicFile) error {
+	buf, err := ioutil.ReadFile(filepath.Join(d.publicDir(), filepath.FromSlash(f.path)))
+	if err != nil {
+		return log.Wrap(err)
+	}
+	if f.contentEncoding == "gzip" {
+		if buf, err = gz.Zip(buf); err != nil {
+			return log.Wrap(err)
+		}
+	}
+	d.uploadBytes += int64(len(buf))
+	return d.repoPutObject(d.stage.Public.Bucket, f.path, buf, aws.PutObjectOptions{
+		ContentType:     f.contentType,
+		CacheControl:    f.cacheControl,
+		ContentEncoding: f.contentEncoding,
+	})
+}
+
+// publicFiles walks project public folder and for each file finds http
+// headers and hash of the content and headers, so that changes in the public
+// configuration also result in the file upload
+func (d *Deploy) publicFiles() ([]publicFile, error) {
+	config := d.stage.PublicConfiguration()
+	basePath := d.publicDir()
+	var files []publicFile
+	err := filepath.Walk(basePath, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return log.Wrap(err)
+		}
+		if info.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(basePath, p)
+		if err != nil {
+			return log.Wrap(err)
+		}
+		relPath = filepath.ToSlash(relPath)
+		if strings.Contains(relPath, "\n") {
+			return errors.New("filenames with newlines are not supported")
+		}
+		buf, err := ioutil.ReadFile(p)
+		if err != nil {
+			return log.Wrap(err)
+		}
+		f := publicFile{
+			path:         relPath,
+			contentType:  contentType(relPath, buf),
+			cacheControl: config.CacheControlFor(relPath),
+		}
+		if config.Compress && compressible(f.contentType) {
+			f.contentEncoding = "gzip"
+		}
+		h := sha256.New()
+		h.Write(buf)
+		fmt.Fprintf(h, "\n%s\n%s\n%s", f.contentType, f.cacheControl, f.contentEncoding)
+		f.hash = hex.EncodeToString(h.Sum(nil))[:HashCharacters]
+		files = append(files, f)
+		return nil
+	})
+	if err != nil {
+		return nil, log.Wrap(err)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].path < files[j].path })
+	return files, nil
+}
+
+func (d *Deploy) publicHash() (string, error) {
+	files, err := d.publicFiles()
 	if err != nil {
 		return "", log.Wrap(err)
 	}
-	return hash[:HashCharacters], nil
+	h := sha256.New()
+	for _, f := range files {
+		fmt.Fprintf(h, "%s  %s\n", f.hash, f.path)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:HashCharacters], nil
+}
+
+func (d *Deploy) publicDir() string {
+	return filepath.Join(d.store.ProjectRoot(), PublicDir)
 }
 
 func (d *Deploy) hasPublic() bool {
-	_, err := os.Stat(filepath.Join(d.store.ProjectRoot(), PublicDir))
+	_, err := os.Stat(d.publicDir())
 	if errors.Is(err, os.ErrNotExist) {
 		return false
 	}
 	return true
 }
+
+// content types of the common web files, system mime tables differ between
+// platforms and some of them are missing or wrong there
+var webContentTypes = map[string]string{
+	".html":        "text/html; charset=utf-8",
+	".htm":         "text/html; charset=utf-8",
+	".css":         "text/css; charset=utf-8",
+	".js":          "text/javascript; charset=utf-8",
+	".mjs":         "text/javascript; charset=utf-8",
+	".json":        "application/json",
+	".map":         "application/json",
+	".webmanifest": "application/manifest+json",
+	".svg":         "image/svg+xml",
+	".wasm":        "application/wasm",
+	".txt":         "text/plain; charset=utf-8",
+	".xml":         "application/xml",
+	".ico":         "image/x-icon",
+	".png":         "image/png",
+	".jpg":         "image/jpeg",
+	".jpeg":        "image/jpeg",
+	".gif":         "image/gif",
+	".webp":        "image/webp",
+	".woff":        "font/woff",
+	".woff2":       "font/woff2",
+}
+
+func contentType(p string, buf []byte) string {
+	ext := strings.ToLower(path.Ext(p))
+	if ct, ok := webContentTypes[ext]; ok {
+		return ct
+	}
+	if ct := mime.TypeByExtension(ext); ct != "" {
+		return ct
+	}
+	return http.DetectContentType(buf)
+}
+
+func compressible(contentType string) bool {
+	ct := strings.Split(contentType, ";")[0]
+	if strings.HasPrefix(ct, "text/") {
+		return true
+	}
+	switch ct {
+	case "application/json",
+		"application/manifest+json",
+		"application/xml",
+		"application/wasm",
+		"image/svg+xml",
+		"image/x-icon":
+		return true
+	}
+	return false
+}
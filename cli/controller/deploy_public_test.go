@@ -0,0 +1,24 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublicContentType(t *testing.T) {
+	require.Equal(t, "text/css; charset=utf-8", contentType("css/main.css", nil))
+	require.Equal(t, "text/javascript; charset=utf-8", contentType("app.JS", nil))
+	require.Equal(t, "text/html; charset=utf-8", contentType("index.html", nil))
+	require.Equal(t, "text/plain; charset=utf-8", contentType("LICENSE", []byte("some text")))
+
+	require.True(t, compressible(contentType("app.js", nil)))
+	require.True(t, compressible(contentType("logo.svg", nil)))
+	require.False(t, compressible(contentType("logo.png", nil)))
+}
+
+func TestPublicInvalidationPaths(t *testing.T) {
+	paths := invalidationPaths([]string{"index.html", "css/main.css", "docs/index.html"})
+	require.Equal(t, []string{"/index.html", "/", "/css/main.css", "/docs/index.html", "/docs/"}, paths)
+	require.Empty(t, invalidationPaths(nil))
+}
@@ -0,0 +1,289 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mantil-io/mantil/cli/log"
+	"github.com/mantil-io/mantil/cli/ui"
+	"github.com/mantil-io/mantil/domain"
+	"github.com/mantil-io/mantil/kit/aws"
+	"github.com/mantil-io/mantil/kit/shell"
+	"github.com/mantil-io/mantil/kit/token"
+)
+
+const (
+	// minimal Go version for building Lambda functions
+	minGoMajor = 1
+	minGoMinor = 16
+	// platform of the Lambda functions
+	lambdaPlatform = "linux/arm64"
+	// timeout of the node reachability check
+	nodeCheckTimeout = 10 * time.Second
+)
+
+const (
+	doctorOK = iota
+	doctorWarning
+	doctorError
+)
+
+type doctorCheck struct {
+	name    string
+	status  int
+	details string
+	fix     string
+}
+
+// Doctor checks local toolchain, AWS credentials, workspace and project
+// files and nodes in one pass and shows how to fix found problems.
+func Doctor() error {
+	var checks []doctorCheck
+	checks = append(checks, checkGoToolchain()...)
+	checks = append(checks, checkAWSCredentials())
+	w, p, problems := domain.CheckStore()
+	checks = append(checks, storeChecks(p, problems)...)
+	if w != nil {
+		checks = append(checks, checkNodes(w)...)
+	}
+	showDoctorChecks(checks)
+	failed := 0
+	for _, c := range checks {
+		if c.status == doctorError {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return log.Wrapf("%d of %d checks failed", failed, len(checks))
+	}
+	return nil
+}
+
+func checkGoToolchain() []doctorCheck {
+	out, err := shell.Output(shell.ExecOptions{Args: []string{"go", "version"}})
+	if err != nil {
+		return []doctorCheck{{
+			name:    "go",
+			status:  doctorError,
+			details: "Go toolchain not found",
+			fix:     "Install Go from https://go.dev/dl/ and add it to the PATH.",
+		}}
+	}
+	c := goVersionCheck(out)
+	if c.status != doctorOK {
+		return []doctorCheck{c}
+	}
+	platforms, err := shell.Output(shell.ExecOptions{Args: []string{"go", "tool", "dist", "list"}})
+	if err != nil || !containsLine(platforms, lambdaPlatform) {
+		return []doctorCheck{c, {
+			name:    "go",
+			status:  doctorError,
+			details: fmt.Sprintf("Go toolchain can't build for %s platform of the Lambda functions", lambdaPlatform),
+			fix:     "Install the official Go distribution from https://go.dev/dl/.",
+		}}
+	}
+	return []doctorCheck{c}
+}
+
+var goVersionRegex = regexp.MustCompile(`go(\d+)\.(\d+)`)
+
+// goVersionCheck checks output of the go version command
+func goVersionCheck(out string) doctorCheck {
+	m := goVersionRegex.FindStringSubmatch(out)
+	if m == nil {
+		return doctorCheck{
+			name:    "go",
+			status:  doctorWarning,
+			details: fmt.Sprintf("could not parse Go version from %q", out),
+		}
+	}
+	major, _ := strconv.Atoi(m[1])
+	minor, _ := strconv.Atoi(m[2])
+	version := strings.TrimPrefix(m[0], "go")
+	if major < minGoMajor || (major == minGoMajor && minor < minGoMinor) {
+		return doctorCheck{
+			name:    "go",
+			status:  doctorError,
+			details: fmt.Sprintf("Go %s is too old, %d.%d or newer is required", version, minGoMajor, minGoMinor),
+			fix:     "Upgrade Go from https://go.dev/dl/.",
+		}
+	}
+	return doctorCheck{name: "go", details: fmt.Sprintf("Go %s", version)}
+}
+
+func containsLine(out, line string) bool {
+	for _, l := range strings.Split(out, "\n") {
+		if strings.TrimSpace(l) == line {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAWSCredentials checks default AWS credentials used when no
+// credentials options are set for the aws commands
+func checkAWSCredentials() doctorCheck {
+	fix := "Set AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_DEFAULT_REGION environment variables or configure default profile in ~/.aws/config. " +
+		"Credentials are needed only for the 'mantil aws' commands, they can also be set with command options."
+	cli, err := aws.New()
+	if err == nil {
+		err = cli.Try()
+	}
+	if err != nil {
+		return doctorCheck{
+			name:    "aws credentials",
+			status:  doctorWarning,
+			details: err.Error(),
+			fix:     fix,
+		}
+	}
+	return doctorCheck{
+		name:    "aws credentials",
+		details: fmt.Sprintf("account %s in region %s", cli.AccountID(), cli.Region()),
+	}
+}
+
+func storeChecks(p *domain.Project, problems []domain.StoreProblem) []doctorCheck {
+	var checks []doctorCheck
+	for _, sp := range problems {
+		details := sp.Problem
+		if sp.File != "" {
+			details = fmt.Sprintf("%s: %s", sp.File, sp.Problem)
+		}
+		checks = append(checks, doctorCheck{
+			name:    "config",
+			status:  doctorError,
+			details: details,
+			fix:     sp.Fix,
+		})
+	}
+	if len(checks) > 0 {
+		return checks
+	}
+	details := "workspace is valid"
+	if p != nil {
+		details = fmt.Sprintf("workspace and project %s are valid", p.Name)
+	}
+	return []doctorCheck{{name: "config", details: details}}
+}
+
+func checkNodes(w *domain.Workspace) []doctorCheck {
+	nodes, err := w.NodeList()
+	if err != nil {
+		return []doctorCheck{{
+			name:    "nodes",
+			status:  doctorError,
+			details: fmt.Sprintf("could not read nodes - %v", err),
+			fix:     "Log in to the node again with 'mantil node login <node-url>'.",
+		}}
+	}
+	if len(nodes) == 0 {
+		return []doctorCheck{{
+			name:    "nodes",
+			status:  doctorWarning,
+			details: "there are no nodes in the workspace",
+			fix:     "Install a node with 'mantil aws install'.",
+		}}
+	}
+	var checks []doctorCheck
+	for _, n := range nodes {
+		name := fmt.Sprintf("node %s", n.Name)
+		checks = append(checks, nodeReachabilityCheck(name, n))
+		if n.Upgrade != nil {
+			checks = append(checks, nodeUpgradeCheck(name, n))
+		} else {
+			checks = append(checks, nodeVersionCheck(name, n.Name, n.Version, domain.Version()))
+		}
+		if n.GithubAuthEnabled() {
+			checks = append(checks, nodeTokenCheck(name, n))
+		}
+	}
+	return checks
+}
+
+func nodeReachabilityCheck(name string, n *domain.Node) doctorCheck {
+	client := &http.Client{Timeout: nodeCheckTimeout}
+	rsp, err := client.Get(n.Endpoints.Rest)
+	if err != nil {
+		return doctorCheck{
+			name:    name,
+			status:  doctorError,
+			details: fmt.Sprintf("endpoint %s is not reachable", n.Endpoints.Rest),
+			fix: fmt.Sprintf("Check your network connection. If the node was uninstalled from another workspace "+
+				"install it again with 'mantil aws install %s'.", n.Name),
+		}
+	}
+	rsp.Body.Close()
+	return doctorCheck{name: name, details: fmt.Sprintf("reachable at %s", n.Endpoints.Rest)}
+}
+
+func nodeVersionCheck(name, node, nodeVersion, cliVersion string) doctorCheck {
+	if cliVersion == "" || nodeVersion == cliVersion {
+		return doctorCheck{name: name, details: fmt.Sprintf("version %s", nodeVersion)}
+	}
+	return doctorCheck{
+		name:    name,
+		status:  doctorWarning,
+		details: fmt.Sprintf("node version %s doesn't match CLI version %s", nodeVersion, cliVersion),
+		fix:     fmt.Sprintf("Upgrade the node with 'mantil aws upgrade %s'.", node),
+	}
+}
+
+func nodeUpgradeCheck(name string, n *domain.Node) doctorCheck {
+	return doctorCheck{
+		name:    name,
+		status:  doctorError,
+		details: fmt.Sprintf("upgrade from version %s to %s was interrupted", n.Upgrade.Version, n.Version),
+		fix:     fmt.Sprintf("Roll back and upgrade the node again with 'mantil aws upgrade %s'.", n.Name),
+	}
+}
+
+func nodeTokenCheck(name string, n *domain.Node) doctorCheck {
+	t, err := n.AuthToken()
+	var terr *domain.TokenExpiredError
+	if errors.As(err, &terr) {
+		if n.RefreshToken() != "" {
+			return doctorCheck{name: name, details: "access token expired, it will be refreshed on the next use"}
+		}
+		return doctorCheck{
+			name:    name,
+			status:  doctorError,
+			details: "access token expired",
+			fix:     fmt.Sprintf("Log in to the node again with 'mantil node login %s'.", n.Endpoints.Rest),
+		}
+	}
+	if err != nil {
+		return doctorCheck{
+			name:    name,
+			status:  doctorError,
+			details: fmt.Sprintf("invalid access token - %v", err),
+			fix:     fmt.Sprintf("Log in to the node again with 'mantil node login %s'.", n.Endpoints.Rest),
+		}
+	}
+	exp, err := token.ExpiresIn(t)
+	if err != nil {
+		return doctorCheck{name: name, status: doctorWarning, details: fmt.Sprintf("could not read token expiry - %v", err)}
+	}
+	return doctorCheck{name: name, details: fmt.Sprintf("access token expires in %s", exp.Round(time.Minute))}
+}
+
+func showDoctorChecks(checks []doctorCheck) {
+	for _, c := range checks {
+		switch c.status {
+		case doctorOK:
+			ui.Info("✔ %s: %s", c.name, c.details)
+		case doctorWarning:
+			ui.Info("! %s: %s", c.name, c.details)
+		case doctorError:
+			ui.ErrorLine("✘ %s: %s", c.name, c.details)
+		}
+		if c.fix != "" {
+			ui.Info("  fix: %s", c.fix)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/mantil-io/mantil/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoVersionCheck(t *testing.T) {
+	c := goVersionCheck("go version go1.17.2 linux/amd64")
+	require.Equal(t, doctorOK, c.status)
+	require.Equal(t, "Go 1.17", c.details)
+
+	c = goVersionCheck("go version go1.15.8 darwin/amd64")
+	require.Equal(t, doctorError, c.status)
+	require.NotEmpty(t, c.fix)
+
+	c = goVersionCheck("go version devel +abc")
+	require.Equal(t, doctorWarning, c.status)
+}
+
+func TestContainsLine(t *testing.T) {
+	out := "linux/amd64\nlinux/arm\nlinux/arm64\n"
+	require.True(t, containsLine(out, "linux/arm64"))
+	require.False(t, containsLine(out, "darwin/arm64"))
+}
+
+func TestNodeVersionCheck(t *testing.T) {
+	c := nodeVersionCheck("node n", "n", "v0.2.1", "v0.2.1")
+	require.Equal(t, doctorOK, c.status)
+
+	c = nodeVersionCheck("node n", "n", "v0.2.0", "v0.2.1")
+	require.Equal(t, doctorWarning, c.status)
+	require.Contains(t, c.fix, "mantil aws upgrade n")
+
+	c = nodeVersionCheck("node n", "n", "v0.2.0", "")
+	require.Equal(t, doctorOK, c.status)
+}
+
+func TestNodeUpgradeCheck(t *testing.T) {
+	n := &domain.Node{Name: "n", Version: "v0.2.1", Upgrade: &domain.NodeUpgrade{Version: "v0.2.0"}}
+	c := nodeUpgradeCheck("node n", n)
+	require.Equal(t, doctorError, c.status)
+	require.Equal(t, "upgrade from version v0.2.0 to v0.2.1 was interrupted", c.details)
+	require.Contains(t, c.fix, "mantil aws upgrade n")
+}
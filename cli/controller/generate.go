@@ -14,7 +14,6 @@ import (
 	"github.com/mantil-io/mantil/cli/log"
 	"github.com/mantil-io/mantil/cli/ui"
 	"github.com/mantil-io/mantil/domain"
-	"golang.org/x/mod/modfile"
 	"golang.org/x/tools/imports"
 )
 
@@ -29,9 +28,17 @@ func (e ApiNewError) Error() string {
 type GenerateApiArgs struct {
 	Name    string
 	Methods []string
+	// OpenAPI document from which apis are created
+	From string
 }
 
 func GenerateApi(a GenerateApiArgs) error {
+	if a.From != "" {
+		return generateApisFromDocument(a)
+	}
+	if a.Name == "" {
+		return log.Wrapf("api name is required")
+	}
 	if err := domain.ValidateName(a.Name); err != nil {
 		return log.Wrap(err)
 	}
@@ -46,7 +53,7 @@ func GenerateApi(a GenerateApiArgs) error {
 	if err != nil {
 		return log.Wrap(err)
 	}
-	importPath, err := findPackageImportPath(projectPath)
+	importPath, err := apiImportPath(projectPath)
 	if err != nil {
 		return log.Wrap(err)
 	}
@@ -56,13 +63,19 @@ func GenerateApi(a GenerateApiArgs) error {
 	return generateApi(projectPath, a.Name, a.Methods)
 }
 
-func findPackageImportPath(projectPath string) (string, error) {
-	modPath := filepath.Join(projectPath, "go.mod")
-	buf, err := ioutil.ReadFile(modPath)
+func generateApisFromDocument(a GenerateApiArgs) error {
+	if len(a.Methods) > 0 {
+		return log.Wrapf("methods can't be set for apis generated from the OpenAPI document")
+	}
+	projectPath, err := domain.FindProjectRoot(".")
+	if err != nil {
+		return log.Wrap(err)
+	}
+	importPath, err := apiImportPath(projectPath)
 	if err != nil {
-		return "", log.Wrap(err)
+		return log.Wrap(err)
 	}
-	return modfile.ModulePath(buf), nil
+	return generateApisFromOpenAPI(importPath, projectPath, a.Name, a.From)
 }
 
 func generateApi(projectPath, functionName string, methods []string) error {
@@ -76,7 +89,11 @@ func generateApi(projectPath, functionName string, methods []string) error {
 }
 
 func generateApiDefault(projectPath, functionName string) error {
-	defaultFile := filepath.Join(projectPath, "api", functionName, fmt.Sprintf("%s.go", functionName))
+	apiDir, err := domain.ProjectApiDir(projectPath)
+	if err != nil {
+		return log.Wrap(err)
+	}
+	defaultFile := filepath.Join(apiDir, functionName, fmt.Sprintf("%s.go", functionName))
 	if fileExists(defaultFile) {
 		ui.Info("%s already exists", relativePath(projectPath, defaultFile))
 		return nil
@@ -93,7 +110,11 @@ func generateApiDefault(projectPath, functionName string) error {
 }
 
 func generateApiMethods(projectPath, functionName string, methods []string) error {
-	functionApi := filepath.Join(projectPath, "api", functionName)
+	apiDir, err := domain.ProjectApiDir(projectPath)
+	if err != nil {
+		return log.Wrap(err)
+	}
+	functionApi := filepath.Join(apiDir, functionName)
 	for _, m := range methods {
 		methodFile := filepath.Join(functionApi, fmt.Sprintf("%s.go", m))
 		if fileExists(methodFile) {
@@ -119,7 +140,11 @@ func generateFunctionTest(importPath, projectPath, functionName string, methods
 	if err := generateApiTestInit(projectPath); err != nil {
 		return log.Wrap(err)
 	}
-	if err := generateApiTest(importPath, projectPath, functionName, methods); err != nil {
+	if err := generateApiTest(projectPath, &test{
+		Name:       functionName,
+		ImportPath: importPath,
+		Methods:    methods,
+	}); err != nil {
 		return log.Wrap(err)
 	}
 	return nil
@@ -141,8 +166,8 @@ func generateApiTestInit(projectPath string) error {
 	return nil
 }
 
-func generateApiTest(importPath, projectPath, functionName string, methods []string) error {
-	apiTest := filepath.Join(projectPath, "test", fmt.Sprintf("%s_test.go", strings.ToLower(functionName)))
+func generateApiTest(projectPath string, data *test) error {
+	apiTest := filepath.Join(projectPath, "test", fmt.Sprintf("%s_test.go", strings.ToLower(data.Name)))
 	if fileExists(apiTest) {
 		ui.Info("%s already exists", relativePath(projectPath, apiTest))
 		return nil
@@ -150,11 +175,7 @@ func generateApiTest(importPath, projectPath, functionName string, methods []str
 	ui.Info("Generating %s...", relativePath(projectPath, apiTest))
 	if err := generateFromTemplate(
 		apiFunctionTestTemplate,
-		&test{
-			Name:       functionName,
-			ImportPath: importPath,
-			Methods:    methods,
-		},
+		data,
 		apiTest,
 	); err != nil {
 		return log.Wrap(err)
@@ -220,23 +241,24 @@ func saveFile(in []byte, path string) error {
 	return nil
 }
 
-func generateMain(api, apiDir, destination string) error {
-	if err := isNewValid(api, apiDir); err != nil {
-		return log.Wrap(err)
-	}
-	projectPath, err := domain.FindProjectRoot(".")
+func generateMain(ap *apiPackage, importPath string, routes []domain.FunctionRoute, trace bool, destination string) error {
+	api := ap.pkg.Name
+	handler, err := newApiHandler(ap, routes, trace)
 	if err != nil {
-		return log.Wrap(err)
-	}
-	importPath, err := findPackageImportPath(projectPath)
-	if err != nil {
-		return log.Wrap(err)
+		return log.Wrap(err, "invalid api %s", api)
 	}
 	if err := generateFromTemplate(
 		apiFunctionMainTemplate,
 		&function{
-			Name:       api,
-			ImportPath: importPath,
+			Name:          api,
+			ImportPath:    importPath,
+			NewContext:    ap.newContext,
+			NewError:      ap.newError,
+			Close:         ap.close,
+			CloseError:    ap.closeError,
+			Shutdown:      ap.shutdown,
+			ShutdownError: ap.shutdownError,
+			Handler:       handler,
 		},
 		destination,
 	); err != nil {
@@ -246,56 +268,165 @@ func generateMain(api, apiDir, destination string) error {
 }
 
 // isNewValid checks whether function New in api is of proper type
-// function should have no parameters and only one return value - struct or pointer to the struct
+// function should have no parameters or only context parameter and return
+// struct or pointer to the struct, optionally followed by an error
 func isNewValid(api, dir string) error {
-	pkgs, err := parser.ParseDir(token.NewFileSet(), dir, nil, parser.AllErrors)
+	_, err := parseApi(api, dir)
+	return err
+}
+
+// apiPackage is parsed api package
+type apiPackage struct {
+	pkg *ast.Package
+	// name of the struct returned by function New
+	typeName string
+	// New accepts context as the only parameter
+	newContext bool
+	// New returns error as the second value
+	newError bool
+	// New returns pointer to the struct
+	newPointer bool
+	// api struct has Close() and Shutdown(ctx) lifecycle hooks and whether
+	// they return error
+	close         bool
+	closeError    bool
+	shutdown      bool
+	shutdownError bool
+	// api struct has Middleware() method with middleware functions which
+	// wrap every api method
+	middleware bool
+}
+
+// parseApi parses api package, finds struct returned by function New and its
+// lifecycle hooks
+func parseApi(api, dir string) (*apiPackage, error) {
+	pkgs, err := parser.ParseDir(token.NewFileSet(), dir, nil, parser.AllErrors|parser.ParseComments)
 	if err != nil {
-		return log.Wrap(err)
+		return nil, log.Wrap(err)
 	}
 	pkg, ok := pkgs[api]
 	if !ok {
-		return log.Wrapf("package %s doesn't exist in folder %s", api, dir)
+		return nil, log.Wrapf("package %s doesn't exist in folder %s", api, dir)
 	}
 	for _, v := range pkg.Files {
 		for _, o := range v.Scope.Objects {
 			if o.Name == "New" && o.Kind.String() == "func" {
 				decl, ok := o.Decl.(*ast.FuncDecl)
 				if !ok {
-					return log.Wrap(&ApiNewError{api})
+					return nil, log.Wrap(&ApiNewError{api})
 				}
 				// is not a function
 				if decl.Recv != nil {
 					continue
 				}
-				// has no parameters
-				if len(decl.Type.Params.List) > 0 {
-					return log.Wrap(&ApiNewError{api})
-				}
-				rl := decl.Type.Results.List
-				// has only one return value which is either struct or pointer to struct
-				if len(rl) > 1 {
-					return log.Wrap(&ApiNewError{api})
-				}
-				var idExpr ast.Expr
-				expr, ok := rl[0].Type.(*ast.StarExpr)
-				if ok {
-					idExpr = expr.X
-				} else {
-					idExpr = rl[0].Type
-				}
-				ident, ok := idExpr.(*ast.Ident)
-				if ok && ident.Obj != nil {
-					ft, ok := ident.Obj.Decl.(*ast.TypeSpec)
-					if ok {
-						_, ok := ft.Type.(*ast.StructType)
-						if ok {
-							return nil
-						}
-					}
+				ap, ok := parseNew(decl)
+				if !ok {
+					return nil, log.Wrap(&ApiNewError{api})
 				}
-				return log.Wrap(&ApiNewError{api})
+				ap.pkg = pkg
+				ap.hooks()
+				return ap, nil
+			}
+		}
+	}
+	return nil, log.Wrap(&ApiNewError{api})
+}
+
+func parseNew(decl *ast.FuncDecl) (*apiPackage, bool) {
+	ap := &apiPackage{}
+	// has no parameters or only context
+	params := flattenFields(decl.Type.Params)
+	switch {
+	case len(params) == 0:
+	case len(params) == 1 && isContext(params[0]):
+		ap.newContext = true
+	default:
+		return nil, false
+	}
+	if decl.Type.Results == nil {
+		return nil, false
+	}
+	// returns struct or pointer to struct optionally followed by error
+	results := flattenFields(decl.Type.Results)
+	switch {
+	case len(results) == 1:
+	case len(results) == 2 && isError(results[1]):
+		ap.newError = true
+	default:
+		return nil, false
+	}
+	var idExpr ast.Expr
+	expr, ok := results[0].(*ast.StarExpr)
+	if ok {
+		idExpr = expr.X
+		ap.newPointer = true
+	} else {
+		idExpr = results[0]
+	}
+	ident, ok := idExpr.(*ast.Ident)
+	if ok && ident.Obj != nil {
+		ft, ok := ident.Obj.Decl.(*ast.TypeSpec)
+		if ok {
+			_, ok := ft.Type.(*ast.StructType)
+			if ok {
+				ap.typeName = ident.Name
+				return ap, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// hooks finds Close(), Shutdown(ctx) and Middleware() methods of the api struct
+func (ap *apiPackage) hooks() {
+	for _, f := range ap.pkg.Files {
+		for _, d := range f.Decls {
+			fd, ok := d.(*ast.FuncDecl)
+			if !ok || fd.Recv == nil || receiverName(fd.Recv.List[0].Type) != ap.typeName {
+				continue
+			}
+			switch {
+			case isCloseHook(fd):
+				ap.close = true
+				ap.closeError = fd.Type.Results != nil && len(fd.Type.Results.List) > 0
+			case isShutdownHook(fd):
+				ap.shutdown = true
+				ap.shutdownError = fd.Type.Results != nil && len(fd.Type.Results.List) > 0
+			case isMiddlewareHook(fd):
+				ap.middleware = true
 			}
 		}
 	}
-	return log.Wrap(&ApiNewError{api})
+}
+
+// isCloseHook checks for Close() or Close() error method
+func isCloseHook(fd *ast.FuncDecl) bool {
+	return fd.Name.Name == "Close" &&
+		len(flattenFields(fd.Type.Params)) == 0 &&
+		hookResults(fd)
+}
+
+// isShutdownHook checks for Shutdown(ctx context.Context) method with
+// optional error result
+func isShutdownHook(fd *ast.FuncDecl) bool {
+	params := flattenFields(fd.Type.Params)
+	return fd.Name.Name == "Shutdown" &&
+		len(params) == 1 && isContext(params[0]) &&
+		hookResults(fd)
+}
+
+// isMiddlewareHook checks for Middleware() method which returns list of
+// middleware functions
+func isMiddlewareHook(fd *ast.FuncDecl) bool {
+	return fd.Name.Name == "Middleware" &&
+		len(flattenFields(fd.Type.Params)) == 0 &&
+		fd.Type.Results != nil && len(flattenFields(fd.Type.Results)) == 1
+}
+
+func hookResults(fd *ast.FuncDecl) bool {
+	if fd.Type.Results == nil {
+		return true
+	}
+	results := flattenFields(fd.Type.Results)
+	return len(results) == 0 || (len(results) == 1 && isError(results[0]))
 }
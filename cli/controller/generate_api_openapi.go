@@ -0,0 +1,832 @@
+package controller
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mantil-io/mantil/cli/log"
+	"github.com/mantil-io/mantil/cli/ui"
+	"github.com/mantil-io/mantil/domain"
+	"gopkg.in/yaml.v2"
+)
+
+// generateApisFromOpenAPI creates one api for each tag or path group of
+// operations in the OpenAPI document, if name is set only that api is created
+func generateApisFromOpenAPI(importPath, projectPath, name, file string) error {
+	buf, err := ioutil.ReadFile(file)
+	if err != nil {
+		return log.Wrap(err)
+	}
+	doc, err := parseSpecDocument(buf)
+	if err != nil {
+		return log.Wrap(err, "invalid OpenAPI document %s", file)
+	}
+	apis, err := doc.apis()
+	if err != nil {
+		return log.Wrap(err)
+	}
+	if name != "" {
+		apis = filterSpecApis(apis, name)
+		if len(apis) == 0 {
+			return log.Wrapf("api %s not found in %s", name, file)
+		}
+	}
+	for _, a := range apis {
+		ui.Info("Generating function %s from %s", a.Name, relativePath(projectPath, file))
+		if err := generateApiTestInit(projectPath); err != nil {
+			return log.Wrap(err)
+		}
+		if err := generateApiTest(projectPath, a.test(importPath)); err != nil {
+			return log.Wrap(err)
+		}
+		if err := generateSpecApi(projectPath, a); err != nil {
+			return log.Wrap(err)
+		}
+	}
+	return nil
+}
+
+func filterSpecApis(apis []*specApi, name string) []*specApi {
+	for _, a := range apis {
+		if a.Name == name {
+			return []*specApi{a}
+		}
+	}
+	return nil
+}
+
+// generateSpecApi writes api struct, each method and component types in
+// separate files, existing files are left untouched
+func generateSpecApi(projectPath string, a *specApi) error {
+	apiDir, err := domain.ProjectApiDir(projectPath)
+	if err != nil {
+		return log.Wrap(err)
+	}
+	dir := filepath.Join(apiDir, a.Name)
+	files := []struct {
+		name string
+		tpl  string
+		data interface{}
+	}{
+		{name: a.Name + ".go", tpl: apiSpecTemplate, data: &function{Name: a.Name}},
+	}
+	for _, m := range a.Methods {
+		files = append(files, struct {
+			name string
+			tpl  string
+			data interface{}
+		}{name: strings.ToLower(m.Name) + ".go", tpl: apiSpecMethodTemplate, data: m})
+	}
+	if len(a.Types) > 0 {
+		files = append(files, struct {
+			name string
+			tpl  string
+			data interface{}
+		}{name: "types.go", tpl: apiSpecTypesTemplate, data: a})
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, f.name)
+		if fileExists(path) {
+			ui.Info("%s already exists", relativePath(projectPath, path))
+			continue
+		}
+		ui.Info("Generating %s...", relativePath(projectPath, path))
+		if err := generateFromTemplate(f.tpl, f.data, path); err != nil {
+			return log.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// specDocument is the part of the OpenAPI document used to scaffold apis
+type specDocument struct {
+	Paths      specPaths      `yaml:"paths"`
+	Components specComponents `yaml:"components"`
+}
+
+type specComponents struct {
+	Schemas       specSchemas                 `yaml:"schemas"`
+	Parameters    map[string]*specParameter   `yaml:"parameters"`
+	RequestBodies map[string]*specRequestBody `yaml:"requestBodies"`
+	Responses     map[string]*specResponse    `yaml:"responses"`
+}
+
+type specPathItem struct {
+	Get        *specOperation   `yaml:"get"`
+	Put        *specOperation   `yaml:"put"`
+	Post       *specOperation   `yaml:"post"`
+	Delete     *specOperation   `yaml:"delete"`
+	Options    *specOperation   `yaml:"options"`
+	Head       *specOperation   `yaml:"head"`
+	Patch      *specOperation   `yaml:"patch"`
+	Parameters []*specParameter `yaml:"parameters"`
+}
+
+func (p *specPathItem) operations() map[string]*specOperation {
+	return map[string]*specOperation{
+		http.MethodGet:     p.Get,
+		http.MethodPut:     p.Put,
+		http.MethodPost:    p.Post,
+		http.MethodDelete:  p.Delete,
+		http.MethodOptions: p.Options,
+		http.MethodHead:    p.Head,
+		http.MethodPatch:   p.Patch,
+	}
+}
+
+type specOperation struct {
+	OperationID string                   `yaml:"operationId"`
+	Summary     string                   `yaml:"summary"`
+	Description string                   `yaml:"description"`
+	Tags        []string                 `yaml:"tags"`
+	Parameters  []*specParameter         `yaml:"parameters"`
+	RequestBody *specRequestBody         `yaml:"requestBody"`
+	Responses   map[string]*specResponse `yaml:"responses"`
+}
+
+type specParameter struct {
+	Ref    string      `yaml:"$ref"`
+	Name   string      `yaml:"name"`
+	In     string      `yaml:"in"`
+	Schema *specSchema `yaml:"schema"`
+}
+
+type specRequestBody struct {
+	Ref     string                   `yaml:"$ref"`
+	Content map[string]specMediaType `yaml:"content"`
+}
+
+type specResponse struct {
+	Ref     string                   `yaml:"$ref"`
+	Content map[string]specMediaType `yaml:"content"`
+}
+
+type specMediaType struct {
+	Schema *specSchema `yaml:"schema"`
+}
+
+type specSchema struct {
+	Ref         string        `yaml:"$ref"`
+	Type        string        `yaml:"type"`
+	Format      string        `yaml:"format"`
+	Description string        `yaml:"description"`
+	Items       *specSchema   `yaml:"items"`
+	Properties  specSchemas   `yaml:"properties"`
+	Required    []string      `yaml:"required"`
+	AllOf       []*specSchema `yaml:"allOf"`
+	OneOf       []*specSchema `yaml:"oneOf"`
+	AnyOf       []*specSchema `yaml:"anyOf"`
+	// schema or boolean
+	AdditionalProperties interface{} `yaml:"additionalProperties"`
+}
+
+// additionalProperties returns schema of the map values, nil if the schema
+// is not a map
+func (s *specSchema) additionalProperties() (*specSchema, error) {
+	switch ap := s.AdditionalProperties.(type) {
+	case nil:
+		return nil, nil
+	case bool:
+		if !ap {
+			return nil, nil
+		}
+		return &specSchema{}, nil
+	}
+	buf, err := yaml.Marshal(s.AdditionalProperties)
+	if err != nil {
+		return nil, err
+	}
+	var v specSchema
+	if err := yaml.Unmarshal(buf, &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// isStruct is true for object schemas with properties which are declared as
+// go structs
+func (s *specSchema) isStruct() bool {
+	if s.Ref != "" || len(s.OneOf) > 0 || len(s.AnyOf) > 0 {
+		return false
+	}
+	if s.Type != "object" && s.Type != "" {
+		return false
+	}
+	if len(s.AllOf) == 1 && len(s.Properties.keys) == 0 {
+		return false
+	}
+	return len(s.Properties.keys) > 0 || len(s.AllOf) > 0
+}
+
+// specSchemas are schemas in the order of the document
+type specSchemas struct {
+	keys   []string
+	values map[string]*specSchema
+}
+
+func (s *specSchemas) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	keys, err := yamlKeys(unmarshal)
+	if err != nil {
+		return err
+	}
+	s.keys = keys
+	return unmarshal(&s.values)
+}
+
+// specPaths are path items in the order of the document
+type specPaths struct {
+	keys   []string
+	values map[string]*specPathItem
+}
+
+func (p *specPaths) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	keys, err := yamlKeys(unmarshal)
+	if err != nil {
+		return err
+	}
+	p.keys = keys
+	return unmarshal(&p.values)
+}
+
+func yamlKeys(unmarshal func(interface{}) error) ([]string, error) {
+	var ms yaml.MapSlice
+	if err := unmarshal(&ms); err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, i := range ms {
+		keys = append(keys, fmt.Sprint(i.Key))
+	}
+	return keys, nil
+}
+
+// parseSpecDocument parses OpenAPI document in YAML or JSON format
+func parseSpecDocument(buf []byte) (*specDocument, error) {
+	doc := &specDocument{}
+	if err := yaml.Unmarshal(buf, doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Paths.keys) == 0 {
+		return nil, fmt.Errorf("document has no paths")
+	}
+	return doc, nil
+}
+
+const (
+	specSchemasRef       = "#/components/schemas/"
+	specParametersRef    = "#/components/parameters/"
+	specRequestBodiesRef = "#/components/requestBodies/"
+	specResponsesRef     = "#/components/responses/"
+)
+
+func (d *specDocument) parameter(p *specParameter) *specParameter {
+	if p.Ref != "" {
+		if rp, ok := d.Components.Parameters[strings.TrimPrefix(p.Ref, specParametersRef)]; ok {
+			return rp
+		}
+	}
+	return p
+}
+
+func (d *specDocument) requestBody(rb *specRequestBody) *specRequestBody {
+	if rb != nil && rb.Ref != "" {
+		return d.Components.RequestBodies[strings.TrimPrefix(rb.Ref, specRequestBodiesRef)]
+	}
+	return rb
+}
+
+func (d *specDocument) response(r *specResponse) *specResponse {
+	if r != nil && r.Ref != "" {
+		return d.Components.Responses[strings.TrimPrefix(r.Ref, specResponsesRef)]
+	}
+	return r
+}
+
+// specApi is api scaffolded from the OpenAPI document
+type specApi struct {
+	Name    string
+	Methods []*specMethod
+	// declarations of the component schemas used by the api methods
+	Types []string
+}
+
+type specMethod struct {
+	Name         string
+	FunctionName string
+	Doc          []string
+	// route directive, empty if the method is called on its default path
+	Route string
+	Req   string
+	Rsp   string
+	// declarations of the request and response types
+	Types []string
+	// test of the method endpoint
+	endpoint testEndpoint
+	request  specRequest
+	response *specResponse
+}
+
+type specRequest struct {
+	body   *specRequestBody
+	params []*specParameter
+}
+
+func (a *specApi) test(importPath string) *test {
+	t := &test{
+		Name:       a.Name,
+		ImportPath: importPath,
+	}
+	for _, m := range a.Methods {
+		t.Endpoints = append(t.Endpoints, m.endpoint)
+	}
+	return t
+}
+
+var specHTTPMethods = []string{
+	http.MethodGet,
+	http.MethodPut,
+	http.MethodPost,
+	http.MethodDelete,
+	http.MethodOptions,
+	http.MethodHead,
+	http.MethodPatch,
+}
+
+// apis groups document operations into apis by the first tag or the first
+// path segment
+func (d *specDocument) apis() ([]*specApi, error) {
+	var apis []*specApi
+	byName := make(map[string]*specApi)
+	for _, path := range d.Paths.keys {
+		item := d.Paths.values[path]
+		if item == nil {
+			continue
+		}
+		ops := item.operations()
+		for _, hm := range specHTTPMethods {
+			op := ops[hm]
+			if op == nil {
+				continue
+			}
+			name, err := specApiName(path, op)
+			if err != nil {
+				return nil, log.Wrap(err)
+			}
+			a, ok := byName[name]
+			if !ok {
+				a = &specApi{Name: name}
+				byName[name] = a
+				apis = append(apis, a)
+			}
+			m, err := d.method(a, hm, path, item, op)
+			if err != nil {
+				return nil, log.Wrap(err, "operation %s %s", hm, path)
+			}
+			a.Methods = append(a.Methods, m)
+		}
+	}
+	for _, a := range apis {
+		if err := d.types(a); err != nil {
+			return nil, log.Wrap(err)
+		}
+	}
+	return apis, nil
+}
+
+var nonAlphanumericRegex = regexp.MustCompile(`[^a-z0-9]+`)
+
+func specApiName(path string, op *specOperation) (string, error) {
+	group := strings.Split(strings.TrimPrefix(path, "/"), "/")[0]
+	if len(op.Tags) > 0 {
+		group = op.Tags[0]
+	}
+	name := nonAlphanumericRegex.ReplaceAllString(strings.ToLower(group), "")
+	if name == "" || strings.HasPrefix(group, "{") || name[0] < 'a' || name[0] > 'z' || domain.ValidateName(name) != nil {
+		return "", log.Wrapf("can't create api name from %s, use tag with a short name for operations on path %s", group, path)
+	}
+	return name, nil
+}
+
+// method creates api method for the operation, method name is taken from the
+// operationId or from the path
+func (d *specDocument) method(a *specApi, httpMethod, path string, item *specPathItem, op *specOperation) (*specMethod, error) {
+	rel := path
+	if rel == "/"+a.Name || strings.HasPrefix(rel, "/"+a.Name+"/") {
+		rel = strings.TrimPrefix(rel, "/"+a.Name)
+	}
+	rel = strings.TrimSuffix(rel, "/")
+	name := goIdentifier(op.OperationID)
+	if name == "" {
+		var parts []string
+		for _, s := range strings.Split(rel, "/") {
+			if !strings.HasPrefix(s, "{") {
+				parts = append(parts, s)
+			}
+		}
+		name = goIdentifier(strings.Join(parts, " "))
+	}
+	if name == "" {
+		name = "Default"
+		if httpMethod != http.MethodPost {
+			name = strings.Title(strings.ToLower(httpMethod))
+		}
+	}
+	for _, m := range a.Methods {
+		if m.Name == name {
+			return nil, log.Wrapf("method %s already exists in api %s, set operationId to distinguish them", name, a.Name)
+		}
+	}
+	m := &specMethod{
+		Name:         name,
+		FunctionName: a.Name,
+	}
+	for _, s := range []string{op.Summary, op.Description} {
+		if s != "" {
+			m.Doc = append(m.Doc, strings.Split(strings.TrimSpace(s), "\n")...)
+		}
+	}
+	routePath := rel
+	if routePath == "" {
+		routePath = "/"
+	}
+	defaultPath := strings.TrimPrefix(domain.MethodPath(a.Name, name), "/"+a.Name)
+	if defaultPath == "" {
+		defaultPath = "/"
+	}
+	if routePath != defaultPath || httpMethod != http.MethodPost {
+		m.Route = httpMethod
+		if routePath != defaultPath {
+			m.Route += " " + routePath
+		}
+		if err := (domain.MethodConfiguration{HTTPMethod: httpMethod, Path: routePath}).Validate(); err != nil {
+			return nil, log.Wrap(err)
+		}
+	}
+	m.endpoint = testEndpoint{
+		HTTPMethod: httpMethod,
+		Path:       domain.MethodPath(a.Name, name),
+		Var:        strings.ToLower(name[:1]) + name[1:],
+	}
+	if m.Route != "" {
+		m.endpoint.Path = strings.TrimSuffix("/"+a.Name+routePath, "/")
+	}
+	var params []*specParameter
+	for _, p := range append(item.Parameters, op.Parameters...) {
+		if p = d.parameter(p); p.In == "path" {
+			params = append(params, p)
+			m.endpoint.Params = append(m.endpoint.Params, p.Name)
+		}
+	}
+	m.request = specRequest{body: d.requestBody(op.RequestBody), params: params}
+	m.response = d.successResponse(op)
+	return m, nil
+}
+
+// successResponse finds the first successful response of the operation
+func (d *specDocument) successResponse(op *specOperation) *specResponse {
+	var codes []string
+	for c := range op.Responses {
+		if strings.HasPrefix(c, "2") {
+			codes = append(codes, c)
+		}
+	}
+	sort.Strings(codes)
+	for _, c := range codes {
+		if r := d.response(op.Responses[c]); r != nil {
+			return r
+		}
+	}
+	return nil
+}
+
+// specTypes declares go types of the api package from the document schemas
+type specTypes struct {
+	doc *specDocument
+	// used type names
+	names map[string]bool
+	// component schemas with their go type names
+	components map[string]string
+	// declarations are written to out, components to their own list
+	out      *[]string
+	declared []string
+	structs  map[string]bool
+}
+
+// types sets request and response types of the api methods and declarations
+// of the used component schemas
+func (d *specDocument) types(a *specApi) error {
+	st := &specTypes{
+		doc:        d,
+		names:      map[string]bool{strings.Title(a.Name): true},
+		components: make(map[string]string),
+		structs:    make(map[string]bool),
+	}
+	for _, m := range a.Methods {
+		st.names[m.Name] = true
+	}
+	for _, m := range a.Methods {
+		st.out = &m.Types
+		req, err := st.request(m)
+		if err != nil {
+			return log.Wrap(err, "method %s", m.Name)
+		}
+		m.Req = req
+		if m.response != nil {
+			if s, raw := specContentSchema(m.response.Content); s != nil {
+				m.Rsp = "string"
+				if !raw {
+					if m.Rsp, err = st.pointer(st.goType(s, m.Name+"Response")); err != nil {
+						return log.Wrap(err, "method %s", m.Name)
+					}
+				}
+			}
+		}
+		m.endpoint.Req = strings.TrimPrefix(m.Req, "*")
+		if m.endpoint.Req != "" && m.endpoint.Req != "string" {
+			m.endpoint.Req = qualifyType(a.Name, m.endpoint.Req, st.names)
+		}
+		m.endpoint.Body = m.Req != "" && hasBody(m.endpoint.HTTPMethod)
+		m.endpoint.Text = m.Req == "string"
+		m.endpoint.JSON = m.Rsp != "" && m.Rsp != "string"
+	}
+	a.Types = st.declared
+	return nil
+}
+
+// request creates request type from the request body and path parameters
+func (st *specTypes) request(m *specMethod) (string, error) {
+	var body *specSchema
+	raw := false
+	if m.request.body != nil {
+		body, raw = specContentSchema(m.request.body.Content)
+	}
+	if len(m.request.params) == 0 {
+		if body == nil {
+			return "", nil
+		}
+		if raw {
+			return "string", nil
+		}
+		return st.pointer(st.goType(body, m.Name+"Request"))
+	}
+	// path parameters are bound to the request fields with the same json name
+	var fields []string
+	names := make(map[string]bool)
+	if body != nil {
+		switch t := st.goType(body, m.Name+"Body"); {
+		case raw:
+			fields = append(fields, "Body string `json:\"body\"`")
+		case st.structs[t]:
+			fields = append(fields, t)
+			for _, n := range st.propertyNames(body) {
+				names[n] = true
+			}
+		default:
+			fields = append(fields, fmt.Sprintf("Body %s `json:\"body\"`", t))
+		}
+	}
+	for _, p := range m.request.params {
+		if names[p.Name] {
+			continue
+		}
+		fields = append(fields, fmt.Sprintf("%s %s `json:\"%s\"`", goIdentifier(p.Name), st.goType(p.Schema, m.Name+goIdentifier(p.Name)), p.Name))
+	}
+	name := st.name(m.Name + "Request")
+	st.structs[name] = true
+	st.declare(name, nil, fields)
+	return "*" + name, nil
+}
+
+// propertyNames returns json names of the struct schema properties
+func (st *specTypes) propertyNames(s *specSchema) []string {
+	if s.Ref != "" {
+		if rs, ok := st.doc.Components.Schemas.values[strings.TrimPrefix(s.Ref, specSchemasRef)]; ok {
+			return st.propertyNames(rs)
+		}
+	}
+	names := s.Properties.keys
+	for _, as := range s.AllOf {
+		names = append(names, st.propertyNames(as)...)
+	}
+	return names
+}
+
+// pointer returns pointer to the struct types
+func (st *specTypes) pointer(t string) (string, error) {
+	if st.structs[t] {
+		return "*" + t, nil
+	}
+	return t, nil
+}
+
+// goType returns go type of the schema, struct types are declared under
+// the name
+func (st *specTypes) goType(s *specSchema, name string) string {
+	if s == nil {
+		return "interface{}"
+	}
+	if s.Ref != "" {
+		return st.component(strings.TrimPrefix(s.Ref, specSchemasRef))
+	}
+	if len(s.AllOf) == 1 && len(s.Properties.keys) == 0 {
+		return st.goType(s.AllOf[0], name)
+	}
+	if len(s.OneOf) > 0 || len(s.AnyOf) > 0 {
+		return "interface{}"
+	}
+	switch s.Type {
+	case "string":
+		if s.Format == "date-time" {
+			return "time.Time"
+		}
+		return "string"
+	case "integer":
+		if s.Format == "int32" {
+			return "int32"
+		}
+		return "int64"
+	case "number":
+		if s.Format == "float" {
+			return "float32"
+		}
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + st.goType(s.Items, name+"Item")
+	case "object", "":
+		if s.isStruct() {
+			name = st.name(name)
+			st.structs[name] = true
+			st.declare(name, s, st.fields(s, name))
+			return name
+		}
+		if ap, err := s.additionalProperties(); err == nil && ap != nil {
+			return "map[string]" + st.goType(ap, name+"Value")
+		}
+		if s.Type == "object" {
+			return "map[string]interface{}"
+		}
+	}
+	return "interface{}"
+}
+
+// component returns go type of the component schema, schema is declared
+// when used for the first time
+func (st *specTypes) component(key string) string {
+	if t, ok := st.components[key]; ok {
+		return t
+	}
+	s, ok := st.doc.Components.Schemas.values[key]
+	if !ok {
+		return "interface{}"
+	}
+	name := st.name(goIdentifier(key))
+	st.components[key] = name
+	out := st.out
+	st.out = &st.declared
+	defer func() { st.out = out }()
+	if s.isStruct() {
+		// registered before fields for the recursive schemas
+		st.structs[name] = true
+		st.declare(name, s, st.fields(s, name))
+		return name
+	}
+	*st.out = append(*st.out, specTypeDecl(name, s.Description, st.goType(s, name)))
+	return name
+}
+
+// fields returns struct fields of the object schema, required properties
+// are always encoded
+func (st *specTypes) fields(s *specSchema, name string) []string {
+	var fields []string
+	for _, as := range s.AllOf {
+		if as.Ref != "" {
+			if t := st.component(strings.TrimPrefix(as.Ref, specSchemasRef)); st.structs[t] {
+				fields = append(fields, t)
+				continue
+			}
+		}
+		fields = append(fields, st.fields(as, name)...)
+	}
+	required := make(map[string]bool)
+	for _, r := range s.Required {
+		required[r] = true
+	}
+	for _, key := range s.Properties.keys {
+		p := s.Properties.values[key]
+		field := goIdentifier(key)
+		if field == "" {
+			continue
+		}
+		t := st.goType(p, name+field)
+		tag := key
+		if !required[key] {
+			tag += ",omitempty"
+			if st.structs[t] {
+				t = "*" + t
+			}
+		}
+		f := fmt.Sprintf("%s %s `json:\"%s\"`", field, t, tag)
+		if p != nil && p.Description != "" {
+			f = specComment(p.Description) + f
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// declare adds struct declaration to the current output
+func (st *specTypes) declare(name string, s *specSchema, fields []string) {
+	description := ""
+	if s != nil {
+		description = s.Description
+	}
+	decl := "struct{}"
+	if len(fields) > 0 {
+		decl = "struct {\n" + strings.Join(fields, "\n") + "\n}"
+	}
+	*st.out = append(*st.out, specTypeDecl(name, description, decl))
+}
+
+func specTypeDecl(name, description, typ string) string {
+	decl := fmt.Sprintf("type %s %s", name, typ)
+	if description != "" {
+		decl = specComment(description) + decl
+	}
+	return decl
+}
+
+func specComment(text string) string {
+	var sb strings.Builder
+	for _, l := range strings.Split(strings.TrimSpace(text), "\n") {
+		sb.WriteString(strings.TrimSpace("// "+l) + "\n")
+	}
+	return sb.String()
+}
+
+// name returns unique type name in the api package
+func (st *specTypes) name(name string) string {
+	n := name
+	for i := 2; st.names[n]; i++ {
+		n = fmt.Sprintf("%s%d", name, i)
+	}
+	st.names[n] = true
+	return n
+}
+
+// specContentSchema returns schema of the json or text content, raw is true
+// for the text content which is passed as string
+func specContentSchema(content map[string]specMediaType) (*specSchema, bool) {
+	if mt, ok := content["application/json"]; ok {
+		if mt.Schema == nil {
+			return &specSchema{}, false
+		}
+		return mt.Schema, false
+	}
+	for ct := range content {
+		if strings.HasPrefix(ct, "text/") {
+			return &specSchema{Type: "string"}, true
+		}
+	}
+	return nil, false
+}
+
+// qualifyType qualifies api package types with the package name
+func qualifyType(pkg, typ string, names map[string]bool) string {
+	return typeIdentRegex.ReplaceAllStringFunc(typ, func(id string) string {
+		if names[id] {
+			return pkg + "." + id
+		}
+		return id
+	})
+}
+
+var (
+	typeIdentRegex   = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_.]*`)
+	identPartsRegex  = regexp.MustCompile(`[A-Za-z0-9]+`)
+	goInitialisms    = map[string]string{"Id": "ID", "Url": "URL", "Uri": "URI", "Api": "API", "Http": "HTTP", "Json": "JSON", "Uuid": "UUID", "Ip": "IP"}
+	initialismSuffix = regexp.MustCompile(`(Id|Url|Uri|Api|Http|Json|Uuid|Ip)$`)
+)
+
+// goIdentifier converts name into exported go identifier, petId to PetID
+func goIdentifier(name string) string {
+	var sb strings.Builder
+	for _, p := range identPartsRegex.FindAllString(name, -1) {
+		p = strings.ToUpper(p[:1]) + p[1:]
+		if i, ok := goInitialisms[p]; ok {
+			p = i
+		}
+		sb.WriteString(initialismSuffix.ReplaceAllStringFunc(p, func(s string) string { return goInitialisms[s] }))
+	}
+	id := sb.String()
+	if id != "" && id[0] >= '0' && id[0] <= '9' {
+		id = "N" + id
+	}
+	return id
+}
@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mantil-io/mantil/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpecApis(t *testing.T) {
+	buf, err := ioutil.ReadFile("testdata/generate/spec/openapi.yaml")
+	require.NoError(t, err)
+	doc, err := parseSpecDocument(buf)
+	require.NoError(t, err)
+	apis, err := doc.apis()
+	require.NoError(t, err)
+	require.Len(t, apis, 2)
+
+	pets := apis[0]
+	require.Equal(t, "pets", pets.Name)
+	methods := make(map[string]*specMethod)
+	var names []string
+	for _, m := range pets.Methods {
+		methods[m.Name] = m
+		names = append(names, m.Name)
+	}
+	require.Equal(t, []string{"ListPets", "Default", "GetPet", "UpdatePet", "DeletePet", "Search"}, names)
+
+	list := methods["ListPets"]
+	require.Equal(t, []string{"List all pets"}, list.Doc)
+	require.Equal(t, "GET /", list.Route)
+	require.Empty(t, list.Req)
+	require.Equal(t, "[]Pet", list.Rsp)
+
+	def := methods["Default"]
+	require.Empty(t, def.Route)
+	require.Equal(t, "*NewPet", def.Req)
+	require.Equal(t, "*Pet", def.Rsp)
+
+	get := methods["GetPet"]
+	require.Equal(t, "GET /{petId}", get.Route)
+	require.Equal(t, "*GetPetRequest", get.Req)
+	require.Equal(t, "*Pet", get.Rsp)
+	require.Equal(t, []string{"type GetPetRequest struct {\nPetID int64 `json:\"petId\"`\n}"}, get.Types)
+	require.Equal(t, testEndpoint{HTTPMethod: "GET", Path: "/pets/{petId}", Params: []string{"petId"}, Var: "getPet", Req: "pets.GetPetRequest", JSON: true}, get.endpoint)
+
+	update := methods["UpdatePet"]
+	require.Equal(t, "PUT /{petId}", update.Route)
+	require.Empty(t, update.Rsp)
+	require.Equal(t, []string{"type UpdatePetRequest struct {\nNewPet\nPetID int64 `json:\"petId\"`\n}"}, update.Types)
+
+	search := methods["Search"]
+	require.Empty(t, search.Route)
+	require.Equal(t, "*SearchRequest", search.Req)
+	require.Equal(t, "*SearchResponse", search.Rsp)
+	require.Len(t, search.Types, 2)
+
+	require.Equal(t, []string{
+		"type NewPetOwner struct {\nEmail string `json:\"email,omitempty\"`\n}",
+		"// Pet attributes set by the client\ntype NewPet struct {\nName string `json:\"name\"`\nTag string `json:\"tag,omitempty\"`\nOwner *NewPetOwner `json:\"owner,omitempty\"`\nLabels map[string]string `json:\"labels,omitempty\"`\n}",
+		"type Pet struct {\nNewPet\n// unique pet id\nID int64 `json:\"id\"`\nCreated time.Time `json:\"created,omitempty\"`\n}",
+	}, pets.Types)
+
+	orders := apis[1]
+	require.Equal(t, "orders", orders.Name)
+	require.Len(t, orders.Methods, 1)
+	place := orders.Methods[0]
+	require.Equal(t, "PlaceOrder", place.Name)
+	require.Equal(t, "POST /store/order", place.Route)
+	require.Equal(t, "string", place.Req)
+	require.Equal(t, "string", place.Rsp)
+	require.True(t, place.endpoint.Text)
+}
+
+func TestSpecApiName(t *testing.T) {
+	name, err := specApiName("/pets/{id}", &specOperation{})
+	require.NoError(t, err)
+	require.Equal(t, "pets", name)
+	name, err = specApiName("/v1/pets", &specOperation{Tags: []string{"Pet Store"}})
+	require.NoError(t, err)
+	require.Equal(t, "petstore", name)
+	_, err = specApiName("/{id}", &specOperation{})
+	require.Error(t, err)
+}
+
+func TestGoIdentifier(t *testing.T) {
+	require.Equal(t, "PetID", goIdentifier("petId"))
+	require.Equal(t, "ID", goIdentifier("id"))
+	require.Equal(t, "ListPets", goIdentifier("list-pets"))
+	require.Equal(t, "ImageURL", goIdentifier("image_url"))
+	require.Equal(t, "N2fa", goIdentifier("2fa"))
+	require.Empty(t, goIdentifier("{}"))
+}
+
+func TestGenerateApisFromOpenAPI(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, domain.DefaultApiDir, "pets", "getpet.go")
+	require.NoError(t, os.MkdirAll(filepath.Dir(existing), os.ModePerm))
+	require.NoError(t, ioutil.WriteFile(existing, []byte("package pets\n"), 0644))
+
+	require.NoError(t, generateApisFromOpenAPI("example.com/project", dir, "pets", "testdata/generate/spec/openapi.yaml"))
+
+	buf, err := ioutil.ReadFile(existing)
+	require.NoError(t, err)
+	require.Equal(t, "package pets\n", string(buf))
+
+	buf, err = ioutil.ReadFile(filepath.Join(dir, domain.DefaultApiDir, "pets", "updatepet.go"))
+	require.NoError(t, err)
+	require.Contains(t, string(buf), "//mantil:route PUT /{petId}\nfunc (p *Pets) UpdatePet(ctx context.Context, req *UpdatePetRequest) error {")
+	buf, err = ioutil.ReadFile(filepath.Join(dir, domain.DefaultApiDir, "pets", "types.go"))
+	require.NoError(t, err)
+	require.Contains(t, string(buf), `import "time"`)
+	require.FileExists(t, filepath.Join(dir, domain.DefaultApiDir, "pets", "pets.go"))
+	require.NoDirExists(t, filepath.Join(dir, domain.DefaultApiDir, "orders"))
+
+	buf, err = ioutil.ReadFile(filepath.Join(dir, "test", "pets_test.go"))
+	require.NoError(t, err)
+	require.Contains(t, string(buf), "api.GET(\"/pets/{petId}\").\n\t\tWithPath(\"petId\", \"TODO\").")
+	require.Contains(t, string(buf), "var updatePetReq pets.UpdatePetRequest")
+	require.FileExists(t, filepath.Join(dir, "test", "init.go"))
+
+	err = generateApisFromOpenAPI("example.com/project", dir, "users", "testdata/generate/spec/openapi.yaml")
+	require.Error(t, err)
+}
+
+func TestParseSpecDocumentJSON(t *testing.T) {
+	doc, err := parseSpecDocument([]byte(`{"paths": {"/ping": {"get": {"operationId": "ping", "responses": {"200": {"description": "pong"}}}}}}`))
+	require.NoError(t, err)
+	apis, err := doc.apis()
+	require.NoError(t, err)
+	require.Len(t, apis, 1)
+	require.Equal(t, "Ping", apis[0].Methods[0].Name)
+	require.Equal(t, "GET /", apis[0].Methods[0].Route)
+
+	_, err = parseSpecDocument([]byte(`openapi: 3.0.3`))
+	require.Error(t, err)
+}
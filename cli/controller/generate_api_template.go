@@ -3,6 +3,17 @@ package controller
 type function struct {
 	Name       string
 	ImportPath string
+	// signature of the api New function
+	NewContext bool
+	NewError   bool
+	// api lifecycle hooks
+	Close         bool
+	CloseError    bool
+	Shutdown      bool
+	ShutdownError bool
+	// wrapper around api with middleware and request validation, nil if
+	// the api is used directly
+	Handler *apiHandler
 }
 
 type method struct {
@@ -14,6 +25,23 @@ type test struct {
 	Name       string
 	ImportPath string
 	Methods    []string
+	// endpoints of the api created from the OpenAPI document, used instead
+	// of the default and additional methods
+	Endpoints []testEndpoint
+}
+
+type testEndpoint struct {
+	HTTPMethod string
+	Path       string
+	Params     []string
+	Var        string
+	// request type, empty if the method doesn't have request
+	Req string
+	// request is sent as json body, or as text for string requests
+	Body bool
+	Text bool
+	// response is json
+	JSON bool
 }
 
 var apiDefaultTemplate = `
@@ -57,14 +85,353 @@ var apiFunctionMainTemplate = `
 package main
 
 import (
-	"{{ .ImportPath }}/api/{{ .Name | toLower }}"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"{{ .ImportPath }}/{{ .Name | toLower }}"
 	"github.com/mantil-io/mantil.go"
+{{- if .Handler }}
+{{- range $name, $path := .Handler.Imports }}
+	{{ $name }} "{{ $path }}"
+{{- end }}
+{{- end }}
 )
 
 func main() {
-	var api = {{ .Name | toLower }}.New()
+{{- if .NewError }}
+	api, err := {{ .Name | toLower }}.New({{ if .NewContext }}context.Background(){{ end }})
+	if err != nil {
+		log.Fatalf("{{ .Name | toLower }} api initialization failed: %v", err)
+	}
+{{- else }}
+	var api = {{ .Name | toLower }}.New({{ if .NewContext }}context.Background(){{ end }})
+{{- end }}
+{{- if or .Close .Shutdown }}
+	onShutdown(func(ctx context.Context) {
+{{- if .ShutdownError }}
+		if err := api.Shutdown(ctx); err != nil {
+			log.Printf("{{ .Name | toLower }} api shutdown failed: %v", err)
+		}
+{{- else if .Shutdown }}
+		api.Shutdown(ctx)
+{{- end }}
+{{- if .CloseError }}
+		if err := api.Close(); err != nil {
+			log.Printf("{{ .Name | toLower }} api close failed: %v", err)
+		}
+{{- else if .Close }}
+		api.Close()
+{{- end }}
+	})
+{{- end }}
+{{- if .Handler }}
+	h := &handler{api: api}
+{{- if .Handler.Middleware }}
+	mws := api.Middleware()
+	h.chain = func(ctx context.Context, method string, req interface{}, next func(context.Context) (interface{}, error)) (interface{}, error) {
+		for i := len(mws) - 1; i >= 0; i-- {
+			mw, inner := mws[i], next
+			next = func(ctx context.Context) (interface{}, error) {
+				return mw(ctx, method, req, inner)
+			}
+		}
+		return next(ctx)
+	}
+{{- end }}
+	mantil.LambdaHandler(h)
+{{- else }}
 	mantil.LambdaHandler(api)
+{{- end }}
+}
+{{- with .Handler }}
+
+// handler exposes api methods, runs them through api middleware and
+// validates requests
+type handler struct {
+	api   {{ .Type }}
+	chain func(ctx context.Context, method string, req interface{}, next func(context.Context) (interface{}, error)) (interface{}, error)
+}
+
+func (h *handler) call(ctx context.Context, method string, req interface{}, fn func(context.Context) (interface{}, error)) (interface{}, error) {
+{{- if .Trace }}
+	echoTraceID(ctx)
+{{- end }}
+	if h.chain == nil {
+		return fn(ctx)
+	}
+	return h.chain(ctx, method, req, fn)
+}
+{{- if .Trace }}
+
+// echoTraceID logs X-Ray trace id of the invocation to the logs stream of
+// the invoke command, the command shows it with the link to the trace
+func echoTraceID(ctx context.Context) {
+	rc, ok := mantil.FromContext(ctx)
+	if !ok || rc.Request.Headers["` + logsConfigHeader + `"] == "" {
+		return
+	}
+	header, _ := ctx.Value("x-amzn-trace-id").(string)
+	for _, part := range strings.Split(header, ";") {
+		if strings.HasPrefix(part, "Root=") {
+			log.Printf("` + traceIDLogPrefix + `%s", strings.TrimPrefix(part, "Root="))
+			return
+		}
+	}
+}
+{{- end }}
+{{ range .Methods }}
+func (h *handler) {{ .Func }}(ctx context.Context{{ if .Req }}, req {{ .Req }}{{ end }}) {{ if .Rsp }}({{ .Rsp }}, error){{ else }}error{{ end }} {
+{{- if .Route }}
+	if key, _ := route(ctx); key != "" && key != "{{ .Route }}" {
+{{- if .Rsp }}
+		var rsp {{ .Rsp }}
+		return rsp, errRouteNotFound
+{{- else }}
+		return errRouteNotFound
+{{- end }}
+	}
+{{- end }}
+	{{ if .Rsp }}out{{ else }}_{{ end }}, err := h.call(ctx, "{{ .Name }}", {{ if .Req }}req{{ else }}nil{{ end }}, func(ctx context.Context) (interface{}, error) {
+{{- if .Validate }}
+		if err := validate(func(errs *[]string) {
+			{{ .Validate }}({{ if not .ReqPtr }}&{{ end }}req, "", errs)
+		}); err != nil {
+			return nil, err
+		}
+{{- end }}
+{{- if and .Rsp .Error }}
+		return h.api.{{ .Name }}({{ if .Context }}ctx{{ end }}{{ if and .Context .Req }}, {{ end }}{{ if .Req }}req{{ end }})
+{{- else if .Rsp }}
+		return h.api.{{ .Name }}({{ if .Context }}ctx{{ end }}{{ if and .Context .Req }}, {{ end }}{{ if .Req }}req{{ end }}), nil
+{{- else if .Error }}
+		return nil, h.api.{{ .Name }}({{ if .Context }}ctx{{ end }}{{ if and .Context .Req }}, {{ end }}{{ if .Req }}req{{ end }})
+{{- else }}
+		h.api.{{ .Name }}({{ if .Context }}ctx{{ end }}{{ if and .Context .Req }}, {{ end }}{{ if .Req }}req{{ end }})
+		return nil, nil
+{{- end }}
+	})
+{{- if .Rsp }}
+	rsp, _ := out.({{ .Rsp }})
+	return rsp, err
+{{- else }}
+	return err
+{{- end }}
+}
+{{ end }}
+{{- if .Routes }}
+// Invoke serves requests of the api method routes, other requests without
+// method name are passed to the api default method
+func (h *handler) Invoke(ctx context.Context) (interface{}, error) {
+	key, {{ if .PathParams }}params{{ else }}_{{ end }} := route(ctx)
+	switch key {
+{{- range .Routes }}
+	case "{{ .Key }}":
+		{{- template "routeCall" . }}
+{{- end }}
+	}
+{{- with .Default }}
+	{{- template "routeCall" . }}
+{{- else }}
+	return nil, errRouteNotFound
+{{- end }}
+}
+
+// httpError is returned with the status code in the response
+type httpError struct {
+	status int
+	msg    string
+}
+
+func (e *httpError) Error() string {
+	return e.msg
+}
+
+func (e *httpError) StatusCode() int {
+	return e.status
+}
+
+var errRouteNotFound = &httpError{status: http.StatusNotFound, msg: "route not found"}
+
+func invalidPathParam(name string, err error) error {
+	return &httpError{status: http.StatusBadRequest, msg: fmt.Sprintf("invalid path parameter %s: %v", name, err)}
+}
+
+// route returns api gateway route key and path parameters of the request,
+// key is empty for requests which are not coming from the api gateway
+func route(ctx context.Context) (string, map[string]string) {
+	rc, ok := mantil.FromContext(ctx)
+	if !ok || rc.Request.Type != mantil.APIGateway {
+		return "", nil
+	}
+	var r struct {
+		RouteKey       string            ` + "`" + `json:"routeKey"` + "`" + `
+		PathParameters map[string]string ` + "`" + `json:"pathParameters"` + "`" + `
+	}
+	if err := json.Unmarshal(rc.Request.Raw, &r); err != nil {
+		return "", nil
+	}
+	return r.RouteKey, r.PathParameters
+}
+
+func requestBody(ctx context.Context) []byte {
+	rc, ok := mantil.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	return rc.Request.Body
+}
+
+func decodeRequest(ctx context.Context, v interface{}) error {
+	body := requestBody(ctx)
+	if len(body) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return &httpError{status: http.StatusBadRequest, msg: fmt.Sprintf("unable to unmarshal request: %v", err)}
+	}
+	return nil
+}
+{{ end }}
+{{- if .Validators }}
+// validationError is returned for invalid request, response status code is
+// taken from the error
+type validationError struct {
+	fields []string
+}
+
+func (e *validationError) Error() string {
+	return "invalid request: " + strings.Join(e.fields, ", ")
+}
+
+func (e *validationError) StatusCode() int {
+	return http.StatusBadRequest
 }
+
+func validate(fn func(errs *[]string)) error {
+	var errs []string
+	fn(&errs)
+	if len(errs) == 0 {
+		return nil
+	}
+	return &validationError{fields: errs}
+}
+
+func validEmail(s string) bool {
+	a, err := mail.ParseAddress(s)
+	return err == nil && a.Address == s
+}
+
+func validURL(s string) bool {
+	u, err := url.ParseRequestURI(s)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+{{ range .Validators }}
+func {{ .Name }}(v *{{ .Type }}, prefix string, errs *[]string) {
+	if v == nil {
+		return
+	}
+{{- range .Checks }}
+	{{ . }}
+{{- end }}
+}
+{{ end }}
+{{- end }}
+{{- end }}
+{{- if or .Close .Shutdown }}
+
+// time which lambda runtime gives to the function to shut down
+const shutdownTimeout = 500 * time.Millisecond
+
+// onShutdown calls fn when lambda runtime sends SIGTERM before shutting down
+// the execution environment. Runtime sends SIGTERM only to functions with a
+// registered extension, so the function registers itself as one.
+func onShutdown(fn func(ctx context.Context)) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		<-ch
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		fn(ctx)
+		os.Exit(0)
+	}()
+	if err := registerExtension(); err != nil {
+		log.Printf("lambda extension registration failed, shutdown hook will not be called: %v", err)
+	}
+}
+
+// registerExtension registers internal extension through the lambda
+// extensions api. Internal extension can't subscribe to the shutdown event,
+// it is registered without events and its next event request only signals
+// to the runtime that the extension is initialized.
+func registerExtension() error {
+	api := os.Getenv("AWS_LAMBDA_RUNTIME_API")
+	if api == "" {
+		return fmt.Errorf("AWS_LAMBDA_RUNTIME_API not set")
+	}
+	req, err := http.NewRequest(http.MethodPost, "http://"+api+"/2020-01-01/extension/register", strings.NewReader(` + "`" + `{"events":[]}` + "`" + `))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Lambda-Extension-Name", filepath.Base(os.Args[0]))
+	rsp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return fmt.Errorf("register status %d", rsp.StatusCode)
+	}
+	id := rsp.Header.Get("Lambda-Extension-Identifier")
+	go func() {
+		req, err := http.NewRequest(http.MethodGet, "http://"+api+"/2020-01-01/extension/event/next", nil)
+		if err != nil {
+			return
+		}
+		req.Header.Set("Lambda-Extension-Identifier", id)
+		if rsp, err := http.DefaultClient.Do(req); err == nil {
+			rsp.Body.Close()
+		}
+	}()
+	return nil
+}
+{{- end }}
+{{- define "routeCall" }}
+{{- if .RawReq }}
+		req := string(requestBody(ctx))
+{{- else if .Req }}
+		var req {{ .Req }}
+		if err := decodeRequest(ctx, &req); err != nil {
+			return nil, err
+		}
+{{- if and .Params .ReqPtr }}
+		if req == nil {
+			req = new({{ .ReqElem }})
+		}
+{{- end }}
+{{- range .Params }}
+		{{ . }}
+{{- end }}
+{{- end }}
+{{- if .Rsp }}
+		return h.{{ .Method }}(ctx{{ if .Req }}, req{{ end }})
+{{- else }}
+		return nil, h.{{ .Method }}(ctx{{ if .Req }}, req{{ end }})
+{{- end }}
+{{- end }}
 `
 
 var apiFunctionTestInit = `
@@ -99,12 +466,34 @@ import (
 	"testing"
 
 	"github.com/gavv/httpexpect"
-	"{{ .ImportPath }}/api/{{ .Name | toLower }}"
+	"{{ .ImportPath }}/{{ .Name | toLower }}"
 )
 
 func Test{{ .Name | toLower | title }}(t *testing.T) {
 	api := httpexpect.New(t, apiURL)
-
+{{ if .Endpoints }}
+{{- range $i, $e := .Endpoints }}
+{{- if $i }}
+{{ end }}
+{{- if .Body }}
+	var {{ .Var }}Req {{ .Req }} // TODO set attributes
+{{- end }}
+	api.{{ .HTTPMethod }}("{{ .Path }}").
+{{- range .Params }}
+			WithPath("{{ . }}", "TODO").
+{{- end }}
+{{- if .Body }}
+			{{ if .Text }}WithText{{ else }}WithJSON{{ end }}({{ .Var }}Req).
+{{- end }}
+			Expect().
+{{- if .JSON }}
+			Status(http.StatusOK).
+			JSON()
+{{- else }}
+			Status(http.StatusOK)
+{{- end }}
+{{- end }}
+{{- else }}
 	req := {{ .Name | toLower }}.DefaultRequest {
 		// TODO add attributes
 	}
@@ -129,5 +518,44 @@ func Test{{ .Name | toLower | title }}(t *testing.T) {
 			Value("TODO")
 
 {{ end }}
+{{- end }}
 }
 `
+
+var apiSpecTemplate = `
+package {{ .Name | toLower }}
+
+type {{ .Name | title }} struct{}
+
+func New() *{{ .Name | title }} {
+	return &{{ .Name | title }}{}
+}
+`
+
+var apiSpecMethodTemplate = `
+package {{ .FunctionName | toLower }}
+
+import (
+	"context"
+)
+{{ range .Types }}
+{{ . }}
+{{ end }}
+{{ range .Doc }}//{{ if . }} {{ . }}{{ end }}
+{{ end -}}
+{{- if .Route }}
+{{- if .Doc }}//
+{{ end -}}
+//mantil:route {{ .Route }}
+{{ end -}}
+func ({{ .FunctionName | first | toLower }} *{{ .FunctionName | title }}) {{ .Name }}(ctx context.Context{{ if .Req }}, req {{ .Req }}{{ end }}) {{ if .Rsp }}({{ .Rsp }}, error){{ else }}error{{ end }} {
+	panic("not implemented")
+}
+`
+
+var apiSpecTypesTemplate = `
+package {{ .Name | toLower }}
+{{ range .Types }}
+{{ . }}
+{{ end }}
+`
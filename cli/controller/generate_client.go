@@ -0,0 +1,431 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/printer"
+	"go/token"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mantil-io/mantil/cli/log"
+	"github.com/mantil-io/mantil/cli/ui"
+	"github.com/mantil-io/mantil/domain"
+)
+
+const (
+	ClientLangGo = "go"
+	ClientLangTs = "ts"
+	ClientDir    = "client"
+)
+
+type GenerateClientArgs struct {
+	Lang   string
+	Stage  string
+	Output string
+}
+
+// GenerateClient generates typed client for the project apis. Client has one
+// method for each api method and can call them over http or websocket.
+func GenerateClient(a GenerateClientArgs) error {
+	if a.Lang != ClientLangGo && a.Lang != ClientLangTs {
+		return log.Wrapf("unsupported client language %s, supported are %s and %s", a.Lang, ClientLangGo, ClientLangTs)
+	}
+	fs, stage, err := newStoreWithStage(a.Stage)
+	if err != nil {
+		return log.Wrap(err)
+	}
+	root := fs.ProjectRoot()
+	importPath, err := apiImportPath(root)
+	if err != nil {
+		return log.Wrap(err)
+	}
+	apis, err := clientApis(fs.ApiDir(), importPath, stage, stagePrivateFunctions(stage))
+	if err != nil {
+		return log.Wrap(err)
+	}
+	output := a.Output
+	if output == "" {
+		output = filepath.Join(root, ClientDir)
+	}
+	if a.Lang == ClientLangTs {
+		return generateTsClient(apis, filepath.Join(output, "client.ts"))
+	}
+	return generateGoClient(apis, output)
+}
+
+// clientApi is the project api with its methods as seen by the client
+type clientApi struct {
+	Name       string
+	ImportPath string
+	Methods    []clientMethod
+	// imports used by the method types
+	Imports map[string]string
+	// typescript type definitions of the api types
+	TsTypes []tsType
+}
+
+type clientMethod struct {
+	Name       string
+	Doc        []string
+	HTTPMethod string
+	// go and typescript expressions of the method path, path parameters are
+	// taken from the request fields
+	GoPath  string
+	TsPath  string
+	URI     string
+	Private bool
+	// go types of the request and response, empty if the method doesn't
+	// have them
+	Req     string
+	Rsp     string
+	RspElem string
+	RspPtr  bool
+	// typescript types of the request and response, raw types are sent
+	// without json encoding
+	TsReq     string
+	TsRsp     string
+	TsRawReq  bool
+	TsRawRsp  bool
+	TsMethod  string
+	TsComment string
+}
+
+func (a clientApi) Type() string {
+	return strings.Title(a.Name)
+}
+
+func clientApis(apiDir, importPath string, stage *domain.Stage, private map[string]bool) ([]clientApi, error) {
+	fis, err := ioutil.ReadDir(apiDir)
+	if err != nil {
+		return nil, log.Wrap(err)
+	}
+	var apis []clientApi
+	for _, fi := range fis {
+		if !fi.IsDir() {
+			continue
+		}
+		name := fi.Name()
+		ap, err := parseApi(name, filepath.Join(apiDir, name))
+		if err != nil {
+			return nil, log.Wrap(err)
+		}
+		schemas := newOpenAPISchemas(ap.pkg, make(map[string]*openAPISchema))
+		gt := &goTypes{
+			pkg:     ap.pkg.Name,
+			local:   schemas.types,
+			imports: make(map[string]string),
+		}
+		api := clientApi{
+			Name:       name,
+			ImportPath: fmt.Sprintf("%s/%s", importPath, name),
+		}
+		routes, err := newMethodRoutes(stage, ap, private[name])
+		if err != nil {
+			return nil, log.Wrap(err)
+		}
+		for _, m := range apiMethods(ap.pkg, ap.typeName) {
+			r := routes.route(m.name)
+			cm := clientMethod{
+				Name:       m.name,
+				HTTPMethod: r.HTTPMethod,
+				URI:        fmt.Sprintf("%s.%s", name, strings.ToLower(m.name)),
+				Private:    r.Private,
+				TsMethod:   strings.ToLower(m.name[:1]) + m.name[1:],
+			}
+			if err := cm.paths(routes, r, m.req); err != nil {
+				return nil, log.Wrap(err)
+			}
+			if m.doc != "" {
+				cm.Doc = strings.Split(m.doc, "\n")
+				cm.TsComment = strings.Replace(strings.Join(cm.Doc, "\n   * "), " * \n", " *\n", -1)
+			}
+			if m.req != nil {
+				cm.Req = gt.expr(m.req, m.file)
+				cm.TsReq = tsTypeExpr(schemas.schema(m.req))
+				cm.TsRawReq = isStringType(m.req)
+			}
+			if m.rsp != nil {
+				cm.Rsp = gt.expr(m.rsp, m.file)
+				cm.RspElem = cm.Rsp
+				if se, ok := m.rsp.(*ast.StarExpr); ok {
+					cm.RspPtr = true
+					cm.RspElem = gt.expr(se.X, m.file)
+				}
+				cm.TsRsp = tsTypeExpr(schemas.schema(m.rsp))
+				if cm.RspPtr && !strings.HasSuffix(cm.TsRsp, " | null") {
+					// nil response is returned as empty body
+					cm.TsRsp += " | null"
+				}
+				cm.TsRawRsp = isStringType(m.rsp) || isBytesType(m.rsp)
+			}
+			api.Methods = append(api.Methods, cm)
+		}
+		api.Imports = gt.imports
+		api.TsTypes = tsTypes(schemas.components)
+		apis = append(apis, api)
+	}
+	return apis, nil
+}
+
+// paths sets go and typescript expressions of the route path
+func (cm *clientMethod) paths(routes *methodRoutes, r domain.FunctionRoute, req ast.Expr) error {
+	fields, err := routes.pathParamFields(r, req)
+	if err != nil {
+		return log.Wrap(err)
+	}
+	goPath := routePathExpr(r.Path, func(p string) string {
+		return fmt.Sprintf("pathParam(req.%s)", fields[p].Name)
+	})
+	tsPath := routePathExpr(r.Path, func(p string) string {
+		name := fields[p].Name
+		if tag := parseJSONTag(fields[p].Field); tag.name != "" {
+			name = tag.name
+		}
+		prop := "." + name
+		if !tsIdentifierRegex.MatchString(name) {
+			prop = fmt.Sprintf("[%s]", strconv.Quote(name))
+		}
+		return fmt.Sprintf("encodeURIComponent(String(req%s))", prop)
+	})
+	cm.GoPath = strings.Join(goPath, " + ")
+	cm.TsPath = strings.Join(tsPath, " + ")
+	return nil
+}
+
+func isStringType(expr ast.Expr) bool {
+	id, ok := expr.(*ast.Ident)
+	return ok && id.Name == "string"
+}
+
+// byte slices are returned by the api unchanged
+func isBytesType(expr ast.Expr) bool {
+	at, ok := expr.(*ast.ArrayType)
+	if !ok || at.Len != nil {
+		return false
+	}
+	id, ok := at.Elt.(*ast.Ident)
+	return ok && id.Name == "byte"
+}
+
+// goTypes renders api method types outside of the api package, types
+// declared in the api package are qualified with the package name
+type goTypes struct {
+	pkg     string
+	local   map[string]*ast.TypeSpec
+	imports map[string]string
+}
+
+func (g *goTypes) expr(expr ast.Expr, file *ast.File) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if _, ok := g.local[t.Name]; ok {
+			return g.pkg + "." + t.Name
+		}
+		return t.Name
+	case *ast.StarExpr:
+		return "*" + g.expr(t.X, file)
+	case *ast.ArrayType:
+		if t.Len == nil {
+			return "[]" + g.expr(t.Elt, file)
+		}
+		return fmt.Sprintf("[%s]%s", printExpr(t.Len), g.expr(t.Elt, file))
+	case *ast.MapType:
+		return fmt.Sprintf("map[%s]%s", g.expr(t.Key, file), g.expr(t.Value, file))
+	case *ast.SelectorExpr:
+		if x, ok := t.X.(*ast.Ident); ok {
+			if p := fileImport(file, x.Name); p != "" {
+				g.imports[x.Name] = p
+			}
+		}
+	}
+	return printExpr(expr)
+}
+
+// fileImport finds import path of the package used under name in file
+func fileImport(file *ast.File, name string) string {
+	for _, imp := range file.Imports {
+		p, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		if imp.Name != nil && imp.Name.Name == name {
+			return p
+		}
+		if imp.Name == nil && path.Base(p) == name {
+			return p
+		}
+	}
+	return ""
+}
+
+func printExpr(expr ast.Expr) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, token.NewFileSet(), expr); err != nil {
+		return "interface{}"
+	}
+	return buf.String()
+}
+
+func generateGoClient(apis []clientApi, dir string) error {
+	imports := make(map[string]string)
+	for _, a := range apis {
+		for n, p := range a.Imports {
+			imports[n] = p
+		}
+	}
+	data := struct {
+		Apis    []clientApi
+		Imports map[string]string
+	}{
+		Apis:    apis,
+		Imports: imports,
+	}
+	for name, tpl := range map[string]string{
+		"client.go": goClientTemplate,
+		"ws.go":     goClientWsTemplate,
+	} {
+		if err := generateFromTemplate(tpl, data, filepath.Join(dir, name)); err != nil {
+			return log.Wrap(err)
+		}
+	}
+	ui.Info("Go client saved to %s", dir)
+	ui.Info("Run 'go mod tidy' to add websocket dependency to the project.")
+	return nil
+}
+
+func generateTsClient(apis []clientApi, file string) error {
+	out, err := renderTemplate(tsClientTemplate, struct{ Apis []clientApi }{Apis: apis})
+	if err != nil {
+		return log.Wrap(err)
+	}
+	if err := saveFile(out, file); err != nil {
+		return log.Wrap(err)
+	}
+	ui.Info("TypeScript client saved to %s", file)
+	return nil
+}
+
+// tsType is typescript definition of the api type
+type tsType struct {
+	Name        string
+	Description string
+	// interface properties, for struct types
+	Properties []tsProperty
+	// type alias, for all other types
+	Alias string
+}
+
+type tsProperty struct {
+	Name        string
+	Type        string
+	Optional    bool
+	Description string
+}
+
+func tsTypes(schemas map[string]*openAPISchema) []tsType {
+	var types []tsType
+	for key, s := range schemas {
+		t := tsType{
+			Name:        tsTypeName(key),
+			Description: s.Description,
+		}
+		if s.Type != "object" || s.AdditionalProperties != nil {
+			t.Alias = tsTypeExpr(s)
+			types = append(types, t)
+			continue
+		}
+		required := make(map[string]bool)
+		for _, r := range s.Required {
+			required[r] = true
+		}
+		for n, p := range s.Properties {
+			t.Properties = append(t.Properties, tsProperty{
+				Name:        tsPropertyName(n),
+				Type:        tsTypeExpr(p),
+				Optional:    !required[n],
+				Description: p.Description,
+			})
+		}
+		sort.Slice(t.Properties, func(i, j int) bool { return t.Properties[i].Name < t.Properties[j].Name })
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].Name < types[j].Name })
+	return types
+}
+
+// tsTypeName converts schema component name, package.Type, into the
+// typescript type name
+func tsTypeName(key string) string {
+	var sb strings.Builder
+	for _, p := range strings.Split(key, ".") {
+		sb.WriteString(strings.Title(p))
+	}
+	return sb.String()
+}
+
+var tsIdentifierRegex = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+func tsPropertyName(name string) string {
+	if tsIdentifierRegex.MatchString(name) {
+		return name
+	}
+	return strconv.Quote(name)
+}
+
+func tsTypeExpr(s *openAPISchema) string {
+	t := tsSchemaType(s)
+	if s.Nullable {
+		t += " | null"
+	}
+	return t
+}
+
+func tsSchemaType(s *openAPISchema) string {
+	if s.Ref != "" {
+		return tsTypeName(strings.TrimPrefix(s.Ref, openAPISchemasRef))
+	}
+	switch s.Type {
+	case "string":
+		return "string"
+	case "integer", "number":
+		return "number"
+	case "boolean":
+		return "boolean"
+	case "array":
+		it := tsTypeExpr(s.Items)
+		if s.Items.Nullable {
+			it = "(" + it + ")"
+		}
+		return it + "[]"
+	case "object":
+		if s.AdditionalProperties != nil {
+			return fmt.Sprintf("{ [key: string]: %s }", tsTypeExpr(s.AdditionalProperties))
+		}
+		required := make(map[string]bool)
+		for _, r := range s.Required {
+			required[r] = true
+		}
+		var names []string
+		for n := range s.Properties {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		var props []string
+		for _, n := range names {
+			opt := "?"
+			if required[n] {
+				opt = ""
+			}
+			props = append(props, fmt.Sprintf("%s%s: %s", tsPropertyName(n), opt, tsTypeExpr(s.Properties[n])))
+		}
+		return "{ " + strings.Join(props, "; ") + " }"
+	}
+	return "unknown"
+}
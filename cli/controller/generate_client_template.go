@@ -0,0 +1,640 @@
+package controller
+
+var goClientTemplate = `
+// Code generated by mantil DO NOT EDIT
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+{{ range $name, $path := .Imports }}
+	{{ $name }} "{{ $path }}"
+{{- end }}
+{{- range .Apis }}
+	"{{ .ImportPath }}"
+{{- end }}
+)
+
+const (
+	apiErrorHeader     = "x-api-error"
+	apiErrorCodeHeader = "x-api-error-code"
+	authorizationHeader = "Authorization"
+)
+
+// Client calls project apis, each api method is one client method
+type Client struct {
+{{- range .Apis }}
+	{{ .Type }} *{{ .Type }}Client
+{{- end }}
+}
+
+func newClient(t transport) *Client {
+	return &Client{
+{{- range .Apis }}
+		{{ .Type }}: &{{ .Type }}Client{t: t},
+{{- end }}
+	}
+}
+
+// New creates client which calls apis over http, url is the stage rest endpoint
+// as shown by 'mantil env --url'
+func New(url string, opts ...Option) *Client {
+	o := newOptions(opts)
+	return newClient(&httpTransport{
+		url:    strings.TrimSuffix(url, "/"),
+		token:  o.token,
+		client: o.httpClient,
+	})
+}
+
+type Option func(*options)
+
+type options struct {
+	token      string
+	httpClient *http.Client
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{httpClient: http.DefaultClient}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithToken sets access token used when calling private apis
+func WithToken(token string) Option {
+	return func(o *options) {
+		o.token = token
+	}
+}
+
+// WithHTTPClient sets http client used for api calls
+func WithHTTPClient(c *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = c
+	}
+}
+
+// Error is returned when api method fails
+type Error struct {
+	StatusCode int
+	Message    string
+	Code       int
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return http.StatusText(e.StatusCode)
+	}
+	return e.Message
+}
+
+type transport interface {
+	call(ctx context.Context, method, path, uri string, private bool, req, rsp interface{}) error
+}
+
+type httpTransport struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func (t *httpTransport) call(ctx context.Context, method, path, uri string, private bool, req, rsp interface{}) error {
+	var body []byte
+	if method != http.MethodGet && method != http.MethodHead {
+		var err error
+		if body, err = encode(req); err != nil {
+			return err
+		}
+	}
+	hr, err := http.NewRequestWithContext(ctx, method, t.url+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if private && t.token != "" {
+		hr.Header.Set(authorizationHeader, "Bearer "+t.token)
+	}
+	hrsp, err := t.client.Do(hr)
+	if err != nil {
+		return err
+	}
+	defer hrsp.Body.Close()
+	buf, err := ioutil.ReadAll(hrsp.Body)
+	if err != nil {
+		return err
+	}
+	if hrsp.StatusCode >= http.StatusBadRequest {
+		e := &Error{
+			StatusCode: hrsp.StatusCode,
+			Message:    hrsp.Header.Get(apiErrorHeader),
+		}
+		e.Code, _ = strconv.Atoi(hrsp.Header.Get(apiErrorCodeHeader))
+		if e.Message == "" {
+			e.Message = strings.TrimSpace(string(buf))
+		}
+		return e
+	}
+	return decode(buf, rsp)
+}
+
+// encode prepares request payload, strings are sent unchanged and everything
+// else as json
+func encode(req interface{}) ([]byte, error) {
+	switch r := req.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []byte(r), nil
+	}
+	return json.Marshal(req)
+}
+
+// pathParam escapes value of the path parameter
+func pathParam(v interface{}) string {
+	return url.PathEscape(fmt.Sprint(v))
+}
+
+// decode reads response payload into rsp, strings and byte slices are
+// returned by apis unchanged
+func decode(buf []byte, rsp interface{}) error {
+	if rsp == nil || len(buf) == 0 {
+		return nil
+	}
+	switch r := rsp.(type) {
+	case *string:
+		*r = string(buf)
+		return nil
+	case *[]byte:
+		*r = buf
+		return nil
+	}
+	if err := json.Unmarshal(buf, rsp); err != nil {
+		return fmt.Errorf("unable to unmarshal response - %w", err)
+	}
+	return nil
+}
+{{ range $api := .Apis }}
+// {{ .Type }}Client calls methods of the {{ .Name }} api
+type {{ .Type }}Client struct {
+	t transport
+}
+{{ range .Methods }}
+{{ range .Doc }}//{{ if . }} {{ . }}{{ end }}
+{{ end -}}
+func (c *{{ $api.Type }}Client) {{ .Name }}(ctx context.Context{{ if .Req }}, req {{ .Req }}{{ end }}) {{ if .Rsp }}({{ .Rsp }}, error){{ else }}error{{ end }} {
+{{- if .Rsp }}
+	var rsp {{ .RspElem }}
+	err := c.t.call(ctx, "{{ .HTTPMethod }}", {{ .GoPath }}, "{{ .URI }}", {{ .Private }}, {{ if .Req }}req{{ else }}nil{{ end }}, &rsp)
+{{- if .RspPtr }}
+	if err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+{{- else }}
+	return rsp, err
+{{- end }}
+{{- else }}
+	return c.t.call(ctx, "{{ .HTTPMethod }}", {{ .GoPath }}, "{{ .URI }}", {{ .Private }}, {{ if .Req }}req{{ else }}nil{{ end }}, nil)
+{{- end }}
+}
+{{ end }}
+{{- end }}
+`
+
+var goClientWsTemplate = `
+// Code generated by mantil DO NOT EDIT
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/mantil-io/mantil.go/proto"
+)
+
+// WS is the client connected to the stage websocket endpoint. Api methods
+// are called with request/response messages over the connection and
+// messages published by the apis can be received by subscribing to the
+// subject.
+type WS struct {
+	*Client
+	conn *websocket.Conn
+
+	wmu sync.Mutex // serializes writes to the connection
+	mu      sync.Mutex
+	inboxes map[string]chan *proto.Message
+	subs    map[string][]chan []byte
+
+	done chan struct{}
+	err  error
+}
+
+// Dial connects to the stage websocket endpoint, url is shown by 'mantil env'
+// as MANTIL_WS_URL. Token is sent on connect and used for all api calls.
+func Dial(ctx context.Context, url string, opts ...Option) (*WS, error) {
+	o := newOptions(opts)
+	hdr := make(http.Header)
+	if o.token != "" {
+		hdr.Set(authorizationHeader, "Bearer "+o.token)
+	}
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, url, hdr)
+	if err != nil {
+		return nil, err
+	}
+	w := &WS{
+		conn:    conn,
+		inboxes: make(map[string]chan *proto.Message),
+		subs:    make(map[string][]chan []byte),
+		done:    make(chan struct{}),
+	}
+	w.Client = newClient(w)
+	go w.read()
+	return w, nil
+}
+
+func (w *WS) call(ctx context.Context, method, path, uri string, private bool, req, rsp interface{}) error {
+	payload, err := encode(req)
+	if err != nil {
+		return err
+	}
+	inbox, err := newInbox()
+	if err != nil {
+		return err
+	}
+	ch := make(chan *proto.Message, 1)
+	w.mu.Lock()
+	w.inboxes[inbox] = ch
+	w.mu.Unlock()
+	defer func() {
+		w.mu.Lock()
+		delete(w.inboxes, inbox)
+		w.mu.Unlock()
+	}()
+	if err := w.send(&proto.Message{
+		Type:    proto.Request,
+		URI:     uri,
+		Inbox:   inbox,
+		Payload: payload,
+	}); err != nil {
+		return err
+	}
+	// failed api calls have no response message, use context to limit waiting
+	select {
+	case m := <-ch:
+		return decode(m.Payload, rsp)
+	case <-w.done:
+		return w.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Subscribe returns channel with payloads of the messages published to the
+// subject. Channel is closed on Unsubscribe or when the connection is closed.
+func (w *WS) Subscribe(subject string) (<-chan []byte, error) {
+	ch := make(chan []byte, 16)
+	w.mu.Lock()
+	first := len(w.subs[subject]) == 0
+	w.subs[subject] = append(w.subs[subject], ch)
+	w.mu.Unlock()
+	if first {
+		if err := w.send(&proto.Message{
+			Type:     proto.Subscribe,
+			Subjects: []string{subject},
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return ch, nil
+}
+
+// Unsubscribe closes all channels subscribed to the subject
+func (w *WS) Unsubscribe(subject string) error {
+	w.mu.Lock()
+	chs := w.subs[subject]
+	delete(w.subs, subject)
+	w.mu.Unlock()
+	if len(chs) == 0 {
+		return nil
+	}
+	for _, ch := range chs {
+		close(ch)
+	}
+	return w.send(&proto.Message{
+		Type:     proto.Unsubscribe,
+		Subjects: []string{subject},
+	})
+}
+
+// Close closes the websocket connection
+func (w *WS) Close() error {
+	err := w.conn.Close()
+	<-w.done
+	return err
+}
+
+func (w *WS) send(m *proto.Message) error {
+	buf, err := m.Encode()
+	if err != nil {
+		return err
+	}
+	w.wmu.Lock()
+	defer w.wmu.Unlock()
+	return w.conn.WriteMessage(websocket.TextMessage, buf)
+}
+
+func (w *WS) read() {
+	defer func() {
+		w.mu.Lock()
+		for s, chs := range w.subs {
+			for _, ch := range chs {
+				close(ch)
+			}
+			delete(w.subs, s)
+		}
+		w.mu.Unlock()
+		close(w.done)
+	}()
+	for {
+		_, buf, err := w.conn.ReadMessage()
+		if err != nil {
+			w.err = err
+			return
+		}
+		m, err := proto.ParseMessage(buf)
+		if err != nil {
+			continue
+		}
+		w.mu.Lock()
+		switch m.Type {
+		case proto.Response:
+			if ch, ok := w.inboxes[m.Inbox]; ok {
+				ch <- m
+				delete(w.inboxes, m.Inbox)
+			}
+		case proto.Publish:
+			for _, ch := range w.subs[m.Subject] {
+				select {
+				case ch <- m.Payload:
+				default:
+					// drop message for the slow subscriber
+				}
+			}
+		}
+		w.mu.Unlock()
+	}
+}
+
+func newInbox() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+`
+
+var tsClientTemplate = `// Code generated by mantil DO NOT EDIT
+{{ range .Apis }}{{ range .TsTypes }}
+{{- if .Description }}
+/** {{ .Description }} */
+{{- end }}
+{{- if .Alias }}
+export type {{ .Name }} = {{ .Alias }};
+{{- else }}
+export interface {{ .Name }} {
+{{- range .Properties }}
+{{- if .Description }}
+  /** {{ .Description }} */
+{{- end }}
+  {{ .Name }}{{ if .Optional }}?{{ end }}: {{ .Type }};
+{{- end }}
+}
+{{- end }}
+{{ end }}{{ end }}
+const apiErrorHeader = "x-api-error";
+const apiErrorCodeHeader = "x-api-error-code";
+
+/** Error returned when api method fails */
+export class ApiError extends Error {
+  constructor(public status: number, message: string, public code?: number) {
+    super(message);
+  }
+}
+
+export interface Options {
+  /** access token used when calling private apis */
+  token?: string;
+}
+
+export interface Transport {
+  call(method: string, path: string, uri: string, priv: boolean, req?: unknown, rawReq?: boolean, rawRsp?: boolean): Promise<unknown>;
+}
+
+function encode(req: unknown, raw?: boolean): string | undefined {
+  if (req === undefined) {
+    return undefined;
+  }
+  return raw ? String(req) : JSON.stringify(req);
+}
+
+function decode(payload: string, raw?: boolean): unknown {
+  if (raw) {
+    return payload;
+  }
+  return payload === "" ? null : JSON.parse(payload);
+}
+
+class HttpTransport implements Transport {
+  constructor(private url: string, private options: Options) {}
+
+  async call(method: string, path: string, uri: string, priv: boolean, req?: unknown, rawReq?: boolean, rawRsp?: boolean): Promise<unknown> {
+    const headers: { [key: string]: string } = {};
+    if (priv && this.options.token) {
+      headers["Authorization"] = "Bearer " + this.options.token;
+    }
+    const rsp = await fetch(this.url + path, {
+      method: method,
+      headers: headers,
+      body: method === "GET" || method === "HEAD" ? undefined : encode(req, rawReq),
+    });
+    const payload = await rsp.text();
+    if (!rsp.ok) {
+      const code = rsp.headers.get(apiErrorCodeHeader);
+      throw new ApiError(rsp.status, rsp.headers.get(apiErrorHeader) || payload || rsp.statusText, code ? Number(code) : undefined);
+    }
+    return decode(payload, rawRsp);
+  }
+}
+
+interface Message {
+  type: string;
+  subject: string;
+  inbox: string;
+  payload: string;
+}
+
+// parseMessage reads mantil protocol message: header line with message
+// type and attributes followed by the payload
+function parseMessage(data: string): Message | undefined {
+  const i = data.indexOf("\n");
+  if (i < 0) {
+    return undefined;
+  }
+  const parts = data.substring(0, i).split(" ");
+  const payload = data.substring(i + 1);
+  switch (parts[0]) {
+    case "RSP":
+      return { type: parts[0], subject: "", inbox: parts[parts.length - 2], payload: payload };
+    case "PUB":
+      return { type: parts[0], subject: parts[1], inbox: "", payload: payload };
+  }
+  return undefined;
+}
+
+function byteLength(s: string): number {
+  return new TextEncoder().encode(s).length;
+}
+
+function newInbox(): string {
+  let inbox = "";
+  for (let i = 0; i < 4; i++) {
+    inbox += Math.floor(Math.random() * 0x100000000).toString(16).padStart(8, "0");
+  }
+  return inbox;
+}
+
+type Pending = { resolve: (payload: string) => void; reject: (err: Error) => void };
+
+class WsTransport implements Transport {
+  private pending = new Map<string, Pending>();
+  private subs = new Map<string, ((payload: string) => void)[]>();
+
+  constructor(private ws: WebSocket) {
+    ws.onmessage = (e: MessageEvent) => this.onMessage(String(e.data));
+    ws.onclose = () => {
+      this.pending.forEach((p) => p.reject(new Error("connection closed")));
+      this.pending.clear();
+    };
+  }
+
+  call(method: string, path: string, uri: string, priv: boolean, req?: unknown, rawReq?: boolean, rawRsp?: boolean): Promise<unknown> {
+    const inbox = newInbox();
+    const payload = encode(req, rawReq) || "";
+    return new Promise<string>((resolve, reject) => {
+      this.pending.set(inbox, { resolve, reject });
+      this.ws.send("REQ " + uri + " " + inbox + " " + byteLength(payload) + "\n" + payload);
+    }).then((payload) => decode(payload, rawRsp));
+  }
+
+  subscribe(subject: string, cb: (payload: string) => void): () => void {
+    const cbs = this.subs.get(subject) || [];
+    if (cbs.length === 0) {
+      this.ws.send("SUB " + subject + "\n");
+    }
+    this.subs.set(subject, cbs.concat(cb));
+    return () => {
+      const rest = (this.subs.get(subject) || []).filter((c) => c !== cb);
+      if (rest.length > 0) {
+        this.subs.set(subject, rest);
+        return;
+      }
+      this.subs.delete(subject);
+      this.ws.send("UNSUB " + subject + "\n");
+    };
+  }
+
+  close() {
+    this.ws.close();
+  }
+
+  private onMessage(data: string) {
+    const m = parseMessage(data);
+    if (!m) {
+      return;
+    }
+    if (m.type === "RSP") {
+      const p = this.pending.get(m.inbox);
+      if (p) {
+        this.pending.delete(m.inbox);
+        p.resolve(m.payload);
+      }
+      return;
+    }
+    (this.subs.get(m.subject) || []).forEach((cb) => cb(m.payload));
+  }
+}
+{{ range $api := .Apis }}
+/** {{ .Type }}Client calls methods of the {{ .Name }} api */
+export class {{ .Type }}Client {
+  constructor(private t: Transport) {}
+{{ range .Methods }}
+{{- if .TsComment }}
+  /**
+   * {{ .TsComment }}
+   */
+{{- end }}
+  {{ .TsMethod }}({{ if .TsReq }}req: {{ .TsReq }}{{ end }}): Promise<{{ if .TsRsp }}{{ .TsRsp }}{{ else }}void{{ end }}> {
+    return this.t.call("{{ .HTTPMethod }}", {{ .TsPath }}, "{{ .URI }}", {{ .Private }}, {{ if .TsReq }}req{{ else }}undefined{{ end }}, {{ .TsRawReq }}, {{ .TsRawRsp }}) as Promise<{{ if .TsRsp }}{{ .TsRsp }}{{ else }}void{{ end }}>;
+  }
+{{ end -}}
+}
+{{ end }}
+/** Client calls project apis, each api method is one client method */
+export class Client {
+{{- range .Apis }}
+  {{ .Name }}: {{ .Type }}Client;
+{{- end }}
+
+  constructor(t: Transport) {
+{{- range .Apis }}
+    this.{{ .Name }} = new {{ .Type }}Client(t);
+{{- end }}
+  }
+}
+
+/** newClient creates client which calls apis over http, url is the stage rest endpoint */
+export function newClient(url: string, options: Options = {}): Client {
+  return new Client(new HttpTransport(url.replace(/\/$/, ""), options));
+}
+
+/**
+ * WsClient calls apis over the stage websocket endpoint and receives
+ * messages published by the apis. Browsers can't set headers on the
+ * websocket connection so only public apis can be called.
+ */
+export class WsClient extends Client {
+  private constructor(private transport: WsTransport) {
+    super(transport);
+  }
+
+  static connect(url: string): Promise<WsClient> {
+    return new Promise((resolve, reject) => {
+      const ws = new WebSocket(url);
+      ws.onopen = () => resolve(new WsClient(new WsTransport(ws)));
+      ws.onerror = () => reject(new Error("unable to connect to " + url));
+    });
+  }
+
+  /** subscribe calls cb with payload of each message published to the subject, returns unsubscribe function */
+  subscribe(subject: string, cb: (payload: string) => void): () => void {
+    return this.transport.subscribe(subject, cb);
+  }
+
+  close() {
+    this.transport.close();
+  }
+}
+`
@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/mantil-io/mantil/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientApis(t *testing.T) {
+	apis, err := clientApis("testdata/generate/openapi/api", "example.com/project/api", &domain.Stage{}, map[string]bool{"todo": true})
+	require.NoError(t, err)
+	require.Len(t, apis, 2)
+
+	ping := apis[0]
+	require.Equal(t, "Ping", ping.Type())
+	require.Equal(t, "example.com/project/api/ping", ping.ImportPath)
+	require.Len(t, ping.Methods, 2)
+	require.Equal(t, "POST", ping.Methods[0].HTTPMethod)
+	require.Equal(t, `"/ping"`, ping.Methods[0].GoPath)
+	require.Equal(t, "ping.default", ping.Methods[0].URI)
+	require.Equal(t, []string{"Default returns pong"}, ping.Methods[0].Doc)
+	require.Equal(t, "string", ping.Methods[1].Req)
+	require.True(t, ping.Methods[1].TsRawReq)
+	require.True(t, ping.Methods[1].TsRawRsp)
+
+	todo := apis[1]
+	require.Equal(t, map[string]string{}, todo.Imports)
+	add := todo.Methods[0]
+	require.Equal(t, `"/todo/add"`, add.GoPath)
+	require.Equal(t, "todo.add", add.URI)
+	require.True(t, add.Private)
+	require.Equal(t, "todo.AddRequest", add.Req)
+	require.Equal(t, "*todo.AddResponse", add.Rsp)
+	require.Equal(t, "todo.AddResponse", add.RspElem)
+	require.True(t, add.RspPtr)
+	require.Equal(t, "TodoAddRequest", add.TsReq)
+	require.Equal(t, "TodoAddResponse | null", add.TsRsp)
+
+	var names []string
+	for _, tt := range todo.TsTypes {
+		names = append(names, tt.Name)
+	}
+	require.Equal(t, []string{"TodoAddRequest", "TodoAddResponse", "TodoAudit", "TodoItem"}, names)
+	item := todo.TsTypes[3]
+	require.Equal(t, "Item is a single todo list entry", item.Description)
+	require.Contains(t, item.Properties, tsProperty{Name: "children", Type: "TodoItem[]", Optional: true, Description: "nested items"})
+	require.Contains(t, item.Properties, tsProperty{Name: "due", Type: "string | null", Optional: true})
+	require.Contains(t, item.Properties, tsProperty{Name: "meta", Type: "{ [key: string]: string }", Optional: true})
+	require.Contains(t, item.Properties, tsProperty{Name: "title", Type: "string"})
+}
+
+func TestGenerateClient(t *testing.T) {
+	apis, err := clientApis("testdata/generate/openapi/api", "example.com/project/api", &domain.Stage{}, map[string]bool{"todo": true})
+	require.NoError(t, err)
+	dir := t.TempDir()
+
+	require.NoError(t, generateGoClient(apis, dir))
+	buf, err := ioutil.ReadFile(filepath.Join(dir, "client.go"))
+	require.NoError(t, err)
+	require.Contains(t, string(buf), `"example.com/project/api/todo"`)
+	require.Contains(t, string(buf), "func (c *TodoClient) Add(ctx context.Context, req todo.AddRequest) (*todo.AddResponse, error) {")
+	require.Contains(t, string(buf), "func (c *TodoClient) Clear(ctx context.Context) error {")
+	require.Contains(t, string(buf), "func (c *PingClient) Hello(ctx context.Context, req string) (string, error) {")
+	require.FileExists(t, filepath.Join(dir, "ws.go"))
+
+	ts := filepath.Join(dir, "client.ts")
+	require.NoError(t, generateTsClient(apis, ts))
+	buf, err = ioutil.ReadFile(ts)
+	require.NoError(t, err)
+	require.Contains(t, string(buf), "export interface TodoItem {")
+	require.Contains(t, string(buf), "add(req: TodoAddRequest): Promise<TodoAddResponse | null> {")
+	require.Contains(t, string(buf), "clear(): Promise<void> {")
+}
+
+// TestGenerateGoClientBuild builds and vets generated client together with
+// the api packages it imports
+func TestGenerateGoClientBuild(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not found")
+	}
+	dir := filepath.Join(t.TempDir(), "project")
+	require.NoError(t, copyTemplateDir("testdata/generate/openapi/api", filepath.Join(dir, "api")))
+	gomod := "module example.com/project\n\ngo 1.16\n\nrequire (\n\tgithub.com/gorilla/websocket v1.4.2\n\tgithub.com/mantil-io/mantil.go v0.1.13\n)\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte(gomod), 0644))
+	// checksums of the client dependencies are in the mantil go.sum
+	gosum, err := ioutil.ReadFile("../../go.sum")
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "go.sum"), gosum, 0644))
+
+	apis, err := clientApis(filepath.Join(dir, "api"), "example.com/project/api", &domain.Stage{}, map[string]bool{"todo": true})
+	require.NoError(t, err)
+	require.NoError(t, generateGoClient(apis, filepath.Join(dir, "client")))
+
+	for _, args := range [][]string{{"build", "./..."}, {"vet", "./..."}} {
+		cmd := exec.Command("go", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod")
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, "go %v\n%s", args, out)
+	}
+}
+
+func TestClientApisRoutes(t *testing.T) {
+	apis, err := clientApis("testdata/generate/routes/api", "example.com/project", &domain.Stage{}, nil)
+	require.NoError(t, err)
+	require.Len(t, apis, 1)
+
+	methods := make(map[string]clientMethod)
+	for _, m := range apis[0].Methods {
+		methods[m.Name] = m
+	}
+	get := methods["Get"]
+	require.Equal(t, "GET", get.HTTPMethod)
+	require.Equal(t, `"/todo/" + pathParam(req.ID)`, get.GoPath)
+	require.Equal(t, `"/todo/" + encodeURIComponent(String(req.id))`, get.TsPath)
+	require.False(t, get.Private)
+
+	update := methods["Update"]
+	require.Equal(t, "PUT", update.HTTPMethod)
+	require.Equal(t, `"/todo/" + pathParam(req.ID) + "/" + pathParam(req.Version)`, update.GoPath)
+	require.Equal(t, `"/todo/" + encodeURIComponent(String(req.id)) + "/" + encodeURIComponent(String(req.Version))`, update.TsPath)
+	require.True(t, update.Private)
+
+	list := methods["List"]
+	require.Equal(t, "POST", list.HTTPMethod)
+	require.Equal(t, `"/todo/list"`, list.GoPath)
+}
@@ -0,0 +1,440 @@
+package controller
+
+import (
+	"fmt"
+	"go/ast"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/mantil-io/mantil/cli/log"
+	"github.com/mantil-io/mantil/domain"
+)
+
+// apiHandler is the wrapper around api struct generated in the function
+// main. It runs api middleware and request validation before calling api
+// methods. Only api methods are exposed by the wrapper so hooks can't be
+// called through the api.
+type apiHandler struct {
+	// api struct type
+	Type       string
+	Middleware bool
+	Methods    []handlerMethod
+	Validators []validator
+	Imports    map[string]string
+	// routes of the api methods with their own configuration and the api
+	// default method, served by the handler router
+	Routes  []handlerRoute
+	Default *handlerRoute
+	// echo X-Ray trace id of the invocation to the invoke command
+	Trace bool
+}
+
+type handlerMethod struct {
+	Name string
+	// name of the handler method, differs from the api method name when
+	// router takes its place
+	Func string
+	// route key of the method, method is not accessible through other routes
+	Route string
+	// request and response types, empty if the method doesn't have them
+	Req    string
+	ReqPtr bool
+	Rsp    string
+	// api method accepts context and returns error
+	Context bool
+	Error   bool
+	// validation function of the request, empty if the request has nothing
+	// to validate
+	Validate string
+}
+
+// validator is generated validation function of the api struct type
+type validator struct {
+	Name   string
+	Type   string
+	Checks []string
+}
+
+// newApiHandler describes wrapper for the api, it returns nil if the api
+// has no middleware, requests which need validation, method routes nor
+// active tracing
+func newApiHandler(ap *apiPackage, routes []domain.FunctionRoute, trace bool) (*apiHandler, error) {
+	schemas := newOpenAPISchemas(ap.pkg, make(map[string]*openAPISchema))
+	gt := &goTypes{
+		pkg:     ap.pkg.Name,
+		local:   schemas.types,
+		imports: make(map[string]string),
+	}
+	v := &validators{
+		pkg:   ap.pkg.Name,
+		types: schemas.types,
+		names: make(map[string]string),
+	}
+	h := &apiHandler{
+		Type:       gt.pkg + "." + ap.typeName,
+		Middleware: ap.middleware,
+		Trace:      trace,
+	}
+	if ap.newPointer {
+		h.Type = "*" + h.Type
+	}
+	for _, m := range apiMethods(ap.pkg, ap.typeName) {
+		hm := handlerMethod{
+			Name:    m.name,
+			Func:    m.name,
+			Context: m.context,
+			Error:   m.error,
+		}
+		if m.req != nil {
+			hm.Req = gt.expr(m.req, m.file)
+			req := m.req
+			if se, ok := req.(*ast.StarExpr); ok {
+				hm.ReqPtr = true
+				req = se.X
+			}
+			if id, ok := req.(*ast.Ident); ok {
+				fn, err := v.function(id.Name)
+				if err != nil {
+					return nil, log.Wrap(err)
+				}
+				hm.Validate = fn
+			}
+		}
+		if m.rsp != nil {
+			hm.Rsp = gt.expr(m.rsp, m.file)
+		}
+		h.Methods = append(h.Methods, hm)
+	}
+	if err := h.routes(ap, routes); err != nil {
+		return nil, log.Wrap(err)
+	}
+	if !h.Middleware && !h.Trace && len(v.list) == 0 && len(h.Routes) == 0 {
+		return nil, nil
+	}
+	h.Validators = v.list
+	h.Imports = gt.imports
+	return h, nil
+}
+
+// validators generates validation functions from the validate struct tags
+type validators struct {
+	pkg   string
+	types map[string]*ast.TypeSpec
+	// validation function name by the type name, empty if the type has
+	// nothing to validate
+	names map[string]string
+	list  []validator
+}
+
+// function returns name of the validation function for the api package
+// type, empty if there is nothing to validate
+func (v *validators) function(typeName string) (string, error) {
+	if fn, ok := v.names[typeName]; ok {
+		return fn, nil
+	}
+	ts, ok := v.types[typeName]
+	if !ok {
+		return "", nil
+	}
+	st, ok := ts.Type.(*ast.StructType)
+	if !ok {
+		v.names[typeName] = ""
+		return "", nil
+	}
+	fn := "validate" + strings.Title(v.pkg) + typeName
+	// set before building checks for self referencing types
+	v.names[typeName] = fn
+	checks, err := v.structChecks(typeName, st)
+	if err != nil {
+		return "", err
+	}
+	if len(checks) == 0 {
+		v.names[typeName] = ""
+		return "", nil
+	}
+	v.list = append(v.list, validator{
+		Name:   fn,
+		Type:   v.pkg + "." + typeName,
+		Checks: checks,
+	})
+	return fn, nil
+}
+
+func (v *validators) structChecks(typeName string, st *ast.StructType) ([]string, error) {
+	var checks []string
+	for _, f := range st.Fields.List {
+		tag := parseJSONTag(f)
+		if tag.skip {
+			continue
+		}
+		if len(f.Names) == 0 {
+			// embedded struct fields are validated with the parent fields
+			expr, ptr := f.Type, false
+			if se, ok := expr.(*ast.StarExpr); ok {
+				expr, ptr = se.X, true
+			}
+			id, ok := expr.(*ast.Ident)
+			if !ok {
+				continue
+			}
+			fn, err := v.function(id.Name)
+			if err != nil {
+				return nil, err
+			}
+			if fn == "" {
+				continue
+			}
+			field := "v." + id.Name
+			prefix := "prefix"
+			if tag.name != "" {
+				prefix = fmt.Sprintf("prefix+%q", tag.name+".")
+			}
+			if ptr {
+				checks = append(checks, fmt.Sprintf("%s(%s, %s, errs)", fn, field, prefix))
+			} else {
+				checks = append(checks, fmt.Sprintf("%s(&%s, %s, errs)", fn, field, prefix))
+			}
+			continue
+		}
+		for _, n := range f.Names {
+			if !n.IsExported() {
+				continue
+			}
+			name := tag.name
+			if name == "" {
+				name = n.Name
+			}
+			fc := fieldChecks{
+				field: "v." + n.Name,
+				name:  name,
+				expr:  f.Type,
+				types: v.types,
+			}
+			if f.Tag != nil {
+				rules, ok := reflect.StructTag(strings.Trim(f.Tag.Value, "`")).Lookup("validate")
+				if ok {
+					c, err := fc.rules(rules)
+					if err != nil {
+						return nil, fmt.Errorf("field %s of type %s - %w", n.Name, typeName, err)
+					}
+					checks = append(checks, c...)
+				}
+			}
+			c, err := v.nestedChecks(fc)
+			if err != nil {
+				return nil, err
+			}
+			checks = append(checks, c...)
+		}
+	}
+	return checks, nil
+}
+
+// nestedChecks validates fields of the api package struct types
+func (v *validators) nestedChecks(fc fieldChecks) ([]string, error) {
+	prefix := fmt.Sprintf("prefix+%q", fc.name+".")
+	switch t := fc.expr.(type) {
+	case *ast.Ident:
+		fn, err := v.function(t.Name)
+		if err != nil || fn == "" {
+			return nil, err
+		}
+		return []string{fmt.Sprintf("%s(&%s, %s, errs)", fn, fc.field, prefix)}, nil
+	case *ast.StarExpr:
+		id, ok := t.X.(*ast.Ident)
+		if !ok {
+			return nil, nil
+		}
+		fn, err := v.function(id.Name)
+		if err != nil || fn == "" {
+			return nil, err
+		}
+		return []string{fmt.Sprintf("%s(%s, %s, errs)", fn, fc.field, prefix)}, nil
+	case *ast.ArrayType:
+		elt, ptr := t.Elt, false
+		if se, ok := elt.(*ast.StarExpr); ok {
+			elt, ptr = se.X, true
+		}
+		id, ok := elt.(*ast.Ident)
+		if !ok {
+			return nil, nil
+		}
+		fn, err := v.function(id.Name)
+		if err != nil || fn == "" {
+			return nil, err
+		}
+		item := fmt.Sprintf("&%s[i]", fc.field)
+		if ptr {
+			item = fmt.Sprintf("%s[i]", fc.field)
+		}
+		return []string{fmt.Sprintf(`for i := range %s {
+	%s(%s, fmt.Sprintf("%%s%s[%%d].", prefix, i), errs)
+}`, fc.field, fn, item, fc.name)}, nil
+	}
+	return nil, nil
+}
+
+// fieldChecks generates checks for the validate rules of the struct field
+type fieldChecks struct {
+	// field selector in the validation function
+	field string
+	// field name in the error messages
+	name  string
+	expr  ast.Expr
+	types map[string]*ast.TypeSpec
+}
+
+const (
+	kindString = "string"
+	kindNumber = "number"
+	kindLength = "length"
+	kindPtr    = "pointer"
+	kindOther  = "other"
+)
+
+var numberTypes = map[string]bool{
+	"int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+	"float32": true, "float64": true, "byte": true, "rune": true,
+}
+
+// kind of the field type, types declared in the api package are resolved
+// to the underlying type
+func (c fieldChecks) kind() string {
+	expr := c.expr
+	for {
+		switch t := expr.(type) {
+		case *ast.Ident:
+			if t.Name == "string" {
+				return kindString
+			}
+			if numberTypes[t.Name] {
+				return kindNumber
+			}
+			ts, ok := c.types[t.Name]
+			if !ok {
+				return kindOther
+			}
+			expr = ts.Type
+			continue
+		case *ast.StarExpr:
+			return kindPtr
+		case *ast.ArrayType:
+			if t.Len == nil {
+				return kindLength
+			}
+		case *ast.MapType:
+			return kindLength
+		}
+		return kindOther
+	}
+}
+
+func (c fieldChecks) rules(tag string) ([]string, error) {
+	kind := c.kind()
+	var zero, nonZero, length, value string
+	switch kind {
+	case kindString:
+		zero, nonZero = fmt.Sprintf(`%s == ""`, c.field), fmt.Sprintf(`%s != ""`, c.field)
+		length = fmt.Sprintf("utf8.RuneCountInString(string(%s))", c.field)
+		value = c.field
+	case kindNumber:
+		zero, nonZero = fmt.Sprintf("%s == 0", c.field), fmt.Sprintf("%s != 0", c.field)
+		value = c.field
+	case kindLength:
+		zero, nonZero = fmt.Sprintf("len(%s) == 0", c.field), fmt.Sprintf("len(%s) > 0", c.field)
+		length = fmt.Sprintf("len(%s)", c.field)
+	case kindPtr:
+		zero, nonZero = fmt.Sprintf("%s == nil", c.field), fmt.Sprintf("%s != nil", c.field)
+	}
+	// checks are chained so that only the first failed rule is reported
+	var checks []string
+	check := func(cond, msg string) {
+		checks = append(checks, fmt.Sprintf(`if %s {
+	*errs = append(*errs, prefix+%q)
+}`, cond, c.name+" "+msg))
+	}
+	omitEmpty := false
+	for _, r := range strings.Split(tag, ",") {
+		if r == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	for _, r := range strings.Split(tag, ",") {
+		rule, param := r, ""
+		if i := strings.Index(r, "="); i >= 0 {
+			rule, param = r[:i], r[i+1:]
+		}
+		unsupported := fmt.Errorf("validation rule %q is not supported for type %s", rule, printExpr(c.expr))
+		// other rules are skipped for empty optional fields
+		cond := func(c string) string {
+			if omitEmpty {
+				return fmt.Sprintf("%s && %s", nonZero, c)
+			}
+			return c
+		}
+		switch rule {
+		case "", "omitempty":
+		case "required":
+			if zero == "" {
+				return nil, unsupported
+			}
+			check(zero, "is required")
+		case "min", "max", "len":
+			if _, err := strconv.ParseFloat(param, 64); err != nil {
+				return nil, fmt.Errorf("invalid %s parameter %q", rule, param)
+			}
+			var op, msg string
+			switch rule {
+			case "min":
+				op, msg = "<", "at least"
+			case "max":
+				op, msg = ">", "at most"
+			case "len":
+				op, msg = "!=", "exactly"
+			}
+			switch {
+			case length != "":
+				if _, err := strconv.Atoi(param); err != nil {
+					return nil, fmt.Errorf("invalid %s parameter %q", rule, param)
+				}
+				check(cond(fmt.Sprintf("%s %s %s", length, op, param)), fmt.Sprintf("length must be %s %s", msg, param))
+			case kind == kindNumber && rule != "len":
+				check(cond(fmt.Sprintf("%s %s %s", value, op, param)), fmt.Sprintf("must be %s %s", msg, param))
+			default:
+				return nil, unsupported
+			}
+		case "oneof":
+			if value == "" {
+				return nil, unsupported
+			}
+			values := strings.Fields(param)
+			if len(values) == 0 {
+				return nil, fmt.Errorf("oneof rule requires values")
+			}
+			var conds []string
+			for _, o := range values {
+				if kind == kindString {
+					o = strconv.Quote(o)
+				} else if _, err := strconv.ParseFloat(o, 64); err != nil {
+					return nil, fmt.Errorf("invalid oneof value %q", o)
+				}
+				conds = append(conds, fmt.Sprintf("%s != %s", value, o))
+			}
+			check(cond(strings.Join(conds, " && ")), "must be one of "+strings.Join(values, " "))
+		case "email", "url":
+			if kind != kindString {
+				return nil, unsupported
+			}
+			fn := map[string]string{"email": "validEmail", "url": "validURL"}[rule]
+			check(cond(fmt.Sprintf("!%s(string(%s))", fn, value)), "must be a valid "+rule)
+		default:
+			return nil, fmt.Errorf("unknown validation rule %q", rule)
+		}
+	}
+	if len(checks) == 0 {
+		return nil, nil
+	}
+	return []string{strings.Join(checks, " else ")}, nil
+}
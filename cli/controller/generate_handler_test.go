@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApiHandler(t *testing.T) {
+	ap, err := parseApi("ping", "testdata/generate/middleware")
+	require.NoError(t, err)
+	require.True(t, ap.middleware)
+
+	h, err := newApiHandler(ap, nil, false)
+	require.NoError(t, err)
+	require.NotNil(t, h)
+	require.Equal(t, "*ping.Ping", h.Type)
+	require.True(t, h.Middleware)
+
+	var methods []string
+	for _, m := range h.Methods {
+		methods = append(methods, m.Name)
+	}
+	require.Equal(t, []string{"Default", "Hello", "Noop", "Touch"}, methods)
+	hello := h.Methods[1]
+	require.Equal(t, "*ping.HelloRequest", hello.Req)
+	require.True(t, hello.ReqPtr)
+	require.Equal(t, "*ping.HelloResponse", hello.Rsp)
+	require.Equal(t, "validatePingHelloRequest", hello.Validate)
+	touch := h.Methods[3]
+	require.False(t, touch.Context)
+	require.True(t, touch.Error)
+	require.Equal(t, "validatePingAddress", touch.Validate)
+
+	require.Len(t, h.Validators, 2)
+	v := h.Validators[1]
+	require.Equal(t, "ping.HelloRequest", v.Type)
+	require.Equal(t, `if v.Name == "" {
+	*errs = append(*errs, prefix+"name is required")
+} else if utf8.RuneCountInString(string(v.Name)) < 2 {
+	*errs = append(*errs, prefix+"name length must be at least 2")
+} else if utf8.RuneCountInString(string(v.Name)) > 20 {
+	*errs = append(*errs, prefix+"name length must be at most 20")
+}`, v.Checks[0])
+	require.Equal(t, `if v.Email != "" && !validEmail(string(v.Email)) {
+	*errs = append(*errs, prefix+"email must be a valid email")
+}`, v.Checks[1])
+	require.Equal(t, `if v.Color != "red" && v.Color != "green" {
+	*errs = append(*errs, prefix+"color must be one of red green")
+}`, v.Checks[3])
+	require.Equal(t, `validatePingAddress(v.Address, prefix+"address.", errs)`, v.Checks[6])
+	require.Equal(t, `for i := range v.Others {
+	validatePingAddress(&v.Others[i], fmt.Sprintf("%sothers[%d].", prefix, i), errs)
+}`, v.Checks[7])
+
+	out, err := renderTemplate(apiFunctionMainTemplate, &function{
+		Name:       "ping",
+		ImportPath: "example.com/project/api",
+		Close:      ap.close,
+		Handler:    h,
+	})
+	require.NoError(t, err)
+	_, err = formatAndAdjustImports(string(out))
+	require.NoError(t, err)
+}
+
+func TestApiHandlerNotNeeded(t *testing.T) {
+	ap, err := parseApi("ping", "testdata/generate/ping_ptr")
+	require.NoError(t, err)
+	h, err := newApiHandler(ap, nil, false)
+	require.NoError(t, err)
+	require.Nil(t, h)
+}
+
+func TestApiHandlerTrace(t *testing.T) {
+	ap, err := parseApi("ping", "testdata/generate/ping_ptr")
+	require.NoError(t, err)
+	h, err := newApiHandler(ap, nil, true)
+	require.NoError(t, err)
+	require.NotNil(t, h)
+	require.True(t, h.Trace)
+
+	out, err := renderTemplate(apiFunctionMainTemplate, &function{
+		Name:       "ping",
+		ImportPath: "example.com/project/api",
+		Handler:    h,
+	})
+	require.NoError(t, err)
+	src, err := formatAndAdjustImports(string(out))
+	require.NoError(t, err)
+	require.Contains(t, string(src), "\techoTraceID(ctx)\n")
+	require.Contains(t, string(src), `rc.Request.Headers["mantil-nats-config"]`)
+	require.Contains(t, string(src), `log.Printf("mantil trace id: %s"`)
+}
+
+func TestApiHandlerUnsupportedRule(t *testing.T) {
+	ap, err := parseApi("ping", "testdata/generate/invalid_rule")
+	require.NoError(t, err)
+	_, err = newApiHandler(ap, nil, false)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `validation rule "required" is not supported for type bool`)
+}
@@ -0,0 +1,653 @@
+package controller
+
+import (
+	"encoding/json"
+	"go/ast"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/mantil-io/mantil/cli/log"
+	"github.com/mantil-io/mantil/cli/ui"
+	"github.com/mantil-io/mantil/domain"
+)
+
+const (
+	OpenAPIFileName   = "openapi.json"
+	openAPIVersion    = "3.0.3"
+	openAPISecurity   = "bearerAuth"
+	openAPIErrorRef   = "#/components/responses/Error"
+	openAPISchemasRef = "#/components/schemas/"
+)
+
+type GenerateOpenAPIArgs struct {
+	Stage  string
+	Output string
+}
+
+// GenerateOpenAPI writes OpenAPI specification of the project apis.
+// Each exported method of the struct returned by api New function is one
+// endpoint.
+func GenerateOpenAPI(a GenerateOpenAPIArgs) error {
+	fs, stage, err := newStoreWithStage(a.Stage)
+	if err != nil {
+		return log.Wrap(err)
+	}
+	spec := openAPISpec{
+		title:   stage.Project().Name,
+		stage:   stage,
+		private: stagePrivateFunctions(stage),
+	}
+	if stage.Endpoints != nil {
+		spec.server = stage.RestEndpoint()
+	} else {
+		ui.Info("Stage %s is not deployed, specification will not contain server url.", stage.Name)
+	}
+	doc, err := spec.build(fs.ApiDir())
+	if err != nil {
+		return log.Wrap(err)
+	}
+	buf, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return log.Wrap(err)
+	}
+	output := a.Output
+	if output == "" {
+		output = filepath.Join(fs.ProjectRoot(), OpenAPIFileName)
+	}
+	if err := ioutil.WriteFile(output, buf, 0644); err != nil {
+		return log.Wrap(err)
+	}
+	ui.Info("OpenAPI specification for stage %s saved to %s", stage.Name, output)
+	return nil
+}
+
+// stagePrivateFunctions returns names of the functions which require
+// access token
+func stagePrivateFunctions(stage *domain.Stage) map[string]bool {
+	private := make(map[string]bool)
+	for _, f := range stage.Functions {
+		if f.Private || f.Authorizer != nil {
+			private[f.Name] = true
+		}
+	}
+	return private
+}
+
+type openAPIDocument struct {
+	OpenAPI    string                      `json:"openapi"`
+	Info       openAPIInfo                 `json:"info"`
+	Servers    []openAPIServer             `json:"servers,omitempty"`
+	Paths      map[string]*openAPIPathItem `json:"paths"`
+	Components openAPIComponents           `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIServer struct {
+	URL string `json:"url"`
+}
+
+type openAPIPathItem struct {
+	Get     *openAPIOperation `json:"get,omitempty"`
+	Put     *openAPIOperation `json:"put,omitempty"`
+	Post    *openAPIOperation `json:"post,omitempty"`
+	Delete  *openAPIOperation `json:"delete,omitempty"`
+	Options *openAPIOperation `json:"options,omitempty"`
+	Head    *openAPIOperation `json:"head,omitempty"`
+	Patch   *openAPIOperation `json:"patch,omitempty"`
+}
+
+func (p *openAPIPathItem) set(httpMethod string, op *openAPIOperation) {
+	switch httpMethod {
+	case http.MethodGet:
+		p.Get = op
+	case http.MethodPut:
+		p.Put = op
+	case http.MethodDelete:
+		p.Delete = op
+	case http.MethodOptions:
+		p.Options = op
+	case http.MethodHead:
+		p.Head = op
+	case http.MethodPatch:
+		p.Patch = op
+	default:
+		p.Post = op
+	}
+}
+
+type openAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Summary     string                      `json:"summary,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Parameters  []openAPIParameter          `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+	Security    []map[string][]string       `json:"security,omitempty"`
+}
+
+type openAPIParameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required"`
+	Schema   *openAPISchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIMediaType struct {
+	Schema *openAPISchema `json:"schema"`
+}
+
+type openAPIResponse struct {
+	Ref         string                      `json:"$ref,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	Headers     map[string]*openAPIHeader   `json:"headers,omitempty"`
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIHeader struct {
+	Description string         `json:"description,omitempty"`
+	Schema      *openAPISchema `json:"schema"`
+}
+
+type openAPIComponents struct {
+	Schemas         map[string]*openAPISchema         `json:"schemas,omitempty"`
+	Responses       map[string]*openAPIResponse       `json:"responses,omitempty"`
+	SecuritySchemes map[string]*openAPISecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type openAPISecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Description          string                    `json:"description,omitempty"`
+	Nullable             bool                      `json:"nullable,omitempty"`
+	Items                *openAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*openAPISchema `json:"properties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	AdditionalProperties *openAPISchema            `json:"additionalProperties,omitempty"`
+}
+
+type openAPISpec struct {
+	title  string
+	server string
+	// method routes are resolved from the stage configuration
+	stage   *domain.Stage
+	private map[string]bool
+}
+
+func (s openAPISpec) build(apiDir string) (*openAPIDocument, error) {
+	doc := &openAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info: openAPIInfo{
+			Title:   s.title,
+			Version: "1.0.0",
+		},
+		Paths: make(map[string]*openAPIPathItem),
+		Components: openAPIComponents{
+			Schemas: make(map[string]*openAPISchema),
+			Responses: map[string]*openAPIResponse{
+				"Error": {
+					Description: "Error returned by the api method",
+					Headers: map[string]*openAPIHeader{
+						"x-api-error": {
+							Description: "error message",
+							Schema:      &openAPISchema{Type: "string"},
+						},
+						"x-api-error-code": {
+							Description: "application error code",
+							Schema:      &openAPISchema{Type: "integer"},
+						},
+					},
+				},
+			},
+		},
+	}
+	if s.server != "" {
+		doc.Servers = []openAPIServer{{URL: s.server}}
+	}
+	fis, err := ioutil.ReadDir(apiDir)
+	if err != nil {
+		return nil, log.Wrap(err)
+	}
+	for _, fi := range fis {
+		if !fi.IsDir() {
+			continue
+		}
+		api := fi.Name()
+		ap, err := parseApi(api, filepath.Join(apiDir, api))
+		if err != nil {
+			return nil, log.Wrap(err)
+		}
+		schemas := newOpenAPISchemas(ap.pkg, doc.Components.Schemas)
+		routes, err := newMethodRoutes(s.stage, ap, s.private[api])
+		if err != nil {
+			return nil, log.Wrap(err)
+		}
+		for _, m := range apiMethods(ap.pkg, ap.typeName) {
+			r := routes.route(m.name)
+			op, err := s.operation(api, m, r, routes, schemas)
+			if err != nil {
+				return nil, log.Wrap(err)
+			}
+			item, ok := doc.Paths[r.Path]
+			if !ok {
+				item = &openAPIPathItem{}
+				doc.Paths[r.Path] = item
+			}
+			item.set(r.HTTPMethod, op)
+			if op.Security != nil {
+				doc.Components.SecuritySchemes = map[string]*openAPISecurityScheme{
+					openAPISecurity: {
+						Type:         "http",
+						Scheme:       "bearer",
+						BearerFormat: "JWT",
+					},
+				}
+			}
+		}
+	}
+	return doc, nil
+}
+
+// apiMethod is exported method of the api struct
+type apiMethod struct {
+	name string
+	doc  string
+	file *ast.File
+	// request and response types, nil if the method doesn't have them
+	req ast.Expr
+	rsp ast.Expr
+	// method accepts context and returns error
+	context bool
+	error   bool
+	// //mantil: comment directives of the method
+	directives []string
+}
+
+// apiMethods finds exported methods of the api struct which are valid
+// lambda handlers: optional context, optional request and optional
+// response with optional error as the last return value
+func apiMethods(pkg *ast.Package, typeName string) []apiMethod {
+	var methods []apiMethod
+	for _, f := range pkg.Files {
+		for _, d := range f.Decls {
+			fd, ok := d.(*ast.FuncDecl)
+			if !ok || fd.Recv == nil || !fd.Name.IsExported() {
+				continue
+			}
+			if receiverName(fd.Recv.List[0].Type) != typeName {
+				continue
+			}
+			// hooks are called by the generated main
+			if isCloseHook(fd) || isShutdownHook(fd) || isMiddlewareHook(fd) {
+				continue
+			}
+			var m apiMethod
+			params := flattenFields(fd.Type.Params)
+			if len(params) > 0 && isContext(params[0]) {
+				params = params[1:]
+				m.context = true
+			}
+			var results []ast.Expr
+			if fd.Type.Results != nil {
+				results = flattenFields(fd.Type.Results)
+			}
+			if len(results) > 0 && isError(results[len(results)-1]) {
+				results = results[:len(results)-1]
+				m.error = true
+			}
+			if len(params) > 1 || len(results) > 1 {
+				continue
+			}
+			m.name = fd.Name.Name
+			m.doc = strings.TrimSpace(fd.Doc.Text())
+			m.file = f
+			if fd.Doc != nil {
+				for _, c := range fd.Doc.List {
+					if strings.HasPrefix(c.Text, directivePrefix) {
+						m.directives = append(m.directives, c.Text)
+					}
+				}
+			}
+			if len(params) == 1 {
+				m.req = params[0]
+			}
+			if len(results) == 1 {
+				m.rsp = results[0]
+			}
+			methods = append(methods, m)
+		}
+	}
+	sort.Slice(methods, func(i, j int) bool { return methods[i].name < methods[j].name })
+	return methods
+}
+
+func (s openAPISpec) operation(api string, m apiMethod, r domain.FunctionRoute, routes *methodRoutes, schemas *openAPISchemas) (*openAPIOperation, error) {
+	op := &openAPIOperation{
+		OperationID: api + m.name,
+		Tags:        []string{api},
+		Responses: map[string]*openAPIResponse{
+			"default": {Ref: openAPIErrorRef},
+		},
+	}
+	if m.doc != "" {
+		op.Summary = strings.Split(m.doc, "\n")[0]
+		op.Description = m.doc
+	}
+	fields, err := routes.pathParamFields(r, m.req)
+	if err != nil {
+		return nil, log.Wrap(err)
+	}
+	for _, p := range r.PathParams() {
+		op.Parameters = append(op.Parameters, openAPIParameter{
+			Name:     p,
+			In:       "path",
+			Required: true,
+			Schema:   schemas.schema(fields[p].Type),
+		})
+	}
+	if m.req != nil && hasBody(r.HTTPMethod) {
+		op.RequestBody = &openAPIRequestBody{
+			Required: true,
+			Content:  openAPIContent(m.req, schemas),
+		}
+	}
+	ok := &openAPIResponse{Description: "Successful response"}
+	if m.rsp != nil {
+		ok.Content = openAPIContent(m.rsp, schemas)
+	}
+	op.Responses["200"] = ok
+	if r.Private {
+		op.Security = []map[string][]string{{openAPISecurity: {}}}
+	}
+	return op, nil
+}
+
+// openAPIContent describes request or response body, strings are passed
+// unchanged and everything else is json encoded
+func openAPIContent(expr ast.Expr, schemas *openAPISchemas) map[string]openAPIMediaType {
+	mediaType := "application/json"
+	if id, ok := expr.(*ast.Ident); ok && id.Name == "string" {
+		mediaType = "text/plain"
+	}
+	return map[string]openAPIMediaType{
+		mediaType: {Schema: schemas.schema(expr)},
+	}
+}
+
+func flattenFields(fl *ast.FieldList) []ast.Expr {
+	var exprs []ast.Expr
+	for _, f := range fl.List {
+		n := len(f.Names)
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			exprs = append(exprs, f.Type)
+		}
+	}
+	return exprs
+}
+
+func receiverName(expr ast.Expr) string {
+	if se, ok := expr.(*ast.StarExpr); ok {
+		expr = se.X
+	}
+	if id, ok := expr.(*ast.Ident); ok {
+		return id.Name
+	}
+	return ""
+}
+
+func isContext(expr ast.Expr) bool {
+	se, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	x, ok := se.X.(*ast.Ident)
+	return ok && x.Name == "context" && se.Sel.Name == "Context"
+}
+
+func isError(expr ast.Expr) bool {
+	id, ok := expr.(*ast.Ident)
+	return ok && id.Name == "error"
+}
+
+// openAPISchemas builds schemas of the api package types, named types are
+// added to the document components
+type openAPISchemas struct {
+	pkg        string
+	types      map[string]*ast.TypeSpec
+	docs       map[string]string
+	components map[string]*openAPISchema
+}
+
+func newOpenAPISchemas(pkg *ast.Package, components map[string]*openAPISchema) *openAPISchemas {
+	s := &openAPISchemas{
+		pkg:        pkg.Name,
+		types:      make(map[string]*ast.TypeSpec),
+		docs:       make(map[string]string),
+		components: components,
+	}
+	for _, f := range pkg.Files {
+		for _, d := range f.Decls {
+			gd, ok := d.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			for _, sp := range gd.Specs {
+				ts, ok := sp.(*ast.TypeSpec)
+				if !ok {
+					continue
+				}
+				s.types[ts.Name.Name] = ts
+				doc := ts.Doc.Text()
+				if doc == "" && len(gd.Specs) == 1 {
+					doc = gd.Doc.Text()
+				}
+				s.docs[ts.Name.Name] = strings.TrimSpace(doc)
+			}
+		}
+	}
+	return s
+}
+
+var openAPIBasicTypes = map[string]openAPISchema{
+	"bool":        {Type: "boolean"},
+	"string":      {Type: "string"},
+	"int":         {Type: "integer"},
+	"int8":        {Type: "integer"},
+	"int16":       {Type: "integer"},
+	"int32":       {Type: "integer", Format: "int32"},
+	"int64":       {Type: "integer", Format: "int64"},
+	"uint":        {Type: "integer"},
+	"uint8":       {Type: "integer"},
+	"uint16":      {Type: "integer"},
+	"uint32":      {Type: "integer", Format: "int32"},
+	"uint64":      {Type: "integer", Format: "int64"},
+	"uintptr":     {Type: "integer"},
+	"byte":        {Type: "integer"},
+	"rune":        {Type: "integer", Format: "int32"},
+	"float32":     {Type: "number", Format: "float"},
+	"float64":     {Type: "number", Format: "double"},
+	"error":       {Type: "string"},
+	"interface{}": {},
+	"any":         {},
+}
+
+func (s *openAPISchemas) schema(expr ast.Expr) *openAPISchema {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if _, ok := s.types[t.Name]; ok {
+			return s.named(t.Name)
+		}
+		if b, ok := openAPIBasicTypes[t.Name]; ok {
+			return &b
+		}
+		return &openAPISchema{}
+	case *ast.StarExpr:
+		sc := s.schema(t.X)
+		// sibling properties of the reference are ignored
+		if sc.Ref == "" {
+			sc.Nullable = true
+		}
+		return sc
+	case *ast.ArrayType:
+		if id, ok := t.Elt.(*ast.Ident); ok && id.Name == "byte" && t.Len == nil {
+			// encoding/json encodes byte slices as base64 strings
+			return &openAPISchema{Type: "string", Format: "byte"}
+		}
+		return &openAPISchema{Type: "array", Items: s.schema(t.Elt)}
+	case *ast.MapType:
+		return &openAPISchema{Type: "object", AdditionalProperties: s.schema(t.Value)}
+	case *ast.StructType:
+		return s.structSchema(t)
+	case *ast.SelectorExpr:
+		if x, ok := t.X.(*ast.Ident); ok && x.Name == "time" && t.Sel.Name == "Time" {
+			return &openAPISchema{Type: "string", Format: "date-time"}
+		}
+		// types from other packages are not resolved
+		return &openAPISchema{}
+	case *ast.InterfaceType:
+		return &openAPISchema{}
+	}
+	return &openAPISchema{}
+}
+
+// named returns reference to the component schema of the package type
+func (s *openAPISchemas) named(name string) *openAPISchema {
+	key := s.pkg + "." + name
+	ref := &openAPISchema{Ref: openAPISchemasRef + key}
+	if _, ok := s.components[key]; ok {
+		return ref
+	}
+	// register before building to stop recursion on self referencing types
+	sc := &openAPISchema{}
+	s.components[key] = sc
+	*sc = *s.schema(s.types[name].Type)
+	if sc.Description == "" {
+		sc.Description = s.docs[name]
+	}
+	return ref
+}
+
+func (s *openAPISchemas) structSchema(st *ast.StructType) *openAPISchema {
+	sc := &openAPISchema{
+		Type:       "object",
+		Properties: make(map[string]*openAPISchema),
+	}
+	for _, f := range st.Fields.List {
+		tag := parseJSONTag(f)
+		if tag.skip {
+			continue
+		}
+		if len(f.Names) == 0 && tag.name == "" {
+			s.embed(sc, f.Type)
+			continue
+		}
+		names := []string{tag.name}
+		if tag.name == "" {
+			names = nil
+			for _, n := range f.Names {
+				if n.IsExported() {
+					names = append(names, n.Name)
+				}
+			}
+		} else if len(f.Names) > 0 && !f.Names[0].IsExported() {
+			continue
+		}
+		for _, n := range names {
+			p := s.schema(f.Type)
+			if tag.asString {
+				p = &openAPISchema{Type: "string"}
+			}
+			if d := strings.TrimSpace(f.Doc.Text() + f.Comment.Text()); d != "" && p.Ref == "" {
+				p.Description = d
+			}
+			sc.Properties[n] = p
+			if _, ptr := f.Type.(*ast.StarExpr); !tag.omitEmpty && !ptr {
+				sc.Required = append(sc.Required, n)
+			}
+		}
+	}
+	return sc
+}
+
+// embed adds fields of the embedded struct to the parent struct schema as
+// encoding/json does
+func (s *openAPISchemas) embed(sc *openAPISchema, expr ast.Expr) {
+	if se, ok := expr.(*ast.StarExpr); ok {
+		expr = se.X
+	}
+	id, ok := expr.(*ast.Ident)
+	if !ok {
+		return
+	}
+	ts, ok := s.types[id.Name]
+	if !ok {
+		return
+	}
+	st, ok := ts.Type.(*ast.StructType)
+	if !ok {
+		return
+	}
+	es := s.structSchema(st)
+	for n, p := range es.Properties {
+		if _, ok := sc.Properties[n]; !ok {
+			sc.Properties[n] = p
+		}
+	}
+	sc.Required = append(sc.Required, es.Required...)
+}
+
+type jsonTag struct {
+	name      string
+	skip      bool
+	omitEmpty bool
+	asString  bool
+}
+
+func parseJSONTag(f *ast.Field) jsonTag {
+	var t jsonTag
+	if f.Tag == nil {
+		return t
+	}
+	v, ok := reflect.StructTag(strings.Trim(f.Tag.Value, "`")).Lookup("json")
+	if !ok {
+		return t
+	}
+	if v == "-" {
+		t.skip = true
+		return t
+	}
+	parts := strings.Split(v, ",")
+	t.name = parts[0]
+	for _, o := range parts[1:] {
+		switch o {
+		case "omitempty":
+			t.omitEmpty = true
+		case "string":
+			t.asString = true
+		}
+	}
+	return t
+}
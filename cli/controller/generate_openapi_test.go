@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/mantil-io/mantil/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPISpec(t *testing.T) {
+	spec := openAPISpec{
+		title:   "my-project",
+		server:  "https://api.example.com",
+		stage:   &domain.Stage{},
+		private: map[string]bool{"todo": true},
+	}
+	doc, err := spec.build("testdata/generate/openapi/api")
+	require.NoError(t, err)
+
+	require.Equal(t, "3.0.3", doc.OpenAPI)
+	require.Equal(t, []openAPIServer{{URL: "https://api.example.com"}}, doc.Servers)
+	require.Len(t, doc.Paths, 4)
+
+	ping := doc.Paths["/ping"].Post
+	require.Equal(t, "Default returns pong", ping.Summary)
+	require.Nil(t, ping.RequestBody)
+	require.Equal(t, "string", ping.Responses["200"].Content["text/plain"].Schema.Type)
+	require.Equal(t, openAPIErrorRef, ping.Responses["default"].Ref)
+	require.Nil(t, ping.Security)
+
+	hello := doc.Paths["/ping/hello"].Post
+	require.Equal(t, "string", hello.RequestBody.Content["text/plain"].Schema.Type)
+
+	add := doc.Paths["/todo/add"].Post
+	require.Equal(t, "Add creates new todo item", add.Summary)
+	require.Equal(t, []map[string][]string{{openAPISecurity: {}}}, add.Security)
+	require.Equal(t, openAPISchemasRef+"todo.AddRequest", add.RequestBody.Content["application/json"].Schema.Ref)
+	require.Equal(t, openAPISchemasRef+"todo.AddResponse", add.Responses["200"].Content["application/json"].Schema.Ref)
+
+	clear := doc.Paths["/todo/clear"].Post
+	require.Nil(t, clear.RequestBody)
+	require.Nil(t, clear.Responses["200"].Content)
+	require.Nil(t, doc.Paths["/todo/invalid"])
+	require.NotNil(t, doc.Components.SecuritySchemes[openAPISecurity])
+
+	item := doc.Components.Schemas["todo.Item"]
+	require.Equal(t, "Item is a single todo list entry", item.Description)
+	require.Equal(t, []string{"id", "title", "created"}, item.Required)
+	require.Len(t, item.Properties, 8)
+	require.Equal(t, "string", item.Properties["id"].Type)
+	require.True(t, item.Properties["due"].Nullable)
+	require.Equal(t, "date-time", item.Properties["created"].Format)
+	require.Equal(t, "string", item.Properties["tags"].Items.Type)
+	require.Equal(t, openAPISchemasRef+"todo.Item", item.Properties["children"].Items.Ref)
+	require.Equal(t, "string", item.Properties["meta"].AdditionalProperties.Type)
+
+	req := doc.Components.Schemas["todo.AddRequest"]
+	require.Len(t, req.Properties, 9)
+	require.Equal(t, openAPISchemasRef+"todo.Audit", req.Properties["audit"].Ref)
+
+	rsp := doc.Components.Schemas["todo.AddResponse"]
+	require.Equal(t, openAPISchemasRef+"todo.Item", rsp.Properties["item"].Ref)
+	require.Empty(t, rsp.Required)
+}
+
+func TestOpenAPISpecRoutes(t *testing.T) {
+	spec := openAPISpec{
+		title: "my-project",
+		stage: &domain.Stage{},
+	}
+	doc, err := spec.build("testdata/generate/routes/api")
+	require.NoError(t, err)
+
+	item := doc.Paths["/todo/{id}"]
+	require.NotNil(t, item.Get)
+	require.NotNil(t, item.Delete)
+	require.Nil(t, item.Post)
+	require.Nil(t, item.Get.RequestBody)
+	require.Equal(t, []openAPIParameter{{Name: "id", In: "path", Required: true, Schema: &openAPISchema{Ref: openAPISchemasRef + "todo.ID"}}}, item.Get.Parameters)
+
+	update := doc.Paths["/todo/{id}/{version}"].Put
+	require.NotNil(t, update.RequestBody)
+	require.Len(t, update.Parameters, 2)
+	require.Equal(t, "integer", update.Parameters[1].Schema.Type)
+	require.Equal(t, []map[string][]string{{openAPISecurity: {}}}, update.Security)
+
+	require.NotNil(t, doc.Paths["/todo"].Post)
+	require.NotNil(t, doc.Paths["/todo/list"].Post)
+}
@@ -0,0 +1,364 @@
+package controller
+
+import (
+	"fmt"
+	"go/ast"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mantil-io/mantil/cli/log"
+	"github.com/mantil-io/mantil/domain"
+)
+
+const directivePrefix = "//mantil:"
+
+// sourceMethods returns configuration of the api methods from the comment
+// directives:
+//
+//	//mantil:private
+//	//mantil:public
+//	//mantil:route GET /{id}
+func (ap *apiPackage) sourceMethods() (map[string]domain.MethodConfiguration, error) {
+	methods := make(map[string]domain.MethodConfiguration)
+	for _, m := range apiMethods(ap.pkg, ap.typeName) {
+		if len(m.directives) == 0 {
+			continue
+		}
+		mc, err := parseDirectives(m.directives)
+		if err != nil {
+			return nil, log.Wrapf("method %s - %v", m.name, err)
+		}
+		methods[strings.ToLower(m.name)] = mc
+	}
+	if len(methods) == 0 {
+		return nil, nil
+	}
+	return methods, nil
+}
+
+func parseDirectives(directives []string) (domain.MethodConfiguration, error) {
+	var mc domain.MethodConfiguration
+	for _, d := range directives {
+		fields := strings.Fields(strings.TrimPrefix(d, directivePrefix))
+		if len(fields) == 0 {
+			return mc, fmt.Errorf("empty directive")
+		}
+		switch name, args := fields[0], fields[1:]; {
+		case name == "private" && len(args) == 0:
+			private := true
+			mc.Private = &private
+		case name == "public" && len(args) == 0:
+			private := false
+			mc.Private = &private
+		case name == "route" && (len(args) == 1 || len(args) == 2):
+			mc.HTTPMethod = strings.ToUpper(args[0])
+			if len(args) == 2 {
+				mc.Path = args[1]
+			}
+		default:
+			return mc, fmt.Errorf("invalid directive %s", d)
+		}
+	}
+	if err := mc.Validate(); err != nil {
+		return mc, err
+	}
+	return mc, nil
+}
+
+// handlerRoute is the http api route of the api method, requests of the
+// method routes are dispatched by the generated handler
+type handlerRoute struct {
+	Key string
+	// handler method which serves the route
+	Method string
+	// request type, empty if the method doesn't have request
+	Req     string
+	ReqPtr  bool
+	ReqElem string
+	// string requests are passed unchanged
+	RawReq bool
+	// statements which bind path parameters into request fields
+	Params []string
+	// method returns response value
+	Rsp bool
+}
+
+// routes sets routes of the api methods to the handler, methods can be
+// reached only through their routes
+func (h *apiHandler) routes(ap *apiPackage, routes []domain.FunctionRoute) error {
+	if len(routes) == 0 {
+		return nil
+	}
+	// router takes place of the Invoke method, which is first of the default
+	// methods, api Invoke method is called by the router
+	if hm := h.method("Invoke"); hm != nil {
+		hm.Func = "invoke"
+	}
+	schemas := newOpenAPISchemas(ap.pkg, make(map[string]*openAPISchema))
+	methods := make(map[string]apiMethod)
+	for _, m := range apiMethods(ap.pkg, ap.typeName) {
+		methods[strings.ToLower(m.name)] = m
+	}
+	keys := make(map[string]string)
+	for _, r := range routes {
+		m, ok := methods[r.Method]
+		if !ok {
+			return log.Wrapf("method %s configured in routes not found", r.Method)
+		}
+		if other, ok := keys[r.Key()]; ok {
+			return log.Wrapf("methods %s and %s have the same route %s", other, m.name, r.Key())
+		}
+		keys[r.Key()] = m.name
+		hm := h.method(m.name)
+		hm.Route = r.Key()
+		hr := handlerRoute{
+			Key:     r.Key(),
+			Method:  hm.Func,
+			Req:     hm.Req,
+			ReqPtr:  hm.ReqPtr,
+			ReqElem: strings.TrimPrefix(hm.Req, "*"),
+			RawReq:  m.req != nil && isStringType(m.req),
+			Rsp:     hm.Rsp != "",
+		}
+		for _, p := range r.PathParams() {
+			bind, err := bindPathParam(p, m.req, ap.pkg.Name, schemas.types)
+			if err != nil {
+				return log.Wrapf("method %s - %v", m.name, err)
+			}
+			hr.Params = append(hr.Params, bind)
+		}
+		h.Routes = append(h.Routes, hr)
+	}
+	sort.Slice(h.Routes, func(i, j int) bool { return h.Routes[i].Key < h.Routes[j].Key })
+	// api default method is served by the router when none of the routes match
+	for _, name := range []string{"Invoke", "Root", "Default"} {
+		if hm := h.method(name); hm != nil {
+			h.Default = &handlerRoute{
+				Method: hm.Func,
+				Req:    hm.Req,
+				ReqPtr: hm.ReqPtr,
+				RawReq: hm.Req == "string",
+				Rsp:    hm.Rsp != "",
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// PathParams is true if any of the routes has path parameters
+func (h *apiHandler) PathParams() bool {
+	for _, r := range h.Routes {
+		if len(r.Params) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *apiHandler) method(name string) *handlerMethod {
+	for i := range h.Methods {
+		if h.Methods[i].Name == name {
+			return &h.Methods[i]
+		}
+	}
+	return nil
+}
+
+var (
+	intTypes   = map[string]int{"int": 64, "int8": 8, "int16": 16, "int32": 32, "int64": 64, "rune": 32}
+	uintTypes  = map[string]int{"uint": 64, "uint8": 8, "uint16": 16, "uint32": 32, "uint64": 64, "byte": 8}
+	floatTypes = map[string]int{"float32": 32, "float64": 64}
+)
+
+// bindPathParam returns statement which sets path parameter to the request
+// field with the same path tag or json name
+func bindPathParam(param string, req ast.Expr, pkg string, types map[string]*ast.TypeSpec) (string, error) {
+	if se, ok := req.(*ast.StarExpr); ok {
+		req = se.X
+	}
+	f, name := findPathParamField(param, req, types)
+	if f == nil {
+		return "", fmt.Errorf("path parameter %s doesn't match any request field", param)
+	}
+	return bindPathParamField(param, "req."+name, f.Type, pkg, types)
+}
+
+// findPathParamField finds field for the path parameter in the api package
+// struct type, fields of the embedded structs are also searched
+func findPathParamField(param string, expr ast.Expr, types map[string]*ast.TypeSpec) (*ast.Field, string) {
+	id, ok := expr.(*ast.Ident)
+	if !ok {
+		return nil, ""
+	}
+	ts, ok := types[id.Name]
+	if !ok {
+		return nil, ""
+	}
+	st, ok := ts.Type.(*ast.StructType)
+	if !ok {
+		return nil, ""
+	}
+	for _, f := range st.Fields.List {
+		if len(f.Names) == 0 {
+			if ef, name := findPathParamField(param, f.Type, types); ef != nil {
+				return ef, name
+			}
+			continue
+		}
+		for _, n := range f.Names {
+			if n.IsExported() && isPathParamField(f, n.Name, param) {
+				return f, n.Name
+			}
+		}
+	}
+	return nil, ""
+}
+
+func isPathParamField(f *ast.Field, name, param string) bool {
+	if f.Tag != nil {
+		if p, ok := reflect.StructTag(strings.Trim(f.Tag.Value, "`")).Lookup("path"); ok {
+			return p == param
+		}
+	}
+	if tag := parseJSONTag(f); tag.name != "" {
+		return tag.name == param
+	}
+	return strings.EqualFold(name, param)
+}
+
+func bindPathParamField(param, field string, expr ast.Expr, pkg string, types map[string]*ast.TypeSpec) (string, error) {
+	typ := printExpr(expr)
+	// resolve types declared in the api package to the underlying type
+	basic := expr
+	for {
+		id, ok := basic.(*ast.Ident)
+		if !ok {
+			return "", fmt.Errorf("path parameter %s can't be bound to field of type %s", param, typ)
+		}
+		ts, ok := types[id.Name]
+		if !ok {
+			break
+		}
+		basic = ts.Type
+	}
+	if _, ok := types[typ]; ok {
+		typ = pkg + "." + typ
+	}
+	value := fmt.Sprintf("params[%q]", param)
+	parse := func(fn string) string {
+		return fmt.Sprintf(`{
+	v, err := %s
+	if err != nil {
+		return nil, invalidPathParam(%q, err)
+	}
+	%s = %s(v)
+}`, fn, param, field, typ)
+	}
+	name := basic.(*ast.Ident).Name
+	switch {
+	case name == "string":
+		return fmt.Sprintf("%s = %s(%s)", field, typ, value), nil
+	case name == "bool":
+		return parse(fmt.Sprintf("strconv.ParseBool(%s)", value)), nil
+	case intTypes[name] > 0:
+		return parse(fmt.Sprintf("strconv.ParseInt(%s, 10, %d)", value, intTypes[name])), nil
+	case uintTypes[name] > 0:
+		return parse(fmt.Sprintf("strconv.ParseUint(%s, 10, %d)", value, uintTypes[name])), nil
+	case floatTypes[name] > 0:
+		return parse(fmt.Sprintf("strconv.ParseFloat(%s, %d)", value, floatTypes[name])), nil
+	}
+	return "", fmt.Errorf("path parameter %s can't be bound to field of type %s", param, printExpr(expr))
+}
+
+// methodRoutes resolves http routes of the api methods for the stage,
+// methods without their own route are called with POST on the method path
+type methodRoutes struct {
+	api     string
+	private bool
+	routes  map[string]domain.FunctionRoute
+	types   map[string]*ast.TypeSpec
+}
+
+func newMethodRoutes(stage *domain.Stage, ap *apiPackage, private bool) (*methodRoutes, error) {
+	methods, err := ap.sourceMethods()
+	if err != nil {
+		return nil, log.Wrap(err, "invalid api %s", ap.pkg.Name)
+	}
+	mr := &methodRoutes{
+		api:     ap.pkg.Name,
+		private: private,
+		routes:  make(map[string]domain.FunctionRoute),
+		types:   newOpenAPISchemas(ap.pkg, make(map[string]*openAPISchema)).types,
+	}
+	for _, r := range stage.FunctionRoutes(mr.api, methods) {
+		mr.routes[r.Method] = r
+	}
+	return mr, nil
+}
+
+func (mr *methodRoutes) route(method string) domain.FunctionRoute {
+	r, ok := mr.routes[strings.ToLower(method)]
+	if !ok {
+		return domain.FunctionRoute{
+			Method:     strings.ToLower(method),
+			HTTPMethod: http.MethodPost,
+			Path:       domain.MethodPath(mr.api, method),
+			Private:    mr.private,
+		}
+	}
+	if r.HTTPMethod == "ANY" {
+		r.HTTPMethod = http.MethodPost
+	}
+	return r
+}
+
+// pathParamField is the request field bound to the path parameter
+type pathParamField struct {
+	*ast.Field
+	Name string
+}
+
+// pathParamFields returns request fields bound to the route path parameters
+func (mr *methodRoutes) pathParamFields(r domain.FunctionRoute, req ast.Expr) (map[string]pathParamField, error) {
+	if se, ok := req.(*ast.StarExpr); ok {
+		req = se.X
+	}
+	fields := make(map[string]pathParamField)
+	for _, p := range r.PathParams() {
+		f, name := findPathParamField(p, req, mr.types)
+		if f == nil {
+			return nil, log.Wrapf("path parameter %s of api %s doesn't match any request field", p, mr.api)
+		}
+		fields[p] = pathParamField{Field: f, Name: name}
+	}
+	return fields, nil
+}
+
+// routePathExpr builds expression of the route path, param returns
+// expression of the path parameter value
+func routePathExpr(path string, param func(string) string) []string {
+	var parts []string
+	literal := ""
+	for _, s := range strings.Split(strings.TrimPrefix(path, "/"), "/") {
+		literal += "/"
+		if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+			parts = append(parts, strconv.Quote(literal), param(s[1:len(s)-1]))
+			literal = ""
+			continue
+		}
+		literal += s
+	}
+	if literal != "" {
+		parts = append(parts, strconv.Quote(literal))
+	}
+	return parts
+}
+
+// hasBody is false for http methods which requests are sent without body
+func hasBody(httpMethod string) bool {
+	return httpMethod != http.MethodGet && httpMethod != http.MethodHead
+}
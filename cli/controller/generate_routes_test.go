@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/mantil-io/mantil/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func boolPtr(v bool) *bool {
+	return &v
+}
+
+func TestSourceMethods(t *testing.T) {
+	ap, err := parseApi("todo", "testdata/generate/routes/api/todo")
+	require.NoError(t, err)
+	methods, err := ap.sourceMethods()
+	require.NoError(t, err)
+	require.Equal(t, map[string]domain.MethodConfiguration{
+		"get":    {HTTPMethod: "GET", Path: "/{id}"},
+		"update": {HTTPMethod: "PUT", Path: "/{id}/{version}", Private: boolPtr(true)},
+		"list":   {Private: boolPtr(false)},
+		"delete": {HTTPMethod: "DELETE", Path: "/{id}"},
+	}, methods)
+}
+
+func TestParseDirectives(t *testing.T) {
+	mc, err := parseDirectives([]string{"//mantil:route post"})
+	require.NoError(t, err)
+	require.Equal(t, domain.MethodConfiguration{HTTPMethod: "POST"}, mc)
+
+	_, err = parseDirectives([]string{"//mantil:private now"})
+	require.Error(t, err)
+	_, err = parseDirectives([]string{"//mantil:route FETCH /"})
+	require.Error(t, err)
+	_, err = parseDirectives([]string{"//mantil:route GET id"})
+	require.Error(t, err)
+}
+
+func TestApiHandlerRoutes(t *testing.T) {
+	ap, err := parseApi("todo", "testdata/generate/routes/api/todo")
+	require.NoError(t, err)
+	methods, err := ap.sourceMethods()
+	require.NoError(t, err)
+	routes := (&domain.Stage{}).FunctionRoutes("todo", methods)
+
+	h, err := newApiHandler(ap, routes, false)
+	require.NoError(t, err)
+	require.NotNil(t, h)
+
+	var keys []string
+	for _, r := range h.Routes {
+		keys = append(keys, r.Key)
+	}
+	require.Equal(t, []string{"ANY /todo/list", "DELETE /todo/{id}", "GET /todo/{id}", "PUT /todo/{id}/{version}"}, keys)
+	require.True(t, h.PathParams())
+	require.Equal(t, "Default", h.Default.Method)
+
+	get := h.Routes[2]
+	require.Equal(t, "Get", get.Method)
+	require.True(t, get.ReqPtr)
+	require.Equal(t, "todo.GetRequest", get.ReqElem)
+	require.Equal(t, []string{`{
+	v, err := strconv.ParseInt(params["id"], 10, 64)
+	if err != nil {
+		return nil, invalidPathParam("id", err)
+	}
+	req.ID = todo.ID(v)
+}`}, get.Params)
+	require.Equal(t, `{
+	v, err := strconv.ParseUint(params["version"], 10, 32)
+	if err != nil {
+		return nil, invalidPathParam("version", err)
+	}
+	req.Version = uint32(v)
+}`, h.Routes[3].Params[1])
+	require.Equal(t, "GET /todo/{id}", h.method("Get").Route)
+	require.Empty(t, h.method("Default").Route)
+
+	out, err := renderTemplate(apiFunctionMainTemplate, &function{
+		Name:       "todo",
+		ImportPath: "example.com/project/api",
+		Handler:    h,
+	})
+	require.NoError(t, err)
+	_, err = formatAndAdjustImports(string(out))
+	require.NoError(t, err)
+}
+
+func TestApiHandlerRoutesErrors(t *testing.T) {
+	ap, err := parseApi("todo", "testdata/generate/routes/api/todo")
+	require.NoError(t, err)
+
+	_, err = newApiHandler(ap, []domain.FunctionRoute{{Method: "missing", HTTPMethod: "GET", Path: "/todo/missing"}}, false)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "method missing configured in routes not found")
+
+	_, err = newApiHandler(ap, []domain.FunctionRoute{{Method: "get", HTTPMethod: "GET", Path: "/todo/{key}"}}, false)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "path parameter key doesn't match any request field")
+
+	_, err = newApiHandler(ap, []domain.FunctionRoute{
+		{Method: "get", HTTPMethod: "GET", Path: "/todo/{id}"},
+		{Method: "delete", HTTPMethod: "GET", Path: "/todo/{id}"},
+	}, false)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "have the same route GET /todo/{id}")
+}
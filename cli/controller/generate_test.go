@@ -1,7 +1,16 @@
 package controller
 
 import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -45,3 +54,134 @@ func TestIsNewValidMethod(t *testing.T) {
 	err := isNewValid("ping", "testdata/generate/new_method")
 	require.Error(t, err)
 }
+
+func TestIsNewValidReturnsError(t *testing.T) {
+	err := isNewValid("ping", "testdata/generate/new_error")
+	require.NoError(t, err)
+}
+
+func TestIsNewValidSecondReturnValueNotError(t *testing.T) {
+	err := isNewValid("ping", "testdata/generate/return_not_error")
+	require.Error(t, err)
+}
+
+func TestParseApiNewWithContext(t *testing.T) {
+	ap, err := parseApi("ping", "testdata/generate/new_context")
+	require.NoError(t, err)
+	require.Equal(t, "Ping", ap.typeName)
+	require.True(t, ap.newContext)
+	require.True(t, ap.newError)
+	require.False(t, ap.close)
+	require.False(t, ap.shutdown)
+}
+
+func TestParseApiLifecycleHooks(t *testing.T) {
+	ap, err := parseApi("ping", "testdata/generate/hooks")
+	require.NoError(t, err)
+	require.False(t, ap.newContext)
+	require.False(t, ap.newError)
+	require.True(t, ap.close)
+	require.True(t, ap.closeError)
+	require.True(t, ap.shutdown)
+	require.False(t, ap.shutdownError)
+	require.Empty(t, apiMethods(ap.pkg, ap.typeName))
+}
+
+func TestRenderMain(t *testing.T) {
+	out, err := renderTemplate(apiFunctionMainTemplate, &function{
+		Name:       "ping",
+		ImportPath: "example.com/project/api",
+		NewContext: true,
+		NewError:   true,
+		Close:      true,
+		CloseError: true,
+		Shutdown:   true,
+	})
+	require.NoError(t, err)
+	out, err = formatAndAdjustImports(string(out))
+	require.NoError(t, err)
+	require.Contains(t, string(out), "api, err := ping.New(context.Background())")
+	require.Contains(t, string(out), "if err := api.Close(); err != nil {")
+	require.Contains(t, string(out), "\t\tapi.Shutdown(ctx)\n")
+	require.Contains(t, string(out), "signal.Notify(ch, syscall.SIGTERM, syscall.SIGINT)")
+	require.Contains(t, string(out), "/2020-01-01/extension/register")
+
+	out, err = renderTemplate(apiFunctionMainTemplate, &function{
+		Name:       "ping",
+		ImportPath: "example.com/project/api",
+	})
+	require.NoError(t, err)
+	out, err = formatAndAdjustImports(string(out))
+	require.NoError(t, err)
+	require.Contains(t, string(out), "var api = ping.New()")
+	require.NotContains(t, string(out), "onShutdown")
+	require.NotContains(t, string(out), `"os/signal"`)
+}
+
+// TestMainShutdownHook builds function with the generated main and runs it
+// against fake lambda runtime api to check that the shutdown hook is called
+// on SIGTERM. Function is built with active tracing so the generated handler
+// wrapper which echoes trace id is compiled too.
+func TestMainShutdownHook(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds lambda function")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not found")
+	}
+	dir := filepath.Join(t.TempDir(), "project")
+	apiDir := filepath.Join(dir, "api", "ping")
+	require.NoError(t, copyTemplateDir("testdata/generate/shutdown", apiDir))
+	gomod := "module example.com/project\n\ngo 1.16\n\nrequire github.com/mantil-io/mantil.go v0.1.13\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte(gomod), 0644))
+	gosum, err := ioutil.ReadFile("../../go.sum")
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "go.sum"), gosum, 0644))
+
+	ap, err := parseApi("ping", apiDir)
+	require.NoError(t, err)
+	require.NoError(t, generateMain(ap, "example.com/project/api", nil, true, filepath.Join(dir, "functions", "ping", "main.go")))
+	cmd := exec.Command("go", "build", "-o", "bootstrap", "./functions/ping")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod")
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, "%s", out)
+
+	// runtime api which accepts extension registration and blocks on next
+	// event and next invocation, as runtime does while function is idle
+	registered := make(chan struct{})
+	done := make(chan struct{})
+	defer close(done)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/2020-01-01/extension/register":
+			w.Header().Set("Lambda-Extension-Identifier", "id")
+			close(registered)
+		case strings.HasSuffix(r.URL.Path, "/next"):
+			select {
+			case <-r.Context().Done():
+			case <-done:
+			}
+		}
+	}))
+	defer srv.Close()
+
+	hookFile := filepath.Join(dir, "shutdown")
+	fn := exec.Command(filepath.Join(dir, "bootstrap"))
+	fn.Env = append(os.Environ(),
+		"AWS_LAMBDA_RUNTIME_API="+strings.TrimPrefix(srv.URL, "http://"),
+		"PING_SHUTDOWN_FILE="+hookFile,
+	)
+	require.NoError(t, fn.Start())
+	select {
+	case <-registered:
+	case <-time.After(10 * time.Second):
+		fn.Process.Kill()
+		t.Fatal("extension not registered")
+	}
+	require.NoError(t, fn.Process.Signal(syscall.SIGTERM))
+	require.NoError(t, fn.Wait())
+	buf, err := ioutil.ReadFile(hookFile)
+	require.NoError(t, err)
+	require.Equal(t, "shutdown", string(buf))
+}
@@ -0,0 +1,343 @@
+package controller
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/mantil-io/mantil/cli/log"
+	"github.com/mantil-io/mantil/cli/ui"
+	"github.com/mantil-io/mantil/domain"
+	"github.com/mantil-io/mantil/kit/aws"
+)
+
+const (
+	// number of datapoints in the invocations trend
+	metricsTrendPoints = 30
+	sparklineTicks     = "▁▂▃▄▅▆▇█"
+)
+
+type MetricsArgs struct {
+	Stage    string
+	Function string
+	Since    time.Duration
+	JSON     bool
+}
+
+type metricsReport struct {
+	Stage     string            `json:"stage"`
+	Start     time.Time         `json:"start"`
+	End       time.Time         `json:"end"`
+	Period    int               `json:"period_seconds"`
+	Functions []functionMetrics `json:"functions"`
+	Api       *apiMetrics       `json:"api,omitempty"`
+}
+
+type functionMetrics struct {
+	Name                 string             `json:"name"`
+	Invocations          float64            `json:"invocations"`
+	Errors               float64            `json:"errors"`
+	Throttles            float64            `json:"throttles"`
+	Duration             latencyPercentiles `json:"duration_ms"`
+	ConcurrentExecutions float64            `json:"concurrent_executions"`
+	Trend                []float64          `json:"invocations_trend"`
+}
+
+type apiMetrics struct {
+	ID        string             `json:"id"`
+	Requests  float64            `json:"requests"`
+	Errors4xx float64            `json:"errors_4xx"`
+	Errors5xx float64            `json:"errors_5xx"`
+	Latency   latencyPercentiles `json:"latency_ms"`
+	Trend     []float64          `json:"requests_trend"`
+}
+
+type latencyPercentiles struct {
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P99 float64 `json:"p99"`
+}
+
+func Metrics(a MetricsArgs) error {
+	_, stage, err := newStoreWithStage(a.Stage)
+	if err != nil {
+		return log.Wrap(err)
+	}
+	functions := stage.Functions
+	if a.Function != "" {
+		fn := stage.FindFunction(a.Function)
+		if fn == nil {
+			return log.Wrapf("function %s not found", a.Function)
+		}
+		functions = []*domain.Function{fn}
+	}
+	if len(functions) == 0 {
+		return log.Wrapf("there are no functions in stage %s", stage.Name)
+	}
+	awsClient, err := awsClient(stage.Node(), stage)
+	if err != nil {
+		return log.Wrap(err)
+	}
+	q := newMetricsQuery(stage, functions, a.Since, time.Now())
+	// api metrics are for all functions so they are shown only for the whole stage
+	if a.Function == "" {
+		q.apiID = stage.RestApiID()
+	}
+	series, err := awsClient.GetMetrics(q.queries(), q.start, q.end)
+	if err != nil {
+		return log.Wrap(err, "could not get metrics for stage %s", stage.Name)
+	}
+	r := q.report(series)
+	if a.JSON {
+		return printJSON(r)
+	}
+	showMetrics(r)
+	return nil
+}
+
+type metricsQuery struct {
+	stage     string
+	functions []*domain.Function
+	apiID     string
+	start     time.Time
+	end       time.Time
+	// period of the trend datapoints
+	period time.Duration
+	// period of the summary values, whole time range
+	total time.Duration
+}
+
+func newMetricsQuery(stage *domain.Stage, functions []*domain.Function, since time.Duration, now time.Time) *metricsQuery {
+	period := metricsPeriod(since / metricsTrendPoints)
+	end := now.Truncate(time.Minute)
+	start := end.Add(-period * metricsTrendPoints)
+	return &metricsQuery{
+		stage:     stage.Name,
+		functions: functions,
+		start:     start,
+		end:       end,
+		period:    period,
+		total:     period * metricsTrendPoints,
+	}
+}
+
+// metricsPeriod rounds d up to the period accepted by CloudWatch, older
+// datapoints are available only in the 5 minute and 1 hour resolution
+func metricsPeriod(d time.Duration) time.Duration {
+	unit := time.Minute
+	if d > time.Hour {
+		unit = time.Hour
+	} else if d > 5*time.Minute {
+		unit = 5 * time.Minute
+	}
+	m := time.Duration(math.Ceil(float64(d) / float64(unit)))
+	if m < 1 {
+		m = 1
+	}
+	return m * unit
+}
+
+func (q *metricsQuery) queries() []aws.MetricQuery {
+	var queries []aws.MetricQuery
+	for i, f := range q.functions {
+		dims := map[string]string{"FunctionName": f.LambdaName()}
+		add := func(name, stat string, period time.Duration) {
+			queries = append(queries, aws.MetricQuery{
+				ID:         functionQueryID(i, name, stat, period == q.period),
+				Namespace:  aws.LambdaMetricsNamespace,
+				Name:       name,
+				Dimensions: dims,
+				Stat:       stat,
+				Period:     period,
+			})
+		}
+		add("Invocations", "Sum", q.total)
+		add("Invocations", "Sum", q.period)
+		add("Errors", "Sum", q.total)
+		add("Throttles", "Sum", q.total)
+		add("Duration", "p50", q.total)
+		add("Duration", "p90", q.total)
+		add("Duration", "p99", q.total)
+		add("ConcurrentExecutions", "Maximum", q.total)
+	}
+	if q.apiID == "" {
+		return queries
+	}
+	dims := map[string]string{"ApiId": q.apiID}
+	add := func(name, stat string, period time.Duration) {
+		queries = append(queries, aws.MetricQuery{
+			ID:         apiQueryID(name, stat, period == q.period),
+			Namespace:  aws.ApiGatewayMetricsNamespace,
+			Name:       name,
+			Dimensions: dims,
+			Stat:       stat,
+			Period:     period,
+		})
+	}
+	add("Count", "Sum", q.total)
+	add("Count", "Sum", q.period)
+	add("4xx", "Sum", q.total)
+	add("5xx", "Sum", q.total)
+	add("Latency", "p50", q.total)
+	add("Latency", "p90", q.total)
+	add("Latency", "p99", q.total)
+	return queries
+}
+
+func functionQueryID(idx int, name, stat string, trend bool) string {
+	return fmt.Sprintf("f%d_%s", idx, metricQueryID(name, stat, trend))
+}
+
+func apiQueryID(name, stat string, trend bool) string {
+	return fmt.Sprintf("api_%s", metricQueryID(name, stat, trend))
+}
+
+func metricQueryID(name, stat string, trend bool) string {
+	id := fmt.Sprintf("%s_%s", strings.ToLower(name), strings.ToLower(stat))
+	if trend {
+		id += "_trend"
+	}
+	return id
+}
+
+func (q *metricsQuery) report(series map[string]aws.MetricSeries) *metricsReport {
+	r := &metricsReport{
+		Stage:  q.stage,
+		Start:  q.start,
+		End:    q.end,
+		Period: int(q.period.Seconds()),
+	}
+	for i, f := range q.functions {
+		value := func(name, stat string) float64 {
+			return summaryValue(series[functionQueryID(i, name, stat, false)], stat)
+		}
+		r.Functions = append(r.Functions, functionMetrics{
+			Name:        f.Name,
+			Invocations: value("Invocations", "Sum"),
+			Errors:      value("Errors", "Sum"),
+			Throttles:   value("Throttles", "Sum"),
+			Duration: latencyPercentiles{
+				P50: value("Duration", "p50"),
+				P90: value("Duration", "p90"),
+				P99: value("Duration", "p99"),
+			},
+			ConcurrentExecutions: value("ConcurrentExecutions", "Maximum"),
+			Trend:                q.trend(series[functionQueryID(i, "Invocations", "Sum", true)]),
+		})
+	}
+	if q.apiID == "" {
+		return r
+	}
+	value := func(name, stat string) float64 {
+		return summaryValue(series[apiQueryID(name, stat, false)], stat)
+	}
+	r.Api = &apiMetrics{
+		ID:        q.apiID,
+		Requests:  value("Count", "Sum"),
+		Errors4xx: value("4xx", "Sum"),
+		Errors5xx: value("5xx", "Sum"),
+		Latency: latencyPercentiles{
+			P50: value("Latency", "p50"),
+			P90: value("Latency", "p90"),
+			P99: value("Latency", "p99"),
+		},
+		Trend: q.trend(series[apiQueryID("Count", "Sum", true)]),
+	}
+	return r
+}
+
+// summaryValue merges datapoints of the whole time range query, there can be
+// more than one if the range is not aligned with the CloudWatch periods.
+// Percentiles can't be merged so the highest one is used.
+func summaryValue(s aws.MetricSeries, stat string) float64 {
+	var v float64
+	for _, val := range s.Values {
+		if stat == "Sum" {
+			v += val
+			continue
+		}
+		v = math.Max(v, val)
+	}
+	return v
+}
+
+// trend places datapoints into the fixed number of buckets, CloudWatch
+// doesn't return datapoints for periods without data
+func (q *metricsQuery) trend(s aws.MetricSeries) []float64 {
+	buckets := make([]float64, metricsTrendPoints)
+	for i, ts := range s.Timestamps {
+		idx := int(ts.Sub(q.start) / q.period)
+		if idx < 0 {
+			idx = 0
+		}
+		if idx >= len(buckets) {
+			idx = len(buckets) - 1
+		}
+		buckets[idx] += s.Values[i]
+	}
+	return buckets
+}
+
+func sparkline(values []float64) string {
+	ticks := []rune(sparklineTicks)
+	var max float64
+	for _, v := range values {
+		max = math.Max(max, v)
+	}
+	var sb strings.Builder
+	for _, v := range values {
+		idx := 0
+		if max > 0 {
+			idx = int(math.Round(v / max * float64(len(ticks)-1)))
+		}
+		sb.WriteRune(ticks[idx])
+	}
+	return sb.String()
+}
+
+func showMetrics(r *metricsReport) {
+	ui.Info("Stage %s metrics from %s to %s", r.Stage, r.Start.Format(time.Stamp), r.End.Format(time.Stamp))
+	ui.Info("")
+	var data [][]string
+	for _, f := range r.Functions {
+		data = append(data, []string{
+			f.Name,
+			formatCount(f.Invocations),
+			formatCount(f.Errors),
+			formatCount(f.Throttles),
+			formatLatency(f.Duration.P50, f.Invocations),
+			formatLatency(f.Duration.P90, f.Invocations),
+			formatLatency(f.Duration.P99, f.Invocations),
+			formatCount(f.ConcurrentExecutions),
+			sparkline(f.Trend),
+		})
+	}
+	ShowTable([]string{"function", "invocations", "errors", "throttles", "p50", "p90", "p99", "concurrency", "invocations trend"}, data)
+	if r.Api == nil {
+		return
+	}
+	ui.Info("")
+	ShowTable([]string{"api", "requests", "4xx", "5xx", "p50", "p90", "p99", "requests trend"}, [][]string{{
+		r.Api.ID,
+		formatCount(r.Api.Requests),
+		formatCount(r.Api.Errors4xx),
+		formatCount(r.Api.Errors5xx),
+		formatLatency(r.Api.Latency.P50, r.Api.Requests),
+		formatLatency(r.Api.Latency.P90, r.Api.Requests),
+		formatLatency(r.Api.Latency.P99, r.Api.Requests),
+		sparkline(r.Api.Trend),
+	}})
+}
+
+func formatCount(v float64) string {
+	return fmt.Sprintf("%.0f", v)
+}
+
+// latency is shown only if there were requests
+func formatLatency(ms, count float64) string {
+	if count == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0fms", ms)
+}
@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mantil-io/mantil/kit/aws"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsPeriod(t *testing.T) {
+	cases := []struct {
+		in  time.Duration
+		out time.Duration
+	}{
+		{time.Second, time.Minute},
+		{time.Minute, time.Minute},
+		{2*time.Minute + time.Second, 3 * time.Minute},
+		{6 * time.Minute, 10 * time.Minute},
+		{90 * time.Minute, 2 * time.Hour},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.out, metricsPeriod(c.in), c.in.String())
+	}
+}
+
+func TestMetricsQueryApi(t *testing.T) {
+	end := time.Date(2021, 11, 1, 12, 0, 0, 0, time.UTC)
+	q := &metricsQuery{
+		stage:  "dev",
+		apiID:  "a1b2c3d4e5",
+		start:  end.Add(-30 * time.Minute),
+		end:    end,
+		period: time.Minute,
+		total:  30 * time.Minute,
+	}
+	queries := q.queries()
+	require.Len(t, queries, 7)
+	ids := make(map[string]bool)
+	for _, mq := range queries {
+		require.Equal(t, aws.ApiGatewayMetricsNamespace, mq.Namespace)
+		require.Equal(t, "a1b2c3d4e5", mq.Dimensions["ApiId"])
+		ids[mq.ID] = true
+	}
+	require.Len(t, ids, 7)
+	require.True(t, ids["api_count_sum_trend"])
+	require.True(t, ids["api_latency_p99"])
+
+	series := map[string]aws.MetricSeries{
+		"api_count_sum": {Values: []float64{10, 5}},
+		"api_count_sum_trend": {
+			Timestamps: []time.Time{q.start, q.start.Add(time.Minute), end.Add(-time.Minute)},
+			Values:     []float64{3, 4, 8},
+		},
+		"api_5xx_sum":     {Values: []float64{1}},
+		"api_latency_p99": {Values: []float64{120, 180}},
+	}
+	r := q.report(series)
+	require.Empty(t, r.Functions)
+	require.NotNil(t, r.Api)
+	require.Equal(t, float64(15), r.Api.Requests)
+	require.Equal(t, float64(0), r.Api.Errors4xx)
+	require.Equal(t, float64(1), r.Api.Errors5xx)
+	require.Equal(t, float64(180), r.Api.Latency.P99)
+	require.Len(t, r.Api.Trend, metricsTrendPoints)
+	require.Equal(t, float64(3), r.Api.Trend[0])
+	require.Equal(t, float64(4), r.Api.Trend[1])
+	require.Equal(t, float64(8), r.Api.Trend[metricsTrendPoints-1])
+}
+
+func TestSparkline(t *testing.T) {
+	require.Equal(t, "▁▁▁", sparkline([]float64{0, 0, 0}))
+	require.Equal(t, "▁▅█", sparkline([]float64{0, 5, 10}))
+}
@@ -0,0 +1,208 @@
+package controller
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mantil-io/mantil/cli/log"
+	"github.com/mantil-io/mantil/domain"
+	"golang.org/x/mod/modfile"
+)
+
+const (
+	goModFile  = "go.mod"
+	goWorkFile = "go.work"
+)
+
+// findPackageImportPath returns import path of the package in dir. Module
+// containing the package is found by walking up from the dir so the project
+// doesn't have to be in the module root, multiple projects can share one module.
+func findPackageImportPath(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", log.Wrap(err)
+	}
+	moduleRoot, modulePath, err := findModule(dir)
+	if err != nil {
+		return "", log.Wrap(err)
+	}
+	rel, err := filepath.Rel(moduleRoot, dir)
+	if err != nil {
+		return "", log.Wrap(err)
+	}
+	if rel == "." {
+		return modulePath, nil
+	}
+	return path.Join(modulePath, filepath.ToSlash(rel)), nil
+}
+
+// apiImportPath returns import path of the directory with project apis
+func apiImportPath(projectRoot string) (string, error) {
+	apiDir, err := domain.ProjectApiDir(projectRoot)
+	if err != nil {
+		return "", log.Wrap(err)
+	}
+	return findPackageImportPath(apiDir)
+}
+
+// findModule walks up from the dir to the first go.mod. If go.work is found
+// first, module containing dir is searched for among the workspace modules.
+func findModule(dir string) (string, string, error) {
+	current := dir
+	for {
+		if modulePath, err := readModulePath(current); err == nil {
+			return current, modulePath, nil
+		}
+		if fileExists(filepath.Join(current, goWorkFile)) {
+			return findWorkspaceModule(current, dir)
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return "", "", log.Wrapf("go.mod not found for %s", dir)
+		}
+		current = parent
+	}
+}
+
+func findWorkspaceModule(workRoot, dir string) (string, string, error) {
+	uses, err := goWorkUses(workRoot)
+	if err != nil {
+		return "", "", log.Wrap(err)
+	}
+	// longest path first so the nested modules are matched before the parent
+	sort.Slice(uses, func(i, j int) bool { return len(uses[i]) > len(uses[j]) })
+	for _, u := range uses {
+		if !isSubPath(u, dir) {
+			continue
+		}
+		modulePath, err := readModulePath(u)
+		if err != nil {
+			return "", "", log.Wrap(err)
+		}
+		return u, modulePath, nil
+	}
+	return "", "", log.Wrapf("%s is not in any module used by %s", dir, filepath.Join(workRoot, goWorkFile))
+}
+
+func readModulePath(dir string) (string, error) {
+	buf, err := ioutil.ReadFile(filepath.Join(dir, goModFile))
+	if err != nil {
+		return "", err
+	}
+	modulePath := modfile.ModulePath(buf)
+	if modulePath == "" {
+		return "", log.Wrapf("module directive not found in %s", filepath.Join(dir, goModFile))
+	}
+	return modulePath, nil
+}
+
+// goWorkUses returns absolute paths of the modules from the use directives
+// in the go.work file
+func goWorkUses(workRoot string) ([]string, error) {
+	name := filepath.Join(workRoot, goWorkFile)
+	buf, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, log.Wrap(err)
+	}
+	// go.work has the same syntax as go.mod, unknown directives are kept in
+	// the syntax tree when parsed in lax mode
+	f, err := modfile.ParseLax(name, buf, nil)
+	if err != nil {
+		return nil, log.Wrap(err)
+	}
+	var uses []string
+	add := func(tokens []string) {
+		if len(tokens) == 0 {
+			return
+		}
+		dir := strings.Trim(tokens[0], `"`)
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(workRoot, dir)
+		}
+		uses = append(uses, filepath.Clean(dir))
+	}
+	for _, stmt := range f.Syntax.Stmt {
+		switch s := stmt.(type) {
+		case *modfile.Line:
+			if len(s.Token) > 0 && s.Token[0] == "use" {
+				add(s.Token[1:])
+			}
+		case *modfile.LineBlock:
+			if len(s.Token) > 0 && s.Token[0] == "use" {
+				for _, l := range s.Line {
+					add(l.Token)
+				}
+			}
+		}
+	}
+	return uses, nil
+}
+
+// findRepositoryRoot returns directory from which all projects in the
+// repository are searched for. That is the go.work directory if there is one
+// or the root of the module containing dir.
+func findRepositoryRoot(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", log.Wrap(err)
+	}
+	moduleRoot := ""
+	current := dir
+	for {
+		if fileExists(filepath.Join(current, goWorkFile)) {
+			return current, nil
+		}
+		if moduleRoot == "" && fileExists(filepath.Join(current, goModFile)) {
+			moduleRoot = current
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			break
+		}
+		current = parent
+	}
+	if moduleRoot == "" {
+		return "", log.Wrapf("go.mod not found for %s", dir)
+	}
+	return moduleRoot, nil
+}
+
+// findProjects returns roots of all Mantil projects under the root directory
+func findProjects(root string) ([]string, error) {
+	var projects []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if path != root && skipProjectsSearch(info.Name()) {
+			return filepath.SkipDir
+		}
+		if domain.IsProjectRoot(path) {
+			projects = append(projects, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, log.Wrap(err)
+	}
+	return projects, nil
+}
+
+func skipProjectsSearch(name string) bool {
+	return strings.HasPrefix(name, ".") || name == "vendor" || name == "node_modules" || name == BuildDir
+}
+
+func isSubPath(parent, child string) bool {
+	rel, err := filepath.Rel(parent, child)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+}
+
+func TestFindPackageImportPath(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "go.mod"), "module example.com/repo\n")
+	writeTestFile(t, filepath.Join(root, "services", "a", "config", "state.yml"), "name: a\n")
+	writeTestFile(t, filepath.Join(root, "services", "b", "config", "state.yml"), "name: b\n")
+	writeTestFile(t, filepath.Join(root, "services", "b", "config", "environment.yml"), "project:\n  api_dir: ../../apis/b\n")
+
+	ip, err := findPackageImportPath(root)
+	require.NoError(t, err)
+	require.Equal(t, "example.com/repo", ip)
+
+	ip, err = findPackageImportPath(filepath.Join(root, "services", "a"))
+	require.NoError(t, err)
+	require.Equal(t, "example.com/repo/services/a", ip)
+
+	ip, err = apiImportPath(filepath.Join(root, "services", "a"))
+	require.NoError(t, err)
+	require.Equal(t, "example.com/repo/services/a/api", ip)
+
+	ip, err = apiImportPath(filepath.Join(root, "services", "b"))
+	require.NoError(t, err)
+	require.Equal(t, "example.com/repo/apis/b", ip)
+
+	projects, err := findProjects(root)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(root, "services", "a"),
+		filepath.Join(root, "services", "b"),
+	}, projects)
+
+	repoRoot, err := findRepositoryRoot(filepath.Join(root, "services", "a"))
+	require.NoError(t, err)
+	require.Equal(t, root, repoRoot)
+}
+
+func TestFindPackageImportPathGoWork(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "go.work"), "go 1.18\n\nuse (\n\t./first\n\t./second\n)\n")
+	writeTestFile(t, filepath.Join(root, "first", "go.mod"), "module example.com/first\n")
+	writeTestFile(t, filepath.Join(root, "second", "go.mod"), "module example.com/second\n")
+	writeTestFile(t, filepath.Join(root, "second", "project", "config", "state.yml"), "name: project\n")
+
+	uses, err := goWorkUses(root)
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(root, "first"), filepath.Join(root, "second")}, uses)
+
+	ip, err := findPackageImportPath(filepath.Join(root, "second", "project"))
+	require.NoError(t, err)
+	require.Equal(t, "example.com/second/project", ip)
+
+	repoRoot, err := findRepositoryRoot(filepath.Join(root, "second", "project"))
+	require.NoError(t, err)
+	require.Equal(t, root, repoRoot)
+
+	_, err = findPackageImportPath(filepath.Join(root, "third"))
+	require.Error(t, err)
+}
@@ -3,14 +3,20 @@ package controller
 import (
 	"errors"
 	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/mantil-io/mantil/cli/log"
 	"github.com/mantil-io/mantil/cli/ui"
 	"github.com/mantil-io/mantil/domain"
 	"github.com/mantil-io/mantil/kit/git"
+	"golang.org/x/mod/modfile"
 )
 
 const (
@@ -50,6 +56,9 @@ type NewArgs struct {
 	Name       string
 	From       string
 	ModuleName string
+	Params     map[string]string
+	// run post create hooks of the remote template without confirmation
+	Yes bool
 }
 
 func New(a NewArgs) error {
@@ -59,36 +68,51 @@ func New(a NewArgs) error {
 	if a.From == "" {
 		a.From = DefaultTemplate
 	}
-	return createProject(a.Name, a.From, a.ModuleName)
+	return createProject(a.Name, a.From, a.ModuleName, a.Params, a.Yes)
 }
 
-func createProject(name, from, moduleName string) error {
+func createProject(name, from, moduleName string, params map[string]string, yes bool) error {
 	if err := domain.ValidateName(name); err != nil {
 		return log.Wrap(err)
 	}
 	projectPath, _ := filepath.Abs(name)
-	repo, err := repoURL(name, from)
+	repo, err := templateSourceURL(name, from)
 	if err != nil {
 		return log.Wrap(err)
 	}
 	ui.Info("")
 	ui.Info("Creating %s in %s...", name, projectPath)
 	ui.Info("Replacing import paths with %s...", moduleName)
-	if err := git.CreateRepo(repo, name, moduleName); err != nil {
-		if errors.Is(err, git.ErrRepositoryNotFound) {
-			return log.Wrap(err, sourceNewUserError(repo))
+	if isExternalRepo(repo) {
+		if err := git.CreateRepo(repo, name, moduleName); err != nil {
+			if errors.Is(err, git.ErrRepositoryNotFound) {
+				return log.Wrap(err, sourceNewUserError(repo))
+			}
+			return log.Wrap(err, "could not initialize repository from source %s: %v", repo, err)
+		}
+	} else {
+		if err := createFromDir(repo, projectPath, moduleName); err != nil {
+			return log.Wrap(err, "could not initialize project from directory %s: %v", repo, err)
 		}
-		return log.Wrap(err, "could not initialize repository from source %s: %v", repo, err)
-
 	}
 
 	// delete unnecessary files from template repositories
-	if !isExternalRepo(from) {
+	if isBuiltinTemplate(from) {
 		os.Remove(filepath.Join(projectPath, LicenseFile))
 		os.Remove(filepath.Join(projectPath, ReadmeFile))
 		os.RemoveAll(filepath.Join(projectPath, ImagesDir))
 	}
 
+	data := templateData{
+		Name:       name,
+		ModuleName: moduleName,
+	}
+	// commands from remote templates are shown to the user before running
+	confirmHooks := isExternalRepo(repo) && !yes
+	if err := applyTemplateManifest(projectPath, data, params, confirmHooks); err != nil {
+		return log.Wrap(err)
+	}
+
 	fs, err := newStore()
 	if err != nil {
 		return log.Wrap(err)
@@ -106,6 +130,113 @@ func createProject(name, from, moduleName string) error {
 	return nil
 }
 
+// templateSourceURL resolves project source, templates from the local
+// registry take precedence over built-in templates
+func templateSourceURL(name, from string) (string, error) {
+	if t := workspaceTemplate(from); t != nil {
+		return t.Source, nil
+	}
+	if isLocalDir(from) {
+		return filepath.Abs(from)
+	}
+	return repoURL(name, from)
+}
+
+// createFromDir copies template from the local directory and replaces
+// template module name with the moduleName
+func createFromDir(dir, projectPath, moduleName string) error {
+	if err := copyTemplateDir(dir, projectPath); err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(projectPath, "go.mod")); err != nil {
+		return nil
+	}
+	old, err := findPackageImportPath(projectPath)
+	if err != nil {
+		return err
+	}
+	if old == "" || moduleName == "" {
+		return nil
+	}
+	return replaceModuleName(projectPath, old, moduleName)
+}
+
+func isLocalDir(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && fi.IsDir()
+}
+
+func isBuiltinTemplate(from string) bool {
+	_, ok := TemplateRepos[from]
+	return ok
+}
+
+// replaceModuleName changes module directive in go.mod and import paths in go
+// files. Only import specs are changed because module names of local templates
+// are usually short and could be found elsewhere in the code.
+func replaceModuleName(projectPath, old, new string) error {
+	modPath := filepath.Join(projectPath, "go.mod")
+	buf, err := ioutil.ReadFile(modPath)
+	if err != nil {
+		return err
+	}
+	mf, err := modfile.Parse(modPath, buf, nil)
+	if err != nil {
+		return err
+	}
+	if err := mf.AddModuleStmt(new); err != nil {
+		return err
+	}
+	if buf, err = mf.Format(); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(modPath, buf, 0644); err != nil {
+		return err
+	}
+	return filepath.Walk(projectPath, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".go") {
+			return nil
+		}
+		return replaceImports(path, info.Mode(), old, new)
+	})
+}
+
+func replaceImports(path string, mode fs.FileMode, old, new string) error {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, buf, parser.ImportsOnly)
+	if err != nil {
+		return err
+	}
+	var out []byte
+	last := 0
+	for _, is := range f.Imports {
+		ip, err := strconv.Unquote(is.Path.Value)
+		if err != nil {
+			return err
+		}
+		if ip != old && !strings.HasPrefix(ip, old+"/") {
+			continue
+		}
+		start := fset.Position(is.Path.Pos()).Offset
+		end := fset.Position(is.Path.End()).Offset
+		out = append(out, buf[last:start]...)
+		out = append(out, strconv.Quote(new+strings.TrimPrefix(ip, old))...)
+		last = end
+	}
+	if last == 0 {
+		return nil
+	}
+	out = append(out, buf[last:]...)
+	return ioutil.WriteFile(path, out, mode)
+}
+
 func repoURL(name, repo string) (string, error) {
 	if !isExternalRepo(repo) {
 		template := projectTemplate(repo)
@@ -139,5 +270,7 @@ func sourceNewUserError(repo string) string {
 	}
 	return fmt.Sprintf(`%s is not a valid project source, please provide one of the following:
 - a link to an existing git repository, starting with 'http(s):' or 'git:'
+- a path to the local directory
+- the name of a template added with 'mantil template add'
 - the name of a predefined template which can be one of: %s`, repo, strings.Join(validTemplates, ", "))
 }
@@ -1,6 +1,8 @@
 package controller
 
 import (
+	"io/ioutil"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -32,3 +34,86 @@ func TestNewRepoURL(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "https://github.com/mantil-io/template-ping", url)
 }
+
+func TestNewCreateFromDir(t *testing.T) {
+	projectPath := filepath.Join(t.TempDir(), "my-service")
+	require.NoError(t, createFromDir("testdata/template", projectPath, "github.com/org/my-service"))
+
+	buf, err := ioutil.ReadFile(filepath.Join(projectPath, "go.mod"))
+	require.NoError(t, err)
+	assert.Contains(t, string(buf), "module github.com/org/my-service")
+
+	buf, err = ioutil.ReadFile(filepath.Join(projectPath, "main.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(buf), `"github.com/org/my-service/api/ping"`)
+	assert.Contains(t, string(buf), `var service = "service"`)
+
+	require.Error(t, createFromDir("testdata/template", projectPath, "my-service"))
+}
+
+func TestNewApplyTemplateManifest(t *testing.T) {
+	projectPath := filepath.Join(t.TempDir(), "my-service")
+	require.NoError(t, createFromDir("testdata/template", projectPath, "my-service"))
+	data := templateData{Name: "my-service", ModuleName: "my-service"}
+
+	err := applyTemplateManifest(projectPath, data, map[string]string{"unknown": "value"}, false)
+	require.Error(t, err)
+
+	err = applyTemplateManifest(projectPath, data, map[string]string{"owner": "platform"}, false)
+	require.NoError(t, err)
+
+	buf, err := ioutil.ReadFile(filepath.Join(projectPath, "config.yml"))
+	require.NoError(t, err)
+	assert.Equal(t, "project: my-service\nmodule: my-service\nregion: eu-central-1\n", string(buf))
+
+	buf, err = ioutil.ReadFile(filepath.Join(projectPath, "api", "ping", "ping.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(buf), "// Owner: platform")
+
+	assert.NoFileExists(t, filepath.Join(projectPath, TemplateManifestFile))
+}
+
+func TestNewTemplateManifestRunHooks(t *testing.T) {
+	dir := t.TempDir()
+	m := TemplateManifest{
+		Hooks: TemplateHooks{PostCreate: []string{`printf '%s' "quoted argument" > hook.txt`}},
+	}
+	// not confirmed hooks are not run without terminal
+	require.NoError(t, m.runHooks(dir, false))
+	assert.NoFileExists(t, filepath.Join(dir, "hook.txt"))
+
+	require.NoError(t, m.runHooks(dir, true))
+	buf, err := ioutil.ReadFile(filepath.Join(dir, "hook.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "quoted argument", string(buf))
+}
+
+func TestNewTemplateManifestResolveParams(t *testing.T) {
+	m := TemplateManifest{
+		Parameters: []TemplateParameter{
+			{Name: "owner", Required: true},
+			{Name: "region", Default: "eu-central-1"},
+		},
+	}
+	params, err := m.resolveParams(map[string]string{"owner": "platform"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"owner": "platform", "region": "eu-central-1"}, params)
+
+	_, err = m.resolveParams(nil, nil)
+	require.Error(t, err)
+
+	prompt := func(p TemplateParameter) (string, error) {
+		return p.Name + "-value", nil
+	}
+	params, err = m.resolveParams(map[string]string{"region": "us-east-1"}, prompt)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"owner": "owner-value", "region": "us-east-1"}, params)
+}
+
+func TestNewTemplateManifestShouldRender(t *testing.T) {
+	m := TemplateManifest{Render: []string{"*.md", "api/*/*.go"}}
+	assert.True(t, m.shouldRender("README.md"))
+	assert.True(t, m.shouldRender(filepath.Join("docs", "index.md")))
+	assert.True(t, m.shouldRender(filepath.Join("api", "ping", "ping.go")))
+	assert.False(t, m.shouldRender("main.go"))
+}
@@ -2,6 +2,7 @@ package controller
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/mantil-io/mantil/cli/ui"
 	"github.com/mantil-io/mantil/domain"
@@ -9,9 +10,13 @@ import (
 )
 
 const (
-	UserAddHTTPMethod    = "node/addUser"
-	UserRemoveHTTPMethod = "node/removeUser"
-	LoginHTTPMethod      = "auth/login"
+	UserAddHTTPMethod       = "node/addUser"
+	UserRemoveHTTPMethod    = "node/removeUser"
+	LoginHTTPMethod         = "auth/login"
+	RefreshTokenHTTPMethod  = "auth/refresh"
+	SessionsHTTPMethod      = "node/sessions"
+	RevokeSessionHTTPMethod = "node/revokeSession"
+	AuditHTTPMethod         = "node/audit"
 )
 
 type NodeUserAddArgs struct {
@@ -103,7 +108,7 @@ func NodeLogin(a NodeLoginArgs) error {
 		return err
 	}
 	w := fs.Workspace()
-	if err := w.AddNodeToken(t); err != nil {
+	if err := w.AddNodeSession(t.JWT, t.RefreshToken); err != nil {
 		return err
 	}
 	return fs.Store()
@@ -122,3 +127,124 @@ func NodeLogout(a NodeLogoutArgs) error {
 	w.RemoveNode(a.NodeName)
 	return fs.Store()
 }
+
+type NodeSessionsArgs struct {
+	Node     string
+	Username string
+	Revoke   string
+}
+
+func NodeSessions(a NodeSessionsArgs) error {
+	fs, err := domain.NewSingleDeveloperWorkspaceStore()
+	if err != nil {
+		return err
+	}
+	n := fs.Workspace().FindNode(a.Node)
+	if n == nil {
+		return fmt.Errorf("node not found")
+	}
+	i, err := nodeInvoker(n)
+	if err != nil {
+		return err
+	}
+	// token could be renewed
+	if err := fs.Store(); err != nil {
+		return err
+	}
+	if a.Revoke != "" {
+		if err := i.Do(RevokeSessionHTTPMethod, &dto.RevokeSessionRequest{ID: a.Revoke}, nil); err != nil {
+			return err
+		}
+		ui.Info("Session %s revoked.", a.Revoke)
+		return nil
+	}
+	var rsp dto.SessionsResponse
+	if err := i.Do(SessionsHTTPMethod, &dto.SessionsRequest{Username: a.Username}, &rsp); err != nil {
+		return err
+	}
+	var data [][]string
+	for _, s := range rsp.Sessions {
+		data = append(data, []string{
+			s.ID,
+			s.Username,
+			roleName(s.Role),
+			formatUnix(s.CreatedAt),
+			formatUnix(s.LastUsedAt),
+			formatUnix(s.ExpiresAt),
+		})
+	}
+	ShowTable([]string{"id", "user", "role", "created", "last used", "expires"}, data)
+	return nil
+}
+
+func roleName(r domain.Role) string {
+	switch r {
+	case domain.Admin:
+		return "admin"
+	case domain.User:
+		return "user"
+	default:
+		return ""
+	}
+}
+
+func formatUnix(sec int64) string {
+	return time.Unix(sec, 0).Format(time.RFC822)
+}
+
+type NodeAuditArgs struct {
+	Node     string
+	Since    time.Duration
+	Stage    string
+	Username string
+	JSON     bool
+}
+
+func NodeAudit(a NodeAuditArgs) error {
+	fs, err := domain.NewSingleDeveloperWorkspaceStore()
+	if err != nil {
+		return err
+	}
+	n := fs.Workspace().FindNode(a.Node)
+	if n == nil {
+		return fmt.Errorf("node not found")
+	}
+	i, err := nodeInvoker(n)
+	if err != nil {
+		return err
+	}
+	// token could be renewed
+	if err := fs.Store(); err != nil {
+		return err
+	}
+	var rsp dto.AuditResponse
+	if err := i.Do(AuditHTTPMethod, &dto.AuditRequest{
+		Since:    time.Now().Add(-a.Since).UnixMilli(),
+		Stage:    a.Stage,
+		Username: a.Username,
+	}, &rsp); err != nil {
+		return err
+	}
+	if a.JSON {
+		return printJSON(rsp.Records)
+	}
+	var data [][]string
+	for _, r := range rsp.Records {
+		result := "ok"
+		if r.Error != "" {
+			result = r.Error
+		}
+		data = append(data, []string{
+			time.UnixMilli(r.Time).Format(time.RFC822),
+			r.Username,
+			r.Action,
+			r.Project,
+			r.Stage,
+			r.Request,
+			result,
+			(time.Duration(r.Duration) * time.Millisecond).String(),
+		})
+	}
+	ShowTable([]string{"time", "user", "action", "project", "stage", "request", "result", "duration"}, data)
+	return nil
+}
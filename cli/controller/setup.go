@@ -32,6 +32,7 @@ var (
 
 type Setup struct {
 	aws                 *aws.AWS
+	api                 nodeAWS
 	nodeName            string
 	store               *domain.FileStore
 	resourceTags        map[string]string
@@ -41,6 +42,7 @@ type Setup struct {
 	force               bool
 	yes                 bool
 	githubUser          string
+	logRetentionDays    int
 }
 
 type stackTemplateData struct {
@@ -51,6 +53,7 @@ type stackTemplateData struct {
 	Suffix             string
 	APIGatewayLogsRole string
 	Env                map[string]string
+	LogRetentionDays   int
 }
 
 func NewSetup(a *SetupArgs) (*Setup, error) {
@@ -67,12 +70,14 @@ func NewSetup(a *SetupArgs) (*Setup, error) {
 	}
 	return &Setup{
 		aws:                 awsClient,
+		api:                 &nodeAWSClient{aws: awsClient},
 		nodeName:            a.NodeName,
 		store:               fs,
 		credentialsProvider: a.credentialsProvider,
 		force:               a.Force,
 		yes:                 a.Yes,
 		githubUser:          a.GithubUser,
+		logRetentionDays:    a.LogRetentionDays,
 	}, nil
 }
 
@@ -88,6 +93,7 @@ Available regions are:
 	if err != nil {
 		return log.Wrap(err)
 	}
+	n.LogRetentionDays = c.logRetentionDays
 	c.stackName = n.SetupStackName()
 	c.lambdaName = n.SetupLambdaName()
 	c.resourceTags = n.ResourceTags()
@@ -113,7 +119,7 @@ func (c *Setup) regionSupported() bool {
 
 func (c *Setup) create(n *domain.Node) error {
 	tmr := timerFn()
-	if err := c.createSetupStack(n.Functions, n.ResourceSuffix(), n.SetupEnv()); err != nil {
+	if err := c.createSetupStack(n.Functions, n.ResourceSuffix(), n.SetupEnv(), n.LogRetention()); err != nil {
 		return log.Wrap(err)
 	}
 	stackDuration := tmr()
@@ -158,7 +164,7 @@ func (c *Setup) backendExists() (bool, error) {
 	return c.aws.LambdaExists(c.lambdaName)
 }
 
-func (c *Setup) createSetupStack(acf domain.NodeFunctions, suffix string, env map[string]string) error {
+func (c *Setup) createSetupStack(acf domain.NodeFunctions, suffix string, env map[string]string, logRetentionDays int) error {
 	td := stackTemplateData{
 		Name:               c.stackName,
 		Bucket:             acf.Bucket,
@@ -167,6 +173,7 @@ func (c *Setup) createSetupStack(acf domain.NodeFunctions, suffix string, env ma
 		Suffix:             suffix,
 		APIGatewayLogsRole: APIGatewayLogsRole,
 		Env:                env,
+		LogRetentionDays:   logRetentionDays,
 	}
 	t, err := c.renderStackTemplate(td)
 	if err != nil {
@@ -190,46 +197,135 @@ func (c *Setup) Upgrade(version string, getPath func(string) (string, string)) e
 	if n == nil {
 		return log.Wrap(&domain.NodeNotFoundError{Name: c.nodeName})
 	}
+	c.stackName = n.SetupStackName()
+	c.lambdaName = n.SetupLambdaName()
+	c.resourceTags = n.ResourceTags()
+
+	if n.Upgrade != nil {
+		ui.Notice("Upgrade of node %s to version %s was interrupted, rolling back to version %s.", n.Name, n.Version, n.Upgrade.Version)
+		err := c.rollback(n)
+		if serr := c.store.Store(); serr != nil {
+			return log.Wrap(serr)
+		}
+		if err != nil {
+			return log.Wrap(err, "rollback to version %s failed", n.Version)
+		}
+	}
 	if n.Version == version {
 		return log.Wrap(&domain.NodeAlreadyUpToDateError{Name: n.Name, Version: n.Version})
 	}
 
-	bucket, key := getPath(c.aws.Region())
-	n.UpgradeVersion(version, bucket, key)
+	bucket, key := getPath(c.api.Region())
+	if err := c.upgradePreflight(n, version, bucket, key); err != nil {
+		return log.Wrap(err)
+	}
+	template, err := c.api.StackTemplate(c.stackName)
+	if err != nil {
+		return log.Wrap(err)
+	}
+	// stored before any node resource is changed so the upgrade can be
+	// rolled back if it is interrupted
+	n.StartUpgrade(version, bucket, key, template)
+	if err := c.store.Store(); err != nil {
+		return log.Wrap(err)
+	}
+	if err := c.upgrade(n); err != nil {
+		return log.Wrap(err)
+	}
+	return nil
+}
 
-	c.stackName = n.SetupStackName()
+// upgradePreflight checks that node functions of the target version exist
+// and that project stages on the node are compatible with that version
+func (c *Setup) upgradePreflight(n *domain.Node, version, bucket, key string) error {
+	exists, err := c.api.ObjectExists(bucket, fmt.Sprintf("%s/setup.zip", key))
+	if err != nil {
+		return log.Wrap(err, "could not find node functions for version %s", version)
+	}
+	if !exists {
+		return log.Wrapf("node functions for version %s not found", version)
+	}
+	incompatible := n.IncompatibleStages(c.store.Project(), version)
+	if len(incompatible) > 0 && !c.force {
+		return log.Wrapf(`stages %s were deployed with a version newer than %s
+Downgrading the node may break their next deploy. Use --force option to downgrade anyway.`, strings.Join(incompatible, ", "), version)
+	}
+	if unchecked := c.uncheckedStages(n); len(unchecked) > 0 {
+		ui.Notice("Stages %s of other projects on the node can't be checked for compatibility with version %s.", strings.Join(unchecked, ", "), version)
+	}
+	return nil
+}
+
+// uncheckedStages returns stages on the node which are not in the current project
+func (c *Setup) uncheckedStages(n *domain.Node) []string {
+	p := c.store.Project()
+	var names []string
+	for _, s := range n.Stages {
+		if p != nil && s.ProjectName == p.Name {
+			continue
+		}
+		names = append(names, fmt.Sprintf("%s/%s", s.ProjectName, s.Name))
+	}
+	return names
+}
+
+// RotateKeys creates new keys for signing node access tokens and applies them
+// to the node infrastructure.
+func (c *Setup) RotateKeys() error {
+	ws := c.store.Workspace()
+	n := ws.Node(c.nodeName)
+	if n == nil {
+		return log.Wrap(&domain.NodeNotFoundError{Name: c.nodeName})
+	}
+	if err := n.RotateKeys(); err != nil {
+		return log.Wrap(err)
+	}
 	c.lambdaName = n.SetupLambdaName()
-	c.resourceTags = n.ResourceTags()
 
-	if err := c.upgrade(n); err != nil {
+	ui.Title("Rotating keys for node %s\n", n.Name)
+	if err := c.invokeUpgrade(n); err != nil {
 		return log.Wrap(err)
 	}
 	if err := c.store.Store(); err != nil {
 		return log.Wrap(err)
 	}
+	ui.Title("\nKeys for node %s rotated.\n", n.Name)
+	ui.Info("Tokens signed with the previous key are accepted for %v.", domain.KeyRotationGracePeriod)
 	return nil
 }
 
 func (c *Setup) upgrade(n *domain.Node) error {
 	tmr := timerFn()
 	if err := c.updateSetupStack(n.Functions, n.ResourceSuffix()); err != nil {
-		return log.Wrap(err)
+		// cloudformation rolls back failed stack update
+		n.RollbackUpgrade()
+		if serr := c.store.Store(); serr != nil {
+			return log.Wrap(serr)
+		}
+		return log.Wrap(err, "node %s left at version %s", n.Name, n.Version)
 	}
 	stackDuration := tmr()
 
 	ui.Info("")
 	ui.Title("Upgrading AWS infrastructure\n")
-	req := &dto.SetupRequest{
-		BucketConfig: &dto.SetupBucketConfig{
-			Name: n.Bucket,
-		},
-		Node: n,
-	}
-	if err := invoke.Lambda(c.aws.Lambda(), c.lambdaName, ui.NodeLogsSink).Do("upgrade", req, nil); err != nil {
-		return log.Wrap(err, "failed to invoke setup function")
+	if err := c.invokeUpgrade(n); err != nil {
+		previous := n.Upgrade.Version
+		ui.ErrorLine("upgrade to version %s failed, rolling back to version %s", n.Version, previous)
+		rerr := c.rollback(n)
+		if serr := c.store.Store(); serr != nil {
+			return log.Wrap(serr)
+		}
+		if rerr != nil {
+			return log.Wrap(err, "rollback to version %s failed - %v", previous, rerr)
+		}
+		return log.Wrap(err, "node %s rolled back to version %s", n.Name, n.Version)
 	}
 	infrastructureDuration := tmr()
 
+	n.FinishUpgrade()
+	if err := c.store.Store(); err != nil {
+		return log.Wrap(err)
+	}
 	log.Event(domain.Event{NodeUpgrade: &domain.NodeEvent{
 		AWSCredentialsProvider: c.credentialsProvider,
 		StackDuration:          stackDuration,
@@ -240,12 +336,56 @@ func (c *Setup) upgrade(n *domain.Node) error {
 	return nil
 }
 
+func (c *Setup) invokeUpgrade(n *domain.Node) error {
+	req := &dto.SetupRequest{
+		BucketConfig: &dto.SetupBucketConfig{
+			Name: n.Bucket,
+		},
+		Node: n,
+	}
+	if err := c.api.InvokeSetup(c.lambdaName, "upgrade", req); err != nil {
+		return log.Wrap(err, "failed to invoke setup function")
+	}
+	return nil
+}
+
+// rollback restores setup stack of the previous version and applies its
+// infrastructure, node keeps the upgrade state until rollback succeeds so
+// it can be repeated
+func (c *Setup) rollback(n *domain.Node) error {
+	prev := n.Upgrade
+	n.UpgradeVersion(prev.Version, prev.Functions.Bucket, prev.Functions.Path)
+	if err := c.restoreSetupStack(prev.Template); err != nil {
+		return log.Wrap(err)
+	}
+	ui.Info("")
+	ui.Title("Rolling back AWS infrastructure\n")
+	if err := c.invokeUpgrade(n); err != nil {
+		return log.Wrap(err)
+	}
+	n.FinishUpgrade()
+	return nil
+}
+
+// restoreSetupStack applies setup stack template of the previous version,
+// stack already has it if the upgrade was interrupted before the stack update
+func (c *Setup) restoreSetupStack(template string) error {
+	current, err := c.api.StackTemplate(c.stackName)
+	if err != nil {
+		return log.Wrap(err)
+	}
+	if current == template {
+		return nil
+	}
+	return c.applySetupStack("Rolling back setup stack", template)
+}
+
 func (c *Setup) updateSetupStack(acf domain.NodeFunctions, suffix string) error {
 	td := stackTemplateData{
 		Name:               c.stackName,
 		Bucket:             acf.Bucket,
 		S3Key:              fmt.Sprintf("%s/setup.zip", acf.Path), // update functions path
-		Region:             c.aws.Region(),
+		Region:             c.api.Region(),
 		Suffix:             suffix,
 		APIGatewayLogsRole: APIGatewayLogsRole,
 	}
@@ -253,12 +393,12 @@ func (c *Setup) updateSetupStack(acf domain.NodeFunctions, suffix string) error
 	if err != nil {
 		return log.Wrap(err, "render template failed")
 	}
-	stackWaiter, err := c.aws.CloudFormation().UpdateStack(c.stackName, string(t), c.resourceTags)
-	if err != nil {
-		return log.Wrap(err)
-	}
-	if err := runStackProgress("Updating setup stack", types.ResourceStatusUpdateComplete, stackWaiter, updateResourceCount); err != nil {
-		return log.Wrap(err, "updating setup stack failed")
+	return c.applySetupStack("Updating setup stack", string(t))
+}
+
+func (c *Setup) applySetupStack(prefix, template string) error {
+	if err := c.api.UpdateStack(prefix, c.stackName, template, c.resourceTags); err != nil {
+		return log.Wrap(err, "%s failed", strings.ToLower(prefix))
 	}
 	return nil
 }
@@ -356,6 +496,44 @@ func (c *Setup) renderStackTemplate(data stackTemplateData) ([]byte, error) {
 	return renderTemplate(setupStackTemplate, data)
 }
 
+// nodeAWS is the part of the AWS api used to change existing node
+type nodeAWS interface {
+	Region() string
+	ObjectExists(bucket, key string) (bool, error)
+	StackTemplate(stackName string) (string, error)
+	// UpdateStack updates stack and shows progress of the update
+	UpdateStack(prefix, stackName, template string, tags map[string]string) error
+	InvokeSetup(lambdaName, method string, req interface{}) error
+}
+
+type nodeAWSClient struct {
+	aws *aws.AWS
+}
+
+func (c *nodeAWSClient) Region() string {
+	return c.aws.Region()
+}
+
+func (c *nodeAWSClient) ObjectExists(bucket, key string) (bool, error) {
+	return c.aws.S3().ObjectExists(bucket, key)
+}
+
+func (c *nodeAWSClient) StackTemplate(stackName string) (string, error) {
+	return c.aws.CloudFormation().GetTemplate(stackName)
+}
+
+func (c *nodeAWSClient) UpdateStack(prefix, stackName, template string, tags map[string]string) error {
+	stackWaiter, err := c.aws.CloudFormation().UpdateStack(stackName, template, tags)
+	if err != nil {
+		return err
+	}
+	return runStackProgress(prefix, types.ResourceStatusUpdateComplete, stackWaiter, updateResourceCount)
+}
+
+func (c *nodeAWSClient) InvokeSetup(lambdaName, method string, req interface{}) error {
+	return invoke.Lambda(c.aws.Lambda(), lambdaName, ui.NodeLogsSink).Do(method, req, nil)
+}
+
 type stackProgress struct {
 	prefix        string
 	currentCnt    int
@@ -6,6 +6,7 @@ import (
 	"github.com/mantil-io/mantil/cli/log"
 	"github.com/mantil-io/mantil/domain"
 	"github.com/mantil-io/mantil/kit/aws"
+	"golang.org/x/mod/semver"
 )
 
 const (
@@ -29,6 +30,9 @@ type SetupArgs struct {
 	Force               bool
 	Yes                 bool
 	GithubUser          string
+	LogRetentionDays    int
+	// released version to upgrade node to, current CLI version if empty
+	Version string
 }
 
 func DefaultNodeName() string { return domain.DefaultNodeName }
@@ -42,6 +46,12 @@ func (a *SetupArgs) ParseArgs(args []string) {
 }
 
 func (a *SetupArgs) validate() error {
+	if err := domain.ValidateLogRetentionDays(a.LogRetentionDays); err != nil {
+		return log.Wrap(NewArgumentError(err.Error()))
+	}
+	if a.Version != "" && !semver.IsValid(a.Version) {
+		return log.Wrap(NewArgumentError("invalid version %s, expected format is vX.Y.Z", a.Version))
+	}
 	if a.AccessKeyID != "" || a.SecretAccessKey != "" {
 		a.credentialsProvider = domain.AWSCredentialsByArguments
 		return a.validateAccessKeys()
@@ -1,10 +1,16 @@
 package controller
 
 import (
+	"errors"
 	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/mantil-io/mantil/domain"
 	"github.com/mantil-io/mantil/kit/testutil"
+	"github.com/mantil-io/mantil/node/dto"
 	"github.com/stretchr/testify/require"
 )
 
@@ -21,9 +27,195 @@ func TestRenderTemplate(t *testing.T) {
 		Env: map[string]string{
 			"key": "value",
 		},
+		LogRetentionDays: 30,
 	}
 	s := &Setup{}
 	actual, err := s.renderStackTemplate(td)
 	require.NoError(t, err)
 	testutil.EqualFiles(t, "testdata/template.yml", string(actual), *update)
 }
+
+func TestSetupArgsVersion(t *testing.T) {
+	a := &SetupArgs{Profile: "default", LogRetentionDays: 14, Version: "v0.2.0"}
+	require.NoError(t, a.validate())
+
+	a.Version = "0.2"
+	require.Error(t, a.validate())
+}
+
+func TestSetupArgsLogRetention(t *testing.T) {
+	a := &SetupArgs{Profile: "default", LogRetentionDays: 0}
+	require.Error(t, a.validate())
+
+	a.LogRetentionDays = 30
+	require.NoError(t, a.validate())
+}
+
+// fakeNodeAWS records changes of the node resources made by the upgrade
+type fakeNodeAWS struct {
+	t             *testing.T
+	workspaceFile string
+	exists        bool
+	template      string
+	stackErr      error
+	// errors of the setup function invoke by the node version
+	invokeErr map[string]error
+	stacks    []string
+	invokes   []string
+}
+
+func (f *fakeNodeAWS) Region() string {
+	return "eu-central-1"
+}
+
+func (f *fakeNodeAWS) ObjectExists(bucket, key string) (bool, error) {
+	return f.exists, nil
+}
+
+func (f *fakeNodeAWS) StackTemplate(stackName string) (string, error) {
+	return f.template, nil
+}
+
+func (f *fakeNodeAWS) UpdateStack(prefix, stackName, template string, tags map[string]string) error {
+	f.requireUpgradeStored()
+	f.stacks = append(f.stacks, prefix)
+	if f.stackErr != nil {
+		return f.stackErr
+	}
+	f.template = template
+	return nil
+}
+
+func (f *fakeNodeAWS) InvokeSetup(lambdaName, method string, req interface{}) error {
+	f.requireUpgradeStored()
+	version := req.(*dto.SetupRequest).Node.Version
+	f.invokes = append(f.invokes, version)
+	return f.invokeErr[version]
+}
+
+// requireUpgradeStored checks that the node state before the upgrade is in
+// the workspace file before any node resource is changed
+func (f *fakeNodeAWS) requireUpgradeStored() {
+	buf, err := ioutil.ReadFile(f.workspaceFile)
+	require.NoError(f.t, err)
+	require.Contains(f.t, string(buf), "upgrade:")
+}
+
+func newUpgradeSetup(t *testing.T) (*Setup, *fakeNodeAWS) {
+	dir := t.TempDir()
+	os.Setenv(domain.EnvWorkspacePath, dir)
+	t.Cleanup(func() { os.Unsetenv(domain.EnvWorkspacePath) })
+	fs, err := newStore()
+	require.NoError(t, err)
+	_, err = fs.Workspace().NewNode("node", "123456789012", "eu-central-1", "functions-bucket", "v0.2.0", "v0.2.0", "")
+	require.NoError(t, err)
+	require.NoError(t, fs.Store())
+	api := &fakeNodeAWS{
+		t:             t,
+		workspaceFile: filepath.Join(dir, "workspace.yml"),
+		exists:        true,
+		template:      "v0.2.0 template",
+		invokeErr:     make(map[string]error),
+	}
+	return &Setup{api: api, store: fs, nodeName: "node"}, api
+}
+
+func upgradePath(string) (string, string) {
+	return "functions-bucket", "v0.2.1"
+}
+
+// storedNode reads node from the workspace file
+func storedNode(t *testing.T) *domain.Node {
+	fs, err := newStore()
+	require.NoError(t, err)
+	n := fs.Workspace().Node("node")
+	require.NotNil(t, n)
+	return n
+}
+
+func TestUpgrade(t *testing.T) {
+	s, api := newUpgradeSetup(t)
+	require.NoError(t, s.Upgrade("v0.2.1", upgradePath))
+	require.Equal(t, []string{"Updating setup stack"}, api.stacks)
+	require.Equal(t, []string{"v0.2.1"}, api.invokes)
+
+	n := storedNode(t)
+	require.Equal(t, "v0.2.1", n.Version)
+	require.Equal(t, domain.NodeFunctions{Bucket: "functions-bucket", Path: "v0.2.1"}, n.Functions)
+	require.Nil(t, n.Upgrade)
+}
+
+func TestUpgradePreflight(t *testing.T) {
+	s, api := newUpgradeSetup(t)
+	api.exists = false
+	err := s.Upgrade("v0.2.1", upgradePath)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "node functions for version v0.2.1 not found")
+	require.Empty(t, api.stacks)
+	require.Empty(t, api.invokes)
+
+	n := storedNode(t)
+	require.Equal(t, "v0.2.0", n.Version)
+	require.Nil(t, n.Upgrade)
+}
+
+func TestUpgradeStackFailure(t *testing.T) {
+	s, api := newUpgradeSetup(t)
+	api.stackErr = errors.New("stack update failed")
+	err := s.Upgrade("v0.2.1", upgradePath)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "node node left at version v0.2.0")
+	require.Empty(t, api.invokes)
+
+	n := storedNode(t)
+	require.Equal(t, "v0.2.0", n.Version)
+	require.Equal(t, domain.NodeFunctions{Bucket: "functions-bucket", Path: "v0.2.0"}, n.Functions)
+	require.Nil(t, n.Upgrade)
+}
+
+func TestUpgradeRollback(t *testing.T) {
+	s, api := newUpgradeSetup(t)
+	api.invokeErr["v0.2.1"] = errors.New("upgrade failed")
+	err := s.Upgrade("v0.2.1", upgradePath)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "node node rolled back to version v0.2.0")
+	require.Equal(t, []string{"Updating setup stack", "Rolling back setup stack"}, api.stacks)
+	require.Equal(t, []string{"v0.2.1", "v0.2.0"}, api.invokes)
+	require.Equal(t, "v0.2.0 template", api.template)
+
+	n := storedNode(t)
+	require.Equal(t, "v0.2.0", n.Version)
+	require.Nil(t, n.Upgrade)
+}
+
+func TestUpgradeRollbackFailure(t *testing.T) {
+	s, api := newUpgradeSetup(t)
+	api.invokeErr["v0.2.1"] = errors.New("upgrade failed")
+	api.invokeErr["v0.2.0"] = errors.New("rollback failed")
+	err := s.Upgrade("v0.2.1", upgradePath)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "rollback to version v0.2.0 failed")
+
+	// upgrade state is kept so the rollback is repeated on the next upgrade
+	n := storedNode(t)
+	require.NotNil(t, n.Upgrade)
+	require.Equal(t, "v0.2.0", n.Upgrade.Version)
+	require.Equal(t, "v0.2.0 template", n.Upgrade.Template)
+}
+
+func TestUpgradeInterrupted(t *testing.T) {
+	s, api := newUpgradeSetup(t)
+	// upgrade interrupted after the stack update
+	n := s.store.Workspace().Node("node")
+	n.StartUpgrade("v0.2.1", "functions-bucket", "v0.2.1", api.template)
+	require.NoError(t, s.store.Store())
+	api.template = "v0.2.1 template"
+
+	require.NoError(t, s.Upgrade("v0.2.1", upgradePath))
+	require.Equal(t, []string{"Rolling back setup stack", "Updating setup stack"}, api.stacks)
+	require.Equal(t, []string{"v0.2.0", "v0.2.1"}, api.invokes)
+
+	n = storedNode(t)
+	require.Equal(t, "v0.2.1", n.Version)
+	require.Nil(t, n.Upgrade)
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/manifoldco/promptui"
 	"github.com/mantil-io/mantil/cli/log"
@@ -12,13 +13,20 @@ import (
 	"github.com/mantil-io/mantil/node/dto"
 )
 
-const DestroyHTTPMethod = "destroy"
+const (
+	DestroyHTTPMethod           = "destroy"
+	StageExpiryHTTPMethod       = "node/stageExpiry"
+	RemoveStageExpiryHTTPMethod = "node/removeStageExpiry"
+)
 
 type StageArgs struct {
 	Node       string
 	Stage      string
 	Yes        bool
 	DestroyAll bool
+	// ttl of the ephemeral stage, when set node destroys the stage after it expires
+	TTL    time.Duration
+	Notify string
 }
 
 type Stage struct {
@@ -87,17 +95,40 @@ func (s *Stage) New() (bool, error) {
 	if stage == nil {
 		return false, nil
 	}
+	if s.TTL != 0 {
+		if err := stage.SetTTL(s.TTL, s.Notify); err != nil {
+			return false, log.Wrap(err)
+		}
+	}
 	d, err := NewDeployWithStage(s.store, stage)
 	if err != nil {
 		return false, log.Wrap(err)
 	}
+	// expiry is registered before the stage is stored so that there is no
+	// stored stage with ttl which node doesn't know about
+	if stage.Ephemeral() {
+		if err := s.expiryRequest(stage); err != nil {
+			return false, log.Wrap(err)
+		}
+	}
 	title := fmt.Sprintf("Creating stage %s on node %s", stage.Name, stage.NodeName)
 	if err := d.DeployWithTitle(title); err != nil {
+		if stage.Ephemeral() {
+			if rerr := s.removeExpiryRequest(stage); rerr != nil {
+				ui.Errorf("%v", rerr)
+			}
+		}
 		return false, log.Wrap(err)
 	}
 	ui.Info("")
 	ui.Title("Stage %s is ready!\n", stage.Name)
 	ui.Info("Endpoint: %s", stage.RestEndpoint())
+	if cdn := stage.CDNEndpoint(); cdn != "" {
+		ui.Info("CDN: %s", cdn)
+	}
+	if stage.Ephemeral() {
+		ui.Info("Expires: %s", formatExpiry(stage))
+	}
 	return true, nil
 }
 
@@ -258,27 +289,112 @@ func (s *Stage) destroyStage(stage *domain.Stage) error {
 }
 
 func (s *Stage) destroyRequest(stage *domain.Stage) error {
+	req := stageDestroyRequest(stage)
+	ni, err := nodeInvoker(stage.Node())
+	if err != nil {
+		return log.Wrap(err)
+	}
+	if err := ni.Do(DestroyHTTPMethod, &req, nil); err != nil {
+		return log.Wrap(err)
+	}
+	return nil
+}
+
+func stageDestroyRequest(stage *domain.Stage) dto.DestroyRequest {
 	node := stage.Node()
-	req := &dto.DestroyRequest{
+	return dto.DestroyRequest{
 		Bucket:                node.Bucket,
 		Region:                node.Region,
-		ProjectName:           s.project.Name,
+		ProjectName:           stage.Project().Name,
 		StageName:             stage.Name,
 		BucketPrefix:          stage.StateBucketPrefix(),
 		ResourceTags:          stage.ResourceTags(),
 		CleanupBucketPrefixes: stage.BucketPrefixes(),
 	}
-	ni, err := nodeInvoker(node)
+}
+
+// expiryRequest records stage expiry on the node, node destroys the stage
+// with the same request as the cli after it expires
+func (s *Stage) expiryRequest(stage *domain.Stage) error {
+	req := &dto.StageExpiry{
+		ExpiresAt: stage.Expiry.ExpiresAt,
+		Notify:    stage.Expiry.Notify,
+		Destroy:   stageDestroyRequest(stage),
+	}
+	ni, err := nodeInvoker(stage.Node())
 	if err != nil {
 		return log.Wrap(err)
 	}
-	if err := ni.Do(DestroyHTTPMethod, req, nil); err != nil {
+	if err := ni.Do(StageExpiryHTTPMethod, req, nil); err != nil {
+		return log.Wrap(err, "could not record stage %s expiry on the node", stage.Name)
+	}
+	return nil
+}
+
+// removeExpiryRequest removes stage expiry from the node
+func (s *Stage) removeExpiryRequest(stage *domain.Stage) error {
+	req := &dto.RemoveStageExpiryRequest{
+		ProjectName: stage.Project().Name,
+		StageName:   stage.Name,
+	}
+	ni, err := nodeInvoker(stage.Node())
+	if err != nil {
+		return log.Wrap(err)
+	}
+	if err := ni.Do(RemoveStageExpiryHTTPMethod, req, nil); err != nil {
+		return log.Wrap(err, "could not remove stage %s expiry from the node, node will destroy the stage after it expires", stage.Name)
+	}
+	return nil
+}
+
+// Extend pushes out expiry of the stage created with ttl.
+func (s *Stage) Extend() error {
+	stage := s.project.Stage(s.Stage)
+	if stage == nil {
+		return log.Wrapf("stage %s not found", s.Stage)
+	}
+	if stage.Expired() {
+		return log.Wrapf("stage %s has already expired", stage.Name)
+	}
+	if err := stage.Extend(s.TTL); err != nil {
+		return log.Wrap(err)
+	}
+	if err := s.expiryRequest(stage); err != nil {
+		return log.Wrap(err)
+	}
+	if err := s.store.Store(); err != nil {
 		return log.Wrap(err)
 	}
+	ui.Info("Stage %s expires %s", stage.Name, formatExpiry(stage))
 	return nil
 }
 
+func formatExpiry(stage *domain.Stage) string {
+	return fmt.Sprintf("%s (in %s)",
+		time.Unix(stage.Expiry.ExpiresAt, 0).Format(time.RFC822),
+		formatTTL(stage))
+}
+
+// formatTTL returns remaining ttl rounded to minutes, empty for the stages
+// without ttl
+func formatTTL(stage *domain.Stage) string {
+	if !stage.Ephemeral() {
+		return ""
+	}
+	if stage.Expired() {
+		return "expired"
+	}
+	d := stage.RemainingTTL().Round(time.Minute)
+	if d == 0 {
+		return "<1m"
+	}
+	return strings.TrimSuffix(d.String(), "0s")
+}
+
 func (s *Stage) List() error {
+	if err := s.pruneExpiredStages(); err != nil {
+		return log.Wrap(err)
+	}
 	if len(s.project.Stages) == 0 {
 		return log.Wrap(&domain.ProjectNoStagesError{})
 	}
@@ -288,12 +404,25 @@ func (s *Stage) List() error {
 		if ps.Default {
 			def = "*"
 		}
-		data = append(data, []string{def, ps.Name, ps.NodeName, ps.RestEndpoint()})
+		data = append(data, []string{def, ps.Name, ps.NodeName, ps.RestEndpoint(), formatTTL(ps)})
 	}
-	ShowTable([]string{"default", "name", "node", "endpoint"}, data)
+	ShowTable([]string{"default", "name", "node", "endpoint", "ttl"}, data)
 	return nil
 }
 
+// pruneExpiredStages removes from the workspace stages which node has
+// destroyed after they expired
+func (s *Stage) pruneExpiredStages() error {
+	removed := s.project.RemoveExpiredStages()
+	if len(removed) == 0 {
+		return nil
+	}
+	for _, name := range removed {
+		ui.Notice("stage %s has expired and was destroyed by the node, removing it from the workspace", name)
+	}
+	return s.store.Store()
+}
+
 func (s *Stage) Use() error {
 	stage := s.project.Stage(s.Stage)
 	if stage == nil {
@@ -305,3 +434,46 @@ func (s *Stage) Use() error {
 	}
 	return nil
 }
+
+func (s *Stage) RotateKeys() error {
+	stage := s.store.Stage(s.Stage)
+	if stage == nil {
+		return log.Wrapf("stage %s not found", s.Stage)
+	}
+	if err := stage.RotateKeys(); err != nil {
+		return log.Wrap(err)
+	}
+	d, err := NewDeployWithStage(s.store, stage)
+	if err != nil {
+		return log.Wrap(err)
+	}
+	title := fmt.Sprintf("Rotating keys for stage %s", stage.Name)
+	if err := d.DeployWithTitle(title); err != nil {
+		return log.Wrap(err)
+	}
+	ui.Info("Tokens signed with the previous key are accepted until %s.",
+		time.Unix(stage.Keys.Previous.ExpireAt, 0).Format(time.RFC822))
+	return nil
+}
+
+type StageTokenArgs struct {
+	Stage     string
+	ExpiresIn time.Duration
+	Subject   string
+	Claims    map[string]string
+	Functions []string
+}
+
+// StageToken prints access token for the stage private functions.
+func StageToken(a StageTokenArgs) error {
+	_, stage, err := newStoreWithStage(a.Stage)
+	if err != nil {
+		return log.Wrap(err)
+	}
+	t, err := stage.ScopedAuthToken(a.Subject, a.Functions, a.Claims, a.ExpiresIn)
+	if err != nil {
+		return log.Wrap(err)
+	}
+	ui.Info("%s", t)
+	return nil
+}
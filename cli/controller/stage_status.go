@@ -0,0 +1,299 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mantil-io/mantil/cli/log"
+	"github.com/mantil-io/mantil/cli/ui"
+	"github.com/mantil-io/mantil/domain"
+	"github.com/mantil-io/mantil/kit/aws"
+	"github.com/mantil-io/mantil/node/dto"
+)
+
+const (
+	DeployPlanHTTPMethod = "deploy/plan"
+	// timeout of the stage endpoints reachability check
+	endpointCheckTimeout = 10 * time.Second
+)
+
+type StageStatusArgs struct {
+	Stage string
+	// redeploy drifted resources from the stage state
+	Fix bool
+}
+
+// stageDrift is difference between the stage state and the deployed resource
+type stageDrift struct {
+	Resource string
+	Property string
+	Expected string
+	Deployed string
+}
+
+type stageStatus struct {
+	store  *domain.FileStore
+	stage  *domain.Stage
+	aws    *aws.AWS
+	drifts []stageDrift
+	// infrastructure differs and is fixed by applying the stage template
+	infrastructure bool
+	// functions which code differs from the deployment package
+	codeDrift []string
+	// functions which deployment package is missing from the node bucket
+	missingPackages []string
+}
+
+// StageStatus compares the stage state with the resources deployed in AWS.
+func StageStatus(a StageStatusArgs) error {
+	fs, stage, err := newStoreWithStage(a.Stage)
+	if err != nil {
+		return log.Wrap(err)
+	}
+	if stage.Endpoints == nil {
+		return log.Wrapf("stage %s is not deployed", stage.Name)
+	}
+	awsClient, err := awsClient(stage.Node(), stage)
+	if err != nil {
+		return log.Wrap(err)
+	}
+	s := &stageStatus{
+		store: fs,
+		stage: stage,
+		aws:   awsClient,
+	}
+	ui.Info("Checking stage %s...", stage.Name)
+	if err := s.checkFunctions(); err != nil {
+		return log.Wrap(err)
+	}
+	s.checkEndpoints()
+	if err := s.checkInfrastructure(); err != nil {
+		return log.Wrap(err)
+	}
+	if names := scheduledFunctions(stage); len(names) > 0 {
+		ui.Info("Schedules of functions %s are checked by the infrastructure plan.", strings.Join(names, ", "))
+	}
+	if len(s.drifts) == 0 {
+		ui.Info("")
+		ui.Title("Stage %s matches its state.\n", stage.Name)
+		return nil
+	}
+	ui.Info("")
+	s.show()
+	if !a.Fix {
+		ui.Info("")
+		ui.Info("Use --fix option to redeploy drifted resources.")
+		return nil
+	}
+	return s.fix()
+}
+
+func (s *stageStatus) add(drifts ...stageDrift) {
+	s.drifts = append(s.drifts, drifts...)
+}
+
+func (s *stageStatus) checkFunctions() error {
+	for _, f := range s.stage.Functions {
+		info, err := s.aws.Lambda().Info(f.LambdaName())
+		if errors.Is(err, aws.ErrNotFound) {
+			s.add(stageDrift{Resource: f.Name, Property: "function", Expected: "deployed", Deployed: "missing"})
+			s.infrastructure = true
+			continue
+		}
+		if err != nil {
+			return log.Wrap(err, "could not get function %s", f.Name)
+		}
+		if drifts := functionDrifts(f, info); len(drifts) > 0 {
+			s.add(drifts...)
+			s.infrastructure = true
+		}
+		sha, err := s.aws.S3().ObjectSHA256(s.stage.Node().Bucket, f.S3Key)
+		if errors.Is(err, aws.ErrNotFound) {
+			s.add(stageDrift{Resource: f.Name, Property: "package", Expected: f.S3Key, Deployed: "missing"})
+			s.missingPackages = append(s.missingPackages, f.Name)
+			continue
+		}
+		if err != nil {
+			return log.Wrap(err, "could not get deployment package of function %s", f.Name)
+		}
+		if sha != info.CodeSha256 {
+			s.add(stageDrift{Resource: f.Name, Property: "code", Expected: shortSha(sha), Deployed: shortSha(info.CodeSha256)})
+			s.codeDrift = append(s.codeDrift, f.Name)
+		}
+	}
+	return nil
+}
+
+// functionDrifts compares function configuration from the stage state with
+// the deployed function, function schedules are event rules in the stage
+// template so their drift is found by the infrastructure plan
+func functionDrifts(f *domain.Function, info *aws.LambdaInfo) []stageDrift {
+	var drifts []stageDrift
+	add := func(property, expected, deployed string) {
+		if expected == deployed {
+			return
+		}
+		drifts = append(drifts, stageDrift{Resource: f.Name, Property: property, Expected: expected, Deployed: deployed})
+	}
+	add("memory_size", fmt.Sprintf("%d", f.MemorySize), fmt.Sprintf("%d", info.MemorySize))
+	add("timeout", fmt.Sprintf("%d", f.Timeout), fmt.Sprintf("%d", info.Timeout))
+	add("tracing", f.TracingMode(), info.TracingMode)
+	// values are not shown, env can contain secrets
+	for _, k := range envKeys(f.Env, info.Env) {
+		ev, eok := f.Env[k]
+		dv, dok := info.Env[k]
+		switch {
+		case !dok:
+			add("env "+k, "set", "not set")
+		case !eok:
+			add("env "+k, "not set", "set")
+		case ev != dv:
+			add("env "+k, "set", "different value")
+		}
+	}
+	return drifts
+}
+
+// scheduledFunctions returns names of the stage functions with cron schedule
+func scheduledFunctions(stage *domain.Stage) []string {
+	var names []string
+	for _, f := range stage.Functions {
+		if f.Cron != "" {
+			names = append(names, f.Name)
+		}
+	}
+	return names
+}
+
+func envKeys(envs ...map[string]string) []string {
+	m := make(map[string]bool)
+	for _, env := range envs {
+		for k := range env {
+			m[k] = true
+		}
+	}
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func shortSha(sha string) string {
+	if len(sha) > HashCharacters {
+		return sha[:HashCharacters]
+	}
+	return sha
+}
+
+// checkEndpoints checks that the stage apis and custom domain respond,
+// any http response is fine
+func (s *stageStatus) checkEndpoints() {
+	endpoints := [][]string{
+		{"rest endpoint", s.stage.Endpoints.Rest},
+		{"ws endpoint", s.stage.Endpoints.Ws},
+	}
+	if s.stage.CustomDomain.DomainName != "" {
+		endpoints = append(endpoints, []string{"custom domain", s.stage.RestEndpoint()})
+	}
+	client := &http.Client{Timeout: endpointCheckTimeout}
+	for _, e := range endpoints {
+		if e[1] == "" {
+			continue
+		}
+		url := strings.Replace(e[1], "wss://", "https://", 1)
+		rsp, err := client.Get(url)
+		if err != nil {
+			s.add(stageDrift{Resource: "api", Property: e[0], Expected: e[1], Deployed: "unreachable"})
+			s.infrastructure = true
+			continue
+		}
+		rsp.Body.Close()
+	}
+}
+
+// checkInfrastructure runs terraform plan of the stage template on the node
+func (s *stageStatus) checkInfrastructure() error {
+	ni, err := nodeInvoker(s.stage.Node())
+	if err != nil {
+		return log.Wrap(err)
+	}
+	d := &Deploy{store: s.store, stage: s.stage}
+	req := dto.DeployRequest{
+		ProjectName:   s.stage.Project().Name,
+		StageName:     s.stage.Name,
+		NodeBucket:    s.stage.Node().Bucket,
+		StageTemplate: d.stageTemplate(),
+	}
+	var rsp dto.PlanResponse
+	if err := ni.Do(DeployPlanHTTPMethod, req, &rsp); err != nil {
+		return log.Wrap(err, "could not plan stage infrastructure")
+	}
+	for _, c := range rsp.Changes {
+		s.add(stageDrift{Resource: c.Address, Property: "infrastructure", Expected: "", Deployed: "will be " + c.Action})
+		s.infrastructure = true
+	}
+	return nil
+}
+
+func (s *stageStatus) show() {
+	var data [][]string
+	for _, d := range s.drifts {
+		data = append(data, []string{d.Resource, d.Property, d.Expected, d.Deployed})
+	}
+	ShowTable([]string{"resource", "property", "expected", "deployed"}, data)
+}
+
+// fix applies the stage template and updates code of the drifted functions
+// from their deployment packages, functions without package are rebuilt on
+// the next deploy so the stage stays drifted until then
+func (s *stageStatus) fix() error {
+	ni, err := nodeInvoker(s.stage.Node())
+	if err != nil {
+		return log.Wrap(err)
+	}
+	d := &Deploy{store: s.store, stage: s.stage}
+	req := dto.DeployRequest{
+		ProjectName: s.stage.Project().Name,
+		StageName:   s.stage.Name,
+		NodeBucket:  s.stage.Node().Bucket,
+	}
+	ui.Info("")
+	if s.infrastructure {
+		ui.Title("Setting up AWS infrastructure...\n")
+		req.StageTemplate = d.stageTemplate()
+		var rsp dto.DeployResponse
+		if err := ni.Do(DeployHTTPMethod, req, &rsp); err != nil {
+			return log.Wrap(err)
+		}
+		d.updateStage(rsp)
+		req.StageTemplate = nil
+	}
+	if len(s.codeDrift) > 0 {
+		ui.Info("Updating functions code...")
+		for _, n := range s.codeDrift {
+			req.FunctionsForUpdate = append(req.FunctionsForUpdate, d.workspaceFunction2dto(*s.stage.FindFunction(n)))
+		}
+		if err := ni.Do(DeployHTTPMethod, req, nil); err != nil {
+			return log.Wrap(err)
+		}
+	}
+	for _, n := range s.missingPackages {
+		s.stage.FindFunction(n).ResetHash()
+	}
+	if err := s.store.Store(); err != nil {
+		return log.Wrap(err)
+	}
+	if len(s.missingPackages) > 0 {
+		return log.Wrapf("stage %s is partially fixed, deployment packages of functions %s are missing, run 'mantil deploy --stage %s' to build and upload them",
+			s.stage.Name, strings.Join(s.missingPackages, ", "), s.stage.Name)
+	}
+	ui.Info("")
+	ui.Title("Stage %s fixed!\n", s.stage.Name)
+	return nil
+}
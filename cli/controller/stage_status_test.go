@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/mantil-io/mantil/domain"
+	"github.com/mantil-io/mantil/kit/aws"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFunctionDrifts(t *testing.T) {
+	f := &domain.Function{
+		Name: "ping",
+		FunctionConfiguration: domain.FunctionConfiguration{
+			MemorySize: 128,
+			Timeout:    900,
+			Tracing:    domain.TracingActive,
+			Env: map[string]string{
+				"key1": "value1",
+				"key2": "value2",
+				"key3": "value3",
+			},
+		},
+	}
+	info := &aws.LambdaInfo{
+		MemorySize:  128,
+		Timeout:     900,
+		TracingMode: "Active",
+		Env: map[string]string{
+			"key1": "value1",
+			"key2": "value2",
+			"key3": "value3",
+		},
+	}
+	require.Empty(t, functionDrifts(f, info))
+
+	info.MemorySize = 256
+	info.TracingMode = "PassThrough"
+	info.Env = map[string]string{
+		"key1": "value1",
+		"key2": "changed",
+		"key4": "value4",
+	}
+	require.Equal(t, []stageDrift{
+		{Resource: "ping", Property: "memory_size", Expected: "128", Deployed: "256"},
+		{Resource: "ping", Property: "tracing", Expected: "Active", Deployed: "PassThrough"},
+		{Resource: "ping", Property: "env key2", Expected: "set", Deployed: "different value"},
+		{Resource: "ping", Property: "env key3", Expected: "set", Deployed: "not set"},
+		{Resource: "ping", Property: "env key4", Expected: "not set", Deployed: "set"},
+	}, functionDrifts(f, info))
+}
+
+func TestShortSha(t *testing.T) {
+	require.Equal(t, "abcdefgh", shortSha("abcdefghijklmn"))
+	require.Equal(t, "abc", shortSha("abc"))
+}
+
+func TestScheduledFunctions(t *testing.T) {
+	stage := &domain.Stage{
+		Functions: []*domain.Function{
+			{Name: "ping", FunctionConfiguration: domain.FunctionConfiguration{Cron: "* * * * ? *"}},
+			{Name: "todo"},
+			{Name: "report", FunctionConfiguration: domain.FunctionConfiguration{Cron: "0 8 * * ? *"}},
+		},
+	}
+	require.Equal(t, []string{"ping", "report"}, scheduledFunctions(stage))
+	require.Empty(t, scheduledFunctions(&domain.Stage{}))
+}
@@ -0,0 +1,383 @@
+package controller
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+	"github.com/mantil-io/mantil/cli/log"
+	"github.com/mantil-io/mantil/cli/ui"
+	"github.com/mantil-io/mantil/domain"
+	"github.com/mantil-io/mantil/kit/shell"
+	"github.com/mantil-io/mantil/kit/term"
+	"gopkg.in/yaml.v2"
+)
+
+// TemplateManifestFile is optional file in the root of the project template.
+// It declares template parameters, files which are rendered with those
+// parameters and hooks which are run after the project is created.
+const TemplateManifestFile = "mantil.template.yml"
+
+type TemplateManifest struct {
+	Description string              `yaml:"description,omitempty"`
+	Parameters  []TemplateParameter `yaml:"parameters,omitempty"`
+	// glob patterns of the files rendered through text/template
+	// pattern without path separator is matched against file name
+	Render []string      `yaml:"render,omitempty"`
+	Hooks  TemplateHooks `yaml:"hooks,omitempty"`
+}
+
+type TemplateParameter struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+	Default     string `yaml:"default,omitempty"`
+	Required    bool   `yaml:"required,omitempty"`
+}
+
+type TemplateHooks struct {
+	PostCreate []string `yaml:"post_create,omitempty"`
+}
+
+// data available in rendered template files
+type templateData struct {
+	Name       string
+	ModuleName string
+	Params     map[string]string
+}
+
+func readTemplateManifest(projectPath string) (*TemplateManifest, error) {
+	buf, err := ioutil.ReadFile(filepath.Join(projectPath, TemplateManifestFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, log.Wrap(err)
+	}
+	var m TemplateManifest
+	if err := yaml.Unmarshal(buf, &m); err != nil {
+		return nil, log.Wrap(err, "could not parse template manifest %s", TemplateManifestFile)
+	}
+	for _, p := range m.Parameters {
+		if p.Name == "" {
+			return nil, log.Wrapf("template manifest %s has parameter without name", TemplateManifestFile)
+		}
+	}
+	return &m, nil
+}
+
+// resolveParams merges values set from the command line with the parameter
+// defaults. Missing values are prompted for when running in terminal.
+func (m *TemplateManifest) resolveParams(set map[string]string, prompt func(TemplateParameter) (string, error)) (map[string]string, error) {
+	params := make(map[string]string)
+	for _, p := range m.Parameters {
+		if val, ok := set[p.Name]; ok {
+			params[p.Name] = val
+			continue
+		}
+		val := p.Default
+		if prompt != nil {
+			v, err := prompt(p)
+			if err != nil {
+				return nil, err
+			}
+			val = v
+		}
+		if val == "" && p.Required {
+			return nil, log.Wrapf("template parameter %s is required, set it with --set %s=value", p.Name, p.Name)
+		}
+		params[p.Name] = val
+	}
+	for k := range set {
+		if !m.hasParameter(k) {
+			return nil, log.Wrapf("unknown template parameter %s", k)
+		}
+	}
+	return params, nil
+}
+
+func (m *TemplateManifest) hasParameter(name string) bool {
+	for _, p := range m.Parameters {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *TemplateManifest) shouldRender(relPath string) bool {
+	relPath = filepath.ToSlash(relPath)
+	for _, pattern := range m.Render {
+		name := relPath
+		if !strings.Contains(pattern, "/") {
+			name = filepath.Base(relPath)
+		}
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *TemplateManifest) render(projectPath string, data templateData) error {
+	if len(m.Render) == 0 {
+		return nil
+	}
+	return filepath.Walk(projectPath, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return log.Wrap(err)
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(projectPath, path)
+		if err != nil {
+			return log.Wrap(err)
+		}
+		if !m.shouldRender(rel) {
+			return nil
+		}
+		buf, err := ioutil.ReadFile(path)
+		if err != nil {
+			return log.Wrap(err)
+		}
+		out, err := renderTemplate(string(buf), data)
+		if err != nil {
+			return log.Wrap(err, "could not render template file %s", rel)
+		}
+		return log.Wrap(ioutil.WriteFile(path, out, info.Mode()))
+	})
+}
+
+// runHooks runs post create hooks in the project directory. Hooks of the
+// templates which are not local are run only after confirmation.
+func (m *TemplateManifest) runHooks(projectPath string, confirmed bool) error {
+	hooks := m.Hooks.PostCreate
+	if len(hooks) == 0 {
+		return nil
+	}
+	if !confirmed && !confirmHooks(hooks) {
+		ui.Notice("Post create hooks skipped, use --yes option to run them or run them manually in %s.", projectPath)
+		return nil
+	}
+	for _, hook := range hooks {
+		ui.Info("Running %s...", hook)
+		if err := shell.Exec(shell.ExecOptions{
+			Args:    hookCommand(hook),
+			WorkDir: projectPath,
+			Logger:  ui.Info,
+		}); err != nil {
+			return log.Wrap(err, "post create hook %s failed", hook)
+		}
+	}
+	return nil
+}
+
+// confirmHooks shows hooks and asks whether to run them, hooks are not run
+// without terminal
+func confirmHooks(hooks []string) bool {
+	ui.Info("")
+	ui.Title("Template runs commands after the project is created:\n")
+	for _, hook := range hooks {
+		ui.Info("  %s", hook)
+	}
+	if !term.IsTerminal() {
+		return false
+	}
+	prompt := promptui.Prompt{
+		Label:     "Run these commands",
+		IsConfirm: true,
+	}
+	_, err := prompt.Run()
+	return err == nil
+}
+
+// hookCommand runs hook through the shell so quoting works as in terminal
+func hookCommand(hook string) []string {
+	if runtime.GOOS == "windows" {
+		return []string{"cmd", "/C", hook}
+	}
+	return []string{"sh", "-c", hook}
+}
+
+// applyTemplateManifest renders template files and runs post create hooks if
+// the project template has manifest. Manifest is removed from the project.
+// Hooks are run without confirmation if confirmHooks is false.
+func applyTemplateManifest(projectPath string, data templateData, set map[string]string, confirmHooks bool) error {
+	m, err := readTemplateManifest(projectPath)
+	if err != nil {
+		return log.Wrap(err)
+	}
+	if m == nil {
+		if len(set) > 0 {
+			return log.Wrapf("template has no %s, parameters can't be set", TemplateManifestFile)
+		}
+		return nil
+	}
+	var prompt func(TemplateParameter) (string, error)
+	if term.IsTerminal() {
+		prompt = promptTemplateParameter
+	}
+	params, err := m.resolveParams(set, prompt)
+	if err != nil {
+		return log.Wrap(err)
+	}
+	data.Params = params
+	if err := m.render(projectPath, data); err != nil {
+		return log.Wrap(err)
+	}
+	if err := os.Remove(filepath.Join(projectPath, TemplateManifestFile)); err != nil {
+		return log.Wrap(err)
+	}
+	return m.runHooks(projectPath, !confirmHooks)
+}
+
+func promptTemplateParameter(p TemplateParameter) (string, error) {
+	label := p.Name
+	if p.Description != "" {
+		label = fmt.Sprintf("%s (%s)", p.Name, p.Description)
+	}
+	prompt := promptui.Prompt{
+		Label:   label,
+		Default: p.Default,
+	}
+	return prompt.Run()
+}
+
+// copyTemplateDir copies local template directory to the project path
+// skipping git metadata
+func copyTemplateDir(src, dst string) error {
+	if _, err := os.Stat(dst); err == nil {
+		return log.Wrapf("path %s already exists", dst)
+	}
+	return filepath.Walk(src, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return log.Wrap(err)
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return log.Wrap(err)
+		}
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return log.Wrap(os.MkdirAll(target, info.Mode()|0700))
+		}
+		return copyFile(path, target, info.Mode())
+	})
+}
+
+func copyFile(src, dst string, mode fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return log.Wrap(err)
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return log.Wrap(err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return log.Wrap(err)
+	}
+	return log.Wrap(out.Close())
+}
+
+type TemplateAddArgs struct {
+	Name        string
+	Source      string
+	Description string
+}
+
+func TemplateAdd(a TemplateAddArgs) error {
+	source, err := templateSource(a.Source)
+	if err != nil {
+		return log.Wrap(err)
+	}
+	fs, err := newStore()
+	if err != nil {
+		return log.Wrap(err)
+	}
+	if _, ok := TemplateRepos[a.Name]; ok {
+		return log.Wrapf("template %s is built-in template", a.Name)
+	}
+	if err := fs.Workspace().AddTemplate(a.Name, source, a.Description); err != nil {
+		return log.Wrap(err)
+	}
+	if err := fs.Store(); err != nil {
+		return log.Wrap(err)
+	}
+	ui.Info("Template %s added, use it with `mantil new <project> --from %s`", a.Name, a.Name)
+	return nil
+}
+
+// templateSource validates that source is git repository URL or existing
+// local directory, local paths are stored as absolute
+func templateSource(source string) (string, error) {
+	if isExternalRepo(source) {
+		return source, nil
+	}
+	path, err := filepath.Abs(source)
+	if err != nil {
+		return "", log.Wrap(err)
+	}
+	fi, err := os.Stat(path)
+	if err != nil || !fi.IsDir() {
+		return "", log.Wrapf("template source %s is neither git repository URL nor local directory", source)
+	}
+	return path, nil
+}
+
+func TemplateList() error {
+	fs, err := newStore()
+	if err != nil {
+		return log.Wrap(err)
+	}
+	var data [][]string
+	for _, t := range fs.Workspace().Templates {
+		data = append(data, []string{t.Name, t.Source, t.Description})
+	}
+	var builtin []string
+	for name := range TemplateRepos {
+		builtin = append(builtin, name)
+	}
+	sort.Strings(builtin)
+	for _, name := range builtin {
+		data = append(data, []string{name, TemplateRepos[name], "built-in"})
+	}
+	ShowTable([]string{"name", "source", "description"}, data)
+	return nil
+}
+
+type TemplateRemoveArgs struct {
+	Name string
+}
+
+func TemplateRemove(a TemplateRemoveArgs) error {
+	fs, err := newStore()
+	if err != nil {
+		return log.Wrap(err)
+	}
+	if err := fs.Workspace().RemoveTemplate(a.Name); err != nil {
+		return log.Wrap(err)
+	}
+	return log.Wrap(fs.Store())
+}
+
+// workspaceTemplate finds template in the local template registry
+func workspaceTemplate(name string) *domain.WorkspaceTemplate {
+	fs, err := newStore()
+	if err != nil {
+		return nil
+	}
+	return fs.Workspace().Template(name)
+}
@@ -0,0 +1,15 @@
+package ping
+
+import "context"
+
+type Ping struct{}
+
+func New() Ping {
+	return Ping{}
+}
+
+func (p *Ping) Close() error {
+	return nil
+}
+
+func (p *Ping) Shutdown(ctx context.Context) {}
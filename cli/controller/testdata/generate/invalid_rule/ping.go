@@ -0,0 +1,13 @@
+package ping
+
+type Ping struct{}
+
+func New() *Ping {
+	return &Ping{}
+}
+
+type Request struct {
+	Active bool `json:"active" validate:"required"`
+}
+
+func (p *Ping) Default(req Request) {}
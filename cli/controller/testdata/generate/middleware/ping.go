@@ -0,0 +1,60 @@
+package ping
+
+import (
+	"context"
+	"time"
+)
+
+type Ping struct{}
+
+type Middleware func(ctx context.Context, method string, req interface{}, next func(context.Context) (interface{}, error)) (interface{}, error)
+
+func New() *Ping {
+	return &Ping{}
+}
+
+func (p *Ping) Middleware() []Middleware {
+	return []Middleware{
+		func(ctx context.Context, method string, req interface{}, next func(context.Context) (interface{}, error)) (interface{}, error) {
+			return next(ctx)
+		},
+	}
+}
+
+type Color string
+
+type Address struct {
+	City string `json:"city" validate:"required"`
+}
+
+type HelloRequest struct {
+	Name    string            `json:"name" validate:"required,min=2,max=20"`
+	Email   string            `json:"email" validate:"omitempty,email"`
+	Age     int               `json:"age" validate:"min=18"`
+	Color   Color             `json:"color" validate:"oneof=red green"`
+	Tags    []string          `json:"tags" validate:"max=3"`
+	Address *Address          `json:"address" validate:"required"`
+	Others  []Address         `json:"others"`
+	Meta    map[string]string `json:"meta"`
+	At      time.Time         `json:"at"`
+}
+
+type HelloResponse struct {
+	Greeting string `json:"greeting"`
+}
+
+func (p *Ping) Default(ctx context.Context) string {
+	return "pong"
+}
+
+func (p *Ping) Hello(ctx context.Context, req *HelloRequest) (*HelloResponse, error) {
+	return &HelloResponse{Greeting: "Hello, " + req.Name}, nil
+}
+
+func (p *Ping) Touch(req Address) error {
+	return nil
+}
+
+func (p *Ping) Noop() {}
+
+func (p *Ping) Close() {}
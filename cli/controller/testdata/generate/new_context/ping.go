@@ -0,0 +1,9 @@
+package ping
+
+import "context"
+
+type Ping struct{}
+
+func New(ctx context.Context) (*Ping, error) {
+	return &Ping{}, nil
+}
@@ -0,0 +1,20 @@
+package ping
+
+import "context"
+
+type Ping struct{}
+
+func New() *Ping {
+	return &Ping{}
+}
+
+// Default returns pong
+func (p *Ping) Default(ctx context.Context) string {
+	return "pong"
+}
+
+func (p *Ping) Hello(ctx context.Context, name string) (string, error) {
+	return "Hello, " + name, nil
+}
+
+func (p *Ping) helper() {}
@@ -0,0 +1,55 @@
+package todo
+
+import (
+	"context"
+	"time"
+)
+
+type Todo struct{}
+
+func New() *Todo {
+	return &Todo{}
+}
+
+// Item is a single todo list entry
+type Item struct {
+	ID      int64     `json:"id,string"`
+	Title   string    `json:"title"`
+	Done    bool      `json:"done,omitempty"`
+	Due     *string   `json:"due"`
+	Created time.Time `json:"created"`
+	Tags    []string  `json:"tags,omitempty"`
+	// nested items
+	Children []Item            `json:"children,omitempty"`
+	Meta     map[string]string `json:"meta,omitempty"`
+	Secret   string            `json:"-"`
+	internal string
+}
+
+type Audit struct {
+	UpdatedBy string `json:"updated_by"`
+}
+
+type AddRequest struct {
+	Item
+	Audit `json:"audit"`
+}
+
+type AddResponse struct {
+	Item *Item `json:"item"`
+}
+
+// Add creates new todo item
+//
+// Item id is assigned by the api.
+func (t *Todo) Add(ctx context.Context, req AddRequest) (*AddResponse, error) {
+	return nil, nil
+}
+
+func (t Todo) Clear() error {
+	return nil
+}
+
+func (t *Todo) Invalid(a, b string) error {
+	return nil
+}
@@ -0,0 +1,7 @@
+package ping
+
+type Ping struct{}
+
+func New() (*Ping, int) {
+	return &Ping{}, 0
+}
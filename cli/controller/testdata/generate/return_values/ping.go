@@ -2,6 +2,6 @@ package ping
 
 type Ping struct{}
 
-func New() (*Ping, error) {
+func New() (*Ping, string, error) {
 	return Ping{}
 }
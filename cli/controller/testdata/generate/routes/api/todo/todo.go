@@ -0,0 +1,55 @@
+package todo
+
+import (
+	"context"
+)
+
+type Todo struct{}
+
+type ID int64
+
+type Item struct {
+	ID    ID     `json:"id"`
+	Title string `json:"title" validate:"required"`
+	Done  bool   `json:"done"`
+}
+
+type GetRequest struct {
+	ID ID `json:"id"`
+}
+
+type UpdateRequest struct {
+	Item
+	Version uint32 `path:"version"`
+}
+
+func New() *Todo {
+	return &Todo{}
+}
+
+func (t *Todo) Default(ctx context.Context) string {
+	return "todo"
+}
+
+// Get returns todo item.
+//
+//mantil:route GET /{id}
+func (t *Todo) Get(ctx context.Context, req *GetRequest) (*Item, error) {
+	return &Item{ID: req.ID}, nil
+}
+
+//mantil:route PUT /{id}/{version}
+//mantil:private
+func (t *Todo) Update(ctx context.Context, req *UpdateRequest) error {
+	return nil
+}
+
+//mantil:public
+func (t *Todo) List(ctx context.Context) ([]Item, error) {
+	return nil, nil
+}
+
+//mantil:route DELETE /{id}
+func (t *Todo) Delete(req GetRequest) error {
+	return nil
+}
@@ -0,0 +1,22 @@
+package ping
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+)
+
+type Ping struct{}
+
+func New() *Ping {
+	return &Ping{}
+}
+
+func (p *Ping) Default(ctx context.Context) string {
+	return "pong"
+}
+
+// Shutdown writes file to show that the hook was called
+func (p *Ping) Shutdown(ctx context.Context) error {
+	return ioutil.WriteFile(os.Getenv("PING_SHUTDOWN_FILE"), []byte("shutdown"), 0644)
+}
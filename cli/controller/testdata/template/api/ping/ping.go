@@ -0,0 +1,8 @@
+package ping
+
+// Owner: {{ .Params.owner }}
+type Ping struct{}
+
+func New() *Ping {
+	return &Ping{}
+}
@@ -0,0 +1,11 @@
+package main
+
+import (
+	"service/api/ping"
+)
+
+var service = "service"
+
+func main() {
+	_ = ping.New()
+}
@@ -59,7 +59,18 @@ func Watch(a WatchArgs) error {
 
 	// add separator at the end so dirs with prefix BuildDir are not matched
 	buildDirPath := filepath.Join(fs.ProjectRoot(), BuildDir) + string(filepath.Separator)
-	return w.run(fs.ProjectRoot(), []string{buildDirPath})
+	return w.run(watchPaths(fs.ProjectRoot(), fs.ApiDir()), []string{buildDirPath})
+}
+
+// watchPaths returns project root and api dir if it is placed outside of the
+// project root with the api_dir option
+func watchPaths(projectRoot, apiDir string) []string {
+	paths := []string{projectRoot}
+	rel, err := filepath.Rel(projectRoot, apiDir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		paths = append(paths, apiDir)
+	}
+	return paths
 }
 
 type watch struct {
@@ -109,7 +120,7 @@ func (w *watch) onChange() {
 	}
 }
 
-func (w *watch) run(path string, ignoredDirs []string) error {
+func (w *watch) run(paths []string, ignoredDirs []string) error {
 	wr := watcher.New()
 	wr.SetMaxEvents(1)
 	wr.FilterOps(watcher.Write, watcher.Create, watcher.Remove)
@@ -140,7 +151,7 @@ func (w *watch) run(path string, ignoredDirs []string) error {
 				}
 				w.onChange()
 				ui.Info("")
-				ui.Info("Watching changes in %s", path)
+				ui.Info("Watching changes in %s", strings.Join(paths, ", "))
 			case err := <-wr.Error:
 				ui.Error(err)
 			case <-wr.Closed:
@@ -151,11 +162,13 @@ func (w *watch) run(path string, ignoredDirs []string) error {
 		}
 	}()
 
-	if err := wr.AddRecursive(path); err != nil {
-		return log.Wrap(err)
+	for _, path := range paths {
+		if err := wr.AddRecursive(path); err != nil {
+			return log.Wrap(err)
+		}
 	}
 	ui.Info("")
-	ui.Info("Watching changes in %s", path)
+	ui.Info("Watching changes in %s", strings.Join(paths, ", "))
 	if err := wr.Start(1 * time.Second); err != nil {
 		return log.Wrap(err)
 	}
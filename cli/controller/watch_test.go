@@ -0,0 +1,19 @@
+package controller
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchPaths(t *testing.T) {
+	root := filepath.Join("repo", "services", "b")
+	require.Equal(t, []string{root}, watchPaths(root, filepath.Join(root, "api")))
+
+	apiDir := filepath.Clean(filepath.Join(root, "../../apis/b"))
+	require.Equal(t, []string{root, apiDir}, watchPaths(root, apiDir))
+
+	// sibling dir with the same prefix is outside of the root
+	require.Equal(t, []string{root, root + "-api"}, watchPaths(root, root+"-api"))
+}
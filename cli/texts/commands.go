@@ -63,8 +63,14 @@ var AwsUpgrade = Command{
 	Long: `Upgrades Mantil node on AWS account
 
 Command will upgrade node on your AWS account to reflect the current version of the CLI.
+Use --to option to upgrade or downgrade the node to another released version.
 You must provide credentials for Mantil to access your AWS account.
 
+Before the upgrade command checks that the target version exists and, on
+downgrade, that stages of the current project were not deployed with a newer
+version. If the upgrade fails node is rolled back to the previous version.
+Upgrade interrupted before it finished is rolled back on the next upgrade.
+
 There is --dry-run option which will show you what credentials will be used
 and what account will be managed by command.`,
 	Arguments: `
@@ -94,6 +100,41 @@ This behaviour can be disabled using the --yes option.`,
 	Examples: setupExamples("uninstall"),
 }
 
+var AwsRotateKeys = Command{
+	Short: "Rotates keys used for signing node access tokens",
+	Long: `Rotates keys used for signing node access tokens
+
+Command will create a new key pair for the node and update node infrastructure with it.
+Tokens signed with the previous key are accepted for 7 days after rotation.
+You must provide credentials for Mantil to access your AWS account.`,
+	Arguments: `
+  [node-name]  Mantil node name.
+               If not provided default name dev will be used.`,
+	Examples: setupExamples("rotate-keys"),
+}
+
+var AwsGC = Command{
+	Short: "Deletes orphaned resources of the node stages",
+	Long: `Deletes orphaned resources of the node stages
+
+Failed deploy or destroy can leave stage resources in the AWS account.
+Command will find resources tagged with the node which belong to the stages
+not known to the workspace nor the node and delete them.
+Lambda functions, log groups, S3 buckets, DynamoDB tables, SSM parameters
+and CloudWatch alarms are deleted. Other resources are only listed so they
+can be deleted manually.
+You must provide credentials for Mantil to access your AWS account.
+
+Use --dry-run option to only list orphaned resources.
+
+By default you will be asked to confirm the deletion.
+This behaviour can be disabled using the --yes option.`,
+	Arguments: `
+  [node-name]  Mantil node name.
+               If not provided default name dev will be used.`,
+	Examples: setupExamples("gc"),
+}
+
 var AwsNodes = Command{
 	Short: "Shows Mantil AWS nodes",
 }
@@ -170,6 +211,49 @@ If the --tail option is set the process will keep running and polling for new lo
   <api>      Name of the API. Your APIs are in /api folder.`,
 }
 
+var Metrics = Command{
+	Short: "Shows metrics of the stage functions",
+	Long: `Shows metrics of the stage functions
+
+Shows invocations, errors, throttles, duration percentiles and maximum concurrent
+executions for each function in the stage together with the invocations trend.
+For the whole stage requests, 4xx and 5xx responses and latency of the stage API are also shown.
+
+Metrics are fetched from CloudWatch for the time range set with the --since option.
+Use the --json option to get metrics in format suitable for dashboards and scripts.`,
+	Arguments: `
+  [function]  Name of the function, if omitted all stage functions are shown.`,
+	Examples: `
+  ==> metrics of all functions for the last hour:
+  $ mantil metrics
+
+  ==> metrics of the ping function for the last day in JSON format:
+  $ mantil metrics ping --since 24h --json
+`,
+}
+
+var Cost = Command{
+	Short: "Estimates cost of the stage",
+	Long: `Estimates cost of the stage
+
+Estimates Lambda, API Gateway, DynamoDB, S3 and CloudWatch Logs cost of the stage
+for the number of days set with the --days option. Estimate is calculated from
+the CloudWatch metrics of the stage resources, memory size of the functions and
+on-demand prices of the node region. Free tier and data transfer are not included.
+Functions are listed by their cost, which includes requests, duration and logs.
+
+With the --actual option the actual cost of the stage is also fetched from AWS
+Cost Explorer. It is available only when the MANTIL_PROJECT and MANTIL_STAGE tags are activated
+as cost allocation tags in the AWS billing console. Each Cost Explorer request is charged by AWS.`,
+	Examples: `
+  ==> estimated cost of the default stage for the last 30 days:
+  $ mantil cost
+
+  ==> estimated and actual cost of the production stage for the last week:
+  $ mantil cost --stage production --days 7 --actual
+`,
+}
+
 var New = Command{
 	Short: "Creates a new Mantil project",
 	Long: `Creates a new Mantil project
@@ -187,10 +271,18 @@ mongo-atlas         - https://github.com/mantil-io/example-mongo-atlas
 presigned-s3-upload - https://github.com/mantil-io/template-presigned-s3-upload
 ngs-chat            - https://github.com/mantil-io/example-ngs-chat
 
+The source can also be a path to the local directory or the name of a template
+added to the local template registry with 'mantil template add'.
+
 If no source is provided it will default to the template "ping".
 
 By default, the Go module name of the initialized project will be the project name.
-This can be changed by setting the --module-name option.`,
+This can be changed by setting the --module-name option.
+
+Templates can declare parameters, rendered files and post create hooks in the
+mantil.template.yml file. Parameters are prompted for or set with the --set option.
+Post create hooks of the remote templates are shown and run only after
+confirmation, use --yes option to run them without it.`,
 	NextSteps: `
 * It's time to start developing in the cloud. Run 'mantil stage new' to
 create your first development environment or check the documentation at
@@ -207,9 +299,44 @@ https://github.com/mantil-io/docs for more details.
 
   ==> new project from any available template:
   $ mantil new my-project --from https://github.com/mantil-io/template-excuses
+
+  ==> new project from the registered template with parameters:
+  $ mantil new my-project --from our-service --set owner=platform
+`,
+}
+
+var Template = Command{
+	Short: "Manage local project templates registry",
+	Long: `Manage local project templates registry
+
+Templates added to the registry can be used by name as source for 'mantil new'.
+Template source can be git repository URL or path to the local directory.`,
+}
+
+var TemplateAdd = Command{
+	Short: "Adds template to the local registry",
+	Arguments: `
+  <name>    Name of the template.
+  <source>  Git repository URL or path to the local directory.`,
+	Examples: `
+  ==> add template from the local directory:
+  $ mantil template add our-service ./templates/service
+
+  ==> add template from the company git repository:
+  $ mantil template add our-worker https://git.example.com/templates/worker
 `,
 }
 
+var TemplateList = Command{
+	Short: "Lists registered and built-in templates",
+}
+
+var TemplateRemove = Command{
+	Short: "Removes template from the local registry",
+	Arguments: `
+  <name>  Name of the template.`,
+}
+
 var Test = Command{
 	Short: "Runs project tests",
 	Long: `Runs project tests
@@ -249,7 +376,12 @@ This command will create a new stage with the given name.
 If the name is left empty it will default to "dev".
 
 If only one node is set up, the stage will be deployed to that node by default.
-Otherwise, you will be asked to pick a node. The node can also be specified via the --node option.`,
+Otherwise, you will be asked to pick a node. The node can also be specified via the --node option.
+
+Stages which are needed only for a limited time, like the stage of a pull request,
+can be created with the --ttl option. The node destroys such a stage after it expires.
+Webhook set with the --notify option is notified when the expired stage is destroyed.
+Use 'mantil stage extend' to push the expiry out.`,
 	NextSteps: `
 * Try 'mantil invoke' to see your fully functional Mantil serverless application in action.
 `,
@@ -272,6 +404,10 @@ This behavior can be disabled using the --yes option.`,
 
 var StageList = Command{
 	Short: "Lists stages in project",
+	Long: `Lists stages in project
+
+Stages created with the --ttl option show remaining ttl. Stages which have expired
+are destroyed by the node and removed from the workspace by this command.`,
 }
 
 var StageUse = Command{
@@ -280,6 +416,64 @@ var StageUse = Command{
   <stage>  Name of the stage which will be default.`,
 }
 
+var StageExtend = Command{
+	Short: "Extends ttl of the stage",
+	Long: `Extends ttl of the stage
+
+Pushes out expiry of the stage created with the --ttl option by the duration
+set with the --ttl option of this command. Remaining ttl of the stages is shown by 'mantil stage list'.`,
+	Arguments: `
+  <stage>  Name of the stage to extend.`,
+}
+
+var StageStatus = Command{
+	Short: "Compares stage state with the deployed resources",
+	Long: `Compares stage state with the deployed resources
+
+Checks that the code, memory size, timeout, tracing and environment variables
+of the deployed Lambda functions match the stage state, that the stage
+endpoints and custom domain respond and runs a terraform plan of the stage
+infrastructure on the node to find resources changed outside of Mantil.
+
+With the --fix option drifted resources are redeployed from the stage state.
+Local changes of the project are not deployed.`,
+	Arguments: `
+  [stage]  Name of the stage to check.
+           If not provided default stage will be used.`,
+}
+
+var StageRotateKeys = Command{
+	Short: "Rotates keys used for signing stage access tokens",
+	Long: `Rotates keys used for signing stage access tokens
+
+Private functions are authorized with tokens signed by the stage private key.
+This command creates a new key pair and deploys it to the stage authorizer.
+Tokens signed with the previous key are accepted for 7 days after rotation.`,
+	Arguments: `
+  [stage]  Name of the stage for which keys will be rotated.
+           If not provided default stage will be used.`,
+}
+
+var StageToken = Command{
+	Short: "Creates access token for stage private functions",
+	Long: `Creates access token for stage private functions
+
+Token is signed with the stage private key and can be handed out to clients of the private functions.
+It is sent in the Authorization header of each request.
+
+Token can be restricted to the list of functions with the --function option.
+Subject and custom claims are available to the function through the claims in request context.`,
+	Arguments: `
+  [stage]  Name of the stage for which token will be created.
+           If not provided default stage will be used.`,
+	Examples: `
+  ==> token valid for 30 days which can call only ping function
+  $ mantil stage token --expires-in 720h --subject partner --function ping
+
+  ==> token with custom claims
+  $ mantil stage token --subject partner --claim tenant=acme --claim plan=basic`,
+}
+
 var Generate = Command{
 	Short: "Automatically generates code in the project",
 }
@@ -294,12 +488,85 @@ An API is a lambda function with at least one (default) request/response method.
 Optionally, you can define additional methods using the --methods option. Each method will have a separate
 entrypoint and request/response structures.
 
+API initialization which can fail should be done in the New function. New can accept context and return
+an error, for example New(ctx context.Context) (*Ping, error). Resources opened in New can be released
+in the Close() or Shutdown(ctx context.Context) method, they are called when Lambda shuts down the function.
+
+API struct can define Middleware() method which returns functions with signature
+func(ctx context.Context, method string, req interface{}, next func(context.Context) (interface{}, error)) (interface{}, error)
+Each API method call is passed through them in the returned order. Request fields are validated
+according to the validate struct tags, supported rules are required, omitempty, min, max, len, oneof, email and url.
+Invalid requests are rejected with status 400 and list of field errors.
+
+Methods can be configured with comment directives above the method declaration. //mantil:private
+and //mantil:public override visibility of the function for that method. //mantil:route GET /{id}
+exposes the method on its own HTTP route, the path is relative to the API path and its parameters
+are bound to the request fields with the same path tag or json name. Methods can also be configured
+under methods in the environment configuration which takes precedence over the directives.
+
 After being deployed the can then be invoked using mantil invoke, for example:
 
 mantil invoke ping
-mantil invoke ping/hello`,
+mantil invoke ping/hello
+
+With the --from option APIs are created from the OpenAPI document, one API for each tag or first
+path segment of the operations. Methods are named by the operationId and get request and response
+types from the document schemas, operations with path parameters or methods other than POST get
+their route directive. Name argument selects only one of the APIs. Existing files are left untouched.`,
+	Examples: `
+  ==> API with additional methods
+  $ mantil generate api ping --methods hello,world
+
+  ==> all APIs from the OpenAPI document
+  $ mantil generate api --from openapi.yaml`,
 	Arguments: `
-  <name>      Name of the API to generate.`,
+  [name]      Name of the API to generate, optional with the --from option.`,
+}
+
+var GenerateOpenAPI = Command{
+	Short: "Generates OpenAPI specification of the project APIs",
+	Long: `Generates OpenAPI specification of the project APIs
+
+Each exported method of the struct returned by the API New function is described as one endpoint
+with its request and response types. Errors returned by the methods are described by the
+x-api-error and x-api-error-code response headers.
+
+Endpoint of the selected stage is used as the server url. Private functions and methods are marked
+as requiring bearer authentication. Methods with configured routes are described with their HTTP
+method and path parameters, all other methods are called with POST.
+
+The specification is written in JSON format to openapi.json in the project root,
+use --output to write it somewhere else.`,
+	Examples: `
+  ==> write specification for the default stage
+  $ mantil generate openapi
+
+  ==> write specification for the production stage to a custom location
+  $ mantil generate openapi --stage production --output docs/openapi.json`,
+}
+
+var GenerateClient = Command{
+	Short: "Generates typed client for the project APIs",
+	Long: `Generates typed client for the project APIs
+
+The client has one method for each API method and uses the API request and response types.
+Go client imports API packages directly, TypeScript client gets interfaces generated from
+the API types.
+
+Access token set on the client is sent when calling private functions and methods of the selected
+stage. Methods with configured routes are called with their HTTP method and path parameters taken
+from the request.
+Client can call APIs over the stage REST endpoint or over the websocket connection which
+also receives messages published to the subscribed subjects.
+
+Go client is written to the client package in the project root, TypeScript client to
+client/client.ts. Use --output to choose another directory.`,
+	Examples: `
+  ==> Go client for the default stage
+  $ mantil generate client
+
+  ==> TypeScript client written to the frontend sources
+  $ mantil generate client --lang ts --output web/src/api`,
 }
 
 var Deploy = Command{
@@ -309,9 +576,20 @@ var Deploy = Command{
 This command checks if any assets, code or configuration have changed since the last deployment
 and applies the necessary updates.
 
-The --stage option accepts any existing stage and defaults to the default stage if omitted.`,
+The --stage option accepts any existing stage and defaults to the default stage if omitted.
+
+With the --all option every Mantil project in the repository is deployed to the stage set with
+the --stage option. Projects are searched for from the go.work directory or from the root of
+the Go module, so multiple projects can share one module.`,
 	NextSteps: `
 * Use 'mantil logs' to see those directly in terminal in an instant.
+`,
+	Examples: `
+  ==> deploy project to the default stage:
+  $ mantil deploy
+
+  ==> deploy all projects in the repository to the staging stage:
+  $ mantil deploy --all --stage staging
 `,
 }
 
@@ -332,6 +610,20 @@ the issue you're having.
 By default last 3 days of logs are included, you can change that with --days option.`, logsDir()),
 }
 
+var Doctor = Command{
+	Short: "Checks the local environment, workspace and nodes",
+	Long: `Check the local environment, workspace and nodes
+
+Checks in one pass:
+  - Go toolchain version and support for building Lambda functions
+  - default AWS credentials
+  - consistency of the workspace and project state files
+  - validity of the project environment file
+  - reachability, version and access token of each node
+
+For each problem found the command shows how to fix it.`,
+}
+
 var Aws = Command{
 	Short: "AWS subcommand",
 }
@@ -0,0 +1,320 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	AlarmMetricErrors               = "errors"
+	AlarmMetricErrorRate            = "error_rate"
+	AlarmMetricThrottles            = "throttles"
+	AlarmMetricDuration             = "duration"
+	AlarmMetricInvocations          = "invocations"
+	AlarmMetricConcurrentExecutions = "concurrent_executions"
+
+	AlarmDefaultPeriod            = 300
+	AlarmDefaultEvaluationPeriods = 1
+
+	AlarmSubscriptionEmail = "email"
+	AlarmSubscriptionHttps = "https"
+)
+
+// lambda metric and default statistic for each supported alarm metric
+var alarmMetrics = map[string]struct {
+	name      string
+	statistic string
+}{
+	AlarmMetricErrors:               {"Errors", "Sum"},
+	AlarmMetricErrorRate:            {"", ""},
+	AlarmMetricThrottles:            {"Throttles", "Sum"},
+	AlarmMetricDuration:             {"Duration", "p99"},
+	AlarmMetricInvocations:          {"Invocations", "Sum"},
+	AlarmMetricConcurrentExecutions: {"ConcurrentExecutions", "Maximum"},
+}
+
+var (
+	alarmStatistics      = []string{"Sum", "Average", "Maximum", "Minimum", "SampleCount"}
+	alarmPercentileRegex = regexp.MustCompile(`^p(\d{1,2}(\.\d{1,2})?|100)$`)
+	snsTopicArnRegex     = regexp.MustCompile(`^arn:aws[a-z-]*:sns:[a-z0-9-]+:\d{12}:[A-Za-z0-9_-]+$`)
+	emailRegex           = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+)
+
+// AlarmConfiguration is CloudWatch alarm on the function metric. Alarm goes
+// off when metric is greater than threshold. Threshold of the error_rate
+// metric is in percents, of the duration in milliseconds.
+// Notify targets are SNS topic ARNs, email addresses or https webhook URLs.
+type AlarmConfiguration struct {
+	Metric            string   `yaml:"metric,omitempty" jsonschema:"enum=errors,enum=error_rate,enum=throttles,enum=duration,enum=invocations,enum=concurrent_executions"`
+	Statistic         string   `yaml:"statistic,omitempty"`
+	Threshold         *float64 `yaml:"threshold,omitempty"`
+	Period            int      `yaml:"period,omitempty" jsonschema:"minimum=60"`
+	EvaluationPeriods int      `yaml:"evaluation_periods,omitempty" jsonschema:"minimum=1"`
+	Notify            []string `yaml:"notify,omitempty" jsonschema:"nullable"`
+	// removes alarm inherited from the project or stage level
+	Disabled bool `yaml:"disabled,omitempty"`
+}
+
+func (ac AlarmConfiguration) merge(s AlarmConfiguration) AlarmConfiguration {
+	if s.Metric != "" {
+		ac.Metric = s.Metric
+	}
+	if s.Statistic != "" {
+		ac.Statistic = s.Statistic
+	}
+	if s.Threshold != nil {
+		ac.Threshold = s.Threshold
+	}
+	if s.Period != 0 {
+		ac.Period = s.Period
+	}
+	if s.EvaluationPeriods != 0 {
+		ac.EvaluationPeriods = s.EvaluationPeriods
+	}
+	if len(s.Notify) > 0 {
+		ac.Notify = s.Notify
+	}
+	if s.Disabled {
+		ac.Disabled = s.Disabled
+	}
+	return ac
+}
+
+// complete is true when alarm can be created without inheriting values
+// from the higher level
+func (ac AlarmConfiguration) complete() bool {
+	return ac.Metric != "" && ac.Threshold != nil
+}
+
+// Validate checks values of the alarm configuration which are set.
+func (ac AlarmConfiguration) Validate() error {
+	if ac.Metric != "" {
+		if _, ok := alarmMetrics[ac.Metric]; !ok {
+			return fmt.Errorf("unknown metric %s", ac.Metric)
+		}
+	}
+	if ac.Statistic != "" {
+		if ac.Metric == AlarmMetricErrorRate {
+			return fmt.Errorf("statistic can't be set for the %s metric", AlarmMetricErrorRate)
+		}
+		if !stringsContain(alarmStatistics, ac.Statistic) && !alarmPercentileRegex.MatchString(ac.Statistic) {
+			return fmt.Errorf("invalid statistic %s, supported are %s or percentile like p99", ac.Statistic, strings.Join(alarmStatistics, ", "))
+		}
+	}
+	if ac.Period%60 != 0 {
+		return fmt.Errorf("period %d is not multiple of 60 seconds", ac.Period)
+	}
+	for _, n := range ac.Notify {
+		if _, _, err := alarmNotifyTarget(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// alarmNotifyTarget returns SNS topic ARN or protocol and endpoint of the
+// subscription to the alarms topic
+func alarmNotifyTarget(n string) (string, AlarmSubscription, error) {
+	switch {
+	case strings.HasPrefix(n, "arn:"):
+		if !snsTopicArnRegex.MatchString(n) {
+			return "", AlarmSubscription{}, fmt.Errorf("invalid SNS topic ARN %s", n)
+		}
+		return n, AlarmSubscription{}, nil
+	case strings.HasPrefix(n, "https://"):
+		return "", AlarmSubscription{Protocol: AlarmSubscriptionHttps, Endpoint: n}, nil
+	case emailRegex.MatchString(n):
+		return "", AlarmSubscription{Protocol: AlarmSubscriptionEmail, Endpoint: n}, nil
+	}
+	return "", AlarmSubscription{}, fmt.Errorf("invalid notify target %s, expected SNS topic ARN, email address or https URL", n)
+}
+
+// AlarmSubscription is email address or webhook subscribed to the alarms
+// topic.
+type AlarmSubscription struct {
+	Protocol string
+	Endpoint string
+}
+
+// AlarmTopic is SNS topic created for the set of email addresses and
+// webhooks, alarms which notify the same set share the topic.
+type AlarmTopic struct {
+	Key           string
+	Subscriptions []AlarmSubscription
+}
+
+// FunctionAlarm is alarm of the function with the defaults applied.
+type FunctionAlarm struct {
+	Name    string
+	AWSName string
+	AlarmConfiguration
+}
+
+// MetricName of the lambda metric, empty for the error rate which is
+// calculated from errors and invocations.
+func (a FunctionAlarm) MetricName() string {
+	return alarmMetrics[a.Metric].name
+}
+
+// Topics returns SNS topic ARNs notified by the alarm.
+func (a FunctionAlarm) Topics() []string {
+	var topics []string
+	for _, n := range a.Notify {
+		if topic, _, _ := alarmNotifyTarget(n); topic != "" {
+			topics = append(topics, topic)
+		}
+	}
+	return topics
+}
+
+// Subscriptions returns unique email addresses and webhooks notified by the
+// alarm, sorted by protocol and endpoint.
+func (a FunctionAlarm) Subscriptions() []AlarmSubscription {
+	m := make(map[AlarmSubscription]bool)
+	var subs []AlarmSubscription
+	for _, n := range a.Notify {
+		topic, sub, err := alarmNotifyTarget(n)
+		if err != nil || topic != "" || m[sub] {
+			continue
+		}
+		m[sub] = true
+		subs = append(subs, sub)
+	}
+	sort.Slice(subs, func(i, j int) bool {
+		if subs[i].Protocol != subs[j].Protocol {
+			return subs[i].Protocol < subs[j].Protocol
+		}
+		return subs[i].Endpoint < subs[j].Endpoint
+	})
+	return subs
+}
+
+// SubscriptionsKey identifies the topic of the alarm email and webhook
+// subscriptions, empty if the alarm has none.
+func (a FunctionAlarm) SubscriptionsKey() string {
+	subs := a.Subscriptions()
+	if len(subs) == 0 {
+		return ""
+	}
+	h := sha256.New()
+	for _, sub := range subs {
+		fmt.Fprintf(h, "%s:%s\n", sub.Protocol, sub.Endpoint)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:8]
+}
+
+// Alarms returns enabled function alarms sorted by name.
+func (f *Function) Alarms() []FunctionAlarm {
+	var alarms []FunctionAlarm
+	for name, ac := range f.FunctionConfiguration.Alarms {
+		if ac.Disabled || !ac.complete() {
+			continue
+		}
+		if ac.Statistic == "" {
+			ac.Statistic = alarmMetrics[ac.Metric].statistic
+		}
+		if ac.Period == 0 {
+			ac.Period = AlarmDefaultPeriod
+		}
+		if ac.EvaluationPeriods == 0 {
+			ac.EvaluationPeriods = AlarmDefaultEvaluationPeriods
+		}
+		alarms = append(alarms, FunctionAlarm{
+			Name:               name,
+			AWSName:            f.stage.resourceName(fmt.Sprintf("%s-%s", f.Name, name)),
+			AlarmConfiguration: ac,
+		})
+	}
+	sort.Slice(alarms, func(i, j int) bool { return alarms[i].Name < alarms[j].Name })
+	return alarms
+}
+
+// AlarmTopics returns topics for the distinct sets of email and webhook
+// subscriptions of the function alarms in the stage, sorted by key.
+func (s *Stage) AlarmTopics() []AlarmTopic {
+	m := make(map[string]bool)
+	var topics []AlarmTopic
+	for _, f := range s.Functions {
+		for _, a := range f.Alarms() {
+			key := a.SubscriptionsKey()
+			if key == "" || m[key] {
+				continue
+			}
+			m[key] = true
+			topics = append(topics, AlarmTopic{Key: key, Subscriptions: a.Subscriptions()})
+		}
+	}
+	sort.Slice(topics, func(i, j int) bool { return topics[i].Key < topics[j].Key })
+	return topics
+}
+
+// AlarmsTopicName is name of the SNS topic created for the alarms which
+// notify the same email addresses and webhooks.
+func (s *Stage) AlarmsTopicName(key string) string {
+	return s.resourceName(alarmsTopic(key))
+}
+
+func alarmsTopic(key string) string {
+	return "alarms-" + key
+}
+
+func (fc *FunctionConfiguration) validateAlarms() error {
+	for name, a := range fc.Alarms {
+		if err := ValidateName(name); err != nil {
+			return fmt.Errorf("alarm %s: %w", name, err)
+		}
+		if err := a.Validate(); err != nil {
+			return fmt.Errorf("alarm %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// validateAlarms checks that each alarm has metric and threshold on the
+// level where it is defined or inherits them from the higher level
+func (ec *EnvironmentConfig) validateAlarms() error {
+	p := ec.Project
+	if err := p.validateAlarms(); err != nil {
+		return err
+	}
+	if err := incompleteAlarms(p.Alarms); err != nil {
+		return err
+	}
+	for _, s := range p.Stages {
+		if err := s.validateAlarms(); err != nil {
+			return fmt.Errorf("stage %s %w", s.Name, err)
+		}
+		if err := incompleteAlarms(s.Alarms, p.Alarms); err != nil {
+			return fmt.Errorf("stage %s %w", s.Name, err)
+		}
+		for _, f := range s.Functions {
+			if err := f.validateAlarms(); err != nil {
+				return fmt.Errorf("function %s %w", f.Name, err)
+			}
+			if err := incompleteAlarms(f.Alarms, p.Alarms, s.Alarms); err != nil {
+				return fmt.Errorf("function %s %w", f.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func incompleteAlarms(alarms map[string]AlarmConfiguration, inherited ...map[string]AlarmConfiguration) error {
+	for name, a := range alarms {
+		if a.Disabled {
+			continue
+		}
+		var merged AlarmConfiguration
+		for _, i := range inherited {
+			merged = merged.merge(i[name])
+		}
+		if !merged.merge(a).complete() {
+			return fmt.Errorf("alarm %s: metric and threshold are required", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package domain_test
+
+import (
+	"testing"
+
+	. "github.com/mantil-io/mantil/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFunctionAlarms(t *testing.T) {
+	env, err := ValidateEnvironmentConfig([]byte(`
+project:
+  alarms:
+    errors:
+      metric: error_rate
+      threshold: 1
+      notify:
+        - ops@example.com
+  stages:
+    - name: dev
+      alarms:
+        errors:
+          threshold: 5
+      functions:
+        - name: todo
+          alarms:
+            slow:
+              metric: duration
+              threshold: 3000
+              period: 60
+              notify:
+                - arn:aws:sns:eu-central-1:123456789012:ops
+                - https://hooks.example.com/alarms
+            throttles:
+              metric: throttles
+              threshold: 0
+        - name: ping
+          alarms:
+            errors:
+              disabled: true
+`))
+	require.NoError(t, err)
+	s := initStage(&Stage{Name: "dev"}, env)
+	_, err = s.ApplyChanges([]Resource{{Name: "todo", Hash: "hash"}, {Name: "ping", Hash: "hash"}}, "")
+	require.NoError(t, err)
+
+	todo := s.FindFunction("todo")
+	alarms := todo.Alarms()
+	require.Len(t, alarms, 3)
+
+	require.Equal(t, "errors", alarms[0].Name)
+	require.Equal(t, "project-dev-todo-errors-uid", alarms[0].AWSName)
+	require.Equal(t, AlarmMetricErrorRate, alarms[0].Metric)
+	require.Equal(t, float64(5), *alarms[0].Threshold)
+	require.Equal(t, AlarmDefaultPeriod, alarms[0].Period)
+	require.Empty(t, alarms[0].MetricName())
+	require.Empty(t, alarms[0].Topics())
+	require.Equal(t, []AlarmSubscription{{AlarmSubscriptionEmail, "ops@example.com"}}, alarms[0].Subscriptions())
+
+	require.Equal(t, "slow", alarms[1].Name)
+	require.Equal(t, "Duration", alarms[1].MetricName())
+	require.Equal(t, "p99", alarms[1].Statistic)
+	require.Equal(t, 60, alarms[1].Period)
+	require.Equal(t, []string{"arn:aws:sns:eu-central-1:123456789012:ops"}, alarms[1].Topics())
+	require.Equal(t, []AlarmSubscription{{AlarmSubscriptionHttps, "https://hooks.example.com/alarms"}}, alarms[1].Subscriptions())
+
+	require.Equal(t, "throttles", alarms[2].Name)
+	require.Equal(t, "Sum", alarms[2].Statistic)
+	require.Equal(t, float64(0), *alarms[2].Threshold)
+
+	require.Empty(t, s.FindFunction("ping").Alarms())
+	require.Empty(t, alarms[2].SubscriptionsKey())
+	topics := s.AlarmTopics()
+	require.Len(t, topics, 2)
+
+	var resources []AwsResource
+	for _, r := range s.Resources() {
+		if r.Type == AwsResourceAlarm || r.Type == AwsResourceSNSTopic {
+			resources = append(resources, r)
+		}
+	}
+	require.Len(t, resources, 5)
+	require.Equal(t, AwsResource{"alarms-" + topics[0].Key, "project-dev-alarms-" + topics[0].Key + "-uid", AwsResourceSNSTopic}, resources[3])
+	require.Equal(t, AwsResource{"alarms-" + topics[1].Key, "project-dev-alarms-" + topics[1].Key + "-uid", AwsResourceSNSTopic}, resources[4])
+}
+
+func TestAlarmTopics(t *testing.T) {
+	env, err := ValidateEnvironmentConfig([]byte(`
+project:
+  stages:
+    - name: dev
+      functions:
+        - name: todo
+          alarms:
+            errors:
+              metric: errors
+              threshold: 0
+              notify:
+                - alice@example.com
+        - name: ping
+          alarms:
+            throttles:
+              metric: throttles
+              threshold: 0
+              notify:
+                - https://hooks.example.com/alarms
+            slow:
+              metric: duration
+              threshold: 3000
+              notify:
+                - https://hooks.example.com/alarms
+                - https://hooks.example.com/alarms
+`))
+	require.NoError(t, err)
+	s := initStage(&Stage{Name: "dev"}, env)
+	_, err = s.ApplyChanges([]Resource{{Name: "todo", Hash: "hash"}, {Name: "ping", Hash: "hash"}}, "")
+	require.NoError(t, err)
+
+	todoErrors := s.FindFunction("todo").Alarms()[0]
+	ping := s.FindFunction("ping").Alarms()
+	slow, throttles := ping[0], ping[1]
+	// alarms with different targets notify different topics
+	require.NotEmpty(t, todoErrors.SubscriptionsKey())
+	require.NotEqual(t, todoErrors.SubscriptionsKey(), throttles.SubscriptionsKey())
+	// alarms with the same targets share the topic
+	require.Equal(t, throttles.SubscriptionsKey(), slow.SubscriptionsKey())
+
+	topics := s.AlarmTopics()
+	require.Len(t, topics, 2)
+	byKey := make(map[string][]AlarmSubscription)
+	for _, topic := range topics {
+		byKey[topic.Key] = topic.Subscriptions
+	}
+	require.Equal(t, []AlarmSubscription{{AlarmSubscriptionEmail, "alice@example.com"}}, byKey[todoErrors.SubscriptionsKey()])
+	require.Equal(t, []AlarmSubscription{{AlarmSubscriptionHttps, "https://hooks.example.com/alarms"}}, byKey[throttles.SubscriptionsKey()])
+	require.Equal(t, "project-dev-alarms-"+todoErrors.SubscriptionsKey()+"-uid", s.AlarmsTopicName(todoErrors.SubscriptionsKey()))
+}
+
+func TestAlarmConfigurationValidate(t *testing.T) {
+	require.NoError(t, AlarmConfiguration{Metric: "duration", Statistic: "p99.9", Period: 120}.Validate())
+	require.Error(t, AlarmConfiguration{Metric: "latency"}.Validate())
+	require.Error(t, AlarmConfiguration{Metric: "error_rate", Statistic: "Sum"}.Validate())
+	require.Error(t, AlarmConfiguration{Metric: "duration", Statistic: "median"}.Validate())
+	require.Error(t, AlarmConfiguration{Period: 90}.Validate())
+	require.Error(t, AlarmConfiguration{Notify: []string{"http://example.com"}}.Validate())
+	require.Error(t, AlarmConfiguration{Notify: []string{"arn:aws:sqs:eu-central-1:123456789012:queue"}}.Validate())
+
+	_, err := ValidateEnvironmentConfig([]byte(`
+project:
+  stages:
+    - name: dev
+      functions:
+        - name: todo
+          alarms:
+            errors:
+              threshold: 1
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "function todo alarm errors: metric and threshold are required")
+}
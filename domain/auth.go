@@ -5,16 +5,24 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/mantil-io/mantil.go"
 	"github.com/mantil-io/mantil/kit/token"
 )
 
 const (
-	AccessTokenHeader    = "Authorization"
-	EnvPublicKey         = "MANTIL_PUBLIC_KEY"
-	ContextUserClaimsKey = "mantilUserClaims"
+	AccessTokenHeader            = "Authorization"
+	EnvPublicKey                 = "MANTIL_PUBLIC_KEY"
+	EnvPreviousPublicKey         = "MANTIL_PREVIOUS_PUBLIC_KEY"
+	EnvPreviousPublicKeyExpireAt = "MANTIL_PREVIOUS_PUBLIC_KEY_EXPIRE_AT"
+	ContextUserClaimsKey         = "mantilUserClaims"
+	// after key rotation previous public key is still accepted for the
+	// duration of the longest token issued with the old private key
+	KeyRotationGracePeriod = 7 * 24 * time.Hour
 )
 
 type AccessTokenClaims struct {
@@ -25,6 +33,28 @@ type AccessTokenClaims struct {
 	Username  string `json:"u,omitempty"`
 	Role      Role   `json:"o,omitempty"`
 	Node      *Node  `json:"n,omitempty"`
+	SessionID string `json:"i,omitempty"`
+	// functions allowed to be called with the token, all if empty
+	Functions []string          `json:"f,omitempty"`
+	Claims    map[string]string `json:"c,omitempty"`
+}
+
+// FunctionAllowed checks whether the function can be called with the token.
+// Tokens scoped to functions are not allowed when the function is unknown,
+// like on the websocket connect where function is chosen per message.
+func (c *AccessTokenClaims) FunctionAllowed(name string) bool {
+	if len(c.Functions) == 0 {
+		return true
+	}
+	if name == "" {
+		return false
+	}
+	for _, f := range c.Functions {
+		if f == name {
+			return true
+		}
+	}
+	return false
 }
 
 type Role int
@@ -38,14 +68,28 @@ var (
 	ErrNotAuthorized = fmt.Errorf("not authorized")
 )
 
-func ReadAccessToken(headers map[string]string, publicKey string) (*AccessTokenClaims, error) {
-	if at, ok := headers[AccessTokenHeader]; ok {
-		return verifyAccessToken(at, publicKey)
+// ReadAccessToken verifies access token from headers with the public key.
+// Previous public keys, still valid after key rotation, are tried if the
+// verification with the current one fails.
+func ReadAccessToken(headers map[string]string, publicKey string, previousKeys ...string) (*AccessTokenClaims, error) {
+	at, ok := headers[AccessTokenHeader]
+	if !ok {
+		at, ok = headers[strings.ToLower(AccessTokenHeader)]
 	}
-	if at, ok := headers[strings.ToLower(AccessTokenHeader)]; ok {
-		return verifyAccessToken(at, publicKey)
+	if !ok {
+		return nil, fmt.Errorf("access token not found in %s header", AccessTokenHeader)
+	}
+	at = strings.TrimPrefix(at, "Bearer ")
+	claims, err := verifyAccessToken(at, publicKey)
+	if err == nil {
+		return claims, nil
+	}
+	for _, pk := range previousKeys {
+		if claims, perr := verifyAccessToken(at, pk); perr == nil {
+			return claims, nil
+		}
 	}
-	return nil, fmt.Errorf("access token not found in %s header", AccessTokenHeader)
+	return nil, err
 }
 
 func verifyAccessToken(at, pk string) (*AccessTokenClaims, error) {
@@ -56,6 +100,43 @@ func verifyAccessToken(at, pk string) (*AccessTokenClaims, error) {
 	return &claims, nil
 }
 
+// PreviousKey is the public key replaced by the key rotation.
+type PreviousKey struct {
+	Public   string `yaml:"public"`
+	ExpireAt int64  `yaml:"expire_at"`
+}
+
+func newPreviousKey(publicKey string) *PreviousKey {
+	return &PreviousKey{
+		Public:   publicKey,
+		ExpireAt: time.Now().Add(KeyRotationGracePeriod).Unix(),
+	}
+}
+
+func (k *PreviousKey) valid() bool {
+	return k != nil && k.Public != "" && time.Now().Unix() < k.ExpireAt
+}
+
+func (k *PreviousKey) authEnv(env map[string]string) {
+	if !k.valid() {
+		return
+	}
+	env[EnvPreviousPublicKey] = k.Public
+	env[EnvPreviousPublicKeyExpireAt] = strconv.FormatInt(k.ExpireAt, 10)
+}
+
+// PreviousPublicKeyFromEnv returns previous public key set by the key
+// rotation if it is still valid.
+func PreviousPublicKeyFromEnv() string {
+	pk := os.Getenv(EnvPreviousPublicKey)
+	exp, _ := strconv.ParseInt(os.Getenv(EnvPreviousPublicKeyExpireAt), 10, 64)
+	k := &PreviousKey{Public: pk, ExpireAt: exp}
+	if !k.valid() {
+		return ""
+	}
+	return k.Public
+}
+
 func StoreUserClaims(claims *AccessTokenClaims, context map[string]interface{}) {
 	buf, _ := json.Marshal(claims)
 	b64 := base64.StdEncoding.EncodeToString(buf)
@@ -75,10 +156,12 @@ func ClaimsFromContext(ctx context.Context) (*AccessTokenClaims, error) {
 	if !ok {
 		return nil, fmt.Errorf("lambda context not found")
 	}
-	return claimsFromAuthorizerContext(lctx.Authorizer())
+	return ClaimsFromAuthorizerContext(lctx.Authorizer())
 }
 
-func claimsFromAuthorizerContext(ac map[string]interface{}) (*AccessTokenClaims, error) {
+// ClaimsFromAuthorizerContext reads claims stored by the authorizer in the
+// request context.
+func ClaimsFromAuthorizerContext(ac map[string]interface{}) (*AccessTokenClaims, error) {
 	c, ok := ac[ContextUserClaimsKey]
 	if !ok {
 		return nil, fmt.Errorf("claims not found")
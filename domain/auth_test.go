@@ -57,7 +57,7 @@ func TestReadUserClaims(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, &c, c2)
 
-	headers[strings.ToLower(AccessTokenHeader)] = token
+	headers[strings.ToLower(AccessTokenHeader)] = "Bearer " + token
 	c2, err = ReadAccessToken(headers, publicKey)
 	require.NoError(t, err)
 	require.Equal(t, &c, c2)
@@ -75,7 +75,7 @@ func TestClaimsFromContext(t *testing.T) {
 	require.Nil(t, c)
 
 	ac := map[string]interface{}{}
-	c, err = claimsFromAuthorizerContext(ac)
+	c, err = ClaimsFromAuthorizerContext(ac)
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "claims not found")
 	require.Nil(t, c)
@@ -83,7 +83,7 @@ func TestClaimsFromContext(t *testing.T) {
 	ac = map[string]interface{}{
 		ContextUserClaimsKey: base64.StdEncoding.EncodeToString([]byte("{\"w\":\"workspace\",\"p\":\"project\",\"s\":\"stage\",\"r\":\"runtime\",\"u\":\"username\",\"o\":1}")),
 	}
-	c, err = claimsFromAuthorizerContext(ac)
+	c, err = ClaimsFromAuthorizerContext(ac)
 	require.Nil(t, err)
 	require.Equal(t, &AccessTokenClaims{
 		Workspace: "workspace",
@@ -94,3 +94,53 @@ func TestClaimsFromContext(t *testing.T) {
 		Role:      User,
 	}, c)
 }
+
+func TestStageRotateKeys(t *testing.T) {
+	publicKey, privateKey, err := token.KeyPair()
+	require.NoError(t, err)
+	stage := &Stage{
+		Keys: StageKeys{
+			Public:  publicKey,
+			Private: privateKey,
+		},
+	}
+	oldToken, err := token.JWT(privateKey, &AccessTokenClaims{Stage: "stage"}, time.Hour)
+	require.NoError(t, err)
+
+	require.NoError(t, stage.RotateKeys())
+	require.NotEqual(t, publicKey, stage.Keys.Public)
+	require.Equal(t, publicKey, stage.Keys.Previous.Public)
+
+	ae := stage.AuthEnv()
+	require.Equal(t, stage.Keys.Public, ae[EnvPublicKey])
+	require.Equal(t, publicKey, ae[EnvPreviousPublicKey])
+	require.NotEmpty(t, ae[EnvPreviousPublicKeyExpireAt])
+
+	// token signed with the old key is accepted only with the previous key
+	headers := map[string]string{AccessTokenHeader: oldToken}
+	_, err = ReadAccessToken(headers, ae[EnvPublicKey])
+	require.Error(t, err)
+	c, err := ReadAccessToken(headers, ae[EnvPublicKey], ae[EnvPreviousPublicKey])
+	require.NoError(t, err)
+	require.Equal(t, "stage", c.Stage)
+
+	require.True(t, stage.applyKeyChanges())
+	require.False(t, stage.applyKeyChanges())
+
+	// expired previous key is removed
+	stage.Keys.Previous.ExpireAt = time.Now().Add(-time.Minute).Unix()
+	require.Empty(t, stage.AuthEnv()[EnvPreviousPublicKey])
+	require.True(t, stage.applyKeyChanges())
+	require.Nil(t, stage.Keys.Previous)
+}
+
+func TestPreviousPublicKeyFromEnv(t *testing.T) {
+	t.Setenv(EnvPreviousPublicKey, "key")
+	t.Setenv(EnvPreviousPublicKeyExpireAt, "")
+	require.Empty(t, PreviousPublicKeyFromEnv())
+
+	env := make(map[string]string)
+	newPreviousKey("key").authEnv(env)
+	t.Setenv(EnvPreviousPublicKeyExpireAt, env[EnvPreviousPublicKeyExpireAt])
+	require.Equal(t, "key", PreviousPublicKeyFromEnv())
+}
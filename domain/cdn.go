@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CDN configures CloudFront distribution in front of the stage public site
+// and api. Public site content is cached, function routes are not.
+type CDN struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// distribution is served on this subdomain of the stage custom domain,
+	// or on the custom domain itself if empty
+	Subdomain string `yaml:"subdomain,omitempty"`
+	// serve index.html for paths without file extension
+	SPA        bool   `yaml:"spa,omitempty"`
+	PriceClass string `yaml:"price_class,omitempty" jsonschema:"enum=PriceClass_100,enum=PriceClass_200,enum=PriceClass_All"`
+}
+
+// Distribution is the CloudFront distribution created for the stage.
+type Distribution struct {
+	ID  string `yaml:"id"`
+	URL string `yaml:"url"`
+}
+
+// CDNDomain returns custom domain of the stage distribution, empty if the
+// stage has no custom domain.
+func (s *Stage) CDNDomain() string {
+	if !s.CDN.Enabled || s.CustomDomain.DomainName == "" {
+		return ""
+	}
+	return strings.Trim(fmt.Sprintf("%s.%s", s.CDN.Subdomain, s.CustomDomain.DomainName), ".")
+}
+
+func (s *Stage) SetDistribution(id, url string) {
+	if id == "" {
+		s.Distribution = nil
+		return
+	}
+	s.Distribution = &Distribution{
+		ID:  id,
+		URL: url,
+	}
+}
+
+func (s *Stage) CDNEndpoint() string {
+	if s.Distribution == nil {
+		return ""
+	}
+	return s.Distribution.URL
+}
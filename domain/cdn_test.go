@@ -0,0 +1,47 @@
+package domain_test
+
+import (
+	"testing"
+
+	. "github.com/mantil-io/mantil/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStageCDN(t *testing.T) {
+	env := &EnvironmentConfig{
+		Project: ProjectEnvironmentConfig{
+			Stages: []StageEnvironmentConfig{
+				{
+					Name: "dev",
+					CDN: CDN{
+						Enabled:   true,
+						Subdomain: "www",
+						SPA:       true,
+					},
+				},
+			},
+		},
+	}
+	s := initStage(&Stage{Name: "dev"}, env)
+	diff, err := s.ApplyChanges(nil, "")
+	require.NoError(t, err)
+	require.True(t, diff.InfrastructureChanged())
+	require.True(t, s.CDN.SPA)
+	// without custom domain distribution uses cloudfront domain
+	require.Empty(t, s.CDNDomain())
+
+	diff, err = s.ApplyChanges(nil, "")
+	require.NoError(t, err)
+	require.False(t, diff.InfrastructureChanged())
+
+	s.CustomDomain.DomainName = "example.com"
+	require.Equal(t, "www.example.com", s.CDNDomain())
+	s.CDN.Subdomain = ""
+	require.Equal(t, "example.com", s.CDNDomain())
+
+	require.Empty(t, s.CDNEndpoint())
+	s.SetDistribution("id", "https://www.example.com")
+	require.Equal(t, "https://www.example.com", s.CDNEndpoint())
+	s.SetDistribution("", "")
+	require.Nil(t, s.Distribution)
+}
@@ -30,6 +30,12 @@ func Deployment() DeployInfo {
 	return newDeployInfo(tag, dev, ontag)
 }
 
+// ReleaseDeployment returns deployment information of the released version,
+// used for upgrading node to a version other than the current one.
+func ReleaseDeployment(version string) DeployInfo {
+	return newDeployInfo(version, "", "1")
+}
+
 // Collects build time information. Decides where is the deployment location; s3
 // bucket and key for lambda function pacakges. That information is used in
 // script/deploy.sh to put build artifacts in the right place.
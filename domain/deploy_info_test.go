@@ -36,3 +36,13 @@ func TestReleaseVersion(t *testing.T) {
 	require.Equal(t, bucket, "mantil-releases-us-east-1")
 	require.Equal(t, key, "v0.1.13")
 }
+
+func TestReleaseDeployment(t *testing.T) {
+	v := ReleaseDeployment("v0.1.12")
+
+	require.True(t, v.Release())
+	require.Equal(t, "v0.1.12", v.String())
+	bucket, key := v.GetPath("eu-west-1")
+	require.Equal(t, "mantil-releases-eu-west-1", bucket)
+	require.Equal(t, "v0.1.12", key)
+}
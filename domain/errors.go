@@ -18,6 +18,22 @@ func (e *StageExistsError) Error() string {
 	return fmt.Sprintf("stage %s already exists", e.Name)
 }
 
+type TemplateExistsError struct {
+	Name string
+}
+
+func (e *TemplateExistsError) Error() string {
+	return fmt.Sprintf("template %s already exists", e.Name)
+}
+
+type TemplateNotFoundError struct {
+	Name string
+}
+
+func (e *TemplateNotFoundError) Error() string {
+	return fmt.Sprintf("template %s not found", e.Name)
+}
+
 type ProjectNoStagesError struct{}
 
 func (e *ProjectNoStagesError) Error() string {
@@ -76,3 +92,11 @@ type SSMPathNotFoundError struct{}
 func (e *SSMPathNotFoundError) Error() string {
 	return fmt.Sprintf("SSM parameter path not found")
 }
+
+type FunctionNotFoundError struct {
+	Name string
+}
+
+func (e *FunctionNotFoundError) Error() string {
+	return fmt.Sprintf("function %s not found", e.Name)
+}
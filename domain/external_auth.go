@@ -0,0 +1,252 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mantil-io/mantil/kit/token"
+)
+
+const (
+	EnvAuthorizerConfig = "MANTIL_AUTHORIZER_CONFIG"
+)
+
+// JWTAuthorizer configures validation of tokens issued by an external
+// identity provider for private functions. Tokens must be signed with RS256
+// or ES256. Signing keys are read from the JWKS url or from the static list
+// of PEM encoded public keys.
+type JWTAuthorizer struct {
+	Issuer         string            `yaml:"issuer,omitempty"`
+	JWKSURL        string            `yaml:"jwks_url,omitempty"`
+	Keys           []string          `yaml:"keys,omitempty"`
+	Audience       string            `yaml:"audience,omitempty"`
+	RequiredClaims map[string]string `yaml:"required_claims,omitempty"`
+}
+
+// AuthorizerConfig holds external authorizer configuration for each function
+// which has one. It is passed to the authorizer function in environment.
+type AuthorizerConfig struct {
+	Functions map[string]JWTAuthorizer
+}
+
+func (c AuthorizerConfig) Encode() string {
+	buf, _ := json.Marshal(c)
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+func DecodeAuthorizerConfig(s string) (*AuthorizerConfig, error) {
+	buf, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var c AuthorizerConfig
+	if err := json.Unmarshal(buf, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// AuthorizerConfigFromEnv returns nil if none of the functions has external
+// authorizer configured.
+func AuthorizerConfigFromEnv() (*AuthorizerConfig, error) {
+	s := os.Getenv(EnvAuthorizerConfig)
+	if s == "" {
+		return nil, nil
+	}
+	return DecodeAuthorizerConfig(s)
+}
+
+// Function returns external authorizer configuration for the function.
+func (c *AuthorizerConfig) Function(name string) (JWTAuthorizer, bool) {
+	if c == nil {
+		return JWTAuthorizer{}, false
+	}
+	a, ok := c.Functions[name]
+	return a, ok
+}
+
+func (s *Stage) authorizerConfig() *AuthorizerConfig {
+	c := AuthorizerConfig{Functions: make(map[string]JWTAuthorizer)}
+	for _, f := range s.Functions {
+		if f.Authorizer != nil {
+			c.Functions[f.Name] = *f.Authorizer
+		}
+	}
+	if len(c.Functions) == 0 {
+		return nil
+	}
+	return &c
+}
+
+// registered claims which are not passed to the function
+var registeredClaims = map[string]struct{}{
+	"iss": {}, "sub": {}, "aud": {}, "exp": {}, "nbf": {}, "iat": {}, "jti": {},
+}
+
+// ReadExternalAccessToken verifies token issued by the external identity
+// provider. Token subject becomes username and other non registered claims
+// are passed to the function as user claims.
+func ReadExternalAccessToken(headers map[string]string, a JWTAuthorizer) (*AccessTokenClaims, error) {
+	at, ok := headers[AccessTokenHeader]
+	if !ok {
+		at, ok = headers[strings.ToLower(AccessTokenHeader)]
+	}
+	if !ok {
+		return nil, fmt.Errorf("access token not found in %s header", AccessTokenHeader)
+	}
+	at = strings.TrimSpace(strings.TrimPrefix(at, "Bearer "))
+	kid, err := token.KeyID(at)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := a.publicKeys(kid)
+	if err != nil {
+		return nil, err
+	}
+	var claims map[string]interface{}
+	if err := token.VerifyExternal(at, keys, &claims); err != nil {
+		return nil, err
+	}
+	if err := a.validate(claims); err != nil {
+		return nil, err
+	}
+	c := &AccessTokenClaims{
+		Role:   User,
+		Claims: make(map[string]string),
+	}
+	if sub, ok := claims["sub"].(string); ok {
+		c.Username = sub
+	}
+	for k, v := range claims {
+		if _, ok := registeredClaims[k]; ok {
+			continue
+		}
+		c.Claims[k] = claimString(v)
+	}
+	return c, nil
+}
+
+func (a JWTAuthorizer) validate(claims map[string]interface{}) error {
+	if a.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != a.Issuer {
+			return fmt.Errorf("invalid token issuer %s", iss)
+		}
+	}
+	if a.Audience != "" && !audienceContains(claims["aud"], a.Audience) {
+		return fmt.Errorf("token audience %s not found", a.Audience)
+	}
+	for k, v := range a.RequiredClaims {
+		cv, ok := claims[k]
+		if !ok {
+			return fmt.Errorf("required claim %s not found", k)
+		}
+		if claimString(cv) != v {
+			return fmt.Errorf("invalid value of claim %s", k)
+		}
+	}
+	return nil
+}
+
+func audienceContains(aud interface{}, audience string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == audience
+	case []interface{}:
+		for _, a := range v {
+			if s, ok := a.(string); ok && s == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func claimString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	buf, _ := json.Marshal(v)
+	return string(buf)
+}
+
+var (
+	// jwks keys are refreshed after ttl to pick up rotated keys
+	jwksCacheTTL = time.Hour
+	// minimal interval between fetches when the token kid is not found in
+	// the cached keys, limits requests caused by tokens with unknown kid
+	jwksRefreshInterval = time.Minute
+)
+
+type jwksEntry struct {
+	keys    []token.PublicKey
+	fetched time.Time
+}
+
+// jwks keys cached by url in the authorizer function instance
+var jwksCache = struct {
+	sync.Mutex
+	entries map[string]*jwksEntry
+}{entries: make(map[string]*jwksEntry)}
+
+func (a JWTAuthorizer) publicKeys(kid string) ([]token.PublicKey, error) {
+	var keys []token.PublicKey
+	for _, pem := range a.Keys {
+		k, err := token.ParsePublicKey(pem)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	if a.JWKSURL == "" {
+		return keys, nil
+	}
+	jk, err := jwksKeys(a.JWKSURL, kid)
+	if err != nil {
+		return nil, err
+	}
+	return append(keys, jk...), nil
+}
+
+// jwksKeys returns cached keys of the jwks url. Keys are fetched again when
+// the cache expires or when the kid is not found and the keys were not
+// fetched in the last refresh interval.
+func jwksKeys(url, kid string) ([]token.PublicKey, error) {
+	jwksCache.Lock()
+	defer jwksCache.Unlock()
+	e := jwksCache.entries[url]
+	if e != nil {
+		age := time.Since(e.fetched)
+		if age < jwksCacheTTL && (hasKeyID(e.keys, kid) || age < jwksRefreshInterval) {
+			return e.keys, nil
+		}
+	}
+	jk, err := token.FetchJWKS(url)
+	if err != nil {
+		if e != nil {
+			// identity provider unavailable, use keys we have
+			return e.keys, nil
+		}
+		return nil, err
+	}
+	jwksCache.entries[url] = &jwksEntry{keys: jk, fetched: time.Now()}
+	return jk, nil
+}
+
+// hasKeyID checks whether one of the keys can verify token with the kid,
+// keys without id are tried for any kid
+func hasKeyID(keys []token.PublicKey, kid string) bool {
+	if kid == "" {
+		return true
+	}
+	for _, k := range keys {
+		if k.ID == "" || k.ID == kid {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+package domain
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kataras/jwt"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFunctionConfigurationMergeAuthorizer(t *testing.T) {
+	stage := &JWTAuthorizer{Issuer: "stage"}
+	function := &JWTAuthorizer{Issuer: "function"}
+
+	fc := FunctionConfiguration{}
+	require.True(t, fc.merge(FunctionConfiguration{Authorizer: stage}, FunctionConfiguration{}))
+	require.Equal(t, "stage", fc.Authorizer.Issuer)
+
+	require.True(t, fc.merge(FunctionConfiguration{Authorizer: stage}, FunctionConfiguration{Authorizer: function}))
+	require.Equal(t, "function", fc.Authorizer.Issuer)
+	require.False(t, fc.merge(FunctionConfiguration{Authorizer: stage}, FunctionConfiguration{Authorizer: function}))
+}
+
+func TestStageAuthEnvAuthorizerConfig(t *testing.T) {
+	s := &Stage{
+		Keys: StageKeys{Public: "public"},
+		Functions: []*Function{
+			{Name: "ping"},
+			{Name: "hello", FunctionConfiguration: FunctionConfiguration{Authorizer: &JWTAuthorizer{Issuer: "issuer"}}},
+		},
+	}
+	env := s.AuthEnv()
+	c, err := DecodeAuthorizerConfig(env[EnvAuthorizerConfig])
+	require.NoError(t, err)
+	_, ok := c.Function("ping")
+	require.False(t, ok)
+	a, ok := c.Function("hello")
+	require.True(t, ok)
+	require.Equal(t, "issuer", a.Issuer)
+
+	s.Functions[1].Authorizer = nil
+	require.NotContains(t, s.AuthEnv(), EnvAuthorizerConfig)
+}
+
+func TestReadExternalAccessToken(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	b64 := func(b []byte) string { return string(jwt.Base64Encode(b)) }
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{
+				{"kid": "1", "kty": "RSA", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+			},
+		})
+	}))
+	defer srv.Close()
+
+	ecPub, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	require.NoError(t, err)
+	ecPem := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: ecPub}))
+
+	a := JWTAuthorizer{
+		Issuer:         "https://issuer.example.com/",
+		JWKSURL:        srv.URL,
+		Keys:           []string{ecPem},
+		Audience:       "api",
+		RequiredClaims: map[string]string{"scope": "ping"},
+	}
+	claims := map[string]interface{}{
+		"iss":   "https://issuer.example.com/",
+		"sub":   "user@example.com",
+		"aud":   []string{"api", "other"},
+		"scope": "ping",
+		"org":   42,
+	}
+	header := func(tkn []byte) map[string]string {
+		return map[string]string{"authorization": "Bearer " + string(tkn)}
+	}
+
+	// rs256 token verified with the jwks key
+	tkn, err := jwt.SignWithHeader(jwt.RS256, rsaKey, claims, map[string]string{"alg": "RS256", "typ": "JWT", "kid": "1"}, jwt.MaxAge(time.Hour))
+	require.NoError(t, err)
+	c, err := ReadExternalAccessToken(header(tkn), a)
+	require.NoError(t, err)
+	require.Equal(t, "user@example.com", c.Username)
+	require.Equal(t, User, c.Role)
+	require.Equal(t, map[string]string{"scope": "ping", "org": "42"}, c.Claims)
+
+	// claims are passed to the function through the authorizer context
+	ctx := make(map[string]interface{})
+	StoreUserClaims(c, ctx)
+	c2, err := ClaimsFromAuthorizerContext(ctx)
+	require.NoError(t, err)
+	require.Equal(t, c, c2)
+
+	// es256 token verified with the static key
+	tkn, err = jwt.Sign(jwt.ES256, ecKey, claims, jwt.MaxAge(time.Hour))
+	require.NoError(t, err)
+	_, err = ReadExternalAccessToken(header(tkn), a)
+	require.NoError(t, err)
+
+	invalid := []func(map[string]interface{}){
+		func(c map[string]interface{}) { c["iss"] = "https://other.example.com/" },
+		func(c map[string]interface{}) { c["aud"] = "other" },
+		func(c map[string]interface{}) { c["scope"] = "admin" },
+		func(c map[string]interface{}) { delete(c, "scope") },
+	}
+	for _, fn := range invalid {
+		ic := make(map[string]interface{})
+		for k, v := range claims {
+			ic[k] = v
+		}
+		fn(ic)
+		tkn, err = jwt.Sign(jwt.ES256, ecKey, ic, jwt.MaxAge(time.Hour))
+		require.NoError(t, err)
+		_, err = ReadExternalAccessToken(header(tkn), a)
+		require.Error(t, err)
+	}
+}
+
+func TestReadExternalAccessTokenKeyRotation(t *testing.T) {
+	key1, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	key2, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	b64 := func(b []byte) string { return string(jwt.Base64Encode(b)) }
+	jwk := func(kid string, k *rsa.PrivateKey) map[string]string {
+		return map[string]string{"kid": kid, "kty": "RSA", "n": b64(k.N.Bytes()), "e": b64(big.NewInt(int64(k.E)).Bytes())}
+	}
+	keys := []map[string]string{jwk("1", key1)}
+	fetches := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	defer srv.Close()
+
+	a := JWTAuthorizer{JWKSURL: srv.URL}
+	read := func(kid string, k *rsa.PrivateKey) error {
+		tkn, err := jwt.SignWithHeader(jwt.RS256, k, map[string]interface{}{"sub": "user"}, map[string]string{"alg": "RS256", "typ": "JWT", "kid": kid}, jwt.MaxAge(time.Hour))
+		require.NoError(t, err)
+		_, err = ReadExternalAccessToken(map[string]string{"authorization": "Bearer " + string(tkn)}, a)
+		return err
+	}
+
+	require.NoError(t, read("1", key1))
+	require.NoError(t, read("1", key1))
+	require.Equal(t, 1, fetches)
+
+	// identity provider rotates keys
+	keys = []map[string]string{jwk("1", key1), jwk("2", key2)}
+
+	// kid miss right after the fetch is rate limited
+	require.Error(t, read("2", key2))
+	require.Equal(t, 1, fetches)
+
+	// kid miss after the refresh interval fetches keys again
+	jwksCache.entries[srv.URL].fetched = time.Now().Add(-2 * jwksRefreshInterval)
+	require.NoError(t, read("2", key2))
+	require.Equal(t, 2, fetches)
+
+	// known kid doesn't fetch until the cache expires
+	jwksCache.entries[srv.URL].fetched = time.Now().Add(-2 * jwksRefreshInterval)
+	require.NoError(t, read("1", key1))
+	require.Equal(t, 2, fetches)
+	jwksCache.entries[srv.URL].fetched = time.Now().Add(-jwksCacheTTL)
+	require.NoError(t, read("1", key1))
+	require.Equal(t, 3, fetches)
+}
@@ -15,6 +15,8 @@ import (
 const EnvWorkspacePath = "MANTIL_WORKSPACE_PATH"
 
 const (
+	DefaultApiDir = "api"
+
 	configDir           = "config"
 	configFilename      = "state.yml"
 	environmentFilename = "environment.yml"
@@ -180,6 +182,11 @@ func (s *FileStore) ProjectRoot() string {
 	return s.projectRoot
 }
 
+// ApiDir returns path of the directory with project apis
+func (s *FileStore) ApiDir() string {
+	return apiDir(s.projectRoot, s.environment)
+}
+
 func (s *FileStore) Stage(name string) *Stage {
 	if s.project == nil {
 		return nil
@@ -351,6 +358,39 @@ func FindProjectRoot(initialPath string) (string, error) {
 	}
 }
 
+// ProjectApiDir returns path of the directory with apis for the project in
+// projectRoot. It is api in the project root if not set in the environment
+// config.
+func ProjectApiDir(projectRoot string) (string, error) {
+	buf, err := ioutil.ReadFile(environmentFilePath(projectRoot))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return apiDir(projectRoot, nil), nil
+		}
+		return "", errors.WithStack(err)
+	}
+	ec, err := ValidateEnvironmentConfig(buf)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return apiDir(projectRoot, ec), nil
+}
+
+func apiDir(projectRoot string, ec *EnvironmentConfig) string {
+	if ec == nil || ec.Project.ApiDir == "" {
+		return filepath.Join(projectRoot, DefaultApiDir)
+	}
+	if filepath.IsAbs(ec.Project.ApiDir) {
+		return ec.Project.ApiDir
+	}
+	return filepath.Join(projectRoot, ec.Project.ApiDir)
+}
+
+// IsProjectRoot checks whether dir contains Mantil project state
+func IsProjectRoot(dir string) bool {
+	return pathExists(stateFilePath(dir))
+}
+
 func environmentFilePath(projectRoot string) string {
 	return filepath.Join(projectRoot, configDir, environmentFilename)
 }
@@ -3,6 +3,9 @@ package domain
 import (
 	"fmt"
 	"reflect"
+	"regexp"
+	"sort"
+	"strings"
 )
 
 type Function struct {
@@ -11,6 +14,8 @@ type Function struct {
 	S3Key                 string `yaml:"s3_key"`
 	FunctionConfiguration `yaml:",inline"`
 	stage                 *Stage
+	// methods configuration from the source code directives
+	sourceMethods map[string]MethodConfiguration
 }
 
 func (f *Function) SetHash(hash string) {
@@ -18,6 +23,11 @@ func (f *Function) SetHash(hash string) {
 	f.S3Key = fmt.Sprintf("%s/%s-%s.zip", f.stage.FunctionsBucketPrefix(), f.Name, f.Hash)
 }
 
+// ResetHash forces upload of the function binary on the next deploy.
+func (f *Function) ResetHash() {
+	f.Hash = ""
+}
+
 func (f *Function) LambdaName() string {
 	return fmt.Sprintf("%s-%s-%s-%s",
 		f.stage.project.Name,
@@ -45,6 +55,35 @@ type FunctionConfiguration struct {
 	Env        map[string]string `yaml:"env,omitempty" jsonschema:"nullable"`
 	Cron       string            `yaml:"cron,omitempty"`
 	Private    bool              `yaml:"private,omitempty"`
+	Authorizer *JWTAuthorizer    `yaml:"authorizer,omitempty"`
+	// configuration of the single api methods, keyed by method name
+	Methods map[string]MethodConfiguration `yaml:"methods,omitempty" jsonschema:"nullable"`
+	// X-Ray tracing mode of the function
+	Tracing string `yaml:"tracing,omitempty" jsonschema:"enum=active,enum=pass_through"`
+	// CloudWatch alarms on the function metrics, keyed by alarm name
+	Alarms map[string]AlarmConfiguration `yaml:"alarms,omitempty" jsonschema:"nullable"`
+}
+
+// MethodConfiguration overrides visibility of the api method and exposes it
+// on its own http route. Path is relative to the function path and can
+// contain path parameters, /{id}, which are bound into request fields.
+type MethodConfiguration struct {
+	Private    *bool  `yaml:"private,omitempty"`
+	HTTPMethod string `yaml:"http_method,omitempty" jsonschema:"enum=ANY,enum=GET,enum=POST,enum=PUT,enum=PATCH,enum=DELETE,enum=HEAD,enum=OPTIONS"`
+	Path       string `yaml:"path,omitempty"`
+}
+
+func (mc MethodConfiguration) merge(s MethodConfiguration) MethodConfiguration {
+	if s.Private != nil {
+		mc.Private = s.Private
+	}
+	if s.HTTPMethod != "" {
+		mc.HTTPMethod = s.HTTPMethod
+	}
+	if s.Path != "" {
+		mc.Path = s.Path
+	}
+	return mc
 }
 
 // merge function configuration from multiple sources ordered by priority
@@ -61,15 +100,35 @@ func (fc *FunctionConfiguration) merge(sources ...FunctionConfiguration) bool {
 		if s.Cron != "" {
 			merged.Cron = s.Cron
 		}
+		if s.Tracing != "" {
+			merged.Tracing = s.Tracing
+		}
 		if s.Private {
 			merged.Private = s.Private
 		}
+		if s.Authorizer != nil {
+			merged.Authorizer = s.Authorizer
+		}
 		for k, v := range s.Env {
 			if merged.Env == nil {
 				merged.Env = make(map[string]string)
 			}
 			merged.Env[k] = v
 		}
+		for k, v := range s.Methods {
+			if merged.Methods == nil {
+				merged.Methods = make(map[string]MethodConfiguration)
+			}
+			// api methods are matched case insensitive
+			k = strings.ToLower(k)
+			merged.Methods[k] = merged.Methods[k].merge(v)
+		}
+		for k, v := range s.Alarms {
+			if merged.Alarms == nil {
+				merged.Alarms = make(map[string]AlarmConfiguration)
+			}
+			merged.Alarms[k] = merged.Alarms[k].merge(v)
+		}
 	}
 	changed := merged.changed(fc)
 	*fc = merged
@@ -86,3 +145,128 @@ func (fc *FunctionConfiguration) validateCron() bool {
 	}
 	return true
 }
+
+var (
+	httpMethods     = []string{"ANY", "GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
+	methodPathRegex = regexp.MustCompile(`^(/([A-Za-z0-9._~-]+|\{[A-Za-z_][A-Za-z0-9_]*\}))*/?$`)
+)
+
+func (fc *FunctionConfiguration) validateMethods() error {
+	for name, m := range fc.Methods {
+		if err := m.Validate(); err != nil {
+			return fmt.Errorf("method %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Validate checks http method and path of the method configuration.
+func (mc MethodConfiguration) Validate() error {
+	if mc.HTTPMethod != "" && !stringsContain(httpMethods, mc.HTTPMethod) {
+		return fmt.Errorf("invalid http method %s, supported are %s", mc.HTTPMethod, strings.Join(httpMethods, ", "))
+	}
+	if mc.Path != "" && !methodPathRegex.MatchString(mc.Path) {
+		return fmt.Errorf("invalid path %s", mc.Path)
+	}
+	return nil
+}
+
+func stringsContain(a []string, s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// FunctionRoute is http api route of the api method with its own
+// configuration. Other methods are reachable through the function proxy route.
+type FunctionRoute struct {
+	Method     string
+	HTTPMethod string
+	Path       string
+	Private    bool
+}
+
+// Key is route key in the api gateway format, method and path.
+func (r FunctionRoute) Key() string {
+	return r.HTTPMethod + " " + r.Path
+}
+
+// PathParams returns names of the path parameters in order of appearance.
+func (r FunctionRoute) PathParams() []string {
+	var params []string
+	for _, s := range strings.Split(r.Path, "/") {
+		if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+			params = append(params, s[1:len(s)-1])
+		}
+	}
+	return params
+}
+
+const (
+	TracingActive      = "active"
+	TracingPassThrough = "pass_through"
+)
+
+// TracingMode returns lambda tracing mode, functions trace requests only
+// when tracing is active.
+func (f *Function) TracingMode() string {
+	if f.Tracing == TracingActive {
+		return "Active"
+	}
+	return "PassThrough"
+}
+
+// IsPrivate is true if function requires authorization.
+func (f *Function) IsPrivate() bool {
+	return f.Private || f.Authorizer != nil
+}
+
+// Routes returns routes of the configured function methods sorted by method
+// name.
+func (f *Function) Routes() []FunctionRoute {
+	var routes []FunctionRoute
+	for name, m := range f.Methods {
+		r := FunctionRoute{
+			Method:     name,
+			HTTPMethod: m.HTTPMethod,
+			Path:       MethodPath(f.Name, name),
+			Private:    f.IsPrivate(),
+		}
+		if r.HTTPMethod == "" {
+			r.HTTPMethod = "ANY"
+		}
+		if m.Path != "" {
+			r.Path = "/" + f.Name + strings.TrimSuffix(m.Path, "/")
+		}
+		if m.Private != nil {
+			r.Private = *m.Private
+		}
+		routes = append(routes, r)
+	}
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Method < routes[j].Method })
+	return routes
+}
+
+// RootRouted is true if one of the method routes takes over the function
+// root route, ANY /<function>.
+func (f *Function) RootRouted() bool {
+	for _, r := range f.Routes() {
+		if r.Key() == "ANY /"+f.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// MethodPath returns http path of the api method, default method is served
+// on the function path.
+func MethodPath(function, method string) string {
+	switch strings.ToLower(method) {
+	case "default", "invoke", "root":
+		return "/" + function
+	}
+	return "/" + function + "/" + strings.ToLower(method)
+}
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/mantil-io/mantil/kit/token"
+	"github.com/pkg/errors"
+	"golang.org/x/mod/semver"
 )
 
 type Node struct {
@@ -23,13 +25,18 @@ type Node struct {
 	Stages    []*NodeStage  `yaml:"stages,omitempty"`
 
 	GithubUser string `yaml:"github_user,omitempty"`
+	// retention of the node log groups, default is used when not set
+	LogRetentionDays int `yaml:"log_retention_days,omitempty"`
+	// state before the upgrade in progress, set until the upgrade finishes
+	Upgrade *NodeUpgrade `yaml:"upgrade,omitempty" json:"-"`
 
 	workspace *Workspace
 }
 
 type NodeKeys struct {
-	Public  string `yaml:"public"`
-	Private string `yaml:"private"`
+	Public   string       `yaml:"public"`
+	Private  string       `yaml:"private"`
+	Previous *PreviousKey `yaml:"previous,omitempty"`
 }
 
 type NodeEndpoints struct {
@@ -41,6 +48,16 @@ type NodeFunctions struct {
 	Path   string `yaml:"key"`
 }
 
+// NodeUpgrade is node state before the upgrade. It is stored in the
+// workspace before node resources are changed so an interrupted upgrade can
+// be rolled back.
+type NodeUpgrade struct {
+	Version   string        `yaml:"version"`
+	Functions NodeFunctions `yaml:"functions"`
+	// setup stack template of the previous version
+	Template string `yaml:"template"`
+}
+
 type NodeStage struct {
 	Name        string `yaml:"name"`
 	ProjectName string `yaml:"project_name"`
@@ -58,12 +75,87 @@ func (n *Node) UpgradeVersion(version, functionsBbucket, functionsPath string) {
 	n.Functions.Path = functionsPath
 }
 
+// StartUpgrade remembers current node state and upgrades node to the version.
+func (n *Node) StartUpgrade(version, functionsBucket, functionsPath, template string) {
+	n.Upgrade = &NodeUpgrade{
+		Version:   n.Version,
+		Functions: n.Functions,
+		Template:  template,
+	}
+	n.UpgradeVersion(version, functionsBucket, functionsPath)
+}
+
+// RollbackUpgrade returns node to the state before the upgrade.
+func (n *Node) RollbackUpgrade() {
+	if n.Upgrade == nil {
+		return
+	}
+	n.UpgradeVersion(n.Upgrade.Version, n.Upgrade.Functions.Bucket, n.Upgrade.Functions.Path)
+	n.Upgrade = nil
+}
+
+// FinishUpgrade forgets node state before the upgrade.
+func (n *Node) FinishUpgrade() {
+	n.Upgrade = nil
+}
+
+// IncompatibleStages returns names of the project stages on the node last
+// deployed with a version newer than the target version. State of those
+// stages may not be supported by the older node.
+func (n *Node) IncompatibleStages(p *Project, version string) []string {
+	if p == nil || !semver.IsValid(version) {
+		return nil
+	}
+	var names []string
+	for _, s := range p.Stages {
+		if s.NodeName != n.Name || s.LastDeployment == nil {
+			continue
+		}
+		if semver.Compare(s.LastDeployment.Version, version) > 0 {
+			names = append(names, s.Name)
+		}
+	}
+	return names
+}
+
 func (n *Node) AuthEnv() map[string]string {
-	return map[string]string{
+	env := map[string]string{
 		EnvPublicKey:     n.Keys.Public,
 		EnvKVTable:       n.KVTableName(),
-		EnvSSMPathPrefix: fmt.Sprintf("/mantil-node-%s", n.ID),
+		EnvSSMPathPrefix: n.SSMPathPrefix(),
 	}
+	n.Keys.Previous.authEnv(env)
+	return env
+}
+
+func (n *Node) SSMPathPrefix() string {
+	return fmt.Sprintf("/mantil-node-%s", n.ID)
+}
+
+// RotateKeys creates new key pair for nodes without GitHub authentication.
+// Nodes with GitHub authentication keep keys in SSM parameters and rotate
+// them on each setup upgrade. Previous public key is accepted by the
+// authorizer during KeyRotationGracePeriod.
+func (n *Node) RotateKeys() error {
+	if n.GithubAuthEnabled() {
+		return nil
+	}
+	publicKey, privateKey, err := token.KeyPair()
+	if err != nil {
+		return errors.Wrap(err, "could not create public/private key pair")
+	}
+	n.Keys = NodeKeys{
+		Public:   publicKey,
+		Private:  privateKey,
+		Previous: newPreviousKey(n.Keys.Public),
+	}
+	return nil
+}
+
+// SetPreviousPublicKey keeps publicKey valid for the authorizer during
+// KeyRotationGracePeriod.
+func (n *Node) SetPreviousPublicKey(publicKey string) {
+	n.Keys.Previous = newPreviousKey(publicKey)
 }
 
 func (n *Node) SetupEnv() map[string]string {
@@ -122,6 +214,16 @@ func (n *Node) UpdateToken(token string) {
 	n.workspace.NodeStore.UpsertNodeToken(token)
 }
 
+// RefreshToken returns refresh token stored for the node, used to silently
+// renew an expired access token without going through GitHub login again.
+func (n *Node) RefreshToken() string {
+	return n.workspace.NodeStore.RefreshToken(n.Name)
+}
+
+func (n *Node) UpdateSession(token, refreshToken string) {
+	n.workspace.NodeStore.UpsertNodeSession(token, refreshToken)
+}
+
 func (n *Node) AddStage(name, projectName, path string) {
 	n.Stages = append(n.Stages, &NodeStage{
 		Name:        name,
@@ -158,15 +260,25 @@ func (n *Node) GithubAuthEnabled() bool {
 	return n.GithubUser != ""
 }
 
+// LogRetention returns retention of the node log groups in days, default
+// for the nodes set up before the retention was configurable.
+func (n *Node) LogRetention() int {
+	if n.LogRetentionDays == 0 {
+		return DefaultLogRetentionDays
+	}
+	return n.LogRetentionDays
+}
+
 type NodeStore struct {
 	Nodes     []*NodeStoreEntry `yaml:"nodes"`
 	workspace *Workspace
 }
 
 type NodeStoreEntry struct {
-	Name  string `yaml:"name"`
-	Token string `yaml:"token"`
-	store *NodeStore
+	Name         string `yaml:"name"`
+	Token        string `yaml:"token"`
+	RefreshToken string `yaml:"refresh_token,omitempty"`
+	store        *NodeStore
 }
 
 func (s *NodeStore) afterRestore() {
@@ -201,15 +313,28 @@ func (s *NodeStore) FindNode(name string) (*Node, error) {
 	return s.Node(name)
 }
 
+// UpsertNodeToken stores access token for the node found in token claims.
+// Refresh token of the existing entry is preserved.
 func (s *NodeStore) UpsertNodeToken(token string) error {
+	n, err := nodeFromToken(token)
+	if err != nil {
+		return err
+	}
+	return s.UpsertNodeSession(token, s.RefreshToken(n.Name))
+}
+
+// UpsertNodeSession stores both access and refresh token for the node found
+// in token claims.
+func (s *NodeStore) UpsertNodeSession(token, refreshToken string) error {
 	n, err := nodeFromToken(token)
 	if err != nil {
 		return err
 	}
 	e := &NodeStoreEntry{
-		Name:  n.Name,
-		Token: token,
-		store: s,
+		Name:         n.Name,
+		Token:        token,
+		RefreshToken: refreshToken,
+		store:        s,
 	}
 	for idx, no := range s.Nodes {
 		if no.Name == n.Name {
@@ -246,3 +371,12 @@ func (s *NodeStore) Token(nodeName string) string {
 	}
 	return ""
 }
+
+func (s *NodeStore) RefreshToken(nodeName string) string {
+	for _, n := range s.Nodes {
+		if n.Name == nodeName {
+			return n.RefreshToken
+		}
+	}
+	return ""
+}
@@ -66,6 +66,28 @@ func TestNodeStore(t *testing.T) {
 	require.Nil(t, n)
 }
 
+func TestNodeStoreRefreshToken(t *testing.T) {
+	ns := &NodeStore{}
+	_, privateKey, _ := token.KeyPair()
+
+	err := ns.UpsertNodeSession(nodeToken(&Node{Name: "node1", Version: "1"}, privateKey), "refresh1")
+	require.NoError(t, err)
+	require.Equal(t, "refresh1", ns.RefreshToken("node1"))
+
+	// renewing access token keeps refresh token
+	tk := nodeToken(&Node{Name: "node1", Version: "2"}, privateKey)
+	err = ns.UpsertNodeToken(tk)
+	require.NoError(t, err)
+	require.Len(t, ns.Nodes, 1)
+	require.Equal(t, tk, ns.Token("node1"))
+	require.Equal(t, "refresh1", ns.RefreshToken("node1"))
+
+	err = ns.UpsertNodeSession(tk, "refresh2")
+	require.NoError(t, err)
+	require.Equal(t, "refresh2", ns.RefreshToken("node1"))
+	require.Equal(t, "", ns.RefreshToken("node2"))
+}
+
 func TestAuthToken(t *testing.T) {
 	// single developer auth
 	publicKey, privateKey, _ := token.KeyPair()
@@ -112,3 +134,19 @@ func nodeToken(n *Node, privateKey string) string {
 	t, _ := token.JWT(privateKey, &c, time.Hour)
 	return t
 }
+
+func TestNodeIncompatibleStages(t *testing.T) {
+	n := &Node{Name: "node1"}
+	p := &Project{
+		Stages: []*Stage{
+			{Name: "old", NodeName: "node1", LastDeployment: &LastDeployment{Version: "v0.1.10"}},
+			{Name: "new", NodeName: "node1", LastDeployment: &LastDeployment{Version: "v0.2.1"}},
+			{Name: "other-node", NodeName: "node2", LastDeployment: &LastDeployment{Version: "v0.2.1"}},
+			{Name: "not-deployed", NodeName: "node1"},
+		},
+	}
+	require.Equal(t, []string{"new"}, n.IncompatibleStages(p, "v0.2.0"))
+	require.Empty(t, n.IncompatibleStages(p, "v0.2.1"))
+	require.Empty(t, n.IncompatibleStages(p, "not-a-version"))
+	require.Empty(t, n.IncompatibleStages(nil, "v0.2.0"))
+}
@@ -2,6 +2,8 @@ package domain
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/mantil-io/mantil/kit/schema"
 	"github.com/mantil-io/mantil/kit/token"
@@ -51,7 +53,7 @@ func (p *Project) SetDefaultStage(name string) {
 		return
 	}
 	s := p.Stage(name)
-	if s == nil || s.Default {
+	if s == nil {
 		return
 	}
 	for _, ps := range p.Stages {
@@ -132,6 +134,21 @@ func (p *Project) RemoveStage(stageName string) {
 	p.setDefaultStage()
 }
 
+// RemoveExpiredStages removes stages whose ttl has passed, node destroys
+// them after they expire. Returns names of the removed stages.
+func (p *Project) RemoveExpiredStages() []string {
+	var names []string
+	for _, s := range p.Stages {
+		if s.Expired() {
+			names = append(names, s.Name)
+		}
+	}
+	for _, name := range names {
+		p.RemoveStage(name)
+	}
+	return names
+}
+
 func (p *Project) NumberOfStages() int {
 	return len(p.Stages)
 }
@@ -183,8 +200,13 @@ type EnvironmentConfig struct {
 }
 
 type ProjectEnvironmentConfig struct {
+	// directory with project apis relative to the project root,
+	// enables sharing one go module between multiple projects
+	ApiDir                string                   `yaml:"api_dir,omitempty"`
 	Stages                []StageEnvironmentConfig `yaml:"stages,omitempty" jsonschema:"nullable,default=[]"`
 	FunctionConfiguration `yaml:",inline"`
+	LogsConfiguration     `yaml:",inline"`
+	Public                PublicConfiguration `yaml:"public,omitempty" jsonschema:"nullable,default={}"`
 }
 
 func (c ProjectEnvironmentConfig) StageEnvConfig(name string) StageEnvironmentConfig {
@@ -200,7 +222,56 @@ type StageEnvironmentConfig struct {
 	Name                  string                      `yaml:"name"`
 	Functions             []FunctionEnvironmentConfig `yaml:"functions,omitempty"`
 	FunctionConfiguration `yaml:",inline"`
-	CustomDomain          CustomDomain `yaml:"custom_domain,omitempty" jsonschema:"nullable,default={}"`
+	LogsConfiguration     `yaml:",inline"`
+	CustomDomain          CustomDomain        `yaml:"custom_domain,omitempty" jsonschema:"nullable,default={}"`
+	Public                PublicConfiguration `yaml:"public,omitempty" jsonschema:"nullable,default={}"`
+	CDN                   CDN                 `yaml:"cdn,omitempty" jsonschema:"nullable,default={}"`
+}
+
+// DefaultLogRetentionDays is retention of the log groups when it is not set
+const DefaultLogRetentionDays = 14
+
+// retention periods supported by CloudWatch Logs
+var logRetentionDays = []int{1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1827, 2192, 2557, 2922, 3288, 3653}
+
+// LogsConfiguration sets retention of the stage log groups and log level
+// passed to the functions through the SDK config. Retention is a pointer so
+// that explicitly set zero is rejected instead of being taken for unset.
+type LogsConfiguration struct {
+	RetentionDays *int   `yaml:"log_retention_days,omitempty"`
+	Level         string `yaml:"log_level,omitempty" jsonschema:"enum=debug,enum=info,enum=warn,enum=error"`
+}
+
+func (c LogsConfiguration) merge(s LogsConfiguration) LogsConfiguration {
+	if s.RetentionDays != nil {
+		c.RetentionDays = s.RetentionDays
+	}
+	if s.Level != "" {
+		c.Level = s.Level
+	}
+	return c
+}
+
+func (c LogsConfiguration) equal(o LogsConfiguration) bool {
+	if c.Level != o.Level || (c.RetentionDays == nil) != (o.RetentionDays == nil) {
+		return false
+	}
+	return c.RetentionDays == nil || *c.RetentionDays == *o.RetentionDays
+}
+
+// ValidateLogRetentionDays checks that days is retention period supported
+// by CloudWatch Logs.
+func ValidateLogRetentionDays(days int) error {
+	for _, d := range logRetentionDays {
+		if d == days {
+			return nil
+		}
+	}
+	var valid []string
+	for _, d := range logRetentionDays {
+		valid = append(valid, strconv.Itoa(d))
+	}
+	return fmt.Errorf("invalid log retention %d days, supported are %s", days, strings.Join(valid, ", "))
 }
 
 type CustomDomain struct {
@@ -258,19 +329,46 @@ const environmentConfigExample = `# Here you can define various configuration pa
 #   KEY3: function
 
 # project:
+#   # directory with apis relative to the project root, default is api
+#   api_dir: api
 #   memory_size: 128
 #   timeout: 30
+#   # retention of the stage log groups in days and level of the structured logs
+#   log_retention_days: 30
+#   log_level: info
 #   env:
 #     KEY: project
 #     KEY2: project
 #     KEY3: project
+#   # alarms go off when the metric is greater than the threshold
+#   # notify SNS topic ARNs, email addresses or https webhooks,
+#   # email and webhook subscriptions must be confirmed
+#   alarms:
+#     errors:
+#       metric: error_rate # percent of failed invocations
+#       threshold: 1
+#       period: 300
+#       notify:
+#         - ops@example.com
 #   stages: 
 #     - name: dev
 #       memory_size: 256
 #       timeout: 60
+#       # X-Ray tracing of the stage functions, active or pass_through
+#       tracing: active
 #       env:
 #         KEY2: stage
 #         KEY3: stage
+#       cdn:
+#         enabled: true
+#         spa: true
+#       public:
+#         compress: true
+#         cache_control:
+#           - pattern: "*.html"
+#             value: no-cache
+#           - pattern: "*"
+#             value: max-age=86400
 #       functions:
 #       - name: ping
 #         memory_size: 512
@@ -278,6 +376,29 @@ const environmentConfigExample = `# Here you can define various configuration pa
 #         private: true
 #         env:
 #           KEY3: function
+#         alarms:
+#           slow:
+#             metric: duration
+#             statistic: p99
+#             threshold: 3000 # milliseconds
+#             notify:
+#               - https://hooks.example.com/alarms
+#           throttles:
+#             metric: throttles
+#             threshold: 0
+#         # private functions also accept tokens issued by the external identity provider
+#         authorizer:
+#           issuer: https://example.com/
+#           jwks_url: https://example.com/.well-known/jwks.json
+#           audience: my-api
+#           required_claims:
+#             scope: ping
+#         # api methods can have their own visibility and http route
+#         methods:
+#           hello:
+#             private: false
+#             http_method: GET
+#             path: /hello/{name}
 `
 
 func ValidateEnvironmentConfig(buf []byte) (*EnvironmentConfig, error) {
@@ -297,6 +418,15 @@ func ValidateEnvironmentConfig(buf []byte) (*EnvironmentConfig, error) {
 			fmt.Errorf("invalid cron syntax"),
 		}
 	}
+	if err := ec.validateMethods(); err != nil {
+		return nil, &EnvironmentConfigValidationError{err}
+	}
+	if err := ec.validateAlarms(); err != nil {
+		return nil, &EnvironmentConfigValidationError{err}
+	}
+	if err := ec.validateLogRetention(); err != nil {
+		return nil, &EnvironmentConfigValidationError{err}
+	}
 	return ec, nil
 }
 
@@ -317,3 +447,41 @@ func (ec *EnvironmentConfig) validateCron() bool {
 	}
 	return true
 }
+
+func (ec *EnvironmentConfig) validateLogRetention() error {
+	p := ec.Project
+	if err := p.LogsConfiguration.validateRetention(); err != nil {
+		return err
+	}
+	for _, s := range p.Stages {
+		if err := s.LogsConfiguration.validateRetention(); err != nil {
+			return fmt.Errorf("stage %s %w", s.Name, err)
+		}
+	}
+	return nil
+}
+
+func (c LogsConfiguration) validateRetention() error {
+	if c.RetentionDays == nil {
+		return nil
+	}
+	return ValidateLogRetentionDays(*c.RetentionDays)
+}
+
+func (ec *EnvironmentConfig) validateMethods() error {
+	p := ec.Project
+	if err := p.validateMethods(); err != nil {
+		return err
+	}
+	for _, s := range p.Stages {
+		if err := s.validateMethods(); err != nil {
+			return err
+		}
+		for _, f := range s.Functions {
+			if err := f.validateMethods(); err != nil {
+				return fmt.Errorf("function %s %w", f.Name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"path"
+	"sort"
+)
+
+// PublicConfiguration of the http headers for the files uploaded to the
+// public site bucket. Stage configuration takes precedence over project.
+type PublicConfiguration struct {
+	// first rule with the pattern matching file path sets Cache-Control header
+	CacheControl []CacheControlRule `yaml:"cache_control,omitempty"`
+	// upload compressible files gzip encoded
+	Compress bool `yaml:"compress,omitempty"`
+}
+
+type CacheControlRule struct {
+	Pattern string `yaml:"pattern"`
+	Value   string `yaml:"value"`
+}
+
+// CacheControlFor returns Cache-Control header value for the file path
+// relative to the public folder. Pattern is matched against the full path and
+// the file name.
+func (c PublicConfiguration) CacheControlFor(p string) string {
+	for _, r := range c.CacheControl {
+		if ok, _ := path.Match(r.Pattern, p); ok {
+			return r.Value
+		}
+		if ok, _ := path.Match(r.Pattern, path.Base(p)); ok {
+			return r.Value
+		}
+	}
+	return ""
+}
+
+func (s *Stage) PublicConfiguration() PublicConfiguration {
+	if s.project == nil || s.project.environment == nil {
+		return PublicConfiguration{}
+	}
+	ec := s.project.environment.Project
+	c := ec.Public
+	sc := ec.StageEnvConfig(s.Name).Public
+	if len(sc.CacheControl) > 0 {
+		c.CacheControl = sc.CacheControl
+	}
+	if sc.Compress {
+		c.Compress = true
+	}
+	return c
+}
+
+// ApplyFiles updates manifest of the public files. Returns paths of the
+// added, updated and removed files.
+func (p *Public) ApplyFiles(files []Resource) (added, updated, removed []string) {
+	current := make(map[string]string)
+	for _, f := range files {
+		current[f.Name] = f.Hash
+		hash, ok := p.Files[f.Name]
+		if !ok {
+			added = append(added, f.Name)
+			continue
+		}
+		if hash != f.Hash {
+			updated = append(updated, f.Name)
+		}
+	}
+	for name := range p.Files {
+		if _, ok := current[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(updated)
+	sort.Strings(removed)
+	p.Files = current
+	return
+}
@@ -0,0 +1,73 @@
+package domain_test
+
+import (
+	"testing"
+
+	. "github.com/mantil-io/mantil/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublicApplyFiles(t *testing.T) {
+	p := &Public{}
+	added, updated, removed := p.ApplyFiles([]Resource{
+		{Name: "index.html", Hash: "1"},
+		{Name: "css/main.css", Hash: "2"},
+	})
+	require.Equal(t, []string{"css/main.css", "index.html"}, added)
+	require.Empty(t, updated)
+	require.Empty(t, removed)
+
+	added, updated, removed = p.ApplyFiles([]Resource{
+		{Name: "index.html", Hash: "1"},
+		{Name: "css/main.css", Hash: "3"},
+		{Name: "js/app.js", Hash: "4"},
+	})
+	require.Equal(t, []string{"js/app.js"}, added)
+	require.Equal(t, []string{"css/main.css"}, updated)
+	require.Empty(t, removed)
+
+	added, updated, removed = p.ApplyFiles([]Resource{
+		{Name: "index.html", Hash: "1"},
+	})
+	require.Empty(t, added)
+	require.Empty(t, updated)
+	require.Equal(t, []string{"css/main.css", "js/app.js"}, removed)
+	require.Equal(t, map[string]string{"index.html": "1"}, p.Files)
+}
+
+func TestPublicConfiguration(t *testing.T) {
+	env := &EnvironmentConfig{
+		Project: ProjectEnvironmentConfig{
+			Public: PublicConfiguration{
+				CacheControl: []CacheControlRule{
+					{Pattern: "*", Value: "max-age=60"},
+				},
+			},
+			Stages: []StageEnvironmentConfig{
+				{
+					Name: "dev",
+					Public: PublicConfiguration{
+						Compress: true,
+						CacheControl: []CacheControlRule{
+							{Pattern: "*.html", Value: "no-cache"},
+							{Pattern: "assets/*", Value: "max-age=31536000"},
+						},
+					},
+				},
+			},
+		},
+	}
+	s := initStage(&Stage{Name: "dev"}, env)
+
+	c := s.PublicConfiguration()
+	require.True(t, c.Compress)
+	require.Equal(t, "no-cache", c.CacheControlFor("index.html"))
+	require.Equal(t, "no-cache", c.CacheControlFor("docs/index.html"))
+	require.Equal(t, "max-age=31536000", c.CacheControlFor("assets/app.js"))
+	require.Equal(t, "", c.CacheControlFor("app.js"))
+
+	s = initStage(&Stage{Name: "prod"}, env)
+	c = s.PublicConfiguration()
+	require.False(t, c.Compress)
+	require.Equal(t, "max-age=60", c.CacheControlFor("app.js"))
+}
@@ -14,6 +14,8 @@ type SDKConfig struct {
 	ResourceTags    map[string]string
 	WsForwarderName string
 	NamingTemplate  string
+	// minimal level of the structured function logs, empty for the SDK default
+	LogLevel string `json:",omitempty"`
 }
 
 func (c *SDKConfig) Encode() string {
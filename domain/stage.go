@@ -6,11 +6,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"net/url"
 	"reflect"
 	"strings"
 	"time"
 
 	"github.com/mantil-io/mantil/kit/token"
+	"github.com/pkg/errors"
 )
 
 const (
@@ -18,27 +20,35 @@ const (
 )
 
 type Stage struct {
-	Name           string          `yaml:"name"`
-	Default        bool            `yaml:"default,omitempty"`
-	NodeName       string          `yaml:"node"`
-	Keys           StageKeys       `yaml:"keys"`
-	Endpoints      *StageEndpoints `yaml:"endpoints,omitempty"`
-	LastDeployment *LastDeployment `yaml:"last_deployment,omitempty"`
-	Functions      []*Function     `yaml:"functions,omitempty"`
-	Public         *Public         `yaml:"public,omitempty"`
-	CustomDomain   CustomDomain    `yaml:"custom_domain,omitempty"`
+	Name           string            `yaml:"name"`
+	Default        bool              `yaml:"default,omitempty"`
+	NodeName       string            `yaml:"node"`
+	Keys           StageKeys         `yaml:"keys"`
+	Endpoints      *StageEndpoints   `yaml:"endpoints,omitempty"`
+	LastDeployment *LastDeployment   `yaml:"last_deployment,omitempty"`
+	Expiry         *StageExpiry      `yaml:"expiry,omitempty"`
+	Functions      []*Function       `yaml:"functions,omitempty"`
+	Public         *Public           `yaml:"public,omitempty"`
+	CustomDomain   CustomDomain      `yaml:"custom_domain,omitempty"`
+	CDN            CDN               `yaml:"cdn,omitempty"`
+	Distribution   *Distribution     `yaml:"distribution,omitempty"`
+	Logs           LogsConfiguration `yaml:"logs,omitempty"`
 	project        *Project
 	node           *Node
+	keysRotated    bool
 }
 
 type StageKeys struct {
-	Public  string `yaml:"public"`
-	Private string `yaml:"private"`
+	Public   string       `yaml:"public"`
+	Private  string       `yaml:"private"`
+	Previous *PreviousKey `yaml:"previous,omitempty"`
 }
 
 type Public struct {
 	Bucket string `yaml:"bucket"`
 	Hash   string `yaml:"hash,omitempty"`
+	// manifest of uploaded files, relative path to the file hash
+	Files map[string]string `yaml:"files,omitempty"`
 }
 
 type LastDeployment struct {
@@ -100,10 +110,64 @@ func (s *Stage) AuthToken() (string, error) {
 	return token.JWT(s.Keys.Private, claims, 7*24*time.Hour)
 }
 
+// ScopedAuthToken creates token for clients of the stage private functions.
+// Token is restricted to the functions list if not empty.
+func (s *Stage) ScopedAuthToken(username string, functions []string, claims map[string]string, maxAge time.Duration) (string, error) {
+	for _, f := range functions {
+		if s.FindFunction(f) == nil {
+			return "", &FunctionNotFoundError{Name: f}
+		}
+	}
+	return token.JWT(s.Keys.Private, &AccessTokenClaims{
+		Workspace: s.node.workspace.ID,
+		Project:   s.project.Name,
+		Stage:     s.Name,
+		Username:  username,
+		Functions: functions,
+		Claims:    claims,
+	}, maxAge)
+}
+
 func (s *Stage) AuthEnv() map[string]string {
-	return map[string]string{
+	env := map[string]string{
 		EnvPublicKey: s.Keys.Public,
 	}
+	s.Keys.Previous.authEnv(env)
+	if c := s.authorizerConfig(); c != nil {
+		env[EnvAuthorizerConfig] = c.Encode()
+	}
+	return env
+}
+
+// RotateKeys creates new key pair for signing stage access tokens. Previous
+// public key is accepted by the authorizer during KeyRotationGracePeriod.
+// New keys are applied to the authorizer on the next deploy.
+func (s *Stage) RotateKeys() error {
+	publicKey, privateKey, err := token.KeyPair()
+	if err != nil {
+		return errors.Wrap(err, "could not create public/private key pair")
+	}
+	s.Keys = StageKeys{
+		Public:   publicKey,
+		Private:  privateKey,
+		Previous: newPreviousKey(s.Keys.Public),
+	}
+	s.keysRotated = true
+	return nil
+}
+
+// applyKeyChanges returns true if the authorizer configuration needs to be
+// updated, because keys are rotated or the previous key expired
+func (s *Stage) applyKeyChanges() bool {
+	if s.keysRotated {
+		s.keysRotated = false
+		return true
+	}
+	if s.Keys.Previous != nil && !s.Keys.Previous.valid() {
+		s.Keys.Previous = nil
+		return true
+	}
+	return false
 }
 
 func (s Stage) mantilResourceNamingTemplate() string {
@@ -139,14 +203,18 @@ func (s *Stage) applyConfiguration(ec *EnvironmentConfig) bool {
 	}
 	sec := ec.Project.StageEnvConfig(s.Name)
 	changed := false
+	// applied before functions configuration, log level is part of the
+	// functions default environment
+	if logs := ec.Project.LogsConfiguration.merge(sec.LogsConfiguration); !logs.equal(s.Logs) {
+		s.Logs = logs
+		changed = true
+	}
 	for _, f := range s.Functions {
 		// ordered by priority from lowest to highest
-		sources := []FunctionConfiguration{
-			s.defaultFunctionConfiguration(),
-			ec.Project.FunctionConfiguration,
-			sec.FunctionConfiguration,
-			sec.FunctionEnvConfig(f.Name).FunctionConfiguration,
-		}
+		sources := append(
+			[]FunctionConfiguration{s.defaultFunctionConfiguration()},
+			functionConfigurationSources(ec, sec, f.Name, f.sourceMethods)...,
+		)
 		fc := f.FunctionConfiguration.merge(sources...)
 		changed = changed || fc
 	}
@@ -155,9 +223,48 @@ func (s *Stage) applyConfiguration(ec *EnvironmentConfig) bool {
 		s.CustomDomain.setDefaults()
 		changed = true
 	}
+	if s.CDN != sec.CDN {
+		s.CDN = sec.CDN
+		changed = true
+	}
 	return changed
 }
 
+// functionConfigurationSources returns function configuration from the source
+// code and environment ordered by priority from lowest to highest
+func functionConfigurationSources(ec *EnvironmentConfig, sec StageEnvironmentConfig, name string, sourceMethods map[string]MethodConfiguration) []FunctionConfiguration {
+	return []FunctionConfiguration{
+		{Methods: sourceMethods},
+		ec.Project.FunctionConfiguration,
+		sec.FunctionConfiguration,
+		sec.FunctionEnvConfig(name).FunctionConfiguration,
+	}
+}
+
+// FunctionRoutes returns routes of the function methods configured in the
+// source code or in the environment configuration.
+func (s *Stage) FunctionRoutes(name string, sourceMethods map[string]MethodConfiguration) []FunctionRoute {
+	return s.configuredFunction(name, sourceMethods).Routes()
+}
+
+// FunctionTracing is true if the function has active tracing in the
+// environment configuration.
+func (s *Stage) FunctionTracing(name string, sourceMethods map[string]MethodConfiguration) bool {
+	return s.configuredFunction(name, sourceMethods).Tracing == TracingActive
+}
+
+// configuredFunction returns function with the configuration from the source
+// code and environment, without the configuration applied to the stage
+func (s *Stage) configuredFunction(name string, sourceMethods map[string]MethodConfiguration) *Function {
+	ec := &EnvironmentConfig{}
+	if s.project != nil && s.project.environment != nil {
+		ec = s.project.environment
+	}
+	f := &Function{Name: name, stage: s}
+	f.FunctionConfiguration.merge(functionConfigurationSources(ec, ec.Project.StageEnvConfig(s.Name), name, sourceMethods)...)
+	return f
+}
+
 func (s *Stage) defaultFunctionConfiguration() FunctionConfiguration {
 	return FunctionConfiguration{
 		MemorySize: 128,
@@ -180,6 +287,7 @@ func (s *Stage) sdkConfigEnv() string {
 		ResourceTags:    s.ResourceTags(),
 		WsForwarderName: s.WsForwarderLambdaName(),
 		NamingTemplate:  s.ResourceNamingTemplate(),
+		LogLevel:        s.Logs.Level,
 	}
 	return c.Encode()
 }
@@ -255,6 +363,35 @@ func (s *Stage) RestEndpoint() string {
 	return ""
 }
 
+// RestApiID returns id of the stage http api parsed from its execute-api
+// endpoint, empty if stage is not deployed
+func (s *Stage) RestApiID() string {
+	if s.Endpoints == nil {
+		return ""
+	}
+	return executeApiID(s.Endpoints.Rest)
+}
+
+// WsApiID returns id of the stage websocket api, empty if stage is not deployed
+func (s *Stage) WsApiID() string {
+	if s.Endpoints == nil {
+		return ""
+	}
+	return executeApiID(s.Endpoints.Ws)
+}
+
+func executeApiID(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return ""
+	}
+	host := u.Hostname()
+	if !strings.Contains(host, ".execute-api.") {
+		return ""
+	}
+	return strings.Split(host, ".")[0]
+}
+
 func (s *Stage) WsEndpoint() string {
 	if s.CustomDomain.DomainName != "" {
 		return fmt.Sprintf("wss://%s.%s", s.CustomDomain.WsSubdomain, s.CustomDomain.DomainName)
@@ -321,6 +458,25 @@ func (s *Stage) HasPublic() bool {
 	return s.Public != nil
 }
 
+// LogRetentionDays returns retention of the stage log groups.
+func (s *Stage) LogRetentionDays() int {
+	if s.Logs.RetentionDays == nil {
+		return DefaultLogRetentionDays
+	}
+	return *s.Logs.RetentionDays
+}
+
+// HasTracing is true if any of the stage functions has active tracing, api
+// lambda functions are then also traced.
+func (s *Stage) HasTracing() bool {
+	for _, f := range s.Functions {
+		if f.Tracing == TracingActive {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *Stage) AsCliStage() *CliStage {
 	if s == nil {
 		return nil
@@ -358,6 +514,8 @@ const (
 	AwsResourceS3Bucket   = "S3 Bucket"
 	AwsResourceDynamoDB   = "DynamoDB Table"
 	AwsResourceStack      = "CloudFormation Stack"
+	AwsResourceAlarm      = "CloudWatch Alarm"
+	AwsResourceSNSTopic   = "SNS Topic"
 )
 
 // Resources list of resources created for the stage
@@ -378,6 +536,15 @@ func (s *Stage) Resources() []AwsResource {
 		ar = append(ar, AwsResource{"", s.Public.Bucket, AwsResourceS3Bucket})
 	}
 
+	for _, f := range s.Functions {
+		for _, a := range f.Alarms() {
+			ar = append(ar, AwsResource{fmt.Sprintf("%s-%s", f.Name, a.Name), a.AWSName, AwsResourceAlarm})
+		}
+	}
+	for _, t := range s.AlarmTopics() {
+		ar = append(ar, AwsResource{alarmsTopic(t.Key), s.AlarmsTopicName(t.Key), AwsResourceSNSTopic})
+	}
+
 	return ar
 }
 
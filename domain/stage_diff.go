@@ -7,6 +7,8 @@ import (
 type Resource struct {
 	Name string
 	Hash string
+	// api methods configured in the function source code
+	Methods map[string]MethodConfiguration
 }
 
 type resourceDiff struct {
@@ -67,10 +69,11 @@ func (s *Stage) ApplyChanges(funcs []Resource, publicHash string) (*StageDiff, e
 	}
 	publicDiff := s.applyPublicChanges(publicHash)
 	configChanged := s.applyConfiguration(s.project.environment)
+	keysChanged := s.applyKeyChanges()
 	return &StageDiff{
 		functions:     funcDiff,
 		public:        publicDiff,
-		configChanged: configChanged,
+		configChanged: configChanged || keysChanged,
 	}, nil
 }
 
@@ -86,11 +89,15 @@ func (s *Stage) applyFunctionChanges(localFuncs []Resource) (resourceDiff, error
 	s.RemoveFunctions(diff.removed)
 	for _, f := range s.Functions {
 		for _, lf := range localFuncs {
-			if f.Name == lf.Name && f.Hash != lf.Hash {
+			if f.Name != lf.Name {
+				continue
+			}
+			f.sourceMethods = lf.Methods
+			if f.Hash != lf.Hash {
 				f.SetHash(lf.Hash)
 				diff.updated = append(diff.updated, f.Name)
-				break
 			}
+			break
 		}
 	}
 	return diff, nil
@@ -1,6 +1,7 @@
 package domain_test
 
 import (
+	"encoding/base64"
 	"testing"
 
 	. "github.com/mantil-io/mantil/domain"
@@ -494,3 +495,154 @@ func initStage(s *Stage, env *EnvironmentConfig) *Stage {
 	}
 	return s
 }
+
+func TestStageFunctionMethods(t *testing.T) {
+	private, public := true, false
+	env := &EnvironmentConfig{
+		Project: ProjectEnvironmentConfig{
+			FunctionConfiguration: FunctionConfiguration{
+				Methods: map[string]MethodConfiguration{
+					"List": {HTTPMethod: "GET"},
+				},
+			},
+			Stages: []StageEnvironmentConfig{
+				{
+					Name: "dev",
+					Functions: []FunctionEnvironmentConfig{
+						{
+							Name: "todo",
+							FunctionConfiguration: FunctionConfiguration{
+								Private: true,
+								Methods: map[string]MethodConfiguration{
+									"get": {Path: "/item/{id}/"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	s := initStage(&Stage{Name: "dev"}, env)
+	methods := map[string]MethodConfiguration{
+		"get":    {HTTPMethod: "GET", Path: "/{id}", Private: &public},
+		"delete": {HTTPMethod: "DELETE", Path: "/{id}"},
+		"list":   {Private: &private},
+	}
+	routes := []FunctionRoute{
+		{Method: "delete", HTTPMethod: "DELETE", Path: "/todo/{id}", Private: true},
+		{Method: "get", HTTPMethod: "GET", Path: "/todo/item/{id}", Private: false},
+		{Method: "list", HTTPMethod: "GET", Path: "/todo/list", Private: true},
+	}
+	require.Equal(t, routes, s.FunctionRoutes("todo", methods))
+
+	diff, err := s.ApplyChanges([]Resource{{Name: "todo", Hash: "hash", Methods: methods}}, "")
+	require.NoError(t, err)
+	require.True(t, diff.HasUpdates())
+	f := s.Functions[0]
+	require.Equal(t, routes, f.Routes())
+	require.Equal(t, []string{"id"}, f.Routes()[0].PathParams())
+	require.Equal(t, "GET /todo/item/{id}", f.Routes()[1].Key())
+	require.False(t, f.RootRouted())
+
+	f.Methods["default"] = MethodConfiguration{}
+	require.True(t, f.RootRouted())
+}
+
+func TestStageFunctionTracing(t *testing.T) {
+	env := &EnvironmentConfig{
+		Project: ProjectEnvironmentConfig{
+			Stages: []StageEnvironmentConfig{
+				{
+					Name:                  "dev",
+					FunctionConfiguration: FunctionConfiguration{Tracing: TracingActive},
+					Functions: []FunctionEnvironmentConfig{
+						{
+							Name:                  "ping",
+							FunctionConfiguration: FunctionConfiguration{Tracing: TracingPassThrough},
+						},
+					},
+				},
+			},
+		},
+	}
+	s := initStage(&Stage{Name: "dev"}, env)
+	require.True(t, s.FunctionTracing("todo", nil))
+	require.False(t, s.FunctionTracing("ping", nil))
+	_, err := s.ApplyChanges([]Resource{{Name: "todo", Hash: "hash"}, {Name: "ping", Hash: "hash"}}, "")
+	require.NoError(t, err)
+	require.Equal(t, "Active", s.FindFunction("todo").TracingMode())
+	require.Equal(t, "PassThrough", s.FindFunction("ping").TracingMode())
+	require.True(t, s.HasTracing())
+
+	s = initStage(&Stage{Name: "prod"}, env)
+	require.False(t, s.FunctionTracing("todo", nil))
+	_, err = s.ApplyChanges([]Resource{{Name: "todo", Hash: "hash"}}, "")
+	require.NoError(t, err)
+	require.Equal(t, "PassThrough", s.FindFunction("todo").TracingMode())
+	require.False(t, s.HasTracing())
+}
+
+func TestStageLogsConfiguration(t *testing.T) {
+	env, err := ValidateEnvironmentConfig([]byte(`
+project:
+  log_retention_days: 30
+  log_level: warn
+  stages:
+    - name: dev
+      log_level: debug
+`))
+	require.NoError(t, err)
+	s := initStage(&Stage{Name: "dev"}, env)
+	_, err = s.ApplyChanges([]Resource{{Name: "todo", Hash: "hash"}}, "")
+	require.NoError(t, err)
+	require.Equal(t, 30, s.LogRetentionDays())
+	require.Equal(t, "debug", s.Logs.Level)
+	sdkConfig := s.FindFunction("todo").Env[EnvSDKConfig]
+	require.Contains(t, decodeSDKConfig(t, sdkConfig), `"LogLevel":"debug"`)
+
+	s = initStage(&Stage{Name: "prod"}, nil)
+	require.Equal(t, DefaultLogRetentionDays, s.LogRetentionDays())
+
+	_, err = ValidateEnvironmentConfig([]byte(`
+project:
+  stages:
+    - name: dev
+      log_retention_days: 10
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "stage dev invalid log retention 10 days")
+
+	_, err = ValidateEnvironmentConfig([]byte(`
+project:
+  log_retention_days: 0
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid log retention 0 days")
+}
+
+func decodeSDKConfig(t *testing.T, s string) string {
+	buf, err := base64.StdEncoding.DecodeString(s)
+	require.NoError(t, err)
+	return string(buf)
+}
+
+func TestMethodConfigurationValidate(t *testing.T) {
+	require.NoError(t, MethodConfiguration{HTTPMethod: "GET", Path: "/{id}/items/"}.Validate())
+	require.Error(t, MethodConfiguration{HTTPMethod: "get"}.Validate())
+	require.Error(t, MethodConfiguration{Path: "items"}.Validate())
+	require.Error(t, MethodConfiguration{Path: "/{1d}"}.Validate())
+
+	_, err := ValidateEnvironmentConfig([]byte(`
+project:
+  stages:
+    - name: dev
+      functions:
+        - name: todo
+          methods:
+            get:
+              path: items
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "function todo method get")
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// StageExpiry is set for the ephemeral stages created with ttl. Node destroys
+// the stage after it expires and notifies webhook if one is set.
+type StageExpiry struct {
+	ExpiresAt int64  `yaml:"expires_at"`
+	Notify    string `yaml:"notify,omitempty"`
+}
+
+// SetTTL makes stage ephemeral, it expires ttl from now.
+func (s *Stage) SetTTL(ttl time.Duration, notify string) error {
+	if ttl <= 0 {
+		return fmt.Errorf("ttl must be positive, got %s", ttl)
+	}
+	if err := validateExpiryNotify(notify); err != nil {
+		return err
+	}
+	s.Expiry = &StageExpiry{
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+		Notify:    notify,
+	}
+	return nil
+}
+
+// Extend pushes the expiry of the ephemeral stage out by d.
+func (s *Stage) Extend(d time.Duration) error {
+	if s.Expiry == nil {
+		return fmt.Errorf("stage %s was not created with ttl", s.Name)
+	}
+	if d <= 0 {
+		return fmt.Errorf("extension must be positive, got %s", d)
+	}
+	from := time.Unix(s.Expiry.ExpiresAt, 0)
+	if now := time.Now(); from.Before(now) {
+		from = now
+	}
+	s.Expiry.ExpiresAt = from.Add(d).Unix()
+	return nil
+}
+
+func (s *Stage) Ephemeral() bool {
+	return s.Expiry != nil
+}
+
+// RemainingTTL returns time until the stage expires, zero for the expired
+// and stages without ttl.
+func (s *Stage) RemainingTTL() time.Duration {
+	if s.Expiry == nil {
+		return 0
+	}
+	d := time.Until(time.Unix(s.Expiry.ExpiresAt, 0))
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+func (s *Stage) Expired() bool {
+	return s.Expiry != nil && s.RemainingTTL() == 0
+}
+
+func validateExpiryNotify(notify string) error {
+	if notify == "" || strings.HasPrefix(notify, "https://") {
+		return nil
+	}
+	return fmt.Errorf("invalid notify target %s, expected https URL", notify)
+}
@@ -0,0 +1,51 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStageExpiry(t *testing.T) {
+	stage := testStage(t)
+	require.False(t, stage.Ephemeral())
+	require.False(t, stage.Expired())
+	require.Zero(t, stage.RemainingTTL())
+	require.Error(t, stage.Extend(time.Hour))
+
+	require.Error(t, stage.SetTTL(0, ""))
+	require.Error(t, stage.SetTTL(time.Hour, "http://example.com/hook"))
+	require.Nil(t, stage.Expiry)
+
+	require.NoError(t, stage.SetTTL(48*time.Hour, "https://example.com/hook"))
+	require.True(t, stage.Ephemeral())
+	require.False(t, stage.Expired())
+	require.Equal(t, "https://example.com/hook", stage.Expiry.Notify)
+	require.InDelta(t, (48 * time.Hour).Seconds(), stage.RemainingTTL().Seconds(), 2)
+
+	require.NoError(t, stage.Extend(24*time.Hour))
+	require.InDelta(t, (72 * time.Hour).Seconds(), stage.RemainingTTL().Seconds(), 2)
+	require.Error(t, stage.Extend(-time.Hour))
+
+	// expired stage is extended from now
+	stage.Expiry.ExpiresAt = time.Now().Add(-time.Hour).Unix()
+	require.True(t, stage.Expired())
+	require.NoError(t, stage.Extend(time.Hour))
+	require.False(t, stage.Expired())
+	require.InDelta(t, time.Hour.Seconds(), stage.RemainingTTL().Seconds(), 2)
+}
+
+func TestRemoveExpiredStages(t *testing.T) {
+	stage := testStage(t)
+	project := stage.Project()
+	require.Empty(t, project.RemoveExpiredStages())
+
+	require.NoError(t, stage.SetTTL(time.Hour, ""))
+	require.Empty(t, project.RemoveExpiredStages())
+	require.Len(t, project.Stages, 1)
+
+	stage.Expiry.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+	require.Equal(t, []string{"my-stage"}, project.RemoveExpiredStages())
+	require.Empty(t, project.Stages)
+}
@@ -3,6 +3,7 @@ package domain_test
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	. "github.com/mantil-io/mantil/domain"
 	"github.com/stretchr/testify/require"
@@ -37,6 +38,27 @@ func TestStageAuthToken(t *testing.T) {
 	require.NotEmpty(t, token)
 }
 
+func TestStageScopedAuthToken(t *testing.T) {
+	stage := testStage(t)
+	_, err := stage.ScopedAuthToken("partner", []string{"func2"}, nil, time.Hour)
+	require.Error(t, err)
+
+	tkn, err := stage.ScopedAuthToken("partner", []string{"func1"}, map[string]string{"tenant": "acme"}, time.Hour)
+	require.NoError(t, err)
+	claims, err := ReadAccessToken(map[string]string{AccessTokenHeader: tkn}, stage.Keys.Public)
+	require.NoError(t, err)
+	require.Equal(t, "partner", claims.Username)
+	require.Equal(t, "my-stage", claims.Stage)
+	require.Equal(t, "acme", claims.Claims["tenant"])
+	require.True(t, claims.FunctionAllowed("func1"))
+	require.False(t, claims.FunctionAllowed("func2"))
+	require.False(t, claims.FunctionAllowed(""))
+
+	claims.Functions = nil
+	require.True(t, claims.FunctionAllowed("func2"))
+	require.True(t, claims.FunctionAllowed(""))
+}
+
 func TestStageAuthEnv(t *testing.T) {
 	stage := testStage(t)
 	ae := stage.AuthEnv()
@@ -60,6 +82,25 @@ func TestStageSetEndpoints(t *testing.T) {
 	require.Equal(t, "ws", stage.Endpoints.Ws)
 }
 
+func TestStageRestApiID(t *testing.T) {
+	stage := testStage(t)
+	require.Empty(t, stage.RestApiID())
+
+	stage.SetEndpoints("https://a1b2c3d4e5.execute-api.eu-central-1.amazonaws.com", "ws")
+	require.Equal(t, "a1b2c3d4e5", stage.RestApiID())
+
+	stage.SetEndpoints("https://api.example.com", "ws")
+	require.Empty(t, stage.RestApiID())
+}
+
+func TestStageWsApiID(t *testing.T) {
+	stage := testStage(t)
+	require.Empty(t, stage.WsApiID())
+
+	stage.SetEndpoints("rest", "wss://f6g7h8i9j0.execute-api.eu-central-1.amazonaws.com")
+	require.Equal(t, "f6g7h8i9j0", stage.WsApiID())
+}
+
 func TestStageSetLastDeployment(t *testing.T) {
 	stage := testStage(t)
 	stage.SetLastDeployment()
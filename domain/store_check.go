@@ -0,0 +1,137 @@
+package domain
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// StoreProblem is inconsistency in the workspace or project files which
+// fails commands using them.
+type StoreProblem struct {
+	File    string
+	Problem string
+	Fix     string
+}
+
+// CheckStore reads workspace and project files one by one and reports all
+// problems found, FileStore fails on the first one. Workspace is nil if it
+// can't be read, project is nil outside of the project.
+func CheckStore() (*Workspace, *Project, []StoreProblem) {
+	w, problems := checkWorkspace()
+	projectRoot, err := FindProjectRoot(".")
+	if err != nil {
+		return w, nil, problems
+	}
+	p, pp := checkProject(projectRoot, w)
+	return w, p, append(problems, pp...)
+}
+
+func checkWorkspace() (*Workspace, []StoreProblem) {
+	path, name, err := WorkspacePathAndName()
+	if err != nil {
+		return nil, []StoreProblem{{Problem: err.Error()}}
+	}
+	file := filepath.Join(path, name)
+	buf, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		// created on the first use
+		w := newWorkspace()
+		w.afterRestore()
+		return w, nil
+	}
+	if err != nil {
+		return nil, []StoreProblem{{File: file, Problem: err.Error()}}
+	}
+	w := &Workspace{}
+	if err := yaml.Unmarshal(buf, w); err != nil {
+		return nil, []StoreProblem{{
+			File:    file,
+			Problem: fmt.Sprintf("could not parse workspace - %v", err),
+			Fix:     "Fix the yaml syntax of the workspace file.",
+		}}
+	}
+	w.afterRestore()
+	return w, checkWorkspaceNodes(file, w)
+}
+
+func checkWorkspaceNodes(file string, w *Workspace) []StoreProblem {
+	var problems []StoreProblem
+	names := make(map[string]bool)
+	for _, n := range w.Nodes {
+		if names[n.Name] {
+			problems = append(problems, StoreProblem{
+				File:    file,
+				Problem: fmt.Sprintf("node %s is defined more than once", n.Name),
+				Fix:     fmt.Sprintf("Remove duplicate entries of the node %s from the workspace file.", n.Name),
+			})
+		}
+		names[n.Name] = true
+	}
+	return problems
+}
+
+func checkProject(projectRoot string, w *Workspace) (*Project, []StoreProblem) {
+	var problems []StoreProblem
+	stateFile := stateFilePath(projectRoot)
+	p, err := readProjectState(projectRoot)
+	if err != nil {
+		problems = append(problems, StoreProblem{
+			File:    stateFile,
+			Problem: fmt.Sprintf("could not read project state - %v", err),
+			Fix:     "State file is maintained by Mantil, restore it from the version control.",
+		})
+	} else {
+		problems = append(problems, checkProjectStages(stateFile, p, w)...)
+	}
+	envFile := environmentFilePath(projectRoot)
+	buf, err := ioutil.ReadFile(envFile)
+	if os.IsNotExist(err) {
+		problems = append(problems, StoreProblem{
+			File:    envFile,
+			Problem: "environment file not found",
+			Fix:     "Restore it from the version control or create an empty file, it is required in the project config directory.",
+		})
+		return p, problems
+	}
+	if err != nil {
+		return p, append(problems, StoreProblem{File: envFile, Problem: err.Error()})
+	}
+	if _, err := ValidateEnvironmentConfig(buf); err != nil {
+		problems = append(problems, StoreProblem{
+			File:    envFile,
+			Problem: fmt.Sprintf("invalid environment configuration - %v", err),
+			Fix:     "Fix the environment file, supported options are described in the comments of a new project environment file.",
+		})
+	}
+	return p, problems
+}
+
+func checkProjectStages(file string, p *Project, w *Workspace) []StoreProblem {
+	var problems []StoreProblem
+	defaults := 0
+	for _, s := range p.Stages {
+		if s.Default {
+			defaults++
+		}
+		if w != nil && w.Node(s.NodeName) == nil {
+			problems = append(problems, StoreProblem{
+				File:    file,
+				Problem: fmt.Sprintf("stage %s references node %s which is not in the workspace", s.Name, s.NodeName),
+				Fix: fmt.Sprintf("Install the node with 'mantil aws install %s' or log in to it with 'mantil node login <node-url>', "+
+					"or remove the stage %s from the state file if the node no longer exists.", s.NodeName, s.Name),
+			})
+		}
+	}
+	if len(p.Stages) > 0 && defaults != 1 {
+		problems = append(problems, StoreProblem{
+			File:    file,
+			Problem: fmt.Sprintf("project has %d default stages", defaults),
+			Fix:     "Set the default stage with 'mantil stage use <stage>'.",
+		})
+	}
+	return problems
+}
@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckProject(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, configDir), os.ModePerm))
+	writeConfig := func(name, content string) {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(root, configDir, name), []byte(content), 0644))
+	}
+	writeConfig(configFilename, `name: project
+stages:
+- name: dev
+  node: dev
+  default: true
+- name: prod
+  node: missing
+  default: true
+`)
+	w := &Workspace{Nodes: []*Node{{Name: "dev"}}}
+	w.afterRestore()
+
+	p, problems := checkProject(root, w)
+	require.NotNil(t, p)
+	require.Len(t, problems, 3)
+	require.Contains(t, problems[0].Problem, "stage prod references node missing")
+	require.Contains(t, problems[1].Problem, "2 default stages")
+	require.Equal(t, "environment file not found", problems[2].Problem)
+
+	writeConfig(environmentFilename, "project:\n  memory_size: wrong\n")
+	_, problems = checkProject(root, w)
+	require.Len(t, problems, 3)
+	require.Contains(t, problems[2].Problem, "invalid environment configuration")
+
+	writeConfig(environmentFilename, "")
+	writeConfig(configFilename, `name: project
+stages:
+- name: dev
+  node: dev
+  default: true
+`)
+	_, problems = checkProject(root, w)
+	require.Empty(t, problems)
+}
+
+func TestCheckWorkspaceNodes(t *testing.T) {
+	w := &Workspace{Nodes: []*Node{{Name: "dev"}, {Name: "prod"}, {Name: "dev"}}}
+	problems := checkWorkspaceNodes("workspace.yml", w)
+	require.Len(t, problems, 1)
+	require.Equal(t, "node dev is defined more than once", problems[0].Problem)
+}
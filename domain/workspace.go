@@ -43,12 +43,13 @@ const (
 )
 
 type Workspace struct {
-	ID        string              `yaml:"id"`
-	Version   string              `yaml:"version"`
-	CreatedAt int64               `yaml:"created_at"`
-	Projects  []*WorkspaceProject `yaml:"projects,omitempty"`
-	Nodes     []*Node             `yaml:"nodes"`
-	NodeStore NodeStore           `yaml:"node_store,omitempty"`
+	ID        string               `yaml:"id"`
+	Version   string               `yaml:"version"`
+	CreatedAt int64                `yaml:"created_at"`
+	Projects  []*WorkspaceProject  `yaml:"projects,omitempty"`
+	Nodes     []*Node              `yaml:"nodes"`
+	NodeStore NodeStore            `yaml:"node_store,omitempty"`
+	Templates []*WorkspaceTemplate `yaml:"templates,omitempty"`
 }
 
 type WorkspaceProject struct {
@@ -56,6 +57,14 @@ type WorkspaceProject struct {
 	Path string `yaml:"path"`
 }
 
+// WorkspaceTemplate is project template registered in the local template
+// registry. Source is git repository URL or path to the local directory.
+type WorkspaceTemplate struct {
+	Name        string `yaml:"name"`
+	Source      string `yaml:"source"`
+	Description string `yaml:"description,omitempty"`
+}
+
 func newWorkspace() *Workspace {
 	return &Workspace{
 		ID:        UID(),
@@ -67,6 +76,10 @@ func (w *Workspace) AddNodeToken(token string) error {
 	return w.NodeStore.UpsertNodeToken(token)
 }
 
+func (w *Workspace) AddNodeSession(token, refreshToken string) error {
+	return w.NodeStore.UpsertNodeSession(token, refreshToken)
+}
+
 func (w *Workspace) AddNode(n *Node) {
 	if w.nodeExists(n.Name) {
 		return
@@ -240,6 +253,40 @@ func (w *Workspace) RemoveProject(name string) {
 	}
 }
 
+func (w *Workspace) AddTemplate(name, source, description string) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	if w.Template(name) != nil {
+		return &TemplateExistsError{Name: name}
+	}
+	w.Templates = append(w.Templates, &WorkspaceTemplate{
+		Name:        name,
+		Source:      source,
+		Description: description,
+	})
+	return nil
+}
+
+func (w *Workspace) RemoveTemplate(name string) error {
+	for idx, t := range w.Templates {
+		if t.Name == name {
+			w.Templates = append(w.Templates[:idx], w.Templates[idx+1:]...)
+			return nil
+		}
+	}
+	return &TemplateNotFoundError{Name: name}
+}
+
+func (w *Workspace) Template(name string) *WorkspaceTemplate {
+	for _, t := range w.Templates {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
 func SSMParameterPath(key string) (string, error) {
 	p, ok := os.LookupEnv(EnvSSMPathPrefix)
 	if !ok {
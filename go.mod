@@ -13,6 +13,7 @@ require (
 	github.com/aws/aws-sdk-go-v2/service/apigateway v1.8.0
 	github.com/aws/aws-sdk-go-v2/service/apigatewaymanagementapi v1.4.0
 	github.com/aws/aws-sdk-go-v2/service/cloudformation v1.10.1
+	github.com/aws/aws-sdk-go-v2/service/cloudwatch v1.15.0
 	github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs v1.5.2
 	github.com/aws/aws-sdk-go-v2/service/dynamodb v1.10.0
 	github.com/aws/aws-sdk-go-v2/service/ec2 v1.19.0
@@ -12,6 +12,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/credentials/endpointcreds"
 	"github.com/aws/aws-sdk-go-v2/service/apigateway"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/iam"
@@ -30,6 +31,7 @@ type AWS struct {
 	lambdaClient         *lambda.Client
 	stsClient            *sts.Client
 	cloudwatchClient     *cloudwatchlogs.Client
+	metricsClient        *cloudwatch.Client
 	rgsaClient           *resourcegroupstaggingapi.Client
 	dynamodbClient       *dynamodb.Client
 	cloudformationClient *cloudformation.Client
@@ -111,6 +113,7 @@ func clientFromConfig(config aws.Config) (*AWS, error) {
 		lambdaClient:         lambda.NewFromConfig(config),
 		stsClient:            sts.NewFromConfig(config),
 		cloudwatchClient:     cloudwatchlogs.NewFromConfig(config),
+		metricsClient:        cloudwatch.NewFromConfig(config),
 		rgsaClient:           resourcegroupstaggingapi.NewFromConfig(config),
 		dynamodbClient:       dynamodb.NewFromConfig(config),
 		cloudformationClient: cloudformation.NewFromConfig(config),
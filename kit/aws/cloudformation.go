@@ -148,6 +148,19 @@ func (f *CloudFormation) DeleteStack(name string) *StackWaiter {
 	return sw
 }
 
+// GetTemplate returns template body of the current stack version
+func (f *CloudFormation) GetTemplate(name string) (string, error) {
+	gti := &cloudformation.GetTemplateInput{
+		StackName:     aws.String(name),
+		TemplateStage: types.TemplateStageOriginal,
+	}
+	out, err := f.cli.GetTemplate(context.Background(), gti)
+	if err != nil {
+		return "", fmt.Errorf("could not get template of stack %s - %w", name, err)
+	}
+	return aws.ToString(out.TemplateBody), nil
+}
+
 // tries to find reason why stack action failed by going through all the events until first one with status failed is encountered
 // asumption is made that first failed event is also the reason for the failure of the stack action
 // for stacks that do not longer exists (like in the case of create failure) stack id must be provided instead of name
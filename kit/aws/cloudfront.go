@@ -0,0 +1,84 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+const (
+	cloudfrontEndpoint   = "https://cloudfront.amazonaws.com/2020-05-31"
+	cloudfrontXMLNS      = "http://cloudfront.amazonaws.com/doc/2020-05-31/"
+	cloudfrontSignRegion = "us-east-1"
+	// maximum number of paths in the invalidation request
+	cloudfrontMaxInvalidationPaths = 3000
+)
+
+type invalidationBatch struct {
+	XMLName         xml.Name `xml:"InvalidationBatch"`
+	XMLNS           string   `xml:"xmlns,attr"`
+	Quantity        int      `xml:"Paths>Quantity"`
+	Items           []string `xml:"Paths>Items>Path"`
+	CallerReference string   `xml:"CallerReference"`
+}
+
+// CreateInvalidation removes paths from the CloudFront distribution edge
+// caches. CloudFront client is not included in the sdk dependencies so the
+// api is called with the signed http request.
+func (a *AWS) CreateInvalidation(distributionID string, paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	if len(paths) > cloudfrontMaxInvalidationPaths {
+		paths = []string{"/*"}
+	}
+	var items []string
+	for _, p := range paths {
+		items = append(items, (&url.URL{Path: p}).EscapedPath())
+	}
+	body, err := xml.Marshal(invalidationBatch{
+		XMLNS:           cloudfrontXMLNS,
+		Quantity:        len(items),
+		Items:           items,
+		CallerReference: strconv.FormatInt(time.Now().UnixNano(), 10),
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost,
+		fmt.Sprintf("%s/distribution/%s/invalidation", cloudfrontEndpoint, distributionID),
+		bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/xml")
+	creds, err := a.Credentials()
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256(body)
+	if err := v4.NewSigner().SignHTTP(context.Background(), creds, req, hex.EncodeToString(hash[:]),
+		"cloudfront", cloudfrontSignRegion, time.Now()); err != nil {
+		return err
+	}
+	rsp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not create invalidation for distribution %s - %w", distributionID, err)
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusCreated {
+		buf, _ := ioutil.ReadAll(rsp.Body)
+		return fmt.Errorf("could not create invalidation for distribution %s - status %d %s", distributionID, rsp.StatusCode, buf)
+	}
+	return nil
+}
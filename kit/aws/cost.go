@@ -0,0 +1,148 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+const (
+	costExplorerEndpoint = "https://ce.us-east-1.amazonaws.com/"
+	costExplorerRegion   = "us-east-1"
+	costExplorerTarget   = "AWSInsightsIndexService.GetCostAndUsage"
+	costExplorerDate     = "2006-01-02"
+)
+
+type costAndUsageRequest struct {
+	TimePeriod  costTimePeriod `json:"TimePeriod"`
+	Granularity string         `json:"Granularity"`
+	Metrics     []string       `json:"Metrics"`
+	Filter      *costFilter    `json:"Filter,omitempty"`
+	NextToken   string         `json:"NextPageToken,omitempty"`
+}
+
+type costTimePeriod struct {
+	Start string `json:"Start"`
+	End   string `json:"End"`
+}
+
+type costFilter struct {
+	And  []costFilter    `json:"And,omitempty"`
+	Tags *costTagsFilter `json:"Tags,omitempty"`
+}
+
+type costTagsFilter struct {
+	Key    string   `json:"Key"`
+	Values []string `json:"Values"`
+}
+
+type costAndUsageResponse struct {
+	NextPageToken string `json:"NextPageToken"`
+	ResultsByTime []struct {
+		Total map[string]struct {
+			Amount string `json:"Amount"`
+			Unit   string `json:"Unit"`
+		} `json:"Total"`
+	} `json:"ResultsByTime"`
+}
+
+// GetCostAndUsage returns unblended cost of the resources tagged with all of
+// the tags in the time range. Tags must be activated as cost allocation tags
+// in the billing console. Cost Explorer client is not included in the sdk
+// dependencies so the api is called with the signed http request.
+func (a *AWS) GetCostAndUsage(tags map[string]string, start, end time.Time) (float64, string, error) {
+	var keys []string
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var filters []costFilter
+	for _, k := range keys {
+		filters = append(filters, costFilter{Tags: &costTagsFilter{Key: k, Values: []string{tags[k]}}})
+	}
+	req := costAndUsageRequest{
+		TimePeriod: costTimePeriod{
+			Start: start.UTC().Format(costExplorerDate),
+			End:   end.UTC().Format(costExplorerDate),
+		},
+		Granularity: "MONTHLY",
+		Metrics:     []string{"UnblendedCost"},
+	}
+	switch len(filters) {
+	case 0:
+	case 1:
+		req.Filter = &filters[0]
+	default:
+		req.Filter = &costFilter{And: filters}
+	}
+	var total float64
+	var unit string
+	for {
+		var rsp costAndUsageResponse
+		if err := a.costExplorer(req, &rsp); err != nil {
+			return 0, "", err
+		}
+		for _, r := range rsp.ResultsByTime {
+			c, ok := r.Total["UnblendedCost"]
+			if !ok {
+				continue
+			}
+			amount, err := strconv.ParseFloat(c.Amount, 64)
+			if err != nil {
+				return 0, "", fmt.Errorf("could not parse cost amount %s - %w", c.Amount, err)
+			}
+			total += amount
+			unit = c.Unit
+		}
+		if rsp.NextPageToken == "" {
+			break
+		}
+		req.NextToken = rsp.NextPageToken
+	}
+	return total, unit, nil
+}
+
+func (a *AWS) costExplorer(in interface{}, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, costExplorerEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", costExplorerTarget)
+	creds, err := a.Credentials()
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256(body)
+	if err := v4.NewSigner().SignHTTP(context.Background(), creds, req, hex.EncodeToString(hash[:]),
+		"ce", costExplorerRegion, time.Now()); err != nil {
+		return err
+	}
+	rsp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not get cost and usage - %w", err)
+	}
+	defer rsp.Body.Close()
+	buf, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		return err
+	}
+	if rsp.StatusCode != http.StatusOK {
+		return fmt.Errorf("could not get cost and usage - status %d %s", rsp.StatusCode, buf)
+	}
+	return json.Unmarshal(buf, out)
+}
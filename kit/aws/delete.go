@@ -0,0 +1,141 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+var ErrDeleteNotSupported = fmt.Errorf("delete not supported")
+
+// DeletableResourceTypes are resource type filters of the resource groups
+// tagging api for the resources which can be deleted by DeleteResource.
+var DeletableResourceTypes = []string{
+	"lambda:function",
+	"logs:log-group",
+	"s3",
+	"dynamodb:table",
+	"ssm:parameter",
+	"cloudwatch:alarm",
+}
+
+// DeleteResource deletes resource by its ARN. Returns ErrDeleteNotSupported
+// for the resource types which are not in the DeletableResourceTypes.
+func (a *AWS) DeleteResource(resourceARN string) error {
+	parts, err := arn.Parse(resourceARN)
+	if err != nil {
+		return fmt.Errorf("error parsing arn - %w", err)
+	}
+	typ, name := resourceTypeAndName(parts)
+	switch parts.Service + ":" + typ {
+	case "lambda:function":
+		_, err = a.lambdaClient.DeleteFunction(context.Background(), &lambda.DeleteFunctionInput{
+			FunctionName: aws.String(name),
+		})
+	case "logs:log-group":
+		err = a.DeleteLogGroup(name)
+	case "s3:":
+		err = a.S3().DeleteBucket(name)
+	case "dynamodb:table":
+		_, err = a.dynamodbClient.DeleteTable(context.Background(), &dynamodb.DeleteTableInput{
+			TableName: aws.String(name),
+		})
+	case "ssm:parameter":
+		_, err = a.ssmClient.DeleteParameter(context.Background(), &ssm.DeleteParameterInput{
+			Name: aws.String(name),
+		})
+	case "cloudwatch:alarm":
+		_, err = a.metricsClient.DeleteAlarms(context.Background(), &cloudwatch.DeleteAlarmsInput{
+			AlarmNames: []string{name},
+		})
+	default:
+		return fmt.Errorf("%s - %w", resourceARN, ErrDeleteNotSupported)
+	}
+	if err != nil {
+		return fmt.Errorf("could not delete %s - %w", resourceARN, err)
+	}
+	return nil
+}
+
+// ResourceType returns service and resource type of the ARN like lambda:function.
+func ResourceType(resourceARN string) string {
+	parts, err := arn.Parse(resourceARN)
+	if err != nil {
+		return ""
+	}
+	typ, _ := resourceTypeAndName(parts)
+	if typ == "" {
+		return parts.Service
+	}
+	return parts.Service + ":" + typ
+}
+
+// ResourceName returns name of the resource from its ARN.
+func ResourceName(resourceARN string) string {
+	parts, err := arn.Parse(resourceARN)
+	if err != nil {
+		return resourceARN
+	}
+	_, name := resourceTypeAndName(parts)
+	return name
+}
+
+// resourceTypeAndName splits resource part of the ARN which is in one of
+// the type/name, type:name or name forms
+func resourceTypeAndName(parts arn.ARN) (string, string) {
+	r := parts.Resource
+	switch parts.Service {
+	case "s3":
+		return "", r
+	case "ssm":
+		// hierarchical parameter names start with /
+		name := strings.TrimPrefix(r, "parameter")
+		if strings.Count(name, "/") == 1 {
+			name = strings.TrimPrefix(name, "/")
+		}
+		return "parameter", name
+	case "logs":
+		return "log-group", strings.TrimSuffix(strings.TrimPrefix(r, "log-group:"), ":*")
+	}
+	if i := strings.IndexAny(r, ":/"); i >= 0 {
+		return r[:i], r[i+1:]
+	}
+	return "", r
+}
+
+func (a *AWS) DeleteLogGroup(name string) error {
+	_, err := a.cloudwatchClient.DeleteLogGroup(context.Background(), &cloudwatchlogs.DeleteLogGroupInput{
+		LogGroupName: aws.String(name),
+	})
+	return err
+}
+
+// ListLogGroups returns names of the log groups starting with prefix.
+func (a *AWS) ListLogGroups(prefix string) ([]string, error) {
+	var names []string
+	dlgi := &cloudwatchlogs.DescribeLogGroupsInput{
+		LogGroupNamePrefix: aws.String(prefix),
+	}
+	for {
+		out, err := a.cloudwatchClient.DescribeLogGroups(context.Background(), dlgi)
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range out.LogGroups {
+			names = append(names, aws.ToString(g.LogGroupName))
+		}
+		if out.NextToken == nil {
+			break
+		}
+		dlgi.NextToken = out.NextToken
+	}
+	return names, nil
+}
@@ -52,15 +52,47 @@ func (l *Lambda) arn(name string) string {
 		name)
 }
 
-func (l *Lambda) Info(name string) (map[string]string, error) {
+// LambdaInfo is configuration of the deployed function.
+type LambdaInfo struct {
+	Tags map[string]string
+	// base64 encoded sha256 of the deployment package
+	CodeSha256  string
+	MemorySize  int
+	Timeout     int
+	Env         map[string]string
+	TracingMode string
+}
+
+// Info returns configuration of the function, ErrNotFound if function
+// doesn't exist.
+func (l *Lambda) Info(name string) (*LambdaInfo, error) {
 	gfi := &lambda.GetFunctionInput{
 		FunctionName: aws.String(name),
 	}
 	gfo, err := l.cli.GetFunction(context.Background(), gfi)
-	if err == nil {
-		return gfo.Tags, nil
+	if err != nil {
+		var rnf *lambdaTypes.ResourceNotFoundException
+		if errors.As(err, &rnf) {
+			return nil, fmt.Errorf("function %s - %w", name, ErrNotFound)
+		}
+		return nil, err
+	}
+	info := &LambdaInfo{
+		Tags: gfo.Tags,
+		Env:  make(map[string]string),
+	}
+	if c := gfo.Configuration; c != nil {
+		info.CodeSha256 = aws.ToString(c.CodeSha256)
+		info.MemorySize = int(aws.ToInt32(c.MemorySize))
+		info.Timeout = int(aws.ToInt32(c.Timeout))
+		if c.Environment != nil {
+			info.Env = c.Environment.Variables
+		}
+		if c.TracingConfig != nil {
+			info.TracingMode = string(c.TracingConfig.Mode)
+		}
 	}
-	return nil, err
+	return info, nil
 }
 
 func (l *Lambda) SetMemory(name string, mem int) error {
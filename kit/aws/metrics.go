@@ -0,0 +1,115 @@
+package aws
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+const (
+	LambdaMetricsNamespace     = "AWS/Lambda"
+	ApiGatewayMetricsNamespace = "AWS/ApiGateway"
+	DynamoDBMetricsNamespace   = "AWS/DynamoDB"
+	S3MetricsNamespace         = "AWS/S3"
+	LogsMetricsNamespace       = "AWS/Logs"
+	// maximum number of queries in the single GetMetricData request
+	metricsMaxQueries = 500
+)
+
+// MetricQuery describes one statistic of the CloudWatch metric. Id must be
+// unique among queries in the request and start with lowercase letter.
+type MetricQuery struct {
+	ID         string
+	Namespace  string
+	Name       string
+	Dimensions map[string]string
+	// statistic like Sum, Maximum or percentile like p99
+	Stat string
+	// datapoints aggregation period, must be multiple of 60 seconds
+	Period time.Duration
+}
+
+// MetricSeries are datapoints of the metric query sorted by timestamp
+type MetricSeries struct {
+	Timestamps []time.Time
+	Values     []float64
+}
+
+// GetMetrics returns datapoints for each query by query id in the time range.
+func (a *AWS) GetMetrics(queries []MetricQuery, start, end time.Time) (map[string]MetricSeries, error) {
+	series := make(map[string]MetricSeries)
+	for len(queries) > 0 {
+		batch := queries
+		if len(batch) > metricsMaxQueries {
+			batch = queries[:metricsMaxQueries]
+		}
+		queries = queries[len(batch):]
+		if err := a.getMetrics(batch, start, end, series); err != nil {
+			return nil, err
+		}
+	}
+	for id, s := range series {
+		sort.Sort(byTimestamp(s))
+		series[id] = s
+	}
+	return series, nil
+}
+
+func (a *AWS) getMetrics(queries []MetricQuery, start, end time.Time, series map[string]MetricSeries) error {
+	var mdq []types.MetricDataQuery
+	for _, q := range queries {
+		var dims []types.Dimension
+		for k, v := range q.Dimensions {
+			dims = append(dims, types.Dimension{
+				Name:  aws.String(k),
+				Value: aws.String(v),
+			})
+		}
+		mdq = append(mdq, types.MetricDataQuery{
+			Id: aws.String(q.ID),
+			MetricStat: &types.MetricStat{
+				Metric: &types.Metric{
+					Namespace:  aws.String(q.Namespace),
+					MetricName: aws.String(q.Name),
+					Dimensions: dims,
+				},
+				Period: aws.Int32(int32(q.Period.Seconds())),
+				Stat:   aws.String(q.Stat),
+			},
+		})
+	}
+	gmdi := &cloudwatch.GetMetricDataInput{
+		MetricDataQueries: mdq,
+		StartTime:         aws.Time(start),
+		EndTime:           aws.Time(end),
+		ScanBy:            types.ScanByTimestampAscending,
+	}
+	p := cloudwatch.NewGetMetricDataPaginator(a.metricsClient, gmdi)
+	for p.HasMorePages() {
+		out, err := p.NextPage(context.Background())
+		if err != nil {
+			return err
+		}
+		for _, r := range out.MetricDataResults {
+			id := aws.ToString(r.Id)
+			s := series[id]
+			s.Timestamps = append(s.Timestamps, r.Timestamps...)
+			s.Values = append(s.Values, r.Values...)
+			series[id] = s
+		}
+	}
+	return nil
+}
+
+type byTimestamp MetricSeries
+
+func (s byTimestamp) Len() int           { return len(s.Timestamps) }
+func (s byTimestamp) Less(i, j int) bool { return s.Timestamps[i].Before(s.Timestamps[j]) }
+func (s byTimestamp) Swap(i, j int) {
+	s.Timestamps[i], s.Timestamps[j] = s.Timestamps[j], s.Timestamps[i]
+	s.Values[i], s.Values[j] = s.Values[j], s.Values[i]
+}
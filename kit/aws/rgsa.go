@@ -8,8 +8,26 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi/types"
 )
 
+// TaggedResource is resource found by tags together with all of its tags.
+type TaggedResource struct {
+	ARN  string
+	Tags map[string]string
+}
+
 func (a *AWS) GetResourcesByTypeAndTag(resourceTypes []string, resourceTags []TagFilter) ([]string, error) {
+	resources, err := a.GetTaggedResources(resourceTypes, resourceTags)
+	if err != nil {
+		return nil, err
+	}
 	var resourceARNs []string
+	for _, r := range resources {
+		resourceARNs = append(resourceARNs, r.ARN)
+	}
+	return resourceARNs, nil
+}
+
+func (a *AWS) GetTaggedResources(resourceTypes []string, resourceTags []TagFilter) ([]TaggedResource, error) {
+	var resources []TaggedResource
 	var tagFilters []types.TagFilter
 	for _, t := range resourceTags {
 		tagFilters = append(tagFilters, types.TagFilter{Key: aws.String(t.Key), Values: t.Values})
@@ -24,14 +42,21 @@ func (a *AWS) GetResourcesByTypeAndTag(resourceTypes []string, resourceTags []Ta
 			return nil, err
 		}
 		for _, r := range gro.ResourceTagMappingList {
-			resourceARNs = append(resourceARNs, *r.ResourceARN)
+			tags := make(map[string]string)
+			for _, t := range r.Tags {
+				tags[aws.ToString(t.Key)] = aws.ToString(t.Value)
+			}
+			resources = append(resources, TaggedResource{
+				ARN:  aws.ToString(r.ResourceARN),
+				Tags: tags,
+			})
 		}
 		if gro.PaginationToken == nil || aws.ToString(gro.PaginationToken) == "" {
 			break
 		}
 		gri.PaginationToken = gro.PaginationToken
 	}
-	return resourceARNs, nil
+	return resources, nil
 }
 
 type TagFilter struct {
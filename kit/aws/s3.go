@@ -3,8 +3,11 @@ package aws
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"io"
 	"mime"
 	"path/filepath"
 	"strings"
@@ -91,6 +94,36 @@ func (a *S3) emptyS3Bucket(name string) error {
 	return a.delete(name, "")
 }
 
+// ListPrefixes returns common prefixes, like directories, directly under the
+// prefix in the bucket.
+func (a *S3) ListPrefixes(name, prefix string) ([]string, error) {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix = fmt.Sprintf("%s/", prefix)
+	}
+	loi := &s3.ListObjectsV2Input{
+		Bucket:    aws.String(name),
+		Delimiter: aws.String("/"),
+	}
+	if prefix != "" {
+		loi.Prefix = aws.String(prefix)
+	}
+	var prefixes []string
+	for {
+		out, err := a.cli.ListObjectsV2(context.Background(), loi)
+		if err != nil {
+			return nil, err
+		}
+		for _, cp := range out.CommonPrefixes {
+			prefixes = append(prefixes, aws.ToString(cp.Prefix))
+		}
+		if !out.IsTruncated {
+			break
+		}
+		loi.ContinuationToken = out.NextContinuationToken
+	}
+	return prefixes, nil
+}
+
 func (a *S3) DeleteBucketPrefix(name, prefix string) error {
 	return a.delete(name, prefix)
 }
@@ -174,13 +207,48 @@ func (a *S3) bucketExists(name string) (bool, error) {
 	return true, nil
 }
 
+func (a *S3) ObjectExists(bucket, key string) (bool, error) {
+	hoi := &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	_, err := a.cli.HeadObject(context.Background(), hoi)
+	if err != nil {
+		var oe smithy.APIError
+		if errors.As(err, &oe) && oe.ErrorCode() == "NotFound" {
+			return false, nil
+		}
+		return false, fmt.Errorf("could not get key %s from bucket %s - %w", key, bucket, err)
+	}
+	return true, nil
+}
+
 func (a *S3) Put(bucket, key string, buf []byte) error {
-	contentType := mime.TypeByExtension(filepath.Ext(key))
+	return a.PutObject(bucket, key, buf, PutObjectOptions{
+		ContentType: mime.TypeByExtension(filepath.Ext(key)),
+	})
+}
+
+// PutObjectOptions sets object metadata returned as http headers when the
+// object is served from the bucket.
+type PutObjectOptions struct {
+	ContentType     string
+	CacheControl    string
+	ContentEncoding string
+}
+
+func (a *S3) PutObject(bucket, key string, buf []byte, o PutObjectOptions) error {
 	poi := &s3.PutObjectInput{
 		Bucket:      aws.String(bucket),
 		Key:         aws.String(key),
 		Body:        bytes.NewReader(buf),
-		ContentType: aws.String(contentType),
+		ContentType: aws.String(o.ContentType),
+	}
+	if o.CacheControl != "" {
+		poi.CacheControl = aws.String(o.CacheControl)
+	}
+	if o.ContentEncoding != "" {
+		poi.ContentEncoding = aws.String(o.ContentEncoding)
 	}
 	_, err := a.cli.PutObject(context.Background(), poi)
 	if err != nil {
@@ -189,6 +257,33 @@ func (a *S3) Put(bucket, key string, buf []byte) error {
 	return nil
 }
 
+// ObjectSHA256 returns base64 encoded sha256 of the object content, same as
+// the code sha of the lambda function deployed from the object.
+func (a *S3) ObjectSHA256(bucket, key string) (string, error) {
+	goi := &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	out, err := a.cli.GetObject(context.Background(), goi)
+	if err != nil {
+		var nsk *types.NoSuchKey
+		if errors.As(err, &nsk) {
+			return "", fmt.Errorf("key %s in bucket %s - %w", key, bucket, ErrNotFound)
+		}
+		return "", fmt.Errorf("could not get key %s from bucket %s - %v", key, bucket, err)
+	}
+	defer out.Body.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, out.Body); err != nil {
+		return "", fmt.Errorf("could not read key %s from bucket %s - %v", key, bucket, err)
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+func (a *S3) DeleteObject(bucket, key string) error {
+	return a.deleteObject(bucket, key)
+}
+
 func (a *S3) deleteObject(bucket, key string) error {
 	doi := &s3.DeleteObjectInput{
 		Bucket: aws.String(bucket),
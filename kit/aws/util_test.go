@@ -27,3 +27,26 @@ func TestResourceFromARN(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "role/api-cloudwatch-logs", roleResource)
 }
+
+func TestResourceTypeAndName(t *testing.T) {
+	cases := []struct {
+		arn  string
+		typ  string
+		name string
+	}{
+		{"arn:aws:lambda:eu-central-1:123456789012:function:project-stage-ping-abcdefg", "lambda:function", "project-stage-ping-abcdefg"},
+		{"arn:aws:logs:eu-central-1:123456789012:log-group:/aws/lambda/name:*", "logs:log-group", "/aws/lambda/name"},
+		{"arn:aws:logs:eu-central-1:123456789012:log-group:/aws/lambda/name", "logs:log-group", "/aws/lambda/name"},
+		{"arn:aws:s3:::bucket-name", "s3", "bucket-name"},
+		{"arn:aws:dynamodb:eu-central-1:123456789012:table/table-name", "dynamodb:table", "table-name"},
+		{"arn:aws:ssm:eu-central-1:123456789012:parameter/mantil/project/stage/key", "ssm:parameter", "/mantil/project/stage/key"},
+		{"arn:aws:ssm:eu-central-1:123456789012:parameter/key", "ssm:parameter", "key"},
+		{"arn:aws:cloudwatch:eu-central-1:123456789012:alarm:alarm-name", "cloudwatch:alarm", "alarm-name"},
+		{"arn:aws:apigateway:eu-central-1::/apis/abcdefg", "apigateway", "apis/abcdefg"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.typ, ResourceType(c.arn), c.arn)
+		assert.Equal(t, c.name, ResourceName(c.arn), c.arn)
+	}
+	assert.Equal(t, "", ResourceType("invalidARN"))
+}
@@ -0,0 +1,10 @@
+package aws
+
+import (
+	"fmt"
+)
+
+// TraceConsoleURL is link to the trace in the X-Ray console.
+func TraceConsoleURL(region, traceID string) string {
+	return fmt.Sprintf("https://%s.console.aws.amazon.com/xray/home?region=%s#/traces/%s", region, region, traceID)
+}
@@ -0,0 +1,207 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/kataras/jwt"
+)
+
+// PublicKey of the external identity provider used for verifying RS256 or
+// ES256 signed tokens.
+type PublicKey struct {
+	ID  string
+	Key crypto.PublicKey
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// FetchJWKS gets public keys from the JSON Web Key Set url.
+func FetchJWKS(url string) ([]PublicKey, error) {
+	rsp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching jwks from %s failed with status %d", url, rsp.StatusCode)
+	}
+	buf, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJWKS(buf)
+}
+
+// ParseJWKS parses RSA and EC P-256 keys from the JSON Web Key Set. Other key
+// types are skipped.
+func ParseJWKS(buf []byte) ([]PublicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(buf, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse jwks %w", err)
+	}
+	var keys []PublicKey
+	for _, k := range set.Keys {
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, err
+		}
+		if key == nil {
+			continue
+		}
+		keys = append(keys, PublicKey{ID: k.Kid, Key: key})
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, nil
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	buf, err := jwt.Base64Decode([]byte(s))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode jwk %w", err)
+	}
+	return new(big.Int).SetBytes(buf), nil
+}
+
+// ParsePublicKey parses PEM encoded RSA or ECDSA public key.
+func ParsePublicKey(pem string) (PublicKey, error) {
+	if key, err := jwt.ParsePublicKeyRSA([]byte(pem)); err == nil {
+		return PublicKey{Key: key}, nil
+	}
+	key, err := jwt.ParsePublicKeyECDSA([]byte(pem))
+	if err != nil {
+		return PublicKey{}, fmt.Errorf("failed to parse public key %w", err)
+	}
+	return PublicKey{Key: key}, nil
+}
+
+// KeyID returns kid header of the token, empty if the token has no kid.
+func KeyID(token string) (string, error) {
+	ut, err := jwt.Decode([]byte(token))
+	if err != nil {
+		return "", err
+	}
+	var header struct {
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(ut.Header, &header); err != nil {
+		return "", err
+	}
+	return header.Kid, nil
+}
+
+// VerifyExternal verifies RS256 or ES256 signed token with one of the keys.
+// Key is selected by the kid token header if present.
+func VerifyExternal(token string, keys []PublicKey, claims interface{}) error {
+	ut, err := jwt.Decode([]byte(token))
+	if err != nil {
+		return err
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(ut.Header, &header); err != nil {
+		return err
+	}
+	var alg jwt.Alg
+	switch header.Alg {
+	case jwt.RS256.Name():
+		alg = jwt.RS256
+	case jwt.ES256.Name():
+		alg = jwt.ES256
+	default:
+		return fmt.Errorf("unsupported token algorithm %s", header.Alg)
+	}
+	err = fmt.Errorf("key not found")
+	for _, k := range keys {
+		if header.Kid != "" && k.ID != "" && header.Kid != k.ID {
+			continue
+		}
+		if !keyMatchesAlg(k.Key, alg) {
+			continue
+		}
+		var vt *jwt.VerifiedToken
+		vt, err = jwt.VerifyWithHeaderValidator(alg, k.Key, []byte(token), headerValidator)
+		if err != nil {
+			continue
+		}
+		return vt.Claims(claims)
+	}
+	return fmt.Errorf("token verify failed: %w", err)
+}
+
+// headerValidator accepts any header with the supported algorithm, tokens of
+// the identity providers usually have kid and other fields in the header
+func headerValidator(alg string, headerDecoded []byte) (jwt.Alg, jwt.PublicKey, error) {
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(headerDecoded, &header); err != nil {
+		return nil, nil, err
+	}
+	if header.Alg != alg {
+		return nil, nil, jwt.ErrTokenAlg
+	}
+	return nil, nil, nil
+}
+
+func keyMatchesAlg(key crypto.PublicKey, alg jwt.Alg) bool {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return alg == jwt.RS256
+	case *ecdsa.PublicKey:
+		return alg == jwt.ES256
+	default:
+		return false
+	}
+}
@@ -0,0 +1,84 @@
+package token_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kataras/jwt"
+	. "github.com/mantil-io/mantil/kit/token"
+	"github.com/stretchr/testify/require"
+)
+
+func b64(b []byte) string {
+	return string(jwt.Base64Encode(b))
+}
+
+func TestVerifyExternal(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	jwks := map[string]interface{}{
+		"keys": []map[string]string{
+			{"kid": "rsa1", "kty": "RSA", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{"kid": "ec1", "kty": "EC", "crv": "P-256", "x": b64(ecKey.X.Bytes()), "y": b64(ecKey.Y.Bytes())},
+			{"kid": "oct1", "kty": "oct"},
+		},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jwks)
+	}))
+	defer srv.Close()
+
+	keys, err := FetchJWKS(srv.URL)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+
+	c := Claims{UID: "123", Email: "someone@example.com"}
+
+	// rs256 with kid in the header
+	tkn, err := jwt.SignWithHeader(jwt.RS256, rsaKey, c, map[string]string{"alg": "RS256", "typ": "JWT", "kid": "rsa1"}, jwt.MaxAge(time.Hour))
+	require.NoError(t, err)
+	kid, err := KeyID(string(tkn))
+	require.NoError(t, err)
+	require.Equal(t, "rsa1", kid)
+	var c2 Claims
+	require.NoError(t, VerifyExternal(string(tkn), keys, &c2))
+	require.Equal(t, c, c2)
+
+	// es256 without kid
+	tkn, err = jwt.Sign(jwt.ES256, ecKey, c, jwt.MaxAge(time.Hour))
+	require.NoError(t, err)
+	kid, err = KeyID(string(tkn))
+	require.NoError(t, err)
+	require.Empty(t, kid)
+	c2 = Claims{}
+	require.NoError(t, VerifyExternal(string(tkn), keys, &c2))
+	require.Equal(t, c, c2)
+
+	// expired
+	tkn, err = jwt.Sign(jwt.ES256, ecKey, c, jwt.MaxAge(-time.Hour))
+	require.NoError(t, err)
+	require.Error(t, VerifyExternal(string(tkn), keys, &c2))
+
+	// signed with unknown key
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tkn, err = jwt.Sign(jwt.ES256, otherKey, c, jwt.MaxAge(time.Hour))
+	require.NoError(t, err)
+	require.Error(t, VerifyExternal(string(tkn), keys, &c2))
+
+	// unsupported algorithm
+	tkn, err = jwt.Sign(jwt.HS256, []byte("secret"), c, jwt.MaxAge(time.Hour))
+	require.NoError(t, err)
+	require.Error(t, VerifyExternal(string(tkn), keys, &c2))
+}
@@ -0,0 +1,105 @@
+// Package audit records node api calls into the node KV table.
+package audit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mantil-io/mantil.go"
+	"github.com/mantil-io/mantil/domain"
+	"github.com/mantil-io/mantil/node/dto"
+)
+
+const (
+	partition = "audit"
+	// sortable time prefix of the record key
+	keyTimeFormat = "2006-01-02T15:04:05.000000000Z"
+)
+
+// Action describes node api call which is recorded.
+type Action struct {
+	Name    string
+	Project string
+	Stage   string
+	Request string
+}
+
+// Log executes fn and appends audit record for the user found in ctx claims.
+func Log(ctx context.Context, a Action, fn func() error) error {
+	claims, _ := domain.ClaimsFromContext(ctx)
+	return LogClaims(claims, a, fn)
+}
+
+// LogClaims executes fn and appends audit record for the user from claims.
+func LogClaims(claims *domain.AccessTokenClaims, a Action, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	Append(claims, a, start, err)
+	return err
+}
+
+// Append stores audit record. Failure to store the record is only logged,
+// it should not break the recorded operation.
+func Append(claims *domain.AccessTokenClaims, a Action, start time.Time, opErr error) {
+	r := dto.AuditRecord{
+		Time:     start.UnixMilli(),
+		Action:   a.Name,
+		Project:  a.Project,
+		Stage:    a.Stage,
+		Request:  a.Request,
+		Duration: time.Since(start).Milliseconds(),
+	}
+	if claims != nil {
+		r.Username = claims.Username
+	}
+	if opErr != nil {
+		r.Error = opErr.Error()
+	}
+	if err := put(start, r); err != nil {
+		log.Printf("failed to store audit record %v: %v", r, err)
+	}
+}
+
+func put(start time.Time, r dto.AuditRecord) error {
+	kv, err := mantil.NewKV(partition)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf("%s-%s", start.UTC().Format(keyTimeFormat), domain.UID())
+	return kv.Put(key, r)
+}
+
+// Find returns audit records matching request, oldest first.
+func Find(req dto.AuditRequest) ([]dto.AuditRecord, error) {
+	kv, err := mantil.NewKV(partition)
+	if err != nil {
+		return nil, err
+	}
+	var items []dto.AuditRecord
+	since := time.UnixMilli(req.Since).UTC().Format(keyTimeFormat)
+	iter, err := kv.Find(&items, mantil.FindGreaterThanOrEqual, since)
+	if err != nil {
+		return nil, err
+	}
+	var records []dto.AuditRecord
+	for {
+		for _, r := range items {
+			if req.Stage != "" && r.Stage != req.Stage {
+				continue
+			}
+			if req.Username != "" && r.Username != req.Username {
+				continue
+			}
+			records = append(records, r)
+		}
+		if !iter.HasMore() {
+			break
+		}
+		if err := iter.Next(&items); err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
@@ -3,8 +3,10 @@ package deploy
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/mantil-io/mantil/kit/aws"
+	"github.com/mantil-io/mantil/node/api/audit"
 	"github.com/mantil-io/mantil/node/dto"
 	"github.com/mantil-io/mantil/node/terraform"
 )
@@ -20,13 +22,46 @@ func New() *Deploy {
 }
 
 func (d *Deploy) Invoke(ctx context.Context, req dto.DeployRequest) (*dto.DeployResponse, error) {
-	if err := d.init(req); err != nil {
+	err := audit.Log(ctx, auditAction(req), func() error {
+		if err := d.init(req); err != nil {
+			return err
+		}
+		return d.deploy()
+	})
+	if err != nil {
 		return nil, err
 	}
-	if err := d.deploy(); err != nil {
+	return &d.rsp, nil
+}
+
+// Plan returns changes which deploy of the stage template would make to
+// the deployed stage resources, changes nothing itself.
+func (d *Deploy) Plan(ctx context.Context, req dto.DeployRequest) (*dto.PlanResponse, error) {
+	if req.StageTemplate == nil {
+		return nil, fmt.Errorf("stage template is required")
+	}
+	tf, err := terraform.Project(*req.StageTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("terrafrom.Project failed %w,", err)
+	}
+	changes, err := tf.Plan()
+	if err != nil {
 		return nil, err
 	}
-	return &d.rsp, nil
+	return &dto.PlanResponse{Changes: changes}, nil
+}
+
+func auditAction(req dto.DeployRequest) audit.Action {
+	var fns []string
+	for _, f := range req.FunctionsForUpdate {
+		fns = append(fns, f.Name)
+	}
+	return audit.Action{
+		Name:    "deploy",
+		Project: req.ProjectName,
+		Stage:   req.StageName,
+		Request: fmt.Sprintf("infrastructure changed: %t, functions updated: %s", req.StageTemplate != nil, strings.Join(fns, ", ")),
+	}
 }
 
 func (d *Deploy) init(req dto.DeployRequest) error {
@@ -70,6 +105,16 @@ func (d *Deploy) applyInfrastructure() error {
 			return err
 		}
 	}
+	if d.req.StageTemplate.CDN.Enabled {
+		d.rsp.CDNDistributionID, err = tf.Output("cdn_distribution_id")
+		if err != nil {
+			return err
+		}
+		d.rsp.CDNURL, err = tf.Output("cdn_url")
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
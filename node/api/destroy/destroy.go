@@ -3,12 +3,20 @@ package destroy
 import (
 	"context"
 	"fmt"
+	"log"
+	"time"
 
 	"github.com/mantil-io/mantil/kit/aws"
+	"github.com/mantil-io/mantil/node/api/audit"
+	"github.com/mantil-io/mantil/node/api/expiry"
 	"github.com/mantil-io/mantil/node/dto"
 	"github.com/mantil-io/mantil/node/terraform"
 )
 
+// time needed to destroy one stage, expired stages are not destroyed after
+// the remaining execution time drops below it, they are left for the next run
+const stageDestroyTime = 5 * time.Minute
+
 type Destroy struct {
 	dto.DestroyRequest
 	awsClient *aws.AWS
@@ -19,6 +27,17 @@ func New() *Destroy {
 }
 
 func (d *Destroy) Invoke(ctx context.Context, req dto.DestroyRequest) error {
+	return audit.Log(ctx, audit.Action{
+		Name:    "destroy",
+		Project: req.ProjectName,
+		Stage:   req.StageName,
+		Request: fmt.Sprintf("region: %s", req.Region),
+	}, func() error {
+		return d.destroy(req)
+	})
+}
+
+func (d *Destroy) destroy(req dto.DestroyRequest) error {
 	if err := d.init(req); err != nil {
 		return err
 	}
@@ -32,6 +51,40 @@ func (d *Destroy) Invoke(ctx context.Context, req dto.DestroyRequest) error {
 	if err := d.cleanupResources(); err != nil {
 		return fmt.Errorf("could not cleanup resources - %w", err)
 	}
+	if err := expiry.Delete(req.ProjectName, req.StageName); err != nil {
+		return fmt.Errorf("could not delete stage expiry - %w", err)
+	}
+	return nil
+}
+
+// Expired destroys stages created with ttl which have expired. It is called
+// periodically by the node scheduled event.
+func (d *Destroy) Expired(ctx context.Context) error {
+	stages, err := expiry.Expired(time.Now())
+	if err != nil {
+		return fmt.Errorf("could not find expired stages - %w", err)
+	}
+	for _, e := range stages {
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < stageDestroyTime {
+			log.Printf("not enough time left, remaining expired stages will be destroyed in the next run")
+			return nil
+		}
+		req := e.Destroy
+		derr := audit.LogClaims(nil, audit.Action{
+			Name:    "destroy expired",
+			Project: req.ProjectName,
+			Stage:   req.StageName,
+			Request: fmt.Sprintf("expired at: %s", time.Unix(e.ExpiresAt, 0).UTC().Format(time.RFC3339)),
+		}, func() error {
+			return d.destroy(req)
+		})
+		if derr != nil {
+			log.Printf("could not destroy expired stage %s/%s: %v", req.ProjectName, req.StageName, derr)
+		}
+		if err := expiry.Notify(e, derr); err != nil {
+			log.Printf("could not notify about expired stage %s/%s: %v", req.ProjectName, req.StageName, err)
+		}
+	}
 	return nil
 }
 
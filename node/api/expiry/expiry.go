@@ -0,0 +1,98 @@
+// Package expiry records ephemeral stages into the node KV table.
+package expiry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mantil-io/mantil.go"
+	"github.com/mantil-io/mantil/node/dto"
+)
+
+const (
+	partition     = "expiry"
+	notifyTimeout = 10 * time.Second
+)
+
+func key(project, stage string) string {
+	return fmt.Sprintf("%s/%s", project, stage)
+}
+
+// Put records stage expiry replacing the previous one.
+func Put(e dto.StageExpiry) error {
+	kv, err := mantil.NewKV(partition)
+	if err != nil {
+		return err
+	}
+	return kv.Put(key(e.Destroy.ProjectName, e.Destroy.StageName), e)
+}
+
+// Delete removes stage expiry, it is not an error if stage has none.
+func Delete(project, stage string) error {
+	kv, err := mantil.NewKV(partition)
+	if err != nil {
+		return err
+	}
+	return kv.Delete(key(project, stage))
+}
+
+// Expired returns stages which expired before now.
+func Expired(now time.Time) ([]dto.StageExpiry, error) {
+	kv, err := mantil.NewKV(partition)
+	if err != nil {
+		return nil, err
+	}
+	var items []dto.StageExpiry
+	iter, err := kv.FindAll(&items)
+	if err != nil {
+		return nil, err
+	}
+	var expired []dto.StageExpiry
+	for {
+		for _, e := range items {
+			if e.ExpiresAt <= now.Unix() {
+				expired = append(expired, e)
+			}
+		}
+		if !iter.HasMore() {
+			break
+		}
+		if err := iter.Next(&items); err != nil {
+			return nil, err
+		}
+	}
+	return expired, nil
+}
+
+// Notify posts notification about destroyed stage to the webhook of the
+// stage expiry, destroyErr is included if destroy failed.
+func Notify(e dto.StageExpiry, destroyErr error) error {
+	if e.Notify == "" {
+		return nil
+	}
+	n := dto.StageExpiredNotification{
+		Project:   e.Destroy.ProjectName,
+		Stage:     e.Destroy.StageName,
+		ExpiresAt: e.ExpiresAt,
+	}
+	if destroyErr != nil {
+		n.Error = destroyErr.Error()
+	}
+	buf, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	client := http.Client{Timeout: notifyTimeout}
+	rsp, err := client.Post(e.Notify, "application/json", bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode < 200 || rsp.StatusCode >= 300 {
+		return fmt.Errorf("notify %s failed with status %d", e.Notify, rsp.StatusCode)
+	}
+	return nil
+}
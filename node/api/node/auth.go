@@ -15,9 +15,13 @@ import (
 	"github.com/mantil-io/mantil/domain"
 	"github.com/mantil-io/mantil/kit/aws"
 	"github.com/mantil-io/mantil/kit/token"
+	"github.com/mantil-io/mantil/node/api/audit"
+	"github.com/mantil-io/mantil/node/dto"
 	"golang.org/x/oauth2"
 )
 
+const refreshTokenTTL = 30 * 24 * time.Hour
+
 type Auth struct {
 	JWTRequest    *JWTRequest
 	store         *Store
@@ -52,12 +56,14 @@ func (a *Auth) JWT(ctx context.Context, req *JWTRequest) error {
 		a.publishError(err)
 		return err
 	}
-	jwt, err := a.generateJWT()
+	start := time.Now()
+	jwt, refreshToken, username, err := a.generateJWT()
+	audit.Append(&domain.AccessTokenClaims{Username: username}, audit.Action{Name: "login"}, start, err)
 	if err != nil {
 		a.publishError(err)
 		return err
 	}
-	if err := a.publishJWT(jwt); err != nil {
+	if err := a.publishJWT(jwt, refreshToken); err != nil {
 		a.publishError(err)
 		return err
 	}
@@ -73,19 +79,7 @@ func (a *Auth) initJWT(req *JWTRequest) error {
 	tc := oauth2.NewClient(context.Background(), ts)
 	a.ghClient = github.NewClient(tc)
 
-	awsClient, err := aws.New()
-	if err != nil {
-		return err
-	}
-	param := func(key string) (string, error) {
-		path, err := domain.SSMParameterPath(key)
-		if err != nil {
-			return "", err
-		}
-		return awsClient.GetSSMParameter(path)
-	}
-	a.privateKey, err = param(domain.SSMPrivateKey)
-	if err != nil {
+	if err := a.readPrivateKey(); err != nil {
 		return err
 	}
 
@@ -101,20 +95,85 @@ func (a *Auth) initJWT(req *JWTRequest) error {
 	return nil
 }
 
-func (a *Auth) generateJWT() (string, error) {
+func (a *Auth) readPrivateKey() error {
+	awsClient, err := aws.New()
+	if err != nil {
+		return err
+	}
+	path, err := domain.SSMParameterPath(domain.SSMPrivateKey)
+	if err != nil {
+		return err
+	}
+	a.privateKey, err = awsClient.GetSSMParameter(path)
+	return err
+}
+
+// generateJWT returns access token, refresh token and the GitHub username
+func (a *Auth) generateJWT() (string, string, string, error) {
 	ghUser, _, err := a.ghClient.Users.Get(context.Background(), "")
 	if err != nil {
-		return "", err
+		return "", "", "", err
 	}
+	username := *ghUser.Login
 	role, err := a.userRole(ghUser)
 	if err != nil {
-		return "", err
+		return "", "", username, err
+	}
+	ss, err := a.store.CreateSession(username, role, refreshTokenTTL)
+	if err != nil {
+		return "", "", username, err
 	}
+	jwt, err := a.accessToken(ss.Username, ss.Role, ss.ID)
+	if err != nil {
+		return "", "", username, err
+	}
+	return jwt, ss.refreshToken(), username, nil
+}
+
+// Refresh issues new access token for the session identified by refresh
+// token. Used by cli to silently renew expired access token.
+func (a *Auth) Refresh(ctx context.Context, req *dto.RefreshTokenRequest) (*dto.RefreshTokenResponse, error) {
+	ss, err := a.store.FindSession(req.RefreshToken)
+	var nerr *mantil.ErrItemNotFound
+	if errors.As(err, &nerr) {
+		return nil, domain.ErrNotAuthorized
+	}
+	if err != nil {
+		return nil, err
+	}
+	if ss.expired() {
+		return nil, domain.ErrNotAuthorized
+	}
+	if ss.Username != a.node.GithubUser {
+		// role could be changed or user removed in the meantime
+		u, err := a.store.FindUser(ss.Username)
+		if errors.As(err, &nerr) {
+			return nil, domain.ErrNotAuthorized
+		}
+		if err != nil {
+			return nil, err
+		}
+		ss.Role = u.Role
+	}
+	if err := a.readPrivateKey(); err != nil {
+		return nil, err
+	}
+	jwt, err := a.accessToken(ss.Username, ss.Role, ss.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.store.TouchSession(ss); err != nil {
+		return nil, err
+	}
+	return &dto.RefreshTokenResponse{Token: jwt}, nil
+}
+
+func (a *Auth) accessToken(username string, role domain.Role, sessionID string) (string, error) {
 	switch role {
 	case domain.Admin:
-		return a.adminToken(*ghUser.Login)
+		return a.adminToken(username, sessionID)
 	case domain.User:
-		return a.userToken(*ghUser.Login)
+		return a.userToken(username, sessionID)
 	default:
 		return "", fmt.Errorf("unsupported role")
 	}
@@ -135,27 +194,31 @@ func (a *Auth) userRole(ghUser *github.User) (domain.Role, error) {
 	return u.Role, nil
 }
 
-func (a *Auth) adminToken(username string) (string, error) {
+func (a *Auth) adminToken(username, sessionID string) (string, error) {
 	return token.JWT(a.privateKey, &domain.AccessTokenClaims{
-		Username: username,
-		Role:     domain.Admin,
-		Node:     a.node,
+		Username:  username,
+		Role:      domain.Admin,
+		Node:      a.node,
+		SessionID: sessionID,
 	}, 7*24*time.Hour)
 }
 
-func (a *Auth) userToken(username string) (string, error) {
+func (a *Auth) userToken(username, sessionID string) (string, error) {
 	return token.JWT(a.privateKey, &domain.AccessTokenClaims{
-		Username: username,
-		Role:     domain.User,
-		Node:     a.node,
+		Username:  username,
+		Role:      domain.User,
+		Node:      a.node,
+		SessionID: sessionID,
 	}, 1*time.Hour)
 }
 
-func (a *Auth) publishJWT(jwt string) error {
+func (a *Auth) publishJWT(jwt, refreshToken string) error {
 	rsp := struct {
-		JWT string `json:"jwt"`
+		JWT          string `json:"jwt"`
+		RefreshToken string `json:"refresh_token"`
 	}{
-		JWT: jwt,
+		JWT:          jwt,
+		RefreshToken: refreshToken,
 	}
 	buf, err := json.Marshal(rsp)
 	if err != nil {
@@ -1,17 +1,28 @@
 package node
 
 import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/mantil-io/mantil.go"
 	"github.com/mantil-io/mantil/domain"
+	"github.com/mantil-io/mantil/node/dto"
 )
 
 const (
-	usersPartition = "users"
+	usersPartition    = "users"
+	sessionsPartition = "sessions"
+	revokedPartition  = "revoked"
 )
 
 type Store struct {
-	users  *mantil.KV
-	config *mantil.KV
+	users    *mantil.KV
+	config   *mantil.KV
+	sessions *mantil.KV
+	revoked  *mantil.KV
 }
 
 func NewStore() (*Store, error) {
@@ -23,9 +34,19 @@ func NewStore() (*Store, error) {
 	if err != nil {
 		return nil, err
 	}
+	sessions, err := mantil.NewKV(sessionsPartition)
+	if err != nil {
+		return nil, err
+	}
+	revoked, err := mantil.NewKV(revokedPartition)
+	if err != nil {
+		return nil, err
+	}
 	return &Store{
-		users:  users,
-		config: config,
+		users:    users,
+		config:   config,
+		sessions: sessions,
+		revoked:  revoked,
 	}, nil
 }
 
@@ -42,7 +63,10 @@ func (s *Store) StoreUser(name string, role domain.Role) error {
 }
 
 func (s *Store) RemoveUser(name string) error {
-	return s.users.Delete(name)
+	if err := s.users.Delete(name); err != nil {
+		return err
+	}
+	return s.RevokeUserSessions(name)
 }
 
 func (s *Store) FindUser(name string) (*user, error) {
@@ -60,3 +84,198 @@ func (s *Store) FindConfig() (*domain.Node, error) {
 	}
 	return n, nil
 }
+
+// session is created on each user login, refresh token holds session id and
+// secret which must match the stored one
+type session struct {
+	ID         string
+	Secret     string
+	Username   string
+	Role       domain.Role
+	CreatedAt  int64
+	LastUsedAt int64
+	ExpiresAt  int64
+}
+
+func (s *session) refreshToken() string {
+	return fmt.Sprintf("%s.%s", s.ID, s.Secret)
+}
+
+func (s *session) expired() bool {
+	return time.Now().Unix() > s.ExpiresAt
+}
+
+func (s *session) toDto() dto.Session {
+	return dto.Session{
+		ID:         s.ID,
+		Username:   s.Username,
+		Role:       s.Role,
+		CreatedAt:  s.CreatedAt,
+		LastUsedAt: s.LastUsedAt,
+		ExpiresAt:  s.ExpiresAt,
+	}
+}
+
+func (s *Store) CreateSession(username string, role domain.Role, ttl time.Duration) (*session, error) {
+	now := time.Now()
+	ss := &session{
+		ID:         domain.UID(),
+		Secret:     domain.UID() + domain.UID(),
+		Username:   username,
+		Role:       role,
+		CreatedAt:  now.Unix(),
+		LastUsedAt: now.Unix(),
+		ExpiresAt:  now.Add(ttl).Unix(),
+	}
+	if err := s.sessions.Put(ss.ID, ss); err != nil {
+		return nil, err
+	}
+	return ss, nil
+}
+
+// FindSession returns session for the refresh token. Returns
+// domain.ErrNotAuthorized if the token is malformed or the secret doesn't
+// match.
+func (s *Store) FindSession(refreshToken string) (*session, error) {
+	parts := strings.Split(refreshToken, ".")
+	if len(parts) != 2 {
+		return nil, domain.ErrNotAuthorized
+	}
+	ss := &session{}
+	if err := s.sessions.Get(parts[0], ss); err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(ss.Secret), []byte(parts[1])) != 1 {
+		return nil, domain.ErrNotAuthorized
+	}
+	return ss, nil
+}
+
+func (s *Store) TouchSession(ss *session) error {
+	ss.LastUsedAt = time.Now().Unix()
+	return s.sessions.Put(ss.ID, ss)
+}
+
+func (s *Store) Sessions(username string) ([]dto.Session, error) {
+	if err := s.pruneRevoked(); err != nil {
+		return nil, err
+	}
+	var items []session
+	iter, err := s.sessions.FindAll(&items)
+	if err != nil {
+		return nil, err
+	}
+	var sessions []dto.Session
+	for {
+		for _, ss := range items {
+			if ss.expired() {
+				continue
+			}
+			if username != "" && ss.Username != username {
+				continue
+			}
+			sessions = append(sessions, ss.toDto())
+		}
+		if !iter.HasMore() {
+			break
+		}
+		if err := iter.Next(&items); err != nil {
+			return nil, err
+		}
+	}
+	return sessions, nil
+}
+
+func (s *Store) FindSessionByID(id string) (*session, error) {
+	ss := &session{}
+	if err := s.sessions.Get(id, ss); err != nil {
+		return nil, err
+	}
+	return ss, nil
+}
+
+type revokedSession struct {
+	ID        string
+	RevokedAt int64
+}
+
+// RevokeSession removes session and adds it to the revocation list so that
+// access tokens issued for the session are rejected by the authorizer.
+func (s *Store) RevokeSession(id string) error {
+	if err := s.pruneRevoked(); err != nil {
+		return err
+	}
+	if err := s.revoked.Put(id, &revokedSession{
+		ID:        id,
+		RevokedAt: time.Now().Unix(),
+	}); err != nil {
+		return err
+	}
+	return s.sessions.Delete(id)
+}
+
+// expired sessions are rejected anyway, revocation is no longer needed after
+// the refresh token of the session would expire
+func (r *revokedSession) expired() bool {
+	return time.Since(time.Unix(r.RevokedAt, 0)) > refreshTokenTTL
+}
+
+// pruneRevoked removes expired sessions from the revocation list
+func (s *Store) pruneRevoked() error {
+	var items []revokedSession
+	iter, err := s.revoked.FindAll(&items)
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for {
+		for _, rs := range items {
+			if rs.expired() {
+				ids = append(ids, rs.ID)
+			}
+		}
+		if !iter.HasMore() {
+			break
+		}
+		if err := iter.Next(&items); err != nil {
+			return err
+		}
+	}
+	for _, id := range ids {
+		if err := s.revoked.Delete(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) RevokeUserSessions(username string) error {
+	sessions, err := s.Sessions(username)
+	if err != nil {
+		return err
+	}
+	for _, ss := range sessions {
+		if err := s.RevokeSession(ss.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SessionRevoked checks whether session is on the revocation list.
+func SessionRevoked(id string) (bool, error) {
+	kv, err := mantil.NewKV(revokedPartition)
+	if err != nil {
+		return false, err
+	}
+	var rs revokedSession
+	err = kv.Get(id, &rs)
+	if err == nil {
+		return true, nil
+	}
+	var nerr *mantil.ErrItemNotFound
+	if errors.As(err, &nerr) {
+		return false, nil
+	}
+	return false, err
+}
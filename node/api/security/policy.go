@@ -16,6 +16,24 @@ const credentialsTemplate = `{
         }
         {{ end }}
         {{ end }}
+        {{- if ne .PublicBucket "" }}
+        ,{
+            "Action": [
+                "s3:DeleteObject"
+            ],
+            "Effect": "Allow",
+            "Resource": "arn:aws:s3:::{{.PublicBucket}}/*"
+        }
+        {{ end }}
+        {{- if ne .DistributionID "" }}
+        ,{
+            "Action": [
+                "cloudfront:CreateInvalidation"
+            ],
+            "Effect": "Allow",
+            "Resource": "arn:aws:cloudfront::{{.AccountID}}:distribution/{{.DistributionID}}"
+        }
+        {{ end }}
         {{- if ne .LogGroupsPrefix "" }}
         ,{
             "Action": [
@@ -24,6 +42,30 @@ const credentialsTemplate = `{
             ],
             "Effect": "Allow",
             "Resource": "arn:aws:logs:{{.Region}}:{{.AccountID}}:log-group:{{.LogGroupsPrefix}}*"
+        },
+        {
+            "Action": [
+                "cloudwatch:GetMetricData",
+                "ce:GetCostAndUsage"
+            ],
+            "Effect": "Allow",
+            "Resource": "*"
+        }
+        {{ end }}
+        {{- if ne .FunctionsPrefix "" }}
+        ,{
+            "Action": [
+                "lambda:GetFunction"
+            ],
+            "Effect": "Allow",
+            "Resource": "arn:aws:lambda:{{.Region}}:{{.AccountID}}:function:{{.FunctionsPrefix}}*"
+        },
+        {
+            "Action": [
+                "s3:GetObject"
+            ],
+            "Effect": "Allow",
+            "Resource": "arn:aws:s3:::{{.FunctionsBucket}}/{{.FunctionsBucketPrefix}}/*"
         }
         {{ end }}
     ]
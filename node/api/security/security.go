@@ -64,9 +64,15 @@ func (s *Security) credentials() (*dto.SecurityResponse, error) {
 func (s *Security) projectPolicyTemplateData() projectPolicyTemplateData {
 	pptd := projectPolicyTemplateData{
 		Buckets:         s.Buckets,
+		PublicBucket:    s.PublicBucket,
+		DistributionID:  s.DistributionID,
 		LogGroupsPrefix: s.LogGroupsPrefix,
 		Region:          s.awsClient.Region(),
 		AccountID:       s.awsClient.AccountID(),
+
+		FunctionsPrefix:       s.FunctionsPrefix,
+		FunctionsBucket:       s.FunctionsBucket,
+		FunctionsBucketPrefix: s.FunctionsBucketPrefix,
 	}
 	return pptd
 }
@@ -90,7 +96,13 @@ func (s *Security) credentialsForPolicy(policy string) (*aws.Credentials, error)
 
 type projectPolicyTemplateData struct {
 	Buckets         []string
+	PublicBucket    string
+	DistributionID  string
 	LogGroupsPrefix string
 	Region          string
 	AccountID       string
+
+	FunctionsPrefix       string
+	FunctionsBucket       string
+	FunctionsBucketPrefix string
 }
@@ -40,13 +40,20 @@ func TestProjectPolicyWithLogGroup(t *testing.T) {
 		SecurityRequest: dto.SecurityRequest{
 			CliRole:         "cliRole",
 			Buckets:         []string{"bucket1", "bucket2", ""},
+			PublicBucket:    "bucket2",
+			DistributionID:  "distributionID",
 			LogGroupsPrefix: "logGroupsPrefix",
+
+			FunctionsPrefix:       "functionsPrefix",
+			FunctionsBucket:       "bucket1",
+			FunctionsBucketPrefix: "functionsBucketPrefix",
 		},
 		awsClient: &awsMock{},
 	}
 	pptd := s.projectPolicyTemplateData()
 	assert.NotEmpty(t, pptd.Buckets)
 	assert.NotEmpty(t, pptd.LogGroupsPrefix)
+	assert.NotEmpty(t, pptd.FunctionsPrefix)
 	assert.NotEmpty(t, pptd.Region)
 	assert.NotEmpty(t, pptd.AccountID)
 
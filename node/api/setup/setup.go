@@ -117,14 +117,15 @@ func (s *Setup) apiCloudwatchRoleArn(name string) (string, error) {
 func (s *Setup) terraformCreate(req *dto.SetupRequest) (*dto.SetupResponse, error) {
 	n := req.Node
 	data := terraform.SetupTemplateData{
-		Bucket:          req.BucketConfig.Name,
-		Region:          s.awsClient.Region(),
-		FunctionsBucket: n.Functions.Bucket,
-		FunctionsPath:   n.Functions.Path,
-		ResourceSuffix:  n.ResourceSuffix(),
-		NamingTemplate:  n.ResourceNamingTemplate(),
-		AuthEnv:         n.AuthEnv(),
-		ResourceTags:    n.ResourceTags(),
+		Bucket:           req.BucketConfig.Name,
+		Region:           s.awsClient.Region(),
+		FunctionsBucket:  n.Functions.Bucket,
+		FunctionsPath:    n.Functions.Path,
+		ResourceSuffix:   n.ResourceSuffix(),
+		NamingTemplate:   n.ResourceNamingTemplate(),
+		AuthEnv:          n.AuthEnv(),
+		ResourceTags:     n.ResourceTags(),
+		LogRetentionDays: n.LogRetention(),
 	}
 	if n.GithubAuthEnabled() {
 		publicKey, privateKey, err := token.KeyPair()
@@ -133,6 +134,11 @@ func (s *Setup) terraformCreate(req *dto.SetupRequest) (*dto.SetupResponse, erro
 		}
 		data.PublicKey = publicKey
 		data.PrivateKey = privateKey
+		// keep accepting tokens signed with the replaced key
+		if pk, err := s.awsClient.GetSSMParameter(fmt.Sprintf("%s/%s", n.SSMPathPrefix(), domain.SSMPublicKey)); err == nil {
+			n.SetPreviousPublicKey(pk)
+			data.AuthEnv = n.AuthEnv()
+		}
 	}
 	tf, err := terraform.Setup(data)
 	if err != nil {
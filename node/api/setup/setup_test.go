@@ -26,10 +26,11 @@ func TestTerraformRender(t *testing.T) {
 			"tag1": "value1",
 			"tag2": "value2",
 		},
-		NamingTemplate: "mantil-%s",
-		PublicKey:      "public_key",
-		PrivateKey:     "private_key",
-		GithubUser:     "github_user",
+		NamingTemplate:   "mantil-%s",
+		PublicKey:        "public_key",
+		PrivateKey:       "private_key",
+		GithubUser:       "github_user",
+		LogRetentionDays: 14,
 	}
 	tf, err := terraform.Setup(data)
 	require.NoError(t, err)
@@ -8,6 +8,7 @@ import (
 
 type DeployRequest struct {
 	ProjectName        string
+	StageName          string
 	NodeBucket         string
 	FunctionsForUpdate []Function
 	StageTemplate      *StageTemplate
@@ -30,6 +31,13 @@ type StageTemplate struct {
 	NamingTemplate      string
 	PublicBucketName    string
 	CustomDomain        CustomDomain
+	CDN                 CDN
+	// topics for the email addresses and webhooks notified by the alarms
+	AlarmTopics []AlarmTopic
+	// enables tracing of the api lambda functions
+	Tracing bool
+	// retention of the stage log groups
+	LogRetentionDays int
 }
 
 type Function struct {
@@ -43,6 +51,48 @@ type Function struct {
 	Env        map[string]string
 	Cron       string
 	EnableAuth bool
+	// http routes of the api methods with their own configuration
+	Routes []Route
+	// one of the routes is on the function path, only proxy route is
+	// created for the function
+	RootRouted bool
+	Alarms     []Alarm
+	// lambda tracing mode, Active or PassThrough
+	TracingMode string
+}
+
+type Alarm struct {
+	Name string
+	// lambda metric, empty for the error rate alarm which is calculated
+	// from errors and invocations metrics
+	MetricName        string
+	Statistic         string
+	ExtendedStatistic string
+	ErrorRate         bool
+	Threshold         float64
+	Period            int
+	EvaluationPeriods int
+	// SNS topics notified by the alarm
+	Topics []string
+	// key of the alarm topic with email and webhook subscriptions notified
+	// by the alarm, empty if there are none
+	SubscriptionsTopic string
+}
+
+type AlarmTopic struct {
+	Key           string
+	Subscriptions []AlarmSubscription
+}
+
+type AlarmSubscription struct {
+	Protocol string
+	Endpoint string
+}
+
+type Route struct {
+	Method     string
+	Path       string
+	EnableAuth bool
 }
 
 type CustomDomains struct {
@@ -58,10 +108,34 @@ type CustomDomain struct {
 	WsSubdomain      string
 }
 
+type CDN struct {
+	Enabled          bool
+	Domain           string
+	CertDomain       string
+	HostedZoneDomain string
+	SPA              bool
+	PriceClass       string
+}
+
 type DeployResponse struct {
-	Rest         string
-	Ws           string
-	PublicBucket string
+	Rest              string
+	Ws                string
+	PublicBucket      string
+	CDNURL            string
+	CDNDistributionID string
+}
+
+// PlanResponse are changes to the stage infrastructure which would be made
+// by the deploy, empty if deployed resources match the stage template.
+type PlanResponse struct {
+	Changes []PlannedChange
+}
+
+type PlannedChange struct {
+	// terraform resource address
+	Address string
+	// created, updated in-place, destroyed, replaced...
+	Action string
 }
 
 type DestroyRequest struct {
@@ -74,6 +148,27 @@ type DestroyRequest struct {
 	CleanupBucketPrefixes []string
 }
 
+// StageExpiry is recorded on the node for the stage created with ttl. Node
+// destroys expired stage with the destroy request.
+type StageExpiry struct {
+	ExpiresAt int64
+	Notify    string
+	Destroy   DestroyRequest
+}
+
+type RemoveStageExpiryRequest struct {
+	ProjectName string
+	StageName   string
+}
+
+// StageExpiredNotification is posted to the notify webhook of the expired stage.
+type StageExpiredNotification struct {
+	Project   string `json:"project"`
+	Stage     string `json:"stage"`
+	ExpiresAt int64  `json:"expires_at"`
+	Error     string `json:"error,omitempty"`
+}
+
 const (
 	RequestQueryParam = "r"
 )
@@ -81,7 +176,16 @@ const (
 type SecurityRequest struct {
 	CliRole         string
 	Buckets         []string
+	PublicBucket    string
+	DistributionID  string
 	LogGroupsPrefix string
+	// read access to the stage functions and their deployment packages
+	FunctionsPrefix       string
+	FunctionsBucket       string
+	FunctionsBucketPrefix string
+	// stage for which credentials are requested, recorded in the audit log
+	ProjectName string
+	StageName   string
 }
 
 // credentials for aws sdk endpointcreds integration on the CLI
@@ -129,3 +233,53 @@ type RemoveUserRequest struct {
 type LoginResponse struct {
 	Node *domain.Node
 }
+
+type RefreshTokenRequest struct {
+	RefreshToken string
+}
+
+type RefreshTokenResponse struct {
+	Token string
+}
+
+type SessionsRequest struct {
+	Username string
+}
+
+type SessionsResponse struct {
+	Sessions []Session
+}
+
+type Session struct {
+	ID         string
+	Username   string
+	Role       domain.Role
+	CreatedAt  int64
+	LastUsedAt int64
+	ExpiresAt  int64
+}
+
+type RevokeSessionRequest struct {
+	ID string
+}
+
+type AuditRequest struct {
+	Since    int64
+	Stage    string
+	Username string
+}
+
+type AuditResponse struct {
+	Records []AuditRecord
+}
+
+type AuditRecord struct {
+	Time     int64
+	Username string
+	Action   string
+	Project  string
+	Stage    string
+	Request  string
+	Error    string
+	Duration int64
+}
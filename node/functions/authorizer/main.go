@@ -6,14 +6,24 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/mantil-io/mantil/domain"
 	"github.com/mantil-io/mantil/kit/aws"
+	"github.com/mantil-io/mantil/node/api/node"
 )
 
-func handleRequest(ctx context.Context, req *events.APIGatewayCustomAuthorizerRequestTypeRequest) (*events.APIGatewayV2CustomAuthorizerSimpleResponse, error) {
+// request extends v1 authorizer request with the fields of the http api
+// payload version 2.0
+type request struct {
+	events.APIGatewayCustomAuthorizerRequestTypeRequest
+	RouteKey string `json:"routeKey"`
+	RawPath  string `json:"rawPath"`
+}
+
+func handleRequest(ctx context.Context, req *request) (*events.APIGatewayV2CustomAuthorizerSimpleResponse, error) {
 	buf, _ := json.Marshal(req)
 	log.Printf("req %s", buf)
 
@@ -21,9 +31,24 @@ func handleRequest(ctx context.Context, req *events.APIGatewayCustomAuthorizerRe
 	if err != nil {
 		return errorResponse(err)
 	}
-	claims, err := domain.ReadAccessToken(req.Headers, pk)
+	var previousKeys []string
+	if ppk := domain.PreviousPublicKeyFromEnv(); ppk != "" {
+		previousKeys = append(previousKeys, ppk)
+	}
+	fn := functionName(req.RawPath)
+	claims, err := domain.ReadAccessToken(req.Headers, pk, previousKeys...)
 	if err != nil {
-		return errorResponse(fmt.Errorf("read runtime access token error %w", err))
+		ec, eerr := externalClaims(req.Headers, fn)
+		if eerr != nil || ec == nil {
+			return errorResponse(fmt.Errorf("read runtime access token error %w", err))
+		}
+		claims = ec
+	}
+	if err := checkSession(claims); err != nil {
+		return errorResponse(err)
+	}
+	if err := authorizeFunction(claims, fn); err != nil {
+		return errorResponse(err)
 	}
 	rsp := allowResponse(claims)
 	buf, _ = json.Marshal(rsp)
@@ -51,6 +76,60 @@ func publicKey() (string, error) {
 	return pk, nil
 }
 
+// externalClaims verifies token issued by the external identity provider if
+// the function has one configured. Returns nil claims if it is not configured.
+func externalClaims(headers map[string]string, fn string) (*domain.AccessTokenClaims, error) {
+	cfg, err := domain.AuthorizerConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	a, ok := cfg.Function(fn)
+	if !ok {
+		return nil, nil
+	}
+	claims, err := domain.ReadExternalAccessToken(headers, a)
+	if err != nil {
+		log.Printf("read external access token error %s", err)
+		return nil, err
+	}
+	return claims, nil
+}
+
+// checkSession rejects tokens issued for revoked node user sessions. Session
+// id is set only in tokens issued by the node auth function.
+func checkSession(claims *domain.AccessTokenClaims) error {
+	if claims.SessionID == "" || os.Getenv(domain.EnvKVTable) == "" {
+		return nil
+	}
+	revoked, err := node.SessionRevoked(claims.SessionID)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return fmt.Errorf("session %s revoked", claims.SessionID)
+	}
+	return nil
+}
+
+// authorizeFunction checks function scope of the token. Function is empty on
+// the websocket connect route, scoped tokens are rejected there because
+// ws-handler can route messages to any function.
+func authorizeFunction(claims *domain.AccessTokenClaims, fn string) error {
+	if claims.FunctionAllowed(fn) {
+		return nil
+	}
+	if fn == "" {
+		return fmt.Errorf("token scoped to functions %s can't be used without function in path", strings.Join(claims.Functions, ", "))
+	}
+	return fmt.Errorf("function %s not allowed", fn)
+}
+
+// functionName returns first path segment, which is function name in
+// Mantil http api routes
+func functionName(path string) string {
+	return strings.Split(strings.TrimPrefix(path, "/"), "/")[0]
+}
+
 func allowResponse(claims *domain.AccessTokenClaims) *events.APIGatewayV2CustomAuthorizerSimpleResponse {
 	rsp := &events.APIGatewayV2CustomAuthorizerSimpleResponse{
 		IsAuthorized: true,
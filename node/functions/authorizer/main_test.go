@@ -0,0 +1,20 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mantil-io/mantil/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthorizeFunction(t *testing.T) {
+	scoped := &domain.AccessTokenClaims{Functions: []string{"ping"}}
+	require.NoError(t, authorizeFunction(scoped, functionName("/ping/hello")))
+	require.Error(t, authorizeFunction(scoped, functionName("/other")))
+	// websocket $connect route has empty raw path
+	require.Error(t, authorizeFunction(scoped, functionName("")))
+
+	unscoped := &domain.AccessTokenClaims{}
+	require.NoError(t, authorizeFunction(unscoped, functionName("")))
+	require.NoError(t, authorizeFunction(unscoped, functionName("/other")))
+}
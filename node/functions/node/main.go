@@ -2,10 +2,14 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"time"
 
 	"github.com/mantil-io/mantil.go"
 	"github.com/mantil-io/mantil/domain"
+	"github.com/mantil-io/mantil/node/api/audit"
+	"github.com/mantil-io/mantil/node/api/expiry"
 	"github.com/mantil-io/mantil/node/api/node"
 	"github.com/mantil-io/mantil/node/dto"
 )
@@ -32,7 +36,12 @@ func (n *Node) AddUser(ctx context.Context, req *dto.AddUserRequest) error {
 	if !ok {
 		return domain.ErrNotAuthorized
 	}
-	return n.store.StoreUser(req.Username, req.Role)
+	return audit.Log(ctx, audit.Action{
+		Name:    "user add",
+		Request: fmt.Sprintf("username: %s, role: %d", req.Username, req.Role),
+	}, func() error {
+		return n.store.StoreUser(req.Username, req.Role)
+	})
 }
 
 func (n *Node) RemoveUser(ctx context.Context, req *dto.RemoveUserRequest) error {
@@ -43,7 +52,90 @@ func (n *Node) RemoveUser(ctx context.Context, req *dto.RemoveUserRequest) error
 	if !ok {
 		return domain.ErrNotAuthorized
 	}
-	return n.store.RemoveUser(req.Username)
+	return audit.Log(ctx, audit.Action{
+		Name:    "user remove",
+		Request: fmt.Sprintf("username: %s", req.Username),
+	}, func() error {
+		return n.store.RemoveUser(req.Username)
+	})
+}
+
+func (n *Node) Sessions(ctx context.Context, req *dto.SessionsRequest) (*dto.SessionsResponse, error) {
+	ok, err := domain.IsAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, domain.ErrNotAuthorized
+	}
+	sessions, err := n.store.Sessions(req.Username)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.SessionsResponse{Sessions: sessions}, nil
+}
+
+func (n *Node) RevokeSession(ctx context.Context, req *dto.RevokeSessionRequest) error {
+	claims, err := domain.ClaimsFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if claims.Role != domain.Admin {
+		// users can revoke only their own sessions
+		ss, err := n.store.FindSessionByID(req.ID)
+		if err != nil {
+			return err
+		}
+		if ss.Username != claims.Username {
+			return domain.ErrNotAuthorized
+		}
+	}
+	return n.store.RevokeSession(req.ID)
+}
+
+func (n *Node) Audit(ctx context.Context, req *dto.AuditRequest) (*dto.AuditResponse, error) {
+	ok, err := domain.IsAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, domain.ErrNotAuthorized
+	}
+	records, err := audit.Find(*req)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.AuditResponse{Records: records}, nil
+}
+
+// StageExpiry records expiry of the stage created with ttl, node destroys the
+// stage after it expires.
+func (n *Node) StageExpiry(ctx context.Context, req *dto.StageExpiry) error {
+	if _, err := domain.ClaimsFromContext(ctx); err != nil {
+		return err
+	}
+	return audit.Log(ctx, audit.Action{
+		Name:    "stage expiry",
+		Project: req.Destroy.ProjectName,
+		Stage:   req.Destroy.StageName,
+		Request: fmt.Sprintf("expires at: %s", time.Unix(req.ExpiresAt, 0).UTC().Format(time.RFC3339)),
+	}, func() error {
+		return expiry.Put(*req)
+	})
+}
+
+// RemoveStageExpiry removes expiry of the stage which failed to be created.
+func (n *Node) RemoveStageExpiry(ctx context.Context, req *dto.RemoveStageExpiryRequest) error {
+	if _, err := domain.ClaimsFromContext(ctx); err != nil {
+		return err
+	}
+	return audit.Log(ctx, audit.Action{
+		Name:    "stage expiry remove",
+		Project: req.ProjectName,
+		Stage:   req.StageName,
+	}, func() error {
+		return expiry.Delete(req.ProjectName, req.StageName)
+	})
 }
 
 func main() {
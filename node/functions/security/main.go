@@ -4,12 +4,16 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/mantil-io/mantil/node/dto"
+	"github.com/mantil-io/mantil/domain"
+	"github.com/mantil-io/mantil/node/api/audit"
 	"github.com/mantil-io/mantil/node/api/security"
+	"github.com/mantil-io/mantil/node/dto"
 )
 
 func main() {
@@ -28,7 +32,18 @@ func handler(ctx context.Context, event events.APIGatewayProxyRequest) (events.A
 		return errorResponse(err), nil
 	}
 
-	resp, err := api.Invoke(context.Background(), req)
+	claims, _ := domain.ClaimsFromAuthorizerContext(event.RequestContext.Authorizer)
+	var resp *dto.SecurityResponse
+	err = audit.LogClaims(claims, audit.Action{
+		Name:    "security",
+		Project: req.ProjectName,
+		Stage:   req.StageName,
+		Request: fmt.Sprintf("buckets: %s, log groups prefix: %s", strings.Join(req.Buckets, ", "), req.LogGroupsPrefix),
+	}, func() error {
+		var err error
+		resp, err = api.Invoke(context.Background(), req)
+		return err
+	})
 	if err != nil {
 		return errorResponse(err), nil
 	}
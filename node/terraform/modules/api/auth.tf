@@ -17,10 +17,14 @@ resource "aws_lambda_function" "authorizer" {
   environment {
     variables = var.authorizer.env
   }
+
+  tracing_config {
+    mode = var.tracing ? "Active" : "PassThrough"
+  }
 }
 
 resource "aws_cloudwatch_log_group" "authorizer_log_group" {
   count             = var.authorizer == null ? 0 : 1
   name              = "/aws/lambda/${local.authorizer_lambda_name}"
-  retention_in_days = 14
+  retention_in_days = var.log_retention_days
 }
@@ -38,6 +38,27 @@ data "aws_iam_policy_document" "authorizer" {
       "*",
     ]
   }
+  statement {
+    effect = "Allow"
+    actions = [
+      "dynamodb:DescribeTable",
+      "dynamodb:GetItem",
+    ]
+    resources = [
+      "arn:aws:dynamodb:*:*:table/mantil-kv-${var.suffix}",
+    ]
+  }
+  dynamic "statement" {
+    for_each = var.tracing ? [1] : []
+    content {
+      effect = "Allow"
+      actions = [
+        "xray:PutTraceSegments",
+        "xray:PutTelemetryRecords",
+      ]
+      resources = ["*"]
+    }
+  }
 }
 
 resource "aws_iam_role_policy" "authorizer" {
@@ -23,7 +23,8 @@ module "http_api" {
     arn                  = aws_lambda_function.authorizer[0].arn
     invoke_arn           = aws_lambda_function.authorizer[0].invoke_arn
   }
-  domain = local.domains.http
+  domain             = local.domains.http
+  log_retention_days = var.log_retention_days
 }
 
 module "ws_api" {
@@ -40,5 +41,7 @@ module "ws_api" {
     arn                  = aws_lambda_function.authorizer[0].arn
     invoke_arn           = aws_lambda_function.authorizer[0].invoke_arn
   }
-  domain = local.domains.ws
+  domain             = local.domains.ws
+  tracing            = var.tracing
+  log_retention_days = var.log_retention_days
 }
@@ -20,6 +20,10 @@ variable "integrations" {
     uri                = string
     lambda_name        = optional(string)
     enable_auth        = optional(bool)
+    # creates {proxy+} route for the paths under the route
+    proxy              = optional(bool)
+    # creates only {proxy+} route, route itself is served by another integration
+    proxy_only         = optional(bool)
     is_default         = optional(bool)
   }))
   default = []
@@ -56,3 +60,15 @@ variable "custom_domain" {
     ws_subdomain       = ""
   }
 }
+
+variable "tracing" {
+  type        = bool
+  default     = false
+  description = "Enables X-Ray tracing of the api lambda functions."
+}
+
+variable "log_retention_days" {
+  type        = number
+  default     = 14
+  description = "Retention of the log groups in days."
+}
@@ -0,0 +1,146 @@
+terraform {
+  required_providers {
+    aws = {
+      source                = "hashicorp/aws"
+      configuration_aliases = [aws.us_east_1]
+    }
+  }
+}
+
+locals {
+  has_public       = var.public_origin != ""
+  has_domain       = var.domain != ""
+  public_origin_id = "public"
+  api_origin_id    = "api"
+  all_methods      = ["GET", "HEAD", "OPTIONS", "PUT", "POST", "PATCH", "DELETE"]
+  # each function is served on /name and /name/* paths
+  api_paths = flatten([for r in var.routes : ["/${r}", "/${r}/*"]])
+}
+
+data "aws_cloudfront_cache_policy" "caching_optimized" {
+  name = "Managed-CachingOptimized"
+}
+
+data "aws_cloudfront_cache_policy" "caching_disabled" {
+  name = "Managed-CachingDisabled"
+}
+
+data "aws_cloudfront_origin_request_policy" "all_viewer_except_host_header" {
+  name = "Managed-AllViewerExceptHostHeader"
+}
+
+resource "aws_cloudfront_function" "spa" {
+  count   = var.spa && local.has_public ? 1 : 0
+  name    = format(var.naming_template, "spa")
+  runtime = "cloudfront-js-1.0"
+  publish = true
+  code    = file("${path.module}/spa.js")
+}
+
+resource "aws_cloudfront_distribution" "cdn" {
+  enabled             = true
+  is_ipv6_enabled     = true
+  comment             = format(var.naming_template, "cdn")
+  price_class         = var.price_class
+  aliases             = local.has_domain ? [var.domain] : []
+  default_root_object = local.has_public ? "index.html" : null
+
+  dynamic "origin" {
+    for_each = local.has_public ? [var.public_origin] : []
+    content {
+      domain_name = origin.value
+      origin_id   = local.public_origin_id
+      # s3 website endpoint supports only http
+      custom_origin_config {
+        http_port              = 80
+        https_port             = 443
+        origin_protocol_policy = "http-only"
+        origin_ssl_protocols   = ["TLSv1.2"]
+      }
+    }
+  }
+
+  origin {
+    domain_name = var.api_origin
+    origin_id   = local.api_origin_id
+    custom_origin_config {
+      http_port              = 80
+      https_port             = 443
+      origin_protocol_policy = "https-only"
+      origin_ssl_protocols   = ["TLSv1.2"]
+    }
+  }
+
+  # public site content is cached, without public site everything goes to the api
+  default_cache_behavior {
+    target_origin_id         = local.has_public ? local.public_origin_id : local.api_origin_id
+    viewer_protocol_policy   = "redirect-to-https"
+    allowed_methods          = local.has_public ? ["GET", "HEAD", "OPTIONS"] : local.all_methods
+    cached_methods           = ["GET", "HEAD"]
+    compress                 = true
+    cache_policy_id          = local.has_public ? data.aws_cloudfront_cache_policy.caching_optimized.id : data.aws_cloudfront_cache_policy.caching_disabled.id
+    origin_request_policy_id = local.has_public ? null : data.aws_cloudfront_origin_request_policy.all_viewer_except_host_header.id
+
+    dynamic "function_association" {
+      for_each = aws_cloudfront_function.spa
+      content {
+        event_type   = "viewer-request"
+        function_arn = function_association.value.arn
+      }
+    }
+  }
+
+  # function routes are never cached
+  dynamic "ordered_cache_behavior" {
+    for_each = local.api_paths
+    content {
+      path_pattern             = ordered_cache_behavior.value
+      target_origin_id         = local.api_origin_id
+      viewer_protocol_policy   = "redirect-to-https"
+      allowed_methods          = local.all_methods
+      cached_methods           = ["GET", "HEAD"]
+      compress                 = true
+      cache_policy_id          = data.aws_cloudfront_cache_policy.caching_disabled.id
+      origin_request_policy_id = data.aws_cloudfront_origin_request_policy.all_viewer_except_host_header.id
+    }
+  }
+
+  restrictions {
+    geo_restriction {
+      restriction_type = "none"
+    }
+  }
+
+  viewer_certificate {
+    cloudfront_default_certificate = !local.has_domain
+    acm_certificate_arn            = local.has_domain ? data.aws_acm_certificate.cdn[0].arn : null
+    ssl_support_method             = local.has_domain ? "sni-only" : null
+    minimum_protocol_version       = local.has_domain ? "TLSv1.2_2021" : "TLSv1"
+  }
+}
+
+# cloudfront accepts only certificates from the us-east-1 region
+data "aws_acm_certificate" "cdn" {
+  count    = local.has_domain ? 1 : 0
+  provider = aws.us_east_1
+  domain   = var.cert_domain
+  statuses = ["ISSUED"]
+}
+
+data "aws_route53_zone" "cdn" {
+  count = local.has_domain ? 1 : 0
+  name  = var.hosted_zone_domain
+}
+
+resource "aws_route53_record" "cdn" {
+  count   = local.has_domain ? 1 : 0
+  name    = var.domain
+  type    = "A"
+  zone_id = data.aws_route53_zone.cdn[0].zone_id
+
+  alias {
+    name                   = aws_cloudfront_distribution.cdn.domain_name
+    zone_id                = aws_cloudfront_distribution.cdn.hosted_zone_id
+    evaluate_target_health = false
+  }
+}
@@ -0,0 +1,7 @@
+output "distribution_id" {
+  value = aws_cloudfront_distribution.cdn.id
+}
+
+output "url" {
+  value = "https://${var.domain != "" ? var.domain : aws_cloudfront_distribution.cdn.domain_name}"
+}
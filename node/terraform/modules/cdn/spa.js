@@ -0,0 +1,10 @@
+// rewrites paths without file extension to index.html so that client side
+// routing of the single page application works on page reload
+function handler(event) {
+  var request = event.request;
+  var name = request.uri.split("/").pop();
+  if (name.indexOf(".") === -1) {
+    request.uri = "/index.html";
+  }
+  return request;
+}
@@ -0,0 +1,42 @@
+variable "naming_template" {
+  type = string
+}
+
+variable "public_origin" {
+  type    = string
+  default = ""
+}
+
+variable "api_origin" {
+  type = string
+}
+
+variable "routes" {
+  type    = list(string)
+  default = []
+}
+
+variable "spa" {
+  type    = bool
+  default = false
+}
+
+variable "price_class" {
+  type    = string
+  default = "PriceClass_100"
+}
+
+variable "domain" {
+  type    = string
+  default = ""
+}
+
+variable "cert_domain" {
+  type    = string
+  default = ""
+}
+
+variable "hosted_zone_domain" {
+  type    = string
+  default = ""
+}
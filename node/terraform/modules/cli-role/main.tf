@@ -25,6 +25,16 @@ data "aws_iam_policy_document" "cli_role" {
     actions   = ["s3:PutObject"]
     resources = ["arn:aws:s3:::*-${var.suffix}/*"]
   }
+  statement {
+    effect    = "Allow"
+    actions   = ["s3:DeleteObject"]
+    resources = ["arn:aws:s3:::*-public-${var.suffix}/*"]
+  }
+  statement {
+    effect    = "Allow"
+    actions   = ["cloudfront:CreateInvalidation"]
+    resources = ["arn:aws:cloudfront::*:distribution/*"]
+  }
   statement {
     effect = "Allow"
     actions = [
@@ -36,6 +46,21 @@ data "aws_iam_policy_document" "cli_role" {
       "arn:aws:logs:*:*:log-group:*-${var.suffix}:log-stream:*",
     ]
   }
+  statement {
+    effect    = "Allow"
+    actions   = ["cloudwatch:GetMetricData", "ce:GetCostAndUsage"]
+    resources = ["*"]
+  }
+  statement {
+    effect    = "Allow"
+    actions   = ["lambda:GetFunction"]
+    resources = ["arn:aws:lambda:*:*:function:*-${var.suffix}"]
+  }
+  statement {
+    effect    = "Allow"
+    actions   = ["s3:GetObject"]
+    resources = ["arn:aws:s3:::*-${var.suffix}/*"]
+  }
 }
 
 resource "aws_iam_role_policy" "cli_role" {
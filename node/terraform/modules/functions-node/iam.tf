@@ -1,4 +1,14 @@
 data "aws_iam_policy_document" "deploy" {
+  statement {
+    effect = "Allow"
+    actions = [
+      "dynamodb:DescribeTable",
+      "dynamodb:PutItem",
+    ]
+    resources = [
+      "arn:aws:dynamodb:*:*:table/mantil-kv-${var.suffix}",
+    ]
+  }
   statement {
     effect = "Allow"
     actions = [
@@ -162,6 +172,37 @@ data "aws_iam_policy_document" "deploy" {
       "arn:aws:events:*:*:rule/*-${var.suffix}",
     ]
   }
+  statement {
+    effect = "Allow"
+    actions = [
+      "cloudwatch:PutMetricAlarm",
+      "cloudwatch:DescribeAlarms",
+      "cloudwatch:DeleteAlarms",
+      "cloudwatch:TagResource",
+      "cloudwatch:ListTagsForResource",
+    ]
+    resources = [
+      "arn:aws:cloudwatch:*:*:alarm:*-${var.suffix}",
+    ]
+  }
+  statement {
+    effect = "Allow"
+    actions = [
+      "sns:CreateTopic",
+      "sns:DeleteTopic",
+      "sns:GetTopicAttributes",
+      "sns:SetTopicAttributes",
+      "sns:TagResource",
+      "sns:ListTagsForResource",
+      "sns:Subscribe",
+      "sns:Unsubscribe",
+      "sns:GetSubscriptionAttributes",
+    ]
+    resources = [
+      "arn:aws:sns:*:*:*-${var.suffix}",
+      "arn:aws:sns:*:*:*-${var.suffix}:*",
+    ]
+  }
   statement {
     effect = "Allow"
     actions = [
@@ -187,9 +228,43 @@ data "aws_iam_policy_document" "deploy" {
       "*",
     ]
   }
+  statement {
+    effect = "Allow"
+    actions = [
+      "cloudfront:CreateDistribution",
+      "cloudfront:UpdateDistribution",
+      "cloudfront:GetDistribution",
+      "cloudfront:DeleteDistribution",
+      "cloudfront:TagResource",
+      "cloudfront:ListTagsForResource",
+      "cloudfront:ListCachePolicies",
+      "cloudfront:GetCachePolicy",
+      "cloudfront:ListOriginRequestPolicies",
+      "cloudfront:GetOriginRequestPolicy",
+      "cloudfront:CreateFunction",
+      "cloudfront:UpdateFunction",
+      "cloudfront:PublishFunction",
+      "cloudfront:DescribeFunction",
+      "cloudfront:GetFunction",
+      "cloudfront:DeleteFunction",
+    ]
+    resources = [
+      "*",
+    ]
+  }
 }
 
 data "aws_iam_policy_document" "security" {
+  statement {
+    effect = "Allow"
+    actions = [
+      "dynamodb:DescribeTable",
+      "dynamodb:PutItem",
+    ]
+    resources = [
+      "arn:aws:dynamodb:*:*:table/mantil-kv-${var.suffix}",
+    ]
+  }
   statement {
     effect = "Allow"
     actions = [
@@ -211,6 +286,18 @@ data "aws_iam_policy_document" "security" {
 }
 
 data "aws_iam_policy_document" "destroy" {
+  statement {
+    effect = "Allow"
+    actions = [
+      "dynamodb:DescribeTable",
+      "dynamodb:PutItem",
+      "dynamodb:Query",
+      "dynamodb:DeleteItem",
+    ]
+    resources = [
+      "arn:aws:dynamodb:*:*:table/mantil-kv-${var.suffix}",
+    ]
+  }
   statement {
     effect = "Allow"
     actions = [
@@ -341,6 +428,37 @@ data "aws_iam_policy_document" "destroy" {
       "arn:aws:events:*:*:rule/*-${var.suffix}",
     ]
   }
+  statement {
+    effect = "Allow"
+    actions = [
+      "cloudwatch:PutMetricAlarm",
+      "cloudwatch:DescribeAlarms",
+      "cloudwatch:DeleteAlarms",
+      "cloudwatch:TagResource",
+      "cloudwatch:ListTagsForResource",
+    ]
+    resources = [
+      "arn:aws:cloudwatch:*:*:alarm:*-${var.suffix}",
+    ]
+  }
+  statement {
+    effect = "Allow"
+    actions = [
+      "sns:CreateTopic",
+      "sns:DeleteTopic",
+      "sns:GetTopicAttributes",
+      "sns:SetTopicAttributes",
+      "sns:TagResource",
+      "sns:ListTagsForResource",
+      "sns:Subscribe",
+      "sns:Unsubscribe",
+      "sns:GetSubscriptionAttributes",
+    ]
+    resources = [
+      "arn:aws:sns:*:*:*-${var.suffix}",
+      "arn:aws:sns:*:*:*-${var.suffix}:*",
+    ]
+  }
   statement {
     effect = "Allow"
     actions = [
@@ -353,6 +471,30 @@ data "aws_iam_policy_document" "destroy" {
       "*",
     ]
   }
+  statement {
+    effect = "Allow"
+    actions = [
+      "cloudfront:CreateDistribution",
+      "cloudfront:UpdateDistribution",
+      "cloudfront:GetDistribution",
+      "cloudfront:DeleteDistribution",
+      "cloudfront:TagResource",
+      "cloudfront:ListTagsForResource",
+      "cloudfront:ListCachePolicies",
+      "cloudfront:GetCachePolicy",
+      "cloudfront:ListOriginRequestPolicies",
+      "cloudfront:GetOriginRequestPolicy",
+      "cloudfront:CreateFunction",
+      "cloudfront:UpdateFunction",
+      "cloudfront:PublishFunction",
+      "cloudfront:DescribeFunction",
+      "cloudfront:GetFunction",
+      "cloudfront:DeleteFunction",
+    ]
+    resources = [
+      "*",
+    ]
+  }
 }
 
 data "aws_iam_policy_document" "auth" {
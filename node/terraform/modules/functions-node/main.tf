@@ -8,6 +8,7 @@ locals {
       architecture = "arm64"
       layers       = ["arn:aws:lambda:${var.region}:477361877445:layer:terraform-1_3_1:1"]
       policy       = data.aws_iam_policy_document.deploy.json
+      env          = var.auth_env
     },
     "security" = {
       method       = "GET"
@@ -16,6 +17,7 @@ locals {
       timeout      = 900
       architecture = "arm64"
       policy       = data.aws_iam_policy_document.security.json
+      env          = var.auth_env
     },
     "destroy" = {
       method       = "POST"
@@ -25,6 +27,10 @@ locals {
       architecture = "arm64"
       layers       = ["arn:aws:lambda:${var.region}:477361877445:layer:terraform-1_3_1:1"]
       policy       = data.aws_iam_policy_document.destroy.json
+      env          = var.auth_env
+      # destroys expired stages
+      cron         = "0/15 * * * ? *"
+      cron_input   = jsonencode({ uri = "destroy.expired" })
     }
     "auth" = {
       method       = "POST"
@@ -52,4 +58,5 @@ module "functions" {
   functions       = local.functions
   s3_bucket       = var.functions_bucket
   naming_template = var.naming_template
+  log_retention_days = var.log_retention_days
 }
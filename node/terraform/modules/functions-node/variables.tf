@@ -24,3 +24,9 @@ variable "cli_role_arn" {
 variable "auth_env" {
   default = {}
 }
+
+variable "log_retention_days" {
+  type        = number
+  default     = 14
+  description = "Retention of the log groups in days."
+}
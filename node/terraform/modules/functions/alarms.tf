@@ -0,0 +1,63 @@
+locals {
+  # alarms of all functions keyed by alarm name
+  alarms = merge([for k, f in var.functions :
+    { for a in try(f.alarms, []) : a.name => merge(a, { function : k }) }
+  ]...)
+}
+
+resource "aws_sns_topic" "alarms" {
+  for_each = { for t in var.alarm_topics : t.key => t }
+  name     = format(var.naming_template, "alarms-${each.key}")
+}
+
+resource "aws_sns_topic_subscription" "alarms" {
+  for_each = { for s in flatten([for t in var.alarm_topics :
+    [for s in t.subscriptions : merge(s, { topic : t.key })]
+  ]) : "${s.topic}:${s.protocol}:${s.endpoint}" => s }
+  topic_arn = aws_sns_topic.alarms[each.value.topic].arn
+  protocol  = each.value.protocol
+  endpoint  = each.value.endpoint
+}
+
+resource "aws_cloudwatch_metric_alarm" "functions" {
+  for_each            = local.alarms
+  alarm_name          = each.key
+  comparison_operator = "GreaterThanThreshold"
+  threshold           = each.value.threshold
+  evaluation_periods  = each.value.evaluation_periods
+  treat_missing_data  = "notBreaching"
+  alarm_actions       = concat(each.value.topics, each.value.subscriptions_topic != "" ? [aws_sns_topic.alarms[each.value.subscriptions_topic].arn] : [])
+
+  namespace          = each.value.error_rate ? null : "AWS/Lambda"
+  metric_name        = each.value.error_rate ? null : each.value.metric_name
+  period             = each.value.error_rate ? null : each.value.period
+  statistic          = each.value.error_rate || each.value.statistic == "" ? null : each.value.statistic
+  extended_statistic = each.value.error_rate || each.value.extended_statistic == "" ? null : each.value.extended_statistic
+  dimensions         = each.value.error_rate ? null : { FunctionName = aws_lambda_function.functions[each.value.function].function_name }
+
+  # error rate in percents calculated from errors and invocations
+  dynamic "metric_query" {
+    for_each = each.value.error_rate ? ["Errors", "Invocations"] : []
+    content {
+      id = lower(metric_query.value)
+      metric {
+        namespace   = "AWS/Lambda"
+        metric_name = metric_query.value
+        period      = each.value.period
+        stat        = "Sum"
+        dimensions = {
+          FunctionName = aws_lambda_function.functions[each.value.function].function_name
+        }
+      }
+    }
+  }
+  dynamic "metric_query" {
+    for_each = each.value.error_rate ? ["error_rate"] : []
+    content {
+      id          = metric_query.value
+      expression  = "IF(invocations > 0, 100 * errors / invocations, 0)"
+      label       = "Error rate"
+      return_data = true
+    }
+  }
+}
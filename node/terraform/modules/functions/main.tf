@@ -13,6 +13,8 @@ locals {
       architecture : try(f.architecture, "arm64")    // default architecture is arm64
       env : length(try(f.env, {})) == 0 ? null : try(f.env, {})
       cron : try(f.cron, "")
+      cron_input : try(f.cron_input, null)           // event passed to the function on schedule
+      tracing : try(f.tracing, "") == "Active" ? "Active" : "PassThrough"
       layers : try(f.layers, [])
       policy : try(f.policy, jsonencode({
         Version = "2012-10-17"
@@ -51,12 +53,16 @@ resource "aws_lambda_function" "functions" {
       variables = environment.value
     }
   }
+
+  tracing_config {
+    mode = each.value.tracing
+  }
 }
 
 resource "aws_cloudwatch_log_group" "functions_log_groups" {
   for_each          = local.functions
   name              = "/aws/lambda/${each.value.function_name}"
-  retention_in_days = 14
+  retention_in_days = var.log_retention_days
 }
 
 resource "aws_cloudwatch_event_rule" "cron" {
@@ -69,6 +75,7 @@ resource "aws_cloudwatch_event_target" "cron" {
   for_each = aws_cloudwatch_event_rule.cron
   rule = each.value.name
   arn = aws_lambda_function.functions[each.key].arn
+  input = local.functions[each.key].cron_input
 }
 
 resource "aws_lambda_permission" "cron" {
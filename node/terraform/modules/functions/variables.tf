@@ -12,3 +12,14 @@ variable "s3_bucket" {
 variable "naming_template" {
   type = string
 }
+
+variable "alarm_topics" {
+  default     = []
+  description = "Topics with email addresses and https endpoints subscriptions notified by the functions alarms, keyed by the subscriptions set."
+}
+
+variable "log_retention_days" {
+  type        = number
+  default     = 14
+  description = "Retention of the log groups in days."
+}
@@ -8,7 +8,7 @@ resource "aws_apigatewayv2_api" "http" {
 
 resource "aws_cloudwatch_log_group" "http_access_logs" {
   name              = "/aws/vendedlogs/${format(var.naming_template, "http-access-logs")}"
-  retention_in_days = 14
+  retention_in_days = var.log_retention_days
 }
 
 resource "aws_apigatewayv2_stage" "http_default" {
@@ -29,7 +29,7 @@ resource "aws_apigatewayv2_stage" "http_default" {
 }
 
 resource "aws_apigatewayv2_route" "http" {
-  for_each           = local.integrations
+  for_each           = { for k, v in local.integrations : k => v if !v.proxy_only }
   api_id             = aws_apigatewayv2_api.http.id
   route_key          = "${each.value.method} ${each.value.route}"
   target             = "integrations/${aws_apigatewayv2_integration.http[each.key].id}"
@@ -38,7 +38,7 @@ resource "aws_apigatewayv2_route" "http" {
 }
 
 resource "aws_apigatewayv2_integration" "http" {
-  for_each               = local.integrations
+  for_each               = { for k, v in local.integrations : k => v if !v.proxy_only }
   api_id                 = aws_apigatewayv2_api.http.id
   payload_format_version = each.value.type == "AWS_PROXY" ? "2.0" : "1.0"
   integration_type       = each.value.type
@@ -47,7 +47,7 @@ resource "aws_apigatewayv2_integration" "http" {
 }
 
 resource "aws_apigatewayv2_route" "http_proxy" {
-  for_each           = local.integrations
+  for_each           = { for k, v in local.integrations : k => v if v.proxy }
   api_id             = aws_apigatewayv2_api.http.id
   route_key          = "${each.value.method} ${each.value.route == "/" ? "" : each.value.route}/{proxy+}"
   target             = "integrations/${aws_apigatewayv2_integration.http_proxy[each.key].id}"
@@ -56,7 +56,7 @@ resource "aws_apigatewayv2_route" "http_proxy" {
 }
 
 resource "aws_apigatewayv2_integration" "http_proxy" {
-  for_each               = local.integrations
+  for_each               = { for k, v in local.integrations : k => v if v.proxy }
   api_id                 = aws_apigatewayv2_api.http.id
   payload_format_version = each.value.type == "AWS_PROXY" ? "2.0" : "1.0"
   integration_type       = each.value.type
@@ -88,7 +88,8 @@ resource "aws_apigatewayv2_deployment" "http" {
 }
 
 resource "aws_lambda_permission" "api_gateway_invoke" {
-  for_each      = { for k, v in local.integrations : k => v if v.type == "AWS_PROXY" }
+  # method routes are served by the same lambda function as the proxy route
+  for_each      = { for k, v in local.integrations : k => v if v.type == "AWS_PROXY" && v.proxy }
   function_name = each.value.lambda_name
   action        = "lambda:InvokeFunction"
   principal     = "apigateway.amazonaws.com"
@@ -108,7 +109,8 @@ resource "aws_apigatewayv2_authorizer" "http" {
   api_id                            = aws_apigatewayv2_api.http.id
   authorizer_type                   = "REQUEST"
   authorizer_uri                    = var.authorizer.invoke_arn
-  identity_sources                  = ["$request.header.${var.authorizer.authorization_header}"]
+  # route is part of the cache key, tokens could be restricted to some functions
+  identity_sources                  = ["$request.header.${var.authorizer.authorization_header}", "$context.routeKey"]
   authorizer_payload_format_version = "2.0"
   name                              = format(var.naming_template, "http-authorizer")
   authorizer_result_ttl_in_seconds  = 300
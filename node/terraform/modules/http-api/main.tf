@@ -1,8 +1,11 @@
 locals {
-  integrations = { for value in var.integrations : "${value.route}" => merge(
+  # routes without proxy are keyed by method, same path can have multiple methods
+  integrations = { for value in var.integrations : (coalesce(value.proxy, true) ? value.route : "${value.method} ${value.route}") => merge(
     value,
     {
       enable_auth : var.authorizer != null && coalesce(value.enable_auth, false),
+      proxy : coalesce(value.proxy, true),
+      proxy_only : coalesce(value.proxy_only, false),
     }
   ) }
 }
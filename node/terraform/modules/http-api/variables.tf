@@ -11,6 +11,10 @@ variable "integrations" {
     uri                = string
     lambda_name        = optional(string)
     enable_auth        = optional(bool)
+    # creates {proxy+} route for the paths under the route
+    proxy              = optional(bool)
+    # creates only {proxy+} route, route itself is served by another integration
+    proxy_only         = optional(bool)
   }))
   default = []
 }
@@ -28,3 +32,9 @@ variable "domain" {
   type    = string
   default = ""
 }
+
+variable "log_retention_days" {
+  type        = number
+  default     = 14
+  description = "Retention of the log groups in days."
+}
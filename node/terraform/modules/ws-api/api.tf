@@ -7,7 +7,7 @@ resource "aws_apigatewayv2_api" "ws" {
 
 resource "aws_cloudwatch_log_group" "ws_access_logs" {
   name              = "/aws/vendedlogs/${format(var.naming_template, "ws-access-logs")}"
-  retention_in_days = 14
+  retention_in_days = var.log_retention_days
 }
 
 resource "aws_apigatewayv2_stage" "ws_default" {
@@ -81,11 +81,15 @@ resource "aws_lambda_function" "ws_handler" {
   environment {
     variables = local.ws_env
   }
+
+  tracing_config {
+    mode = var.tracing ? "Active" : "PassThrough"
+  }
 }
 
 resource "aws_cloudwatch_log_group" "ws_handler_log_group" {
   name              = "/aws/lambda/${local.ws_handler.name}"
-  retention_in_days = 14
+  retention_in_days = var.log_retention_days
 }
 
 resource "aws_lambda_permission" "ws_handler_api_gateway_invoke" {
@@ -111,11 +115,15 @@ resource "aws_lambda_function" "ws_forwarder" {
   environment {
     variables = local.ws_env
   }
+
+  tracing_config {
+    mode = var.tracing ? "Active" : "PassThrough"
+  }
 }
 
 resource "aws_cloudwatch_log_group" "ws_forwarder_log_group" {
   name              = "/aws/lambda/${local.ws_forwarder.name}"
-  retention_in_days = 14
+  retention_in_days = var.log_retention_days
 }
 
 resource "aws_dynamodb_table" "table" {
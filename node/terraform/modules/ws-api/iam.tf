@@ -44,6 +44,17 @@ data "aws_iam_policy_document" "ws_handler" {
       "arn:aws:logs:*:*:log-group:*-${var.suffix}:log-stream:*",
     ]
   }
+  dynamic "statement" {
+    for_each = var.tracing ? [1] : []
+    content {
+      effect = "Allow"
+      actions = [
+        "xray:PutTraceSegments",
+        "xray:PutTelemetryRecords",
+      ]
+      resources = ["*"]
+    }
+  }
 }
 
 resource "aws_iam_role_policy" "ws_handler" {
@@ -98,6 +109,17 @@ data "aws_iam_policy_document" "ws_forwarder" {
       "arn:aws:logs:*:*:log-group:*-${var.suffix}:log-stream:*",
     ]
   }
+  dynamic "statement" {
+    for_each = var.tracing ? [1] : []
+    content {
+      effect = "Allow"
+      actions = [
+        "xray:PutTraceSegments",
+        "xray:PutTelemetryRecords",
+      ]
+      resources = ["*"]
+    }
+  }
 }
 
 resource "aws_iam_role_policy" "ws_forwarder" {
@@ -32,3 +32,15 @@ variable "domain" {
   type    = string
   default = ""
 }
+
+variable "tracing" {
+  type        = bool
+  default     = false
+  description = "Enables X-Ray tracing of the api lambda functions."
+}
+
+variable "log_retention_days" {
+  type        = number
+  default     = 14
+  description = "Retention of the log groups in days."
+}
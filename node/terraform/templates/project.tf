@@ -17,10 +17,52 @@ locals {
         {{- end}}
       }
       cron = "{{.Cron}}"
+      tracing = "{{.TracingMode}}"
       enable_auth = {{.EnableAuth}}
+      root_routed = {{.RootRouted}}
+      routes = [
+        {{- range .Routes}}
+        {
+          method = "{{.Method}}"
+          path = "{{.Path}}"
+          enable_auth = {{.EnableAuth}}
+        },
+        {{- end}}
+      ]
+      alarms = [
+        {{- range .Alarms}}
+        {
+          name = "{{.Name}}"
+          metric_name = "{{.MetricName}}"
+          statistic = "{{.Statistic}}"
+          extended_statistic = "{{.ExtendedStatistic}}"
+          error_rate = {{.ErrorRate}}
+          threshold = {{.Threshold}}
+          period = {{.Period}}
+          evaluation_periods = {{.EvaluationPeriods}}
+          topics = [{{range $i, $t := .Topics}}{{if $i}}, {{end}}"{{$t}}"{{end}}]
+          subscriptions_topic = "{{.SubscriptionsTopic}}"
+        },
+        {{- end}}
+      ]
     }
     {{- end}}
   }
+  alarm_topics = [
+    {{- range .AlarmTopics}}
+    {
+      key = "{{.Key}}"
+      subscriptions = [
+        {{- range .Subscriptions}}
+        {
+          protocol = "{{.Protocol}}"
+          endpoint = "{{.Endpoint}}"
+        },
+        {{- end}}
+      ]
+    },
+    {{- end}}
+  ]
   ws_env = {
     {{- range $key, $value := .WsEnv}}
     {{$key}} = "{{$value}}"
@@ -56,12 +98,31 @@ provider "aws" {
     }
   }
 }
+{{- if .CDN.Enabled}}
+
+# used by the cdn resources which must be in us-east-1
+provider "aws" {
+  alias  = "us_east_1"
+  region = "us-east-1"
+  skip_get_ec2_platforms = true
+
+  default_tags {
+    tags = {
+      {{- range $key, $value := .ResourceTags}}
+      {{$key}} = "{{$value}}"
+      {{- end}}
+    }
+  }
+}
+{{- end}}
 
 module "functions" {
   source     = "../../modules/functions"
   functions  = local.functions
   s3_bucket  = local.project_bucket
   naming_template = "{{.NamingTemplate}}"
+  alarm_topics = local.alarm_topics
+  log_retention_days = {{.LogRetentionDays}}
 }
 
 module "public_site" {
@@ -87,8 +148,23 @@ module "api" {
       uri : f.invoke_arn
       lambda_name : f.arn,
       enable_auth: local.functions[f.name].enable_auth,
+      proxy_only: local.functions[f.name].root_routed,
+    }
+  ],
+  # routes of the api methods with their own configuration
+  flatten([ for f in module.functions.functions : [
+    for r in local.functions[f.name].routes :
+    {
+      type : "AWS_PROXY"
+      method : r.method
+      integration_method : "POST"
+      route : r.path
+      uri : f.invoke_arn
+      lambda_name : f.arn,
+      enable_auth: r.enable_auth,
+      proxy: false,
     }
-  ]{{if .HasPublic}},
+  ]]){{if .HasPublic}},
   [
     {
       type : "HTTP_PROXY"
@@ -108,7 +184,28 @@ module "api" {
     }
   }
   custom_domain = local.custom_domain
+  tracing = {{.Tracing}}
+  log_retention_days = {{.LogRetentionDays}}
 }
+{{- if .CDN.Enabled}}
+
+module "cdn" {
+  source = "../../modules/cdn"
+  providers = {
+    aws           = aws
+    aws.us_east_1 = aws.us_east_1
+  }
+  naming_template = "{{.NamingTemplate}}"
+  public_origin = {{if .HasPublic}}module.public_site[0].url{{else}}""{{end}}
+  api_origin = trimprefix(module.api.http_url, "https://")
+  routes = [for f in module.functions.functions : f.name]
+  spa = {{.CDN.SPA}}
+  price_class = "{{.CDN.PriceClass}}"
+  domain = "{{.CDN.Domain}}"
+  cert_domain = "{{.CDN.CertDomain}}"
+  hosted_zone_domain = "{{.CDN.HostedZoneDomain}}"
+}
+{{- end}}
 
 output "url" {
   value = module.api.http_url
@@ -125,3 +222,11 @@ output "public_site_bucket" {
 output "ws_url" {
   value = module.api.ws_url
 }
+
+output "cdn_distribution_id" {
+  value = {{if .CDN.Enabled}}module.cdn.distribution_id{{else}}""{{end}}
+}
+
+output "cdn_url" {
+  value = {{if .CDN.Enabled}}module.cdn.url{{else}}""{{end}}
+}
@@ -42,6 +42,7 @@ module "functions" {
   cli_role_arn     = module.cli_role.arn
   naming_template  = "{{.NamingTemplate}}"
   auth_env         = local.auth_env
+  log_retention_days = {{.LogRetentionDays}}
 }
 
 
@@ -72,6 +73,7 @@ module "api" {
     authorization_header = "Authorization"
     env = local.auth_env
   }
+  log_retention_days = {{.LogRetentionDays}}
 }
 
 module "params" {
@@ -8,6 +8,7 @@ import (
 	stdlog "log"
 	"os"
 	"path"
+	"regexp"
 	"strings"
 	"text/template"
 
@@ -75,6 +76,8 @@ type SetupTemplateData struct {
 	GithubUser      string
 	PublicKey       string
 	PrivateKey      string
+	// retention of the node log groups
+	LogRetentionDays int
 }
 
 // Prepare setup templates
@@ -111,6 +114,43 @@ func (t *Terraform) Destroy() error {
 	return t.initPlanApply(true)
 }
 
+// Plan returns changes which Create would make to the infrastructure
+// without applying them
+func (t *Terraform) Plan() ([]dto.PlannedChange, error) {
+	t.path = t.createPath
+	if err := t.init(); err != nil {
+		return nil, err
+	}
+	var lines []string
+	args := []string{"terraform", "plan", "-no-color", "-input=false", "-compact-warnings"}
+	opt := t.shellExecOpts(logPrefix, args)
+	logger := opt.Logger
+	opt.Logger = func(format string, v ...interface{}) {
+		lines = append(lines, fmt.Sprintf(format, v...))
+		logger(format, v...)
+	}
+	if err := t.shellExec(opt); err != nil {
+		return nil, err
+	}
+	return parsePlan(lines), nil
+}
+
+// matches plan lines like:
+// # module.functions.aws_lambda_function.functions["ping"] will be updated in-place
+var planChangeRegex = regexp.MustCompile(`^\s*# (.+?) (?:will|must) be (.+)$`)
+
+func parsePlan(lines []string) []dto.PlannedChange {
+	var changes []dto.PlannedChange
+	for _, l := range lines {
+		m := planChangeRegex.FindStringSubmatch(l)
+		if m == nil {
+			continue
+		}
+		changes = append(changes, dto.PlannedChange{Address: m[1], Action: m[2]})
+	}
+	return changes
+}
+
 // path to create/main.tf
 func (t *Terraform) CreateTf() string {
 	return path.Join(t.createPath, mainTf)
@@ -36,10 +36,11 @@ func TestRenderSetup(t *testing.T) {
 			"tag1": "value1",
 			"tag2": "value2",
 		},
-		NamingTemplate: "prefix-%s-suffix",
-		PublicKey:      "public_key",
-		PrivateKey:     "private_key",
-		GithubUser:     "github_user",
+		NamingTemplate:   "prefix-%s-suffix",
+		PublicKey:        "public_key",
+		PrivateKey:       "private_key",
+		GithubUser:       "github_user",
+		LogRetentionDays: 30,
 	}
 	tf, err := renderSetup(data)
 	require.NoError(t, err)
@@ -65,6 +66,31 @@ func TestRenderProject(t *testing.T) {
 				Name:  "function1",
 				S3Key: "function1.zip",
 				Cron:  "* * * * ? *",
+				Routes: []dto.Route{
+					{Method: "GET", Path: "/function1/{id}", EnableAuth: true},
+					{Method: "ANY", Path: "/function1"},
+				},
+				RootRouted:  true,
+				TracingMode: "Active",
+				Alarms: []dto.Alarm{
+					{
+						Name:               "prefix-function1-errors-suffix",
+						ErrorRate:          true,
+						Threshold:          1,
+						Period:             300,
+						EvaluationPeriods:  1,
+						SubscriptionsTopic: "5f1e6c2a",
+					},
+					{
+						Name:              "prefix-function1-slow-suffix",
+						MetricName:        "Duration",
+						ExtendedStatistic: "p99",
+						Threshold:         2500.5,
+						Period:            60,
+						EvaluationPeriods: 3,
+						Topics:            []string{"arn:aws:sns:aws-region:123456789012:ops"},
+					},
+				},
 			},
 			{
 				Name:  "function2",
@@ -88,6 +114,24 @@ func TestRenderProject(t *testing.T) {
 			HttpSubdomain:    "",
 			WsSubdomain:      "ws",
 		},
+		CDN: dto.CDN{
+			Enabled:          true,
+			Domain:           "example.com",
+			CertDomain:       "example.com",
+			HostedZoneDomain: "example.com",
+			SPA:              true,
+			PriceClass:       "PriceClass_100",
+		},
+		AlarmTopics: []dto.AlarmTopic{
+			{
+				Key: "5f1e6c2a",
+				Subscriptions: []dto.AlarmSubscription{
+					{Protocol: "email", Endpoint: "ops@example.com"},
+				},
+			},
+		},
+		Tracing:          true,
+		LogRetentionDays: 90,
 	}
 	tf, err := renderProject(data)
 	require.NoError(t, err)
@@ -97,3 +141,21 @@ func TestRenderProject(t *testing.T) {
 	testutil.EqualFiles(t, "testdata/project.tf", "/tmp/mantil/my-project-my-stage/create/main.tf", *update)
 	testutil.EqualFiles(t, "testdata/project-destroy.tf", "/tmp/mantil/my-project-my-stage/destroy/main.tf", *update)
 }
+
+func TestParsePlan(t *testing.T) {
+	lines := []string{
+		"Terraform will perform the following actions:",
+		`  # module.functions.aws_lambda_function.functions["ping"] will be updated in-place`,
+		`  ~ resource "aws_lambda_function" "functions" {`,
+		`  # module.api.aws_apigatewayv2_route.http["ANY /ping/{proxy+}"] will be created`,
+		`  # module.functions.aws_cloudwatch_event_rule.cron["ping"] must be replaced`,
+		"Plan: 1 to add, 1 to change, 0 to destroy.",
+	}
+	changes := parsePlan(lines)
+	require.Equal(t, []dto.PlannedChange{
+		{Address: `module.functions.aws_lambda_function.functions["ping"]`, Action: "updated in-place"},
+		{Address: `module.api.aws_apigatewayv2_route.http["ANY /ping/{proxy+}"]`, Action: "created"},
+		{Address: `module.functions.aws_cloudwatch_event_rule.cron["ping"]`, Action: "replaced"},
+	}, changes)
+	require.Empty(t, parsePlan([]string{"No changes. Your infrastructure matches the configuration."}))
+}
@@ -13,7 +13,47 @@ locals {
       env = {
       }
       cron = "* * * * ? *"
+      tracing = "Active"
       enable_auth = false
+      root_routed = true
+      routes = [
+        {
+          method = "GET"
+          path = "/function1/{id}"
+          enable_auth = true
+        },
+        {
+          method = "ANY"
+          path = "/function1"
+          enable_auth = false
+        },
+      ]
+      alarms = [
+        {
+          name = "prefix-function1-errors-suffix"
+          metric_name = ""
+          statistic = ""
+          extended_statistic = ""
+          error_rate = true
+          threshold = 1
+          period = 300
+          evaluation_periods = 1
+          topics = []
+          subscriptions_topic = "5f1e6c2a"
+        },
+        {
+          name = "prefix-function1-slow-suffix"
+          metric_name = "Duration"
+          statistic = ""
+          extended_statistic = "p99"
+          error_rate = false
+          threshold = 2500.5
+          period = 60
+          evaluation_periods = 3
+          topics = ["arn:aws:sns:aws-region:123456789012:ops"]
+          subscriptions_topic = ""
+        },
+      ]
     }
     function2 = {
       s3_key = "function2.zip"
@@ -24,9 +64,26 @@ locals {
       env = {
       }
       cron = ""
+      tracing = ""
       enable_auth = false
+      root_routed = false
+      routes = [
+      ]
+      alarms = [
+      ]
     }
   }
+  alarm_topics = [
+    {
+      key = "5f1e6c2a"
+      subscriptions = [
+        {
+          protocol = "email"
+          endpoint = "ops@example.com"
+        },
+      ]
+    },
+  ]
   ws_env = {
     key = "value"
   }
@@ -60,11 +117,27 @@ provider "aws" {
   }
 }
 
+# used by the cdn resources which must be in us-east-1
+provider "aws" {
+  alias  = "us_east_1"
+  region = "us-east-1"
+  skip_get_ec2_platforms = true
+
+  default_tags {
+    tags = {
+      tag1 = "value1"
+      tag2 = "value2"
+    }
+  }
+}
+
 module "functions" {
   source     = "../../modules/functions"
   functions  = local.functions
   s3_bucket  = local.project_bucket
   naming_template = "prefix-%s-suffix"
+  alarm_topics = local.alarm_topics
+  log_retention_days = 90
 }
 
 module "public_site" {
@@ -90,8 +163,23 @@ module "api" {
       uri : f.invoke_arn
       lambda_name : f.arn,
       enable_auth: local.functions[f.name].enable_auth,
+      proxy_only: local.functions[f.name].root_routed,
     }
   ],
+  # routes of the api methods with their own configuration
+  flatten([ for f in module.functions.functions : [
+    for r in local.functions[f.name].routes :
+    {
+      type : "AWS_PROXY"
+      method : r.method
+      integration_method : "POST"
+      route : r.path
+      uri : f.invoke_arn
+      lambda_name : f.arn,
+      enable_auth: r.enable_auth,
+      proxy: false,
+    }
+  ]]),
   [
     {
       type : "HTTP_PROXY"
@@ -108,6 +196,25 @@ module "api" {
     }
   }
   custom_domain = local.custom_domain
+  tracing = true
+  log_retention_days = 90
+}
+
+module "cdn" {
+  source = "../../modules/cdn"
+  providers = {
+    aws           = aws
+    aws.us_east_1 = aws.us_east_1
+  }
+  naming_template = "prefix-%s-suffix"
+  public_origin = module.public_site[0].url
+  api_origin = trimprefix(module.api.http_url, "https://")
+  routes = [for f in module.functions.functions : f.name]
+  spa = true
+  price_class = "PriceClass_100"
+  domain = "example.com"
+  cert_domain = "example.com"
+  hosted_zone_domain = "example.com"
 }
 
 output "url" {
@@ -125,3 +232,11 @@ output "public_site_bucket" {
 output "ws_url" {
   value = module.api.ws_url
 }
+
+output "cdn_distribution_id" {
+  value = module.cdn.distribution_id
+}
+
+output "cdn_url" {
+  value = module.cdn.url
+}
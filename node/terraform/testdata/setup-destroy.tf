@@ -10,3 +10,10 @@ provider "aws" {
   region                 = "aws-region"
   skip_get_ec2_platforms = true
 }
+
+# used by the cdn resources which must be in us-east-1
+provider "aws" {
+  alias                  = "us_east_1"
+  region                 = "us-east-1"
+  skip_get_ec2_platforms = true
+}
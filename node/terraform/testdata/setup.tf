@@ -39,6 +39,7 @@ module "functions" {
   cli_role_arn     = module.cli_role.arn
   naming_template  = "prefix-%s-suffix"
   auth_env         = local.auth_env
+  log_retention_days = 30
 }
 
 
@@ -69,6 +70,7 @@ module "api" {
     authorization_header = "Authorization"
     env = local.auth_env
   }
+  log_retention_days = 30
 }
 
 module "params" {
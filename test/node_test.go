@@ -25,10 +25,10 @@ func testNodeResources(t *testing.T, ws *domain.Workspace) {
 		}
 		for _, rs := range node.Resources() {
 			if rs.Type == domain.AwsResourceLambda {
-				tags, err := awsCli.Lambda().Info(rs.AWSName)
+				info, err := awsCli.Lambda().Info(rs.AWSName)
 				require.NoError(t, err)
-				require.Equal(t, tags[domain.TagKey], node.ID)
-				require.Equal(t, tags[domain.TagWorkspace], ws.ID)
+				require.Equal(t, info.Tags[domain.TagKey], node.ID)
+				require.Equal(t, info.Tags[domain.TagWorkspace], ws.ID)
 				//t.Logf("lambda: %s, tags: %v", rs.AWSName, tags)
 			}
 		}